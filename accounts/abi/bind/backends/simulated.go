@@ -455,6 +455,7 @@ func (m callmsg) GasPrice() *big.Int                   { return m.CallMsg.GasPri
 func (m callmsg) FeeCurrency() *common.Address         { return m.CallMsg.FeeCurrency }
 func (m callmsg) GatewayFeeRecipient() *common.Address { return m.CallMsg.GatewayFeeRecipient }
 func (m callmsg) GatewayFee() *big.Int                 { return m.CallMsg.GatewayFee }
+func (m callmsg) FeePayer() *common.Address            { return nil }
 func (m callmsg) Gas() uint64                          { return m.CallMsg.Gas }
 func (m callmsg) Value() *big.Int                      { return m.CallMsg.Value }
 func (m callmsg) Data() []byte                         { return m.CallMsg.Data }
@@ -19,12 +19,16 @@ package main
 
 import (
 	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"net"
 	"os"
 
 	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/forkid"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
@@ -50,6 +54,12 @@ func main() {
 		vmodule          = flag.String("vmodule", "", "log verbosity pattern")
 		networkId        = flag.Uint64("networkid", 0, "network ID")
 		pingIPFromPacket = flag.Bool("ping-ip-from-packet", false, "Has the discovery protocol use the IP address given by a ping packet")
+		genesisFile      = flag.String("genesis", "", "genesis JSON file of the network, enables fork ID checks of listed and served nodes")
+		nodeDBPath       = flag.String("nodedb", "", "path of the node database (in memory if empty)")
+		ipRateLimit      = flag.Float64("ratelimit.ip", 0, "maximum discovery packets per second from a single IP (0 = unlimited)")
+		subnetRateLimit  = flag.Float64("ratelimit.subnet", 0, "maximum discovery packets per second from a single /24 (IPv4) or /64 (IPv6) subnet (0 = unlimited)")
+		dnsDomain        = flag.String("dns.domain", "", "domain of the published DNS discovery node list (list disabled if empty)")
+		httpAddr         = flag.String("http", "", "listen address of the HTTP statistics endpoint (disabled if empty)")
 
 		nodeKey *ecdsa.PrivateKey
 		err     error
@@ -110,14 +120,22 @@ func main() {
 		}
 	}
 
+	var filter forkid.Filter
+	if *genesisFile != "" {
+		if filter, err = loadForkFilter(*genesisFile); err != nil {
+			utils.Fatalf("-genesis: %v", err)
+		}
+	}
+
 	addr, err := net.ResolveUDPAddr("udp", *listenAddr)
 	if err != nil {
 		utils.Fatalf("-ResolveUDPAddr: %v", err)
 	}
-	conn, err := net.ListenUDP("udp", addr)
+	udpConn, err := net.ListenUDP("udp", addr)
 	if err != nil {
 		utils.Fatalf("-ListenUDP: %v", err)
 	}
+	conn := newRateLimitedConn(udpConn, *ipRateLimit, *subnetRateLimit)
 
 	realaddr := conn.LocalAddr().(*net.UDPAddr)
 	if natm != nil {
@@ -135,14 +153,21 @@ func main() {
 	// and then v5 if v4 decoding fails, following the same pattern as full
 	// nodes that use v4 and v5:
 	// https://github.com/celo-org/celo-blockchain/blob/7fbd6f3574f1c1c1e657c152fc63fb771adab3af/p2p/server.go#L588
+	// The shared connection is fed the packets read by v4 through the rate
+	// limited connection, so both protocols are rate limited.
 	var unhandled chan discover.ReadPacket
 	var sconn *p2p.SharedUDPConn
+	var disc *discover.UDPv4
+	var list *nodeList
 	if *runv4 {
 		if *runv5 {
 			unhandled = make(chan discover.ReadPacket, 100)
-			sconn = &p2p.SharedUDPConn{UDPConn: conn, Unhandled: unhandled}
+			sconn = &p2p.SharedUDPConn{UDPConn: conn.UDPConn, Unhandled: unhandled}
+		}
+		db, err := enode.OpenDB(*nodeDBPath)
+		if err != nil {
+			utils.Fatalf("-nodedb: %v", err)
 		}
-		db, _ := enode.OpenDB("")
 		ln := enode.NewLocalNode(db, nodeKey, *networkId)
 		cfg := discover.Config{
 			PrivateKey:       nodeKey,
@@ -150,9 +175,19 @@ func main() {
 			PingIPFromPacket: *pingIPFromPacket,
 			Unhandled:        unhandled,
 		}
-		if _, err := discover.ListenUDP(conn, ln, cfg); err != nil {
+		// Only serve the nodes checked to be compatible when fork IDs are enforced
+		if filter != nil || *dnsDomain != "" {
+			list = newNodeList(filter, nodeKey, *dnsDomain)
+		}
+		if filter != nil {
+			cfg.NodeFilter = list.listed
+		}
+		if disc, err = discover.ListenUDP(conn, ln, cfg); err != nil {
 			utils.Fatalf("%v", err)
 		}
+		if list != nil {
+			go list.run(disc)
+		}
 	}
 
 	if *runv5 {
@@ -167,9 +202,34 @@ func main() {
 		}
 	}
 
+	if *httpAddr != "" {
+		server := &statsServer{conn: conn, disc: disc, list: list}
+		if err := server.start(*httpAddr); err != nil {
+			utils.Fatalf("-http: %v", err)
+		}
+	}
+
 	select {}
 }
 
+// loadForkFilter creates the fork ID filter of the network defined by a genesis file.
+func loadForkFilter(path string) (forkid.Filter, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	genesis := new(core.Genesis)
+	if err := json.NewDecoder(file).Decode(genesis); err != nil {
+		return nil, fmt.Errorf("invalid genesis file: %v", err)
+	}
+	if genesis.Config == nil {
+		return nil, errors.New("genesis has no chain configuration")
+	}
+	return forkid.NewStaticFilter(genesis.Config, genesis.ToBlock(nil).Hash()), nil
+}
+
 func printNotice(nodeKey *ecdsa.PublicKey, addr net.UDPAddr) {
 	if addr.IP.IsUnspecified() {
 		addr.IP = net.IP{127, 0, 0, 1}
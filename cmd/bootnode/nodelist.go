@@ -0,0 +1,206 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/forkid"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+	"github.com/ethereum/go-ethereum/p2p/dnsdisc"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	crawlInterval   = 100 * time.Millisecond // Delay between checking two crawled nodes
+	recheckInterval = 10 * time.Minute       // Minimum time before a listed node is checked again
+	maxNodeAge      = time.Hour              // Time after which an unchecked node is dropped from the list
+	publishInterval = time.Minute            // Interval between rebuilding the published tree
+)
+
+// listedNode is a node that passed the checks for inclusion in the node list.
+type listedNode struct {
+	node    *enode.Node
+	checked time.Time
+}
+
+// nodeList crawls the discovery network and maintains the list of nodes whose
+// ENR advertises a fork ID compatible with the network. The list restricts the
+// nodes served by discovery, and is published as a DNS discovery tree, signed
+// by the bootnode's key, if a domain is set.
+type nodeList struct {
+	disc   *discover.UDPv4
+	filter forkid.Filter // Fork ID filter, nil if fork IDs are not enforced
+	key    *ecdsa.PrivateKey
+	domain string // Domain of the published tree, empty if not published
+
+	lock     sync.RWMutex
+	nodes    map[enode.ID]*listedNode
+	seq      uint
+	url      string            // URL of the published tree
+	records  map[string]string // TXT records of the published tree
+	accepted uint64            // Number of nodes accepted into the list
+	rejected uint64            // Number of nodes rejected for their ENR
+}
+
+func newNodeList(filter forkid.Filter, key *ecdsa.PrivateKey, domain string) *nodeList {
+	return &nodeList{
+		filter: filter,
+		key:    key,
+		domain: domain,
+		nodes:  make(map[enode.ID]*listedNode),
+	}
+}
+
+// run crawls the network and publishes the list until the discovery table is closed.
+func (l *nodeList) run(disc *discover.UDPv4) {
+	l.disc = disc
+	it := disc.RandomNodes()
+	defer it.Close()
+
+	publish := time.NewTicker(publishInterval)
+	defer publish.Stop()
+
+	for it.Next() {
+		l.check(it.Node())
+
+		select {
+		case <-publish.C:
+			l.expire()
+			if l.domain != "" {
+				l.publish()
+			}
+		default:
+		}
+		time.Sleep(crawlInterval)
+	}
+}
+
+// listed reports whether the node is in the list, i.e. it was checked to be
+// compatible with the network recently.
+func (l *nodeList) listed(n *enode.Node) bool {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	_, ok := l.nodes[n.ID()]
+	return ok
+}
+
+// check requests the ENR of a crawled node and adds it to the list, or removes
+// it, depending on whether it is compatible with the network.
+func (l *nodeList) check(n *enode.Node) {
+	l.lock.RLock()
+	listed, ok := l.nodes[n.ID()]
+	l.lock.RUnlock()
+	if ok && time.Since(listed.checked) < recheckInterval {
+		return
+	}
+	record, err := l.disc.RequestENR(n)
+	accept := err == nil && l.compatible(record)
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if !accept {
+		log.Trace("Rejected crawled node", "id", n.ID(), "err", err)
+		delete(l.nodes, n.ID())
+		l.rejected++
+		return
+	}
+	if !ok {
+		l.accepted++
+	}
+	l.nodes[n.ID()] = &listedNode{node: record, checked: time.Now()}
+}
+
+// compatible reports whether the record advertises an acceptable fork ID.
+func (l *nodeList) compatible(n *enode.Node) bool {
+	if l.filter == nil {
+		return true
+	}
+	var eth struct {
+		ForkID forkid.ID
+		_      []rlp.RawValue `rlp:"tail"`
+	}
+	if n.Load(enr.WithEntry("eth", &eth)) != nil {
+		return false
+	}
+	return l.filter(eth.ForkID) == nil
+}
+
+// expire drops the nodes which weren't checked for too long.
+func (l *nodeList) expire() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	for id, listed := range l.nodes {
+		if time.Since(listed.checked) > maxNodeAge {
+			delete(l.nodes, id)
+		}
+	}
+}
+
+// publish rebuilds the signed tree out of the list.
+func (l *nodeList) publish() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	nodes := make([]*enode.Node, 0, len(l.nodes))
+	for _, listed := range l.nodes {
+		nodes = append(nodes, listed.node)
+	}
+	tree, err := dnsdisc.MakeTree(l.seq+1, nodes, nil)
+	if err != nil {
+		log.Error("Failed to build node list", "err", err)
+		return
+	}
+	url, err := tree.Sign(l.key, l.domain)
+	if err != nil {
+		log.Error("Failed to sign node list", "err", err)
+		return
+	}
+	l.seq++
+	l.url, l.records = url, tree.ToTXT(l.domain)
+	log.Debug("Published node list", "seq", l.seq, "nodes", len(nodes))
+}
+
+// nodeListStats is the summary of the node list served by the stats endpoint.
+type nodeListStats struct {
+	Nodes    int    `json:"nodes"`
+	Seq      uint   `json:"seq"`
+	Accepted uint64 `json:"accepted"`
+	Rejected uint64 `json:"rejected"`
+}
+
+func (l *nodeList) stats() nodeListStats {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	return nodeListStats{Nodes: len(l.nodes), Seq: l.seq, Accepted: l.accepted, Rejected: l.rejected}
+}
+
+// tree returns the URL and TXT records of the published tree.
+func (l *nodeList) tree() (string, map[string]string) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	return l.url, l.records
+}
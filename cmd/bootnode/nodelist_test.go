@@ -0,0 +1,129 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/forkid"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+var testForkID = forkid.ID{Hash: [4]byte{1, 2, 3, 4}}
+
+// testEthEntry is the eth entry of an ENR, advertising the node's fork ID.
+type testEthEntry struct {
+	ForkID forkid.ID
+	Tail   []rlp.RawValue `rlp:"tail"`
+}
+
+func (e testEthEntry) ENRKey() string { return "eth" }
+
+// testFilter accepts only testForkID.
+func testFilter(id forkid.ID) error {
+	if id != testForkID {
+		return errors.New("incompatible fork ID")
+	}
+	return nil
+}
+
+// newTestNode creates a signed node record, with an eth entry if id is set.
+func newTestNode(t *testing.T, id *forkid.ID) *enode.Node {
+	key, _ := crypto.GenerateKey()
+	var r enr.Record
+	r.Set(enr.IP(net.IP{127, 0, 0, 1}))
+	r.Set(enr.UDP(30303))
+	if id != nil {
+		r.Set(testEthEntry{ForkID: *id})
+	}
+	if err := enode.SignV4(&r, key); err != nil {
+		t.Fatalf("failed to sign record: %v", err)
+	}
+	n, err := enode.New(enode.ValidSchemes, &r)
+	if err != nil {
+		t.Fatalf("failed to create node: %v", err)
+	}
+	return n
+}
+
+// Tests that only nodes advertising a compatible fork ID are accepted.
+func TestNodeListCompatible(t *testing.T) {
+	other := forkid.ID{Hash: [4]byte{4, 3, 2, 1}}
+	var (
+		compatible   = newTestNode(t, &testForkID)
+		incompatible = newTestNode(t, &other)
+		missing      = newTestNode(t, nil)
+	)
+	list := newNodeList(testFilter, nil, "")
+	if !list.compatible(compatible) {
+		t.Error("compatible node rejected")
+	}
+	if list.compatible(incompatible) {
+		t.Error("incompatible node accepted")
+	}
+	if list.compatible(missing) {
+		t.Error("node without fork ID accepted")
+	}
+	// Without a filter all nodes are accepted
+	list = newNodeList(nil, nil, "")
+	if !list.compatible(incompatible) || !list.compatible(missing) {
+		t.Error("node rejected without filter")
+	}
+}
+
+// Tests that listed nodes expire and that the published tree contains the list.
+func TestNodeListPublish(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	list := newNodeList(testFilter, key, "nodes.example.org")
+
+	fresh, stale := newTestNode(t, &testForkID), newTestNode(t, &testForkID)
+	list.nodes[fresh.ID()] = &listedNode{node: fresh, checked: time.Now()}
+	list.nodes[stale.ID()] = &listedNode{node: stale, checked: time.Now().Add(-2 * maxNodeAge)}
+
+	list.expire()
+	if !list.listed(fresh) || list.listed(stale) {
+		t.Fatalf("listed nodes mismatch: fresh %v, stale %v", list.listed(fresh), list.listed(stale))
+	}
+	if url, _ := list.tree(); url != "" {
+		t.Fatalf("tree published early: %s", url)
+	}
+	list.publish()
+	url, records := list.tree()
+	if !strings.HasPrefix(url, "enrtree://") || !strings.HasSuffix(url, "@nodes.example.org") {
+		t.Fatalf("invalid tree URL: %s", url)
+	}
+	if _, ok := records["nodes.example.org"]; !ok {
+		t.Fatalf("tree root record missing: %v", records)
+	}
+	var found bool
+	for _, record := range records {
+		found = found || record == fresh.String()
+	}
+	if !found {
+		t.Fatalf("listed node missing from tree: %v", records)
+	}
+	if stats := list.stats(); stats.Nodes != 1 || stats.Seq != 1 {
+		t.Fatalf("stats mismatch: %+v", stats)
+	}
+}
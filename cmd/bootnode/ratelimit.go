@@ -0,0 +1,135 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// bucketExpiry is how long an idle rate limiting bucket is kept around.
+const bucketExpiry = time.Minute
+
+// tokenBucket allows a burst of packets, refilled at a constant rate.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter limits the rate of events per key.
+type rateLimiter struct {
+	rate    float64 // Events per second, zero for unlimited
+	burst   float64
+	buckets map[string]*tokenBucket
+	swept   time.Time
+}
+
+func newRateLimiter(rate float64) *rateLimiter {
+	return &rateLimiter{
+		rate:    rate,
+		burst:   2 * rate,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// allow reports whether an event for the given key is within the limit.
+func (l *rateLimiter) allow(key string, now time.Time) bool {
+	if l.rate == 0 {
+		return true
+	}
+	// Drop idle buckets every now and then to bound the memory used
+	if now.Sub(l.swept) > bucketExpiry {
+		for key, b := range l.buckets {
+			if now.Sub(b.last) > bucketExpiry {
+				delete(l.buckets, key)
+			}
+		}
+		l.swept = now
+	}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * l.rate
+	if b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// rateLimitedConn is a discovery UDP connection which silently drops incoming
+// packets exceeding the per-IP or per-subnet rate limits.
+type rateLimitedConn struct {
+	*net.UDPConn
+
+	lock   sync.Mutex
+	ip     *rateLimiter
+	subnet *rateLimiter
+
+	received uint64 // Number of packets read, atomically accessed
+	dropped  uint64 // Number of packets dropped for exceeding limits, atomically accessed
+}
+
+func newRateLimitedConn(conn *net.UDPConn, ipRate, subnetRate float64) *rateLimitedConn {
+	return &rateLimitedConn{
+		UDPConn: conn,
+		ip:      newRateLimiter(ipRate),
+		subnet:  newRateLimiter(subnetRate),
+	}
+}
+
+// ReadFromUDP reads the next packet within the rate limits.
+func (c *rateLimitedConn) ReadFromUDP(b []byte) (int, *net.UDPAddr, error) {
+	for {
+		n, addr, err := c.UDPConn.ReadFromUDP(b)
+		if err != nil {
+			return n, addr, err
+		}
+		atomic.AddUint64(&c.received, 1)
+		if c.allow(addr.IP) {
+			return n, addr, nil
+		}
+		atomic.AddUint64(&c.dropped, 1)
+	}
+}
+
+func (c *rateLimitedConn) allow(ip net.IP) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	now := time.Now()
+	if !c.ip.allow(ip.String(), now) {
+		return false
+	}
+	return c.subnet.allow(subnetKey(ip), now)
+}
+
+// subnetKey returns the /24 (IPv4) or /64 (IPv6) network of an address.
+func subnetKey(ip net.IP) string {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(64, 128)).String()
+}
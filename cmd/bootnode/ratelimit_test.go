@@ -0,0 +1,103 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Tests that the rate limiter allows bursts of twice the rate, refilled over time.
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(2)
+	now := time.Now()
+	for i := 0; i < 4; i++ {
+		if !l.allow("a", now) {
+			t.Fatalf("event %d of burst rejected", i)
+		}
+	}
+	if l.allow("a", now) {
+		t.Fatal("event over burst allowed")
+	}
+	if !l.allow("b", now) {
+		t.Fatal("event of other key rejected")
+	}
+	if !l.allow("a", now.Add(500*time.Millisecond)) {
+		t.Fatal("event after refill rejected")
+	}
+	// Idle buckets are dropped
+	l.allow("c", now.Add(2*bucketExpiry))
+	if _, ok := l.buckets["a"]; ok {
+		t.Fatal("idle bucket not dropped")
+	}
+	if unlimited := newRateLimiter(0); !unlimited.allow("a", now) || len(unlimited.buckets) != 0 {
+		t.Fatal("unlimited rate limiter rejected event or kept state")
+	}
+}
+
+func TestSubnetKey(t *testing.T) {
+	tests := []struct {
+		ip, key string
+	}{
+		{"10.1.2.3", "10.1.2.0"},
+		{"10.1.2.200", "10.1.2.0"},
+		{"2001:db8:1:2:3:4:5:6", "2001:db8:1:2::"},
+	}
+	for _, tt := range tests {
+		if key := subnetKey(net.ParseIP(tt.ip)); key != tt.key {
+			t.Errorf("subnet of %s mismatch: have %s, want %s", tt.ip, key, tt.key)
+		}
+	}
+}
+
+// Tests that the rate limited connection drops the packets over the limit.
+func TestRateLimitedConn(t *testing.T) {
+	listener, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IP{127, 0, 0, 1}})
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	conn := newRateLimitedConn(listener, 1, 0)
+	defer conn.Close()
+
+	sender, err := net.DialUDP("udp", nil, conn.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer sender.Close()
+	for i := 0; i < 4; i++ {
+		if _, err := sender.Write([]byte{byte(i)}); err != nil {
+			t.Fatalf("failed to send packet: %v", err)
+		}
+	}
+	// The burst of two packets is let through, the rest is dropped
+	buf := make([]byte, 16)
+	for i := 0; i < 2; i++ {
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		if _, _, err := conn.ReadFromUDP(buf); err != nil {
+			t.Fatalf("failed to read packet %d: %v", i, err)
+		}
+	}
+	conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if _, _, err := conn.ReadFromUDP(buf); err == nil {
+		t.Fatal("packet over the limit read")
+	}
+	if received, dropped := atomic.LoadUint64(&conn.received), atomic.LoadUint64(&conn.dropped); received != 4 || dropped != 2 {
+		t.Fatalf("packet counters mismatch: received %d, dropped %d", received, dropped)
+	}
+}
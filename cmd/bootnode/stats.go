@@ -0,0 +1,97 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+)
+
+// bootnodeStats is the response of the /stats endpoint.
+type bootnodeStats struct {
+	PacketsReceived uint64         `json:"packetsReceived"`
+	PacketsDropped  uint64         `json:"packetsDropped"`
+	TableNodes      int            `json:"tableNodes"`
+	NodeList        *nodeListStats `json:"nodeList,omitempty"`
+}
+
+// dnsTree is the response of the /dns endpoint.
+type dnsTree struct {
+	URL     string            `json:"url"`
+	Records map[string]string `json:"records"`
+}
+
+// statsServer serves the bootnode statistics and the published node list over HTTP.
+type statsServer struct {
+	conn *rateLimitedConn
+	disc *discover.UDPv4
+	list *nodeList // Nil if no node list is published
+}
+
+// start listens on the given address and serves requests in the background.
+func (s *statsServer) start(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/stats", s.serveStats)
+	if s.list != nil && s.list.domain != "" {
+		mux.HandleFunc("/dns", s.serveTree)
+	}
+	go http.Serve(listener, mux)
+	log.Info("Bootnode HTTP endpoint opened", "url", "http://"+listener.Addr().String())
+	return nil
+}
+
+func (s *statsServer) serveStats(w http.ResponseWriter, r *http.Request) {
+	stats := bootnodeStats{
+		PacketsReceived: atomic.LoadUint64(&s.conn.received),
+		PacketsDropped:  atomic.LoadUint64(&s.conn.dropped),
+	}
+	if s.disc != nil {
+		for _, b := range s.disc.Info().Buckets {
+			stats.TableNodes += len(b.Entries)
+		}
+	}
+	if s.list != nil {
+		listStats := s.list.stats()
+		stats.NodeList = &listStats
+	}
+	writeJSON(w, stats)
+}
+
+func (s *statsServer) serveTree(w http.ResponseWriter, r *http.Request) {
+	url, records := s.list.tree()
+	if url == "" {
+		http.Error(w, "node list not published yet", http.StatusServiceUnavailable)
+		return
+	}
+	writeJSON(w, dnsTree{URL: url, Records: records})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Debug("Failed to write HTTP response", "err", err)
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Tests that the stats endpoint reports the packet counters and the node list,
+// and that the DNS endpoint serves the tree once published.
+func TestStatsServer(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	list := newNodeList(testFilter, key, "nodes.example.org")
+	node := newTestNode(t, &testForkID)
+	list.nodes[node.ID()] = &listedNode{node: node, checked: time.Now()}
+	list.accepted = 1
+
+	server := &statsServer{conn: &rateLimitedConn{received: 10, dropped: 3}, list: list}
+
+	rec := httptest.NewRecorder()
+	server.serveStats(rec, httptest.NewRequest("GET", "/stats", nil))
+	var stats bootnodeStats
+	if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+		t.Fatalf("invalid stats response: %v", err)
+	}
+	if stats.PacketsReceived != 10 || stats.PacketsDropped != 3 || stats.NodeList == nil || stats.NodeList.Nodes != 1 || stats.NodeList.Accepted != 1 {
+		t.Fatalf("stats mismatch: %+v, list %+v", stats, stats.NodeList)
+	}
+
+	rec = httptest.NewRecorder()
+	server.serveTree(rec, httptest.NewRequest("GET", "/dns", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("unpublished tree status mismatch: have %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	list.publish()
+	rec = httptest.NewRecorder()
+	server.serveTree(rec, httptest.NewRequest("GET", "/dns", nil))
+	var tree dnsTree
+	if err := json.NewDecoder(rec.Body).Decode(&tree); err != nil {
+		t.Fatalf("invalid tree response: %v", err)
+	}
+	if url, records := list.tree(); tree.URL != url || len(tree.Records) != len(records) {
+		t.Fatalf("tree mismatch: have %s with %d records, want %s with %d", tree.URL, len(tree.Records), url, len(records))
+	}
+}
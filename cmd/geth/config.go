@@ -26,6 +26,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/internal/debug"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/params"
 	whisper "github.com/ethereum/go-ethereum/whisper/whisperv6"
@@ -71,11 +72,19 @@ type ethstatsConfig struct {
 	URL string `toml:",omitempty"`
 }
 
+// logConfig configures the logger, unless overridden by the --verbosity and
+// --vmodule flags.
+type logConfig struct {
+	Verbosity int    `toml:",omitempty"` // Log level, 0 keeps the level of the --verbosity flag
+	Vmodule   string `toml:",omitempty"` // Per-module log levels
+}
+
 type gethConfig struct {
 	Eth      eth.Config
 	Shh      whisper.Config
 	Node     node.Config
 	Ethstats ethstatsConfig
+	Log      logConfig
 }
 
 func loadConfig(file string, cfg *gethConfig) error {
@@ -103,13 +112,19 @@ func defaultNodeConfig() node.Config {
 	return cfg
 }
 
-func makeConfigNode(ctx *cli.Context) (*node.Node, gethConfig) {
-	// Load defaults.
-	cfg := gethConfig{
+// defaultGethConfig returns the configuration used if neither the configuration
+// file nor the flags override it.
+func defaultGethConfig() gethConfig {
+	return gethConfig{
 		Eth:  eth.DefaultConfig,
 		Shh:  whisper.DefaultConfig,
 		Node: defaultNodeConfig(),
 	}
+}
+
+func makeConfigNode(ctx *cli.Context) (*node.Node, gethConfig) {
+	// Load defaults.
+	cfg := defaultGethConfig()
 
 	// Load config file.
 	if file := ctx.GlobalString(configFileFlag.Name); file != "" {
@@ -117,6 +132,7 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, gethConfig) {
 			utils.Fatalf("%v", err)
 		}
 	}
+	setLogConfig(ctx, cfg.Log)
 
 	// Apply flags.
 	utils.SetNodeConfig(ctx, &cfg.Node)
@@ -134,6 +150,19 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, gethConfig) {
 	return stack, cfg
 }
 
+// setLogConfig applies the logger configuration of the configuration file, if
+// not overridden by flags.
+func setLogConfig(ctx *cli.Context, cfg logConfig) {
+	if cfg.Verbosity != 0 && !ctx.GlobalIsSet("verbosity") {
+		debug.Handler.Verbosity(cfg.Verbosity)
+	}
+	if cfg.Vmodule != "" && !ctx.GlobalIsSet("vmodule") {
+		if err := debug.Handler.Vmodule(cfg.Vmodule); err != nil {
+			utils.Fatalf("Invalid Log.Vmodule: %v", err)
+		}
+	}
+}
+
 // enableWhisper returns true in case one of the whisper flags is set.
 func enableWhisper(ctx *cli.Context) bool {
 	for _, flag := range whisperFlags {
@@ -146,6 +175,7 @@ func enableWhisper(ctx *cli.Context) bool {
 
 func makeFullNode(ctx *cli.Context) *node.Node {
 	stack, cfg := makeConfigNode(ctx)
+	stack.SetConfigReloader(newConfigReloader(ctx, stack, cfg))
 	utils.RegisterEthService(stack, &cfg.Eth)
 
 	// Whisper must be explicitly enabled by specifying at least 1 whisper flag or in dev mode
@@ -167,6 +197,10 @@ func makeFullNode(ctx *cli.Context) *node.Node {
 	if ctx.GlobalIsSet(utils.GraphQLEnabledFlag.Name) {
 		utils.RegisterGraphQLService(stack, cfg.Node.GraphQLEndpoint(), cfg.Node.GraphQLCors, cfg.Node.GraphQLVirtualHosts, cfg.Node.HTTPTimeouts)
 	}
+	// Add the explorer index if requested
+	if ctx.GlobalBool(utils.ExplorerIndexFlag.Name) {
+		utils.RegisterExplorerService(stack)
+	}
 	// Add the Ethereum Stats daemon if requested.
 	if cfg.Ethstats.URL != "" {
 		utils.RegisterEthStatsService(stack, cfg.Ethstats.URL)
@@ -90,6 +90,7 @@ var (
 		utils.SyncModeFlag,
 		utils.ExitWhenSyncedFlag,
 		utils.GCModeFlag,
+		utils.TxSenderIndexFlag,
 		utils.LightServeFlag,
 		utils.LightIngressFlag,
 		utils.LightEgressFlag,
@@ -137,17 +138,24 @@ var (
 		utils.VMEnableDebugFlag,
 		utils.NetworkIdFlag,
 		utils.EthStatsURLFlag,
+		utils.ExplorerIndexFlag,
 		utils.FakePoWFlag,
 		utils.NoCompactionFlag,
 		utils.EWASMInterpreterFlag,
 		utils.EVMInterpreterFlag,
+		utils.VMParallelTxExecutionFlag,
 		configFileFlag,
 		utils.IstanbulRequestTimeoutFlag,
 		utils.IstanbulBlockPeriodFlag,
 		utils.IstanbulProposerPolicyFlag,
 		utils.IstanbulLookbackWindowFlag,
+		utils.IstanbulAuditLogFlag,
+		utils.IstanbulAuditLogMaxSizeFlag,
+		utils.IstanbulAuditLogSyncIntervalFlag,
+		utils.IstanbulRefuseSigningFlag,
 		utils.AnnounceGossipPeriodFlag,
 		utils.AnnounceAggressiveGossipOnEnablementFlag,
+		utils.AnnounceLegacyEnodeURLFlag,
 		utils.PingIPFromPacketFlag,
 		utils.UseInMemoryDiscoverTableFlag,
 		utils.VersionCheckFlag,
@@ -231,6 +239,8 @@ func init() {
 		licenseCommand,
 		// See config.go
 		dumpConfigCommand,
+		// See preflightcmd.go
+		preflightCommand,
 		// See retesteth.go
 		retestethCommand,
 	}
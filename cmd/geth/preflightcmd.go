@@ -0,0 +1,266 @@
+// Copyright 2020 The Celo Authors
+// This file is part of celo-blockchain.
+//
+// celo-blockchain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// celo-blockchain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with celo-blockchain. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/contract_comm"
+	ccerrors "github.com/ethereum/go-ethereum/contract_comm/errors"
+	"github.com/ethereum/go-ethereum/contract_comm/validators"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/p2p"
+	cli "gopkg.in/urfave/cli.v1"
+)
+
+var (
+	preflightCommand = cli.Command{
+		Action:    utils.MigrateFlags(preflight),
+		Name:      "preflight",
+		Usage:     "Check the node configuration before starting it",
+		ArgsUsage: "",
+		Flags:     append(append(append(nodeFlags, rpcFlags...), whisperFlags...), preflightChecksFlag),
+		Category:  "MISCELLANEOUS COMMANDS",
+		Description: `
+The preflight command loads the configuration exactly like the node does on
+startup and checks it without starting the node:
+
+  keystore  the validator signer and BLS accounts are in the keystore, are
+            unlocked on startup and can be unlocked with the given password
+  bls       the BLS public key derived from the BLS account matches the key
+            registered in the Validators contract at the head of the local chain
+  proxy     the proxy enodes of a proxied validator use the same node key and
+            the internal facing proxy holds that key (skipped on proxies)
+
+Checks which do not apply to the configuration are skipped. The command exits
+with a non-zero code if any check fails.`,
+	}
+
+	preflightChecksFlag = cli.StringFlag{
+		Name:  "checks",
+		Usage: "Comma separated list of checks to run",
+		Value: "keystore,bls,proxy",
+	}
+)
+
+// errPreflightSkipped is returned by checks which do not apply to the configuration.
+var errPreflightSkipped = errors.New("skipped")
+
+// preflightCheck is a single check of the preflight command. The error returned
+// by a failing check tells the operator what to fix.
+type preflightCheck struct {
+	name string
+	run  func(*preflightEnv) (string, error)
+}
+
+var preflightChecks = []preflightCheck{
+	{"keystore", checkKeystore},
+	{"bls", checkBLSKey},
+	{"proxy", checkProxy},
+}
+
+// preflightEnv is the configuration shared by the preflight checks.
+type preflightEnv struct {
+	ctx   *cli.Context
+	stack *node.Node
+	cfg   gethConfig
+	ks    *keystore.KeyStore
+
+	signer   accounts.Account // Validator signer account, set by the keystore check
+	blsbase  accounts.Account // BLS account, set by the keystore check
+	unlocked bool             // Whether the keystore check unlocked the accounts
+}
+
+// validator reports whether the node is configured to run as a validator.
+func (env *preflightEnv) validator() bool {
+	return env.ctx.GlobalBool(utils.MiningEnabledFlag.Name) || env.ctx.GlobalBool(utils.DeveloperFlag.Name)
+}
+
+// preflight is the entry point of the preflight command.
+func preflight(ctx *cli.Context) error {
+	stack, cfg := makeConfigNode(ctx)
+	defer stack.Close()
+
+	env := &preflightEnv{
+		ctx:   ctx,
+		stack: stack,
+		cfg:   cfg,
+		ks:    stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore),
+	}
+	known := make(map[string]bool)
+	for _, check := range preflightChecks {
+		known[check.name] = true
+	}
+	enabled := make(map[string]bool)
+	for _, name := range strings.Split(ctx.String(preflightChecksFlag.Name), ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		if !known[name] {
+			return fmt.Errorf("unknown preflight check %q", name)
+		}
+		enabled[name] = true
+	}
+	var failed int
+	for _, check := range preflightChecks {
+		if !enabled[check.name] {
+			continue
+		}
+		result, err := check.run(env)
+		switch {
+		case err == errPreflightSkipped:
+			fmt.Printf("SKIP  %-8s %s\n", check.name, result)
+		case err != nil:
+			fmt.Printf("FAIL  %-8s %v\n", check.name, err)
+			failed++
+		default:
+			fmt.Printf("PASS  %-8s %s\n", check.name, result)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d preflight check(s) failed", failed)
+	}
+	return nil
+}
+
+// checkKeystore verifies that the validator signer and BLS accounts are in the
+// keystore, are unlocked on startup and that the password unlocks them.
+func checkKeystore(env *preflightEnv) (string, error) {
+	if !env.validator() {
+		return "node is not a validator (--mine is not set)", errPreflightSkipped
+	}
+	signer := env.cfg.Eth.Etherbase
+	if signer == (common.Address{}) {
+		// Same fallback as the node, the first account of the first wallet
+		if wallets := env.stack.AccountManager().Wallets(); len(wallets) > 0 && len(wallets[0].Accounts()) > 0 {
+			signer = wallets[0].Accounts()[0].Address
+		} else {
+			return "", fmt.Errorf("no validator signer configured: set --%s to the signer address of the validator", utils.MinerEtherbaseFlag.Name)
+		}
+	}
+	blsbase := env.cfg.Eth.BLSbase
+	if blsbase == (common.Address{}) {
+		blsbase = signer
+	}
+	if !env.ks.HasAddress(signer) {
+		return "", fmt.Errorf("validator signer %s is not in the keystore: import its key, or fix --%s or --%s", signer.Hex(), utils.MinerEtherbaseFlag.Name, utils.KeyStoreDirFlag.Name)
+	}
+	if !env.ks.HasAddress(blsbase) {
+		return "", fmt.Errorf("BLS account %s is not in the keystore: import its key, or fix --%s or --%s", blsbase.Hex(), utils.BLSbaseFlag.Name, utils.KeyStoreDirFlag.Name)
+	}
+	env.signer, env.blsbase = accounts.Account{Address: signer}, accounts.Account{Address: blsbase}
+
+	// The accounts must be unlocked on startup, using the password at the same index
+	passwords := utils.MakePasswordList(env.ctx)
+	unlocks := make(map[common.Address]int)
+	var index int
+	for _, input := range strings.Split(env.ctx.GlobalString(utils.UnlockedAccountFlag.Name), ",") {
+		if input = strings.TrimSpace(input); input == "" {
+			continue
+		}
+		account, err := utils.MakeAddress(env.ks, input)
+		if err != nil {
+			return "", fmt.Errorf("invalid account %q in --%s: %v", input, utils.UnlockedAccountFlag.Name, err)
+		}
+		unlocks[account.Address] = index
+		index++
+	}
+	for _, account := range []accounts.Account{env.signer, env.blsbase} {
+		i, ok := unlocks[account.Address]
+		if !ok {
+			return "", fmt.Errorf("account %s is not unlocked on startup: add it to --%s and provide --%s", account.Address.Hex(), utils.UnlockedAccountFlag.Name, utils.PasswordFileFlag.Name)
+		}
+		password := getPassPhrase(fmt.Sprintf("Unlocking account %s", account.Address.Hex()), false, i, passwords)
+		if err := env.ks.Unlock(account, password); err != nil {
+			return "", fmt.Errorf("cannot unlock account %s: %v: check the password at line %d of --%s", account.Address.Hex(), err, i+1, utils.PasswordFileFlag.Name)
+		}
+	}
+	env.unlocked = true
+
+	if signer == blsbase {
+		return fmt.Sprintf("validator signer %s unlocked", signer.Hex()), nil
+	}
+	return fmt.Sprintf("validator signer %s and BLS account %s unlocked", signer.Hex(), blsbase.Hex()), nil
+}
+
+// checkBLSKey verifies that the BLS public key of the BLS account matches the
+// key registered for the validator signer at the head of the local chain.
+func checkBLSKey(env *preflightEnv) (string, error) {
+	if !env.validator() {
+		return "node is not a validator (--mine is not set)", errPreflightSkipped
+	}
+	if !env.unlocked {
+		return "", errors.New("the BLS key can't be derived without unlocking the BLS account: run the keystore check and fix its failures first")
+	}
+	// Derive the BLS public key the same way the proof of possession does
+	publicKey, _, err := env.ks.GenerateProofOfPossessionBLS(env.blsbase, env.signer.Address)
+	if err != nil {
+		return "", fmt.Errorf("cannot derive the BLS key of %s: %v", env.blsbase.Address.Hex(), err)
+	}
+
+	chain, chainDb := utils.MakeChain(env.ctx, env.stack)
+	defer chainDb.Close()
+	defer chain.Stop()
+	contract_comm.SetInternalEVMHandler(chain)
+
+	header := chain.CurrentHeader()
+	state, err := chain.StateAt(header.Root)
+	if err != nil {
+		return "", fmt.Errorf("cannot load the state at block %d: %v", header.Number, err)
+	}
+	registered, err := validators.GetValidatorData(header, state, []common.Address{env.signer.Address})
+	if err != nil {
+		if err == ccerrors.ErrRegistryContractNotDeployed || header.Number.Sign() == 0 {
+			return "", fmt.Errorf("the Validators contract is not available at block %d: sync the node before running this check", header.Number)
+		}
+		return "", fmt.Errorf("%s is not the signer of a registered validator at block %d (%v): check --%s or register the validator", env.signer.Address.Hex(), header.Number, err, utils.MinerEtherbaseFlag.Name)
+	}
+	if !bytes.Equal(registered[0].BLSPublicKey[:], publicKey) {
+		return "", fmt.Errorf("the BLS key of %s (%x) does not match the key registered for %s at block %d (%x): check --%s or update the registered key",
+			env.blsbase.Address.Hex(), publicKey, env.signer.Address.Hex(), header.Number, registered[0].BLSPublicKey, utils.BLSbaseFlag.Name)
+	}
+	return fmt.Sprintf("BLS key matches the registration at block %d", header.Number), nil
+}
+
+// checkProxy verifies that the proxy enodes of a proxied validator identify the
+// same node, and that the internal facing proxy holds the key of that node.
+func checkProxy(env *preflightEnv) (string, error) {
+	istanbulCfg := env.cfg.Eth.Istanbul
+	if istanbulCfg.Proxy {
+		return fmt.Sprintf("node is a proxy of validator %s, run the check on the validator", istanbulCfg.ProxiedValidatorAddress.Hex()), errPreflightSkipped
+	}
+	if !istanbulCfg.Proxied {
+		return "node is not a proxied validator (--proxy.proxied is not set)", errPreflightSkipped
+	}
+	internal, external := istanbulCfg.ProxyInternalFacingNode, istanbulCfg.ProxyExternalFacingNode
+	if internal.ID() != external.ID() {
+		return "", fmt.Errorf("the internal (%x) and external (%x) facing proxy enodes have different node keys: both must use the public key of the proxy in --%s",
+			internal.Pubkey(), external.Pubkey(), utils.ProxyEnodeURLPairFlag.Name)
+	}
+	if err := p2p.CheckNodeKey(internal, env.stack.Config().NodeKey()); err != nil {
+		return "", fmt.Errorf("handshake with the proxy at %s failed (%v): check that the proxy is running and that its node key matches the enode in --%s",
+			internal.URLv4(), err, utils.ProxyEnodeURLPairFlag.Name)
+	}
+	return fmt.Sprintf("proxy %s reachable with the expected node key", internal.URLv4()), nil
+}
@@ -0,0 +1,202 @@
+// Copyright 2020 The Celo Authors
+// This file is part of celo-blockchain.
+//
+// celo-blockchain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// celo-blockchain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with celo-blockchain. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"flag"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	cli "gopkg.in/urfave/cli.v1"
+)
+
+// testAccount is the account of the key copied from the keystore test data
+// into the keystore of every test environment. Its password is "foobar".
+var testAccount = accounts.Account{Address: common.HexToAddress("0x7ef5a6135f1fd6a02593eedc869c6d41d934aef8")}
+
+// newPreflightTestEnv creates a preflight environment with a keystore holding
+// testAccount in a temporary data directory and the given command line flags.
+func newPreflightTestEnv(t *testing.T, key *ecdsa.PrivateKey, flags map[string]string) (*preflightEnv, func()) {
+	const keyfile = "UTC--2016-03-22T12-57-55.920751759Z--7ef5a6135f1fd6a02593eedc869c6d41d934aef8"
+
+	dir, err := ioutil.TempDir("", "preflight-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyjson, err := ioutil.ReadFile(filepath.Join("..", "..", "accounts", "keystore", "testdata", "keystore", keyfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "keystore"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "keystore", keyfile), keyjson, 0600); err != nil {
+		t.Fatal(err)
+	}
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	set.Bool(utils.MiningEnabledFlag.Name, false, "")
+	set.Bool(utils.DeveloperFlag.Name, false, "")
+	set.String(utils.UnlockedAccountFlag.Name, "", "")
+	set.String(utils.PasswordFileFlag.Name, "", "")
+	for name, value := range flags {
+		if err := set.Set(name, value); err != nil {
+			t.Fatalf("failed to set flag %s: %v", name, err)
+		}
+	}
+	stack, err := node.New(&node.Config{DataDir: dir, UseLightweightKDF: true, P2P: p2p.Config{PrivateKey: key}})
+	if err != nil {
+		t.Fatalf("failed to create node: %v", err)
+	}
+	env := &preflightEnv{
+		ctx:   cli.NewContext(nil, set, nil),
+		stack: stack,
+		cfg:   defaultGethConfig(),
+		ks:    stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore),
+	}
+	return env, func() {
+		stack.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// writePasswords writes a password file into the data directory of the node.
+func writePasswords(t *testing.T, env *preflightEnv, passwords ...string) string {
+	path := filepath.Join(env.stack.Config().DataDir, "passwords")
+	if err := ioutil.WriteFile(path, []byte(strings.Join(passwords, "\n")), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPreflightKeystore(t *testing.T) {
+	// Non validators are skipped
+	env, cleanup := newPreflightTestEnv(t, nil, nil)
+	defer cleanup()
+	if _, err := checkKeystore(env); err != errPreflightSkipped {
+		t.Fatalf("non validator check error mismatch: have %v, want %v", err, errPreflightSkipped)
+	}
+
+	env, cleanup = newPreflightTestEnv(t, nil, map[string]string{"mine": "true"})
+	defer cleanup()
+	env.cfg.Eth.Etherbase = crypto.PubkeyToAddress(mustGenerateKey(t).PublicKey)
+	if _, err := checkKeystore(env); err == nil || !strings.Contains(err.Error(), "not in the keystore") {
+		t.Fatalf("missing signer error mismatch: %v", err)
+	}
+	// Without a configured signer the first account of the keystore is used,
+	// which must be unlocked on startup with the right password
+	account := testAccount
+	env.cfg.Eth.Etherbase = common.Address{}
+	if _, err := checkKeystore(env); err == nil || !strings.Contains(err.Error(), "not unlocked on startup") {
+		t.Fatalf("locked signer error mismatch: %v", err)
+	}
+	env.ctx.Set(utils.UnlockedAccountFlag.Name, account.Address.Hex())
+	env.ctx.Set(utils.PasswordFileFlag.Name, writePasswords(t, env, "wrong"))
+	if _, err := checkKeystore(env); err == nil || !strings.Contains(err.Error(), "cannot unlock") {
+		t.Fatalf("wrong password error mismatch: %v", err)
+	}
+	env.ctx.Set(utils.PasswordFileFlag.Name, writePasswords(t, env, "foobar"))
+	if _, err := checkKeystore(env); err != nil {
+		t.Fatalf("valid configuration failed: %v", err)
+	}
+	if !env.unlocked || env.signer.Address != account.Address || env.blsbase.Address != account.Address {
+		t.Fatalf("accounts not recorded: unlocked %v, signer %x, BLS %x", env.unlocked, env.signer.Address, env.blsbase.Address)
+	}
+	// A BLS account missing from the keystore is reported
+	env.cfg.Eth.BLSbase = crypto.PubkeyToAddress(mustGenerateKey(t).PublicKey)
+	if _, err := checkKeystore(env); err == nil || !strings.Contains(err.Error(), "BLS account") {
+		t.Fatalf("missing BLS account error mismatch: %v", err)
+	}
+}
+
+func TestPreflightBLSKey(t *testing.T) {
+	env, cleanup := newPreflightTestEnv(t, nil, nil)
+	defer cleanup()
+	if _, err := checkBLSKey(env); err != errPreflightSkipped {
+		t.Fatalf("non validator check error mismatch: have %v, want %v", err, errPreflightSkipped)
+	}
+	// The key can't be checked without the keystore check unlocking the account
+	env.ctx.Set(utils.MiningEnabledFlag.Name, "true")
+	if _, err := checkBLSKey(env); err == nil || !strings.Contains(err.Error(), "keystore check") {
+		t.Fatalf("locked BLS account error mismatch: %v", err)
+	}
+}
+
+func TestPreflightProxy(t *testing.T) {
+	key := mustGenerateKey(t)
+	env, cleanup := newPreflightTestEnv(t, key, nil)
+	defer cleanup()
+
+	// Nodes which are not proxied validators are skipped, including proxies
+	if _, err := checkProxy(env); err != errPreflightSkipped {
+		t.Fatalf("standalone node check error mismatch: have %v, want %v", err, errPreflightSkipped)
+	}
+	env.cfg.Eth.Istanbul.Proxy = true
+	if _, err := checkProxy(env); err != errPreflightSkipped {
+		t.Fatalf("proxy check error mismatch: have %v, want %v", err, errPreflightSkipped)
+	}
+	env.cfg.Eth.Istanbul.Proxy = false
+	env.cfg.Eth.Istanbul.Proxied = true
+
+	// Start a proxy to handshake with
+	proxyKey := mustGenerateKey(t)
+	proxy := &p2p.Server{Config: p2p.Config{PrivateKey: proxyKey, ListenAddr: "127.0.0.1:0", NoDiscovery: true, MaxPeers: 1}}
+	if err := proxy.Start(); err != nil {
+		t.Fatalf("failed to start proxy: %v", err)
+	}
+	defer proxy.Stop()
+	port := proxy.ListenAddr
+	addr, err := net.ResolveTCPAddr("tcp", port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxyNode := enode.NewV4(&proxyKey.PublicKey, addr.IP, addr.Port, 0)
+	otherNode := enode.NewV4(&mustGenerateKey(t).PublicKey, addr.IP, addr.Port, 0)
+
+	env.cfg.Eth.Istanbul.ProxyInternalFacingNode, env.cfg.Eth.Istanbul.ProxyExternalFacingNode = proxyNode, otherNode
+	if _, err := checkProxy(env); err == nil || !strings.Contains(err.Error(), "different node keys") {
+		t.Fatalf("mismatching enodes error mismatch: %v", err)
+	}
+	env.cfg.Eth.Istanbul.ProxyInternalFacingNode, env.cfg.Eth.Istanbul.ProxyExternalFacingNode = otherNode, otherNode
+	if _, err := checkProxy(env); err == nil || !strings.Contains(err.Error(), "handshake") {
+		t.Fatalf("wrong proxy key error mismatch: %v", err)
+	}
+	env.cfg.Eth.Istanbul.ProxyInternalFacingNode, env.cfg.Eth.Istanbul.ProxyExternalFacingNode = proxyNode, proxyNode
+	if _, err := checkProxy(env); err != nil {
+		t.Fatalf("valid proxy failed: %v", err)
+	}
+}
+
+func mustGenerateKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
@@ -0,0 +1,270 @@
+// Copyright 2020 The Celo Authors
+// This file is part of celo-blockchain.
+//
+// celo-blockchain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// celo-blockchain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with celo-blockchain. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/internal/debug"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	cli "gopkg.in/urfave/cli.v1"
+)
+
+// reloadableSettings are the settings of the configuration file which can be
+// applied to a running node, mapped to the flags overriding them.
+var reloadableSettings = map[string][]string{
+	"Eth.TxPool.PriceLimit":                {utils.TxPoolPriceLimitFlag.Name},
+	"Eth.TxPool.PriceBump":                 {utils.TxPoolPriceBumpFlag.Name},
+	"Eth.TxPool.AccountSlots":              {utils.TxPoolAccountSlotsFlag.Name},
+	"Eth.TxPool.GlobalSlots":               {utils.TxPoolGlobalSlotsFlag.Name},
+	"Eth.TxPool.AccountQueue":              {utils.TxPoolAccountQueueFlag.Name},
+	"Eth.TxPool.GlobalQueue":               {utils.TxPoolGlobalQueueFlag.Name},
+	"Eth.TxPool.Lifetime":                  {utils.TxPoolLifetimeFlag.Name},
+	"Eth.LightServ":                        {utils.LightServeFlag.Name},
+	"Eth.LightPeers":                       {utils.LightMaxPeersFlag.Name},
+	"Eth.GatewayFee":                       {utils.GatewayFeeFlag.Name},
+	"Eth.Istanbul.ProxyInternalFacingNode": {utils.ProxyEnodeURLPairFlag.Name},
+	"Eth.Istanbul.ProxyExternalFacingNode": {utils.ProxyEnodeURLPairFlag.Name},
+	"Log.Verbosity":                        {"verbosity"},
+	"Log.Vmodule":                          {"vmodule"},
+}
+
+// configReloader re-reads the configuration file of a running node and applies
+// the reloadable settings which changed.
+type configReloader struct {
+	ctx   *cli.Context
+	stack *node.Node
+
+	running gethConfig // Configuration the node runs with, flags applied
+	loaded  gethConfig // Configuration loaded from the file, without flags
+}
+
+// newConfigReloader creates the reloader of the node started with the given
+// configuration, and reloads it whenever the process receives SIGHUP.
+func newConfigReloader(ctx *cli.Context, stack *node.Node, running gethConfig) node.ConfigReloader {
+	r := &configReloader{ctx: ctx, stack: stack, running: running, loaded: defaultGethConfig()}
+	if file := ctx.GlobalString(configFileFlag.Name); file != "" {
+		if err := loadConfig(file, &r.loaded); err != nil {
+			utils.Fatalf("%v", err)
+		}
+	}
+	go func() {
+		sighup := make(chan os.Signal, 1)
+		signal.Notify(sighup, syscall.SIGHUP)
+		for range sighup {
+			log.Info("Got SIGHUP, reloading configuration")
+			if changes, err := stack.ReloadConfig(); err != nil {
+				log.Error("Failed to reload configuration", "err", err)
+			} else {
+				log.Info("Reloaded configuration", "applied", changes.Applied, "restartRequired", changes.RestartRequired, "overridden", changes.Overridden)
+			}
+		}
+	}()
+	return r.reload
+}
+
+// reload loads the configuration file, applies the reloadable settings that
+// changed since the last reload and reports the other changes.
+func (r *configReloader) reload() (*node.ConfigChanges, error) {
+	file := r.ctx.GlobalString(configFileFlag.Name)
+	if file == "" {
+		return nil, fmt.Errorf("no configuration file given with --%s", configFileFlag.Name)
+	}
+	next := defaultGethConfig()
+	if err := loadConfig(file, &next); err != nil {
+		return nil, err
+	}
+	var changed []string
+	diffConfig("", reflect.ValueOf(r.loaded), reflect.ValueOf(next), &changed)
+	sort.Strings(changed)
+
+	changes := &node.ConfigChanges{Applied: []string{}, RestartRequired: []string{}, Overridden: []string{}}
+	var reloadable []string
+	for _, path := range changed {
+		flags, ok := reloadableSettings[path]
+		switch {
+		case !ok:
+			changes.RestartRequired = append(changes.RestartRequired, path)
+		case r.overridden(flags):
+			changes.Overridden = append(changes.Overridden, path)
+		default:
+			reloadable = append(reloadable, path)
+		}
+	}
+	if len(reloadable) == 0 {
+		return changes, nil
+	}
+	// Apply the changed settings on top of the running configuration
+	running := r.running
+	for _, path := range reloadable {
+		configField(&running, path).Set(configField(&next, path))
+	}
+	failed := make(map[string]error)
+	for _, group := range []struct {
+		prefixes []string
+		apply    func(cfg *gethConfig) error
+	}{
+		{[]string{"Eth.TxPool."}, r.applyTxPool},
+		{[]string{"Eth.LightServ", "Eth.LightPeers", "Eth.GatewayFee"}, r.applyLightServing},
+		{[]string{"Eth.Istanbul.Proxy"}, r.applyProxy},
+		{[]string{"Log."}, r.applyLog},
+	} {
+		var paths []string
+		for _, path := range reloadable {
+			for _, prefix := range group.prefixes {
+				if strings.HasPrefix(path, prefix) {
+					paths = append(paths, path)
+				}
+			}
+		}
+		if len(paths) == 0 {
+			continue
+		}
+		if err := group.apply(&running); err != nil {
+			for _, path := range paths {
+				failed[path] = err
+			}
+		}
+	}
+	for _, path := range reloadable {
+		if err, ok := failed[path]; ok {
+			changes.RestartRequired = append(changes.RestartRequired, fmt.Sprintf("%s (%v)", path, err))
+			configField(&running, path).Set(configField(&r.running, path))
+			continue
+		}
+		changes.Applied = append(changes.Applied, path)
+		configField(&r.loaded, path).Set(configField(&next, path))
+	}
+	r.running = running
+	return changes, nil
+}
+
+// overridden reports whether any of the given flags is set on the command line.
+func (r *configReloader) overridden(flags []string) bool {
+	for _, name := range flags {
+		if r.ctx.GlobalIsSet(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *configReloader) ethereum() (*eth.Ethereum, error) {
+	var ethereum *eth.Ethereum
+	if err := r.stack.Service(&ethereum); err != nil {
+		return nil, errors.New("not supported by light clients")
+	}
+	return ethereum, nil
+}
+
+func (r *configReloader) applyTxPool(cfg *gethConfig) error {
+	ethereum, err := r.ethereum()
+	if err != nil {
+		return err
+	}
+	ethereum.TxPool().SetLimits(cfg.Eth.TxPool)
+	if cfg.Eth.TxPool.PriceLimit != r.running.Eth.TxPool.PriceLimit {
+		ethereum.TxPool().SetGasPrice(new(big.Int).SetUint64(cfg.Eth.TxPool.PriceLimit))
+	}
+	return nil
+}
+
+func (r *configReloader) applyLightServing(cfg *gethConfig) error {
+	ethereum, err := r.ethereum()
+	if err != nil {
+		return err
+	}
+	gatewayFee := cfg.Eth.GatewayFee
+	if gatewayFee == nil || gatewayFee.Sign() <= 0 {
+		gatewayFee = new(big.Int).Set(eth.DefaultConfig.GatewayFee)
+	}
+	return ethereum.SetLightServing(cfg.Eth.LightServ, cfg.Eth.LightPeers, gatewayFee)
+}
+
+func (r *configReloader) applyProxy(cfg *gethConfig) error {
+	ethereum, err := r.ethereum()
+	if err != nil {
+		return err
+	}
+	internal, external := cfg.Eth.Istanbul.ProxyInternalFacingNode, cfg.Eth.Istanbul.ProxyExternalFacingNode
+	if internal == nil || external == nil {
+		return errors.New("both proxy enodes must be set")
+	}
+	if external.IsPrivateIP() && !r.ctx.GlobalBool(utils.ProxyAllowPrivateIPFlag.Name) {
+		return errors.New("external facing proxy enode can't be a private IP")
+	}
+	proxied, ok := ethereum.Engine().(interface {
+		SetProxy(node, externalNode *enode.Node) error
+	})
+	if !ok {
+		return errors.New("consensus engine has no proxy")
+	}
+	return proxied.SetProxy(internal, external)
+}
+
+func (r *configReloader) applyLog(cfg *gethConfig) error {
+	if err := debug.Handler.Vmodule(cfg.Log.Vmodule); err != nil {
+		return err
+	}
+	// A zero verbosity restores the level of the --verbosity flag, as on startup
+	verbosity := cfg.Log.Verbosity
+	if verbosity == 0 {
+		verbosity = r.ctx.GlobalInt("verbosity")
+	}
+	debug.Handler.Verbosity(verbosity)
+	return nil
+}
+
+// diffConfig collects the paths of the exported fields which differ between two
+// configuration structs, descending into nested structs.
+func diffConfig(prefix string, a, b reflect.Value, changed *[]string) {
+	for i := 0; i < a.NumField(); i++ {
+		field := a.Type().Field(i)
+		if field.PkgPath != "" || field.Type.Kind() == reflect.Func {
+			continue // unexported or not configurable
+		}
+		path := prefix + field.Name
+		if field.Type.Kind() == reflect.Struct {
+			diffConfig(path+".", a.Field(i), b.Field(i), changed)
+			continue
+		}
+		if !reflect.DeepEqual(a.Field(i).Interface(), b.Field(i).Interface()) {
+			*changed = append(*changed, path)
+		}
+	}
+}
+
+// configField returns the settable field of a configuration at the given path.
+func configField(cfg *gethConfig, path string) reflect.Value {
+	v := reflect.ValueOf(cfg).Elem()
+	for _, name := range strings.Split(path, ".") {
+		v = v.FieldByName(name)
+	}
+	return v
+}
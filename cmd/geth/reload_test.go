@@ -0,0 +1,76 @@
+// Copyright 2020 The Celo Authors
+// This file is part of celo-blockchain.
+//
+// celo-blockchain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// celo-blockchain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with celo-blockchain. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestDiffConfig(t *testing.T) {
+	a, b := defaultGethConfig(), defaultGethConfig()
+	b.Eth.TxPool.GlobalSlots++
+	b.Eth.GatewayFee = big.NewInt(1)
+	b.Node.P2P.MaxPeers++
+	b.Log.Verbosity = 5
+
+	var changed []string
+	diffConfig("", reflect.ValueOf(a), reflect.ValueOf(b), &changed)
+
+	want := []string{"Eth.GatewayFee", "Eth.TxPool.GlobalSlots", "Node.P2P.MaxPeers", "Log.Verbosity"}
+	if !reflect.DeepEqual(changed, want) {
+		t.Errorf("changed settings mismatch: have %v, want %v", changed, want)
+	}
+	for _, path := range changed {
+		configField(&a, path).Set(configField(&b, path))
+	}
+	changed = changed[:0]
+	if diffConfig("", reflect.ValueOf(a), reflect.ValueOf(b), &changed); len(changed) != 0 {
+		t.Errorf("settings still differ after copying: %v", changed)
+	}
+}
+
+// Tests that the reloadable settings can be loaded from a configuration file.
+func TestLoadReloadableConfig(t *testing.T) {
+	file, err := ioutil.TempFile("", "geth-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+
+	file.WriteString("[Eth]\nLightServ = 50\nLightPeers = 10\nGatewayFee = 5000\n\n[Eth.TxPool]\nGlobalSlots = 100\n")
+	file.Close()
+
+	cfg := defaultGethConfig()
+	if err := loadConfig(file.Name(), &cfg); err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if cfg.Eth.LightServ != 50 || cfg.Eth.LightPeers != 10 || cfg.Eth.TxPool.GlobalSlots != 100 {
+		t.Errorf("settings mismatch: lightserv %d, lightpeers %d, globalslots %d", cfg.Eth.LightServ, cfg.Eth.LightPeers, cfg.Eth.TxPool.GlobalSlots)
+	}
+	if cfg.Eth.GatewayFee == nil || cfg.Eth.GatewayFee.Cmp(big.NewInt(5000)) != 0 {
+		t.Errorf("gateway fee mismatch: have %v, want %v", cfg.Eth.GatewayFee, 5000)
+	}
+	for path := range reloadableSettings {
+		if !configField(&cfg, path).IsValid() {
+			t.Errorf("reloadable setting %s not in config", path)
+		}
+	}
+}
@@ -19,6 +19,8 @@ package main
 import (
 	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"errors"
 	"fmt"
 	"math/big"
 	"os"
@@ -33,6 +35,7 @@ import (
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/consensus/istanbul"
+	istanbulBackend "github.com/ethereum/go-ethereum/consensus/istanbul/backend"
 	"github.com/ethereum/go-ethereum/consensus/misc"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
@@ -40,6 +43,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
+	blscrypto "github.com/ethereum/go-ethereum/crypto/bls"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/node"
@@ -109,12 +113,15 @@ type RetestethAPI struct {
 	author        common.Address
 	extraData     []byte
 	genesisHash   common.Hash
-	engine        *NoRewardEngine
+	engine        consensus.Engine
 	blockchain    *core.BlockChain
 	blockNumber   uint64
 	txMap         map[common.Address]map[uint64]*types.Transaction // Sender -> Nonce -> Transaction
 	txSenders     map[common.Address]struct{}                      // Set of transaction senders
 	blockInterval uint64
+	istanbul      *istanbulBackend.Backend // Set if the chain runs the Istanbul engine
+	blockPeriod   uint64                   // Minimum interval between Istanbul blocks
+	validatorKeys []*ecdsa.PrivateKey      // Keys sealing the Istanbul blocks
 }
 
 type ChainParams struct {
@@ -122,6 +129,7 @@ type ChainParams struct {
 	Params     CParamsParams                     `json:"params"`
 	Genesis    CParamsGenesis                    `json:"genesis"`
 	Accounts   map[common.Address]CParamsAccount `json:"accounts"`
+	Istanbul   *CParamsIstanbul                  `json:"istanbul"`
 }
 
 type CParamsParams struct {
@@ -158,6 +166,17 @@ type CParamsGenesis struct {
 	GasLimit   math.HexOrDecimal64   `json:"gasLimit"`
 }
 
+// CParamsIstanbul configures the Istanbul engine, used with the "Istanbul" seal
+// engine. Validators holds the private keys of the genesis validators, which
+// propose and commit every mined block.
+type CParamsIstanbul struct {
+	Epoch          math.HexOrDecimal64  `json:"epoch"`
+	LookbackWindow math.HexOrDecimal64  `json:"lookbackWindow"`
+	BlockPeriod    *math.HexOrDecimal64 `json:"blockPeriod"`
+	ProposerPolicy math.HexOrDecimal64  `json:"proposerPolicy"`
+	Validators     []hexutil.Bytes      `json:"validators"`
+}
+
 type CParamsAccount struct {
 	Balance     *math.HexOrDecimal256 `json:"balance"`
 	Precompiled *CPAccountPrecompiled `json:"precompiled"`
@@ -384,13 +403,27 @@ func (api *RetestethAPI) SetChainParams(ctx context.Context, chainParams ChainPa
 		ParentHash: chainParams.Genesis.ParentHash,
 		Alloc:      accounts,
 	}
+	var (
+		istanbulConfig *istanbul.Config
+		validatorKeys  []*ecdsa.PrivateKey
+		err            error
+	)
+	if chainParams.SealEngine == "Istanbul" {
+		if istanbulConfig, validatorKeys, err = setupIstanbulGenesis(genesis, chainParams.Istanbul); err != nil {
+			return false, err
+		}
+	}
 	chainConfig, genesisHash, err := core.SetupGenesisBlock(ethDb, genesis)
 	if err != nil {
 		return false, err
 	}
 	fmt.Printf("Chain config: %v\n", chainConfig)
 
-	var inner consensus.Engine
+	var (
+		engine      consensus.Engine
+		istanbulEng *istanbulBackend.Backend
+		inner       consensus.Engine
+	)
 	switch chainParams.SealEngine {
 	case "NoProof", "NoReward":
 		inner = ethash.NewFaker()
@@ -402,15 +435,25 @@ func (api *RetestethAPI) SetChainParams(ctx context.Context, chainParams ChainPa
 			DatasetsInMem:  1,
 			DatasetsOnDisk: 2,
 		}, nil, false)
+	case "Istanbul":
+		istanbulEng = istanbulBackend.New(istanbulConfig, ethDb).(*istanbulBackend.Backend)
+		engine = istanbulEng
 	default:
 		return false, fmt.Errorf("unrecognised seal engine: %s", chainParams.SealEngine)
 	}
-	engine := &NoRewardEngine{inner: inner, rewardsOn: chainParams.SealEngine != "NoReward"}
+	if inner != nil {
+		engine = &NoRewardEngine{inner: inner, rewardsOn: chainParams.SealEngine != "NoReward"}
+	}
 
 	blockchain, err := core.NewBlockChain(ethDb, nil, chainConfig, engine, vm.Config{}, nil)
 	if err != nil {
 		return false, err
 	}
+	if istanbulEng != nil {
+		istanbulEng.SetChain(blockchain, blockchain.CurrentBlock, func(hash common.Hash) (*state.StateDB, error) {
+			return blockchain.StateAt(blockchain.GetHeaderByHash(hash).Root)
+		})
+	}
 
 	api.chainConfig = chainConfig
 	api.genesisHash = genesisHash
@@ -424,9 +467,73 @@ func (api *RetestethAPI) SetChainParams(ctx context.Context, chainParams ChainPa
 	api.txMap = make(map[common.Address]map[uint64]*types.Transaction)
 	api.txSenders = make(map[common.Address]struct{})
 	api.blockInterval = 0
+	api.istanbul = istanbulEng
+	api.blockPeriod = 0
+	if istanbulConfig != nil {
+		api.blockPeriod = istanbulConfig.BlockPeriod
+	}
+	api.validatorKeys = validatorKeys
 	return true, nil
 }
 
+// setupIstanbulGenesis turns the genesis into an Istanbul genesis with the
+// configured validators, and returns the engine configuration and the keys of
+// the validators.
+func setupIstanbulGenesis(genesis *core.Genesis, cfg *CParamsIstanbul) (*istanbul.Config, []*ecdsa.PrivateKey, error) {
+	if cfg == nil || len(cfg.Validators) == 0 {
+		return nil, nil, errors.New("the Istanbul seal engine needs the private keys of the validators")
+	}
+	config := *istanbul.DefaultConfig
+	config.ValidatorEnodeDBPath = ""
+	config.RoundStateDBPath = ""
+	config.ProposerPolicy = istanbul.ProposerPolicy(cfg.ProposerPolicy)
+	if config.ProposerPolicy > istanbul.ShuffledRoundRobin {
+		return nil, nil, fmt.Errorf("unknown proposer policy %d", config.ProposerPolicy)
+	}
+	if cfg.Epoch != 0 {
+		config.Epoch = uint64(cfg.Epoch)
+	}
+	if cfg.LookbackWindow != 0 {
+		config.LookbackWindow = uint64(cfg.LookbackWindow)
+	}
+	if config.LookbackWindow >= config.Epoch-1 {
+		return nil, nil, fmt.Errorf("lookback window %d must be less than the epoch size %d minus one", config.LookbackWindow, config.Epoch)
+	}
+	if cfg.BlockPeriod != nil {
+		config.BlockPeriod = uint64(*cfg.BlockPeriod)
+	}
+
+	keys := make([]*ecdsa.PrivateKey, len(cfg.Validators))
+	validators := make([]istanbul.ValidatorData, len(cfg.Validators))
+	for i, raw := range cfg.Validators {
+		key, err := crypto.ToECDSA(raw)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid key of validator %d: %v", i, err)
+		}
+		blsPrivateKey, err := blscrypto.ECDSAToBLS(key)
+		if err != nil {
+			return nil, nil, err
+		}
+		blsPublicKey, err := blscrypto.PrivateToPublic(blsPrivateKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		keys[i] = key
+		validators[i] = istanbul.ValidatorData{Address: crypto.PubkeyToAddress(key.PublicKey), BLSPublicKey: blsPublicKey}
+	}
+
+	genesis.Config.Istanbul = &params.IstanbulConfig{
+		Epoch:          config.Epoch,
+		ProposerPolicy: uint64(config.ProposerPolicy),
+		LookbackWindow: config.LookbackWindow,
+	}
+	genesis.Config.FullHeaderChainAvailable = true
+	genesis.Nonce = 0
+	genesis.Mixhash = types.IstanbulDigest
+	istanbulBackend.AppendValidatorsToGenesisBlock(genesis, validators)
+	return &config, keys, nil
+}
+
 func (api *RetestethAPI) SendRawTransaction(ctx context.Context, rawTx hexutil.Bytes) (common.Hash, error) {
 	tx := new(types.Transaction)
 	if err := rlp.DecodeBytes(rawTx, tx); err != nil {
@@ -479,7 +586,15 @@ func (api *RetestethAPI) mineBlock() error {
 		Time:       timestamp,
 	}
 	header.Coinbase = api.author
-	if api.engine != nil {
+	if api.istanbul != nil {
+		if err := api.istanbul.PrepareLocal(api.blockchain, header); err != nil {
+			return err
+		}
+		// Like the Istanbul miner, wait for a timestamp which is not in the future
+		if api.blockInterval == 0 {
+			time.Sleep(time.Until(time.Unix(int64(header.Time), 0)))
+		}
+	} else if api.engine != nil {
 		api.engine.Prepare(api.blockchain, header)
 	}
 	// If we are care about TheDAO hard-fork check whether to override the extra-data or not
@@ -521,7 +636,7 @@ func (api *RetestethAPI) mineBlock() error {
 				receipt, err := core.ApplyTransaction(
 					api.chainConfig,
 					api.blockchain,
-					&api.author,
+					&header.Coinbase,
 					gasPool,
 					statedb,
 					header,
@@ -554,6 +669,15 @@ func (api *RetestethAPI) mineBlock() error {
 	if err != nil {
 		return err
 	}
+	if api.istanbul != nil {
+		// The last block of an epoch carries the validator set of the next epoch
+		if err := api.istanbul.UpdateValSetDiff(api.blockchain, block.MutableHeader(), statedb); err != nil {
+			return err
+		}
+		if block, err = api.istanbul.SealLocal(api.blockchain, block, api.validatorKeys); err != nil {
+			return err
+		}
+	}
 	return api.importBlock(block)
 }
 
@@ -567,6 +691,9 @@ func (api *RetestethAPI) importBlock(block *types.Block) error {
 }
 
 func (api *RetestethAPI) ModifyTimestamp(ctx context.Context, interval uint64) (bool, error) {
+	if api.istanbul != nil && interval != 0 && interval < api.blockPeriod {
+		return false, fmt.Errorf("timestamp interval %d is below the Istanbul block period %d", interval, api.blockPeriod)
+	}
 	api.blockInterval = interval
 	return true, nil
 }
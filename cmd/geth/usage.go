@@ -79,7 +79,9 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.SyncModeFlag,
 			utils.ExitWhenSyncedFlag,
 			utils.GCModeFlag,
+			utils.TxSenderIndexFlag,
 			utils.EthStatsURLFlag,
+			utils.ExplorerIndexFlag,
 			utils.IdentityFlag,
 			utils.LightKDFFlag,
 			utils.WhitelistFlag,
@@ -217,6 +219,7 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.VMEnableDebugFlag,
 			utils.EVMInterpreterFlag,
 			utils.EWASMInterpreterFlag,
+			utils.VMParallelTxExecutionFlag,
 		},
 	},
 	{
@@ -241,6 +244,10 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.IstanbulBlockPeriodFlag,
 			utils.IstanbulProposerPolicyFlag,
 			utils.IstanbulLookbackWindowFlag,
+			utils.IstanbulAuditLogFlag,
+			utils.IstanbulAuditLogMaxSizeFlag,
+			utils.IstanbulAuditLogSyncIntervalFlag,
+			utils.IstanbulRefuseSigningFlag,
 		},
 	},
 	{
@@ -248,6 +255,7 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.AnnounceGossipPeriodFlag,
 			utils.AnnounceAggressiveGossipOnEnablementFlag,
+			utils.AnnounceLegacyEnodeURLFlag,
 		},
 	},
 	{
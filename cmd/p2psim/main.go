@@ -34,6 +34,11 @@
 //     $ p2psim node connect node01 node02
 //     Connected node01 to node02
 //
+// The scenario command runs a network of Celo nodes in-process instead and
+// reports how consensus and announce messages propagate through it:
+//
+//     $ p2psim scenario --template proxied --duration 1m
+//
 package main
 
 import (
@@ -103,6 +108,7 @@ func main() {
 			Usage:  "load a network snapshot from stdin",
 			Action: loadSnapshot,
 		},
+		scenarioCommand,
 		{
 			Name:   "node",
 			Usage:  "manage simulation nodes",
@@ -0,0 +1,131 @@
+// Copyright 2020 The Celo Authors
+// This file is part of celo-blockchain.
+//
+// celo-blockchain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// celo-blockchain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with celo-blockchain. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/simulations"
+	"github.com/ethereum/go-ethereum/p2p/simulations/adapters"
+	"github.com/ethereum/go-ethereum/p2p/simulations/celo"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var scenarioCommand = cli.Command{
+	Name:   "scenario",
+	Usage:  "run a Celo network scenario in-process",
+	Action: runScenario,
+	Description: `
+Runs a network of Celo validators, proxies, full nodes and light clients built
+from a topology template, in-process with the simulation adapter. The scenario
+measures the latency of consensus messages and the propagation time of announce
+messages, and checks that the addresses of proxied validators never reach any
+node but their proxies. Validators with standby proxies fail over to them
+halfway through the scenario.
+
+The template sets the numbers of nodes, which the flags override. Available
+templates: ` + strings.Join(celo.TemplateNames(), ", ") + `.`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "template",
+			Value: "proxied",
+			Usage: "topology template",
+		},
+		cli.IntFlag{
+			Name:  "validators",
+			Usage: "number of validators",
+		},
+		cli.IntFlag{
+			Name:  "proxies",
+			Usage: "number of proxies per validator, the ones after the first are on standby",
+		},
+		cli.IntFlag{
+			Name:  "fullnodes",
+			Usage: "number of full nodes",
+		},
+		cli.IntFlag{
+			Name:  "lightclients",
+			Usage: "number of light clients",
+		},
+		cli.DurationFlag{
+			Name:  "duration",
+			Value: 30 * time.Second,
+			Usage: "duration of the scenario",
+		},
+		cli.StringFlag{
+			Name:  "serve",
+			Value: "",
+			Usage: "address to serve the simulation API on while the scenario runs",
+		},
+	},
+}
+
+func runScenario(ctx *cli.Context) error {
+	if len(ctx.Args()) != 0 {
+		return cli.ShowCommandHelp(ctx, ctx.Command.Name)
+	}
+	topology, ok := celo.Templates[ctx.String("template")]
+	if !ok {
+		return fmt.Errorf("unknown topology template %q", ctx.String("template"))
+	}
+	for flag, value := range map[string]*int{
+		"validators":   &topology.Validators,
+		"proxies":      &topology.ProxiesPerValidator,
+		"fullnodes":    &topology.FullNodes,
+		"lightclients": &topology.LightClients,
+	} {
+		if ctx.IsSet(flag) {
+			*value = ctx.Int(flag)
+		}
+	}
+	if err := topology.Validate(); err != nil {
+		return err
+	}
+	spec, err := celo.NewSpec(topology.Validators)
+	if err != nil {
+		return err
+	}
+	network := simulations.NewNetwork(adapters.NewSimAdapter(spec.Services()), &simulations.NetworkConfig{ID: "celo"})
+	defer network.Shutdown()
+
+	layout, err := topology.Build(network, spec)
+	if err != nil {
+		return err
+	}
+	if addr := ctx.String("serve"); addr != "" {
+		log.Info("Serving the simulation API", "addr", addr)
+		go func() {
+			if err := http.ListenAndServe(addr, simulations.NewServer(network)); err != nil {
+				log.Error("Simulation API failed", "err", err)
+			}
+		}()
+	}
+	fmt.Fprintf(ctx.App.Writer, "Running %+v for %v\n", topology, ctx.Duration("duration"))
+	result, err := celo.Run(network, layout, ctx.Duration("duration"))
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(ctx.App.Writer, result)
+	if len(result.Leaks) > 0 {
+		return fmt.Errorf("%d validator IP leaks", len(result.Leaks))
+	}
+	return nil
+}
@@ -47,6 +47,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/ethstats"
+	"github.com/ethereum/go-ethereum/explorer"
 	"github.com/ethereum/go-ethereum/graphql"
 	"github.com/ethereum/go-ethereum/les"
 	"github.com/ethereum/go-ethereum/log"
@@ -218,6 +219,10 @@ var (
 		Usage: `Blockchain garbage collection mode ("full", "archive")`,
 		Value: "full",
 	}
+	TxSenderIndexFlag = cli.BoolFlag{
+		Name:  "txsenderindex",
+		Usage: "Index the transactions by sender and nonce (enables eth_getTransactionBySenderAndNonce)",
+	}
 	LightKDFFlag = cli.BoolFlag{
 		Name:  "lightkdf",
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
@@ -487,7 +492,11 @@ var (
 	// Logging and debug settings
 	EthStatsURLFlag = cli.StringFlag{
 		Name:  "ethstats",
-		Usage: "Reporting URL of a ethstats service (nodename:secret@host:port)",
+		Usage: "Reporting URL of a ethstats service (nodename:secret@host:port), or local sink of the signed reports (nodename@file://path, nodename@pull://host:port)",
+	}
+	ExplorerIndexFlag = cli.BoolFlag{
+		Name:  "explorer.index",
+		Usage: "Index the transactions, transfers and fees by address for the celo_getAddressHistory API (full nodes only)",
 	}
 	FakePoWFlag = cli.BoolFlag{
 		Name:  "fakepow",
@@ -741,6 +750,10 @@ var (
 		Usage: "External EVM configuration (default = built-in interpreter)",
 		Value: "",
 	}
+	VMParallelTxExecutionFlag = cli.BoolFlag{
+		Name:  "vm.parallel",
+		Usage: "Execute the transactions of a block optimistically in parallel (experimental)",
+	}
 
 	// Istanbul settings
 	IstanbulRequestTimeoutFlag = cli.Uint64Flag{
@@ -763,6 +776,25 @@ var (
 		Usage: "A validator's signature must be absent for this many consecutive blocks to be considered down for the uptime score",
 		Value: eth.DefaultConfig.Istanbul.LookbackWindow,
 	}
+	IstanbulAuditLogFlag = DirectoryFlag{
+		Name:  "istanbul.auditlog",
+		Usage: "Hash-chained log of the validator's key uses (relative to the data directory, disabled by default)",
+		Value: DirectoryString(eth.DefaultConfig.Istanbul.AuditLogPath),
+	}
+	IstanbulAuditLogMaxSizeFlag = cli.Uint64Flag{
+		Name:  "istanbul.auditlogmaxsize",
+		Usage: "Size in bytes above which the audit log is rotated, all the rotated logs being kept to verify the whole history (0 = no limit)",
+		Value: eth.DefaultConfig.Istanbul.AuditLogMaxSize,
+	}
+	IstanbulAuditLogSyncIntervalFlag = cli.Uint64Flag{
+		Name:  "istanbul.auditlogsyncinterval",
+		Usage: "Interval in milliseconds between the syncs of the audit log to disk, the key uses of the last interval being lost on a crash (0 = sync on every key use)",
+		Value: eth.DefaultConfig.Istanbul.AuditLogSyncInterval,
+	}
+	IstanbulRefuseSigningFlag = cli.StringFlag{
+		Name:  "istanbul.refusesigning",
+		Usage: "Comma separated key uses the validator refuses (consensus, seal, committedseal, epochseal, announce, versioncertificate, valenodesshare, decrypt, ethstats)",
+	}
 
 	// Announce settings
 	AnnounceGossipPeriodFlag = cli.Uint64Flag{
@@ -774,6 +806,10 @@ var (
 		Name:  "announce.aggressivegossiponenablement",
 		Usage: "Specifies if this node should do aggressive gossip on announce enablement",
 	}
+	AnnounceLegacyEnodeURLFlag = cli.BoolFlag{
+		Name:  "announce.legacyenodeurl",
+		Usage: "Also announce this node's enode URL unencrypted to the peers older than celo/67 (exposes the node's IP to any peer, only meant for the transition to the encrypted announce format)",
+	}
 
 	// Proxy node settings
 	ProxyFlag = cli.BoolFlag{
@@ -1449,8 +1485,23 @@ func setIstanbul(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	if ctx.GlobalIsSet(IstanbulProposerPolicyFlag.Name) {
 		cfg.Istanbul.ProposerPolicy = istanbul.ProposerPolicy(ctx.GlobalUint64(IstanbulProposerPolicyFlag.Name))
 	}
+	if ctx.GlobalIsSet(IstanbulAuditLogFlag.Name) {
+		cfg.Istanbul.AuditLogPath = ctx.GlobalString(IstanbulAuditLogFlag.Name)
+	}
+	if ctx.GlobalIsSet(IstanbulAuditLogMaxSizeFlag.Name) {
+		cfg.Istanbul.AuditLogMaxSize = ctx.GlobalUint64(IstanbulAuditLogMaxSizeFlag.Name)
+	}
+	if ctx.GlobalIsSet(IstanbulAuditLogSyncIntervalFlag.Name) {
+		cfg.Istanbul.AuditLogSyncInterval = ctx.GlobalUint64(IstanbulAuditLogSyncIntervalFlag.Name)
+	}
+	if ctx.GlobalIsSet(IstanbulRefuseSigningFlag.Name) {
+		cfg.Istanbul.RefusedSigningPurposes = splitAndTrim(ctx.GlobalString(IstanbulRefuseSigningFlag.Name))
+	}
 	cfg.Istanbul.ValidatorEnodeDBPath = stack.ResolvePath(cfg.Istanbul.ValidatorEnodeDBPath)
 	cfg.Istanbul.RoundStateDBPath = stack.ResolvePath(cfg.Istanbul.RoundStateDBPath)
+	if cfg.Istanbul.AuditLogPath != "" {
+		cfg.Istanbul.AuditLogPath = stack.ResolvePath(cfg.Istanbul.AuditLogPath)
+	}
 }
 
 func setAnnounce(ctx *cli.Context, cfg *eth.Config) {
@@ -1460,6 +1511,11 @@ func setAnnounce(ctx *cli.Context, cfg *eth.Config) {
 	} else {
 		cfg.Istanbul.AnnounceAggressiveGossipOnEnablement = istanbul.DefaultConfig.AnnounceAggressiveGossipOnEnablement
 	}
+	if ctx.GlobalIsSet(AnnounceLegacyEnodeURLFlag.Name) {
+		cfg.Istanbul.AnnounceLegacyEnodeURL = true
+	} else {
+		cfg.Istanbul.AnnounceLegacyEnodeURL = istanbul.DefaultConfig.AnnounceLegacyEnodeURL
+	}
 }
 
 func setProxyP2PConfig(ctx *cli.Context, proxyCfg *p2p.Config) {
@@ -1641,6 +1697,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	if ctx.GlobalIsSet(CacheNoPrefetchFlag.Name) {
 		cfg.NoPrefetch = ctx.GlobalBool(CacheNoPrefetchFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxSenderIndexFlag.Name) {
+		cfg.TxSenderIndex = ctx.GlobalBool(TxSenderIndexFlag.Name)
+	}
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheTrieFlag.Name) {
 		cfg.TrieCleanCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheTrieFlag.Name) / 100
 	}
@@ -1663,6 +1722,10 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 		cfg.EVMInterpreter = ctx.GlobalString(EVMInterpreterFlag.Name)
 	}
 
+	if ctx.GlobalIsSet(VMParallelTxExecutionFlag.Name) {
+		cfg.ParallelTxExecution = ctx.GlobalBool(VMParallelTxExecutionFlag.Name)
+	}
+
 	if ctx.GlobalIsSet(RPCGlobalGasCap.Name) {
 		cfg.RPCGasCap = new(big.Int).SetUint64(ctx.GlobalUint64(RPCGlobalGasCap.Name))
 	}
@@ -1771,6 +1834,20 @@ func RegisterEthStatsService(stack *node.Node, url string) {
 	}
 }
 
+// RegisterExplorerService configures the explorer index and adds it to the
+// given node.
+func RegisterExplorerService(stack *node.Node) {
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		var ethServ *eth.Ethereum
+		if err := ctx.Service(&ethServ); err != nil {
+			return nil, errors.New("explorer index requires a full node")
+		}
+		return explorer.New(ethServ), nil
+	}); err != nil {
+		Fatalf("Failed to register the explorer index service: %v", err)
+	}
+}
+
 // RegisterGraphQLService is a utility function to construct a new service and register it against a node.
 func RegisterGraphQLService(stack *node.Node, endpoint string, cors, vhosts []string, timeouts rpc.HTTPTimeouts) {
 	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
@@ -1908,7 +1985,10 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cache.TrieDirtyLimit = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
 	}
-	vmcfg := vm.Config{EnablePreimageRecording: ctx.GlobalBool(VMEnableDebugFlag.Name)}
+	vmcfg := vm.Config{
+		EnablePreimageRecording: ctx.GlobalBool(VMEnableDebugFlag.Name),
+		ParallelTxExecution:     ctx.GlobalBool(VMParallelTxExecutionFlag.Name),
+	}
 	chain, err = core.NewBlockChain(chainDb, cache, config, engine, vmcfg, nil)
 	if err != nil {
 		Fatalf("Can't create BlockChain: %v", err)
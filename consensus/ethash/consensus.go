@@ -300,6 +300,9 @@ func (ethash *Ethash) verifyHeader(chain consensus.ChainReader, header, parent *
 	if err := misc.VerifyForkHashes(chain.Config(), header, uncle); err != nil {
 		return err
 	}
+	if err := misc.VerifyBaseFee(chain.Config(), parent, header); err != nil {
+		return err
+	}
 	return nil
 }
 
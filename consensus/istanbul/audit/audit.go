@@ -0,0 +1,417 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package audit records the uses of the validator's keys in an append-only,
+// hash-chained log, and refuses the uses the operator's policy forbids.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/istanbul"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Purpose is the class of messages a key is used for.
+type Purpose string
+
+const (
+	PurposeConsensus          Purpose = "consensus"          // Istanbul consensus messages
+	PurposeSeal               Purpose = "seal"               // Block header seals
+	PurposeCommittedSeal      Purpose = "committedseal"      // BLS committed seals
+	PurposeEpochSeal          Purpose = "epochseal"          // BLS epoch validator set seals
+	PurposeAnnounce           Purpose = "announce"           // Announce messages
+	PurposeVersionCertificate Purpose = "versioncertificate" // Announce version certificates
+	PurposeValEnodesShare     Purpose = "valenodesshare"     // Validator enodes shared with the proxy
+	PurposeDecrypt            Purpose = "decrypt"            // Decryption of announce records
+	PurposeEthstats           Purpose = "ethstats"           // Ethstats reports
+)
+
+// Purposes are all the purposes keys are used for.
+var Purposes = []Purpose{
+	PurposeConsensus, PurposeSeal, PurposeCommittedSeal, PurposeEpochSeal, PurposeAnnounce,
+	PurposeVersionCertificate, PurposeValEnodesShare, PurposeDecrypt, PurposeEthstats,
+}
+
+var (
+	// ErrRefused is returned if the policy refuses a key use.
+	ErrRefused = errors.New("key use refused by policy")
+
+	errBrokenChain       = errors.New("broken audit log hash chain")
+	errMissingRotatedLog = errors.New("missing rotated audit log")
+)
+
+// Entry is a record of the audit log.
+type Entry struct {
+	Seq     uint64         `json:"seq"`
+	Time    int64          `json:"time"` // Unix time in nanoseconds
+	Purpose Purpose        `json:"purpose"`
+	Digest  common.Hash    `json:"digest"` // Keccak256 hash of the message
+	View    *istanbul.View `json:"view,omitempty"`
+	Refused bool           `json:"refused,omitempty"`
+	Prev    common.Hash    `json:"prev"` // Hash of the previous entry
+	Hash    common.Hash    `json:"hash"`
+}
+
+// hash returns the hash of the entry, chaining it to the previous one.
+func (e *Entry) hash() common.Hash {
+	unhashed := *e
+	unhashed.Hash = common.Hash{}
+	enc, _ := json.Marshal(&unhashed)
+	return crypto.Keccak256Hash(enc)
+}
+
+// link is a position in the hash chain: the sequence number and the previous
+// hash of the entry at that position.
+type link struct {
+	seq  uint64
+	prev common.Hash
+}
+
+// Auditor records the key uses in the audit log, if any, and refuses the uses of
+// the refused purposes. The log is opened on the first use, and rotated once it
+// grows past the maximum size, the hash chain continuing in the new log. Rotated
+// logs are never deleted, so the whole history can be verified. A nil Auditor
+// allows all key uses without recording them.
+type Auditor struct {
+	path         string
+	maxSize      int64
+	syncInterval time.Duration
+	refused      map[Purpose]bool
+
+	file        *os.File
+	size        int64
+	rotated     int  // Number of rotated logs
+	syncPending bool // Whether a sync of the log is scheduled
+	next        link
+	mu          sync.Mutex
+}
+
+// New creates an auditor recording key uses to the log at path, or nowhere if the
+// path is empty, and refusing the uses of the given purposes. The log is rotated
+// once it's larger than maxSize bytes, unless maxSize is 0. The log is synced to
+// disk before every key use if syncInterval is 0, and otherwise at most once per
+// syncInterval, so the key uses of the last interval may be lost on a crash.
+func New(path string, maxSize uint64, syncInterval time.Duration, refused []string) (*Auditor, error) {
+	a := &Auditor{path: path, maxSize: int64(maxSize), syncInterval: syncInterval, refused: make(map[Purpose]bool)}
+	for _, name := range refused {
+		purpose, err := parsePurpose(name)
+		if err != nil {
+			return nil, err
+		}
+		a.refused[purpose] = true
+	}
+	return a, nil
+}
+
+func parsePurpose(name string) (Purpose, error) {
+	for _, purpose := range Purposes {
+		if string(purpose) == name {
+			return purpose, nil
+		}
+	}
+	return "", fmt.Errorf("unknown key use purpose %q", name)
+}
+
+// Use records a key use of the given purpose on the message with the given digest,
+// at the given consensus view if any. It returns ErrRefused if the policy refuses
+// the use, and an error if the use can't be recorded, in which case the key must
+// not be used either.
+func (a *Auditor) Use(purpose Purpose, digest common.Hash, view *istanbul.View) error {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	refused := a.refused[purpose]
+	if a.path != "" {
+		if err := a.append(purpose, digest, view, refused); err != nil {
+			log.Error("Failed to record key use", "purpose", purpose, "err", err)
+			return err
+		}
+	}
+	if refused {
+		return ErrRefused
+	}
+	return nil
+}
+
+// Close closes the audit log.
+func (a *Auditor) Close() error {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.file == nil {
+		return nil
+	}
+	err := a.closeFile()
+	a.file = nil
+	return err
+}
+
+// closeFile closes the log, syncing it first if a sync is pending.
+func (a *Auditor) closeFile() error {
+	if a.syncPending {
+		a.syncPending = false
+		if err := a.file.Sync(); err != nil {
+			a.file.Close()
+			return err
+		}
+	}
+	return a.file.Close()
+}
+
+// sync syncs the log to disk, or schedules it once per sync interval.
+func (a *Auditor) sync() error {
+	if a.syncInterval == 0 {
+		return a.file.Sync()
+	}
+	if !a.syncPending {
+		a.syncPending = true
+		time.AfterFunc(a.syncInterval, a.flush)
+	}
+	return nil
+}
+
+// flush performs the scheduled sync of the log, unless it was closed meanwhile.
+func (a *Auditor) flush() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.syncPending || a.file == nil {
+		return
+	}
+	a.syncPending = false
+	if err := a.file.Sync(); err != nil {
+		log.Error("Failed to sync key use log", "path", a.path, "err", err)
+	}
+}
+
+// append appends an entry to the log, opening it if needed.
+func (a *Auditor) append(purpose Purpose, digest common.Hash, view *istanbul.View, refused bool) error {
+	if a.file == nil {
+		if err := a.open(); err != nil {
+			return err
+		}
+	}
+	entry := &Entry{
+		Seq:     a.next.seq,
+		Time:    time.Now().UnixNano(),
+		Purpose: purpose,
+		Digest:  digest,
+		View:    view,
+		Refused: refused,
+		Prev:    a.next.prev,
+	}
+	entry.Hash = entry.hash()
+	enc, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := a.file.Write(append(enc, '\n')); err != nil {
+		return err
+	}
+	if err := a.sync(); err != nil {
+		return err
+	}
+	a.size += int64(len(enc) + 1)
+	a.next = link{seq: entry.Seq + 1, prev: entry.Hash}
+
+	// The entry is recorded, so a failed rotation doesn't prevent the key use
+	if a.maxSize > 0 && a.size >= a.maxSize {
+		if err := a.rotate(); err != nil {
+			log.Error("Failed to rotate key use log", "path", a.path, "err", err)
+		}
+	}
+	return nil
+}
+
+// rotate moves the log after the rotated ones. The next entry reopens a new log.
+func (a *Auditor) rotate() error {
+	err := a.closeFile()
+	a.file, a.size = nil, 0
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(a.path, rotatedPath(a.path, a.rotated+1)); err != nil {
+		return err
+	}
+	a.rotated++
+	return nil
+}
+
+// rotatedPath returns the path of the i-th rotated log, the first one being the
+// oldest.
+func rotatedPath(path string, i int) string {
+	return fmt.Sprintf("%s.%d", path, i)
+}
+
+// rotatedLogs returns the number of rotated logs of the log at path, checking
+// none of them is missing.
+func rotatedLogs(path string) (int, error) {
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return 0, err
+	}
+	found := make(map[int]bool)
+	for _, match := range matches {
+		if i, err := strconv.Atoi(strings.TrimPrefix(match, path+".")); err == nil && i > 0 {
+			found[i] = true
+		}
+	}
+	for i := 1; i <= len(found); i++ {
+		if !found[i] {
+			return 0, fmt.Errorf("%v %s", errMissingRotatedLog, rotatedPath(path, i))
+		}
+	}
+	return len(found), nil
+}
+
+// open opens the log for appending after verifying its hash chain. A partially
+// written last entry is dropped. An empty log continues the hash chain of the
+// last rotated one.
+func (a *Auditor) open() error {
+	rotated, err := rotatedLogs(a.path)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(a.path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	_, next, size, err := verify(file)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	if size == 0 && rotated > 0 {
+		if _, next, err = verifyFile(rotatedPath(a.path, rotated)); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if info, err := file.Stat(); err == nil && info.Size() > size {
+		log.Warn("Dropping partially written key use record", "path", a.path)
+		if err := file.Truncate(size); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if _, err := file.Seek(size, io.SeekStart); err != nil {
+		file.Close()
+		return err
+	}
+	a.file, a.size, a.rotated, a.next = file, size, rotated, next
+	return nil
+}
+
+// Verify checks the hash chain of the log at path and of its rotated logs from
+// the first entry ever recorded, returning the number of entries in them. Any
+// removed rotated log or entry before the last one is detected.
+func Verify(path string) (uint64, error) {
+	rotated, err := rotatedLogs(path)
+	if err != nil {
+		return 0, err
+	}
+	var next link
+	for i := 1; i <= rotated+1; i++ {
+		logPath := path
+		if i <= rotated {
+			logPath = rotatedPath(path, i)
+		}
+		start, end, err := verifyFile(logPath)
+		if os.IsNotExist(err) && i > 1 {
+			// The current log is only created by the entry after a rotation
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		if start == end {
+			// Empty log, only the current one can be empty after a rotation
+			continue
+		}
+		if start != next {
+			return 0, fmt.Errorf("%v at %s", errBrokenChain, logPath)
+		}
+		next = end
+	}
+	return next.seq, nil
+}
+
+// verifyFile checks the hash chain of the log at path, returning the links
+// before its first entry and after its last one.
+func verifyFile(path string) (link, link, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return link{}, link{}, err
+	}
+	defer file.Close()
+
+	start, end, _, err := verify(file)
+	return start, end, err
+}
+
+// verify checks the hash chain of the log, returning the links before its first
+// entry and after its last one, and the size of the complete entries. The chain
+// starts at the first entry, which continues the chain of the rotated logs.
+func verify(r io.Reader) (link, link, int64, error) {
+	var (
+		start, next link
+		size        int64
+		buf         = bufio.NewReader(r)
+	)
+	for count := 0; ; count++ {
+		line, err := buf.ReadBytes('\n')
+		if err == io.EOF {
+			// A last line without newline is a partially written entry
+			return start, next, size, nil
+		}
+		if err != nil {
+			return start, next, size, err
+		}
+		var entry Entry
+		if err := json.Unmarshal(bytes.TrimSpace(line), &entry); err != nil {
+			return start, next, size, fmt.Errorf("invalid audit log entry %d: %v", count, err)
+		}
+		if count == 0 {
+			start = link{seq: entry.Seq, prev: entry.Prev}
+			next = start
+		}
+		if entry.Seq != next.seq || entry.Prev != next.prev || entry.Hash != entry.hash() {
+			return start, next, size, fmt.Errorf("%v at entry %d", errBrokenChain, entry.Seq)
+		}
+		next = link{seq: entry.Seq + 1, prev: entry.Hash}
+		size += int64(len(line))
+	}
+}
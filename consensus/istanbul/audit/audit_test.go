@@ -0,0 +1,214 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package audit
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/istanbul"
+)
+
+func TestAuditor(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	if _, err := New(path, 0, 0, []string{"unknown"}); err == nil {
+		t.Fatalf("Unknown purpose accepted")
+	}
+	a, err := New(path, 0, 0, []string{"ethstats"})
+	if err != nil {
+		t.Fatalf("Error creating auditor: %v", err)
+	}
+	view := &istanbul.View{Round: big.NewInt(1), Sequence: big.NewInt(10)}
+	if err := a.Use(PurposeConsensus, common.HexToHash("0x01"), view); err != nil {
+		t.Fatalf("Error recording consensus key use: %v", err)
+	}
+	if err := a.Use(PurposeEthstats, common.HexToHash("0x02"), nil); err != ErrRefused {
+		t.Fatalf("Refused key use error mismatch: have %v, want %v", err, ErrRefused)
+	}
+	a.Close()
+
+	// Refused key uses are recorded too, and the chain continues after reopening
+	if count, err := Verify(path); err != nil || count != 2 {
+		t.Fatalf("Verification mismatch: have %d entries (err %v), want 2", count, err)
+	}
+	a, _ = New(path, 0, 0, nil)
+	if err := a.Use(PurposeAnnounce, common.HexToHash("0x03"), nil); err != nil {
+		t.Fatalf("Error recording announce key use: %v", err)
+	}
+	a.Close()
+	if count, err := Verify(path); err != nil || count != 3 {
+		t.Fatalf("Verification mismatch: have %d entries (err %v), want 3", count, err)
+	}
+
+	// A partially written entry is dropped on reopening
+	file, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	file.Write([]byte(`{"seq":3,`))
+	file.Close()
+	a, _ = New(path, 0, 0, nil)
+	if err := a.Use(PurposeSeal, common.HexToHash("0x04"), nil); err != nil {
+		t.Fatalf("Error recording seal key use: %v", err)
+	}
+	a.Close()
+	if count, err := Verify(path); err != nil || count != 4 {
+		t.Fatalf("Verification mismatch: have %d entries (err %v), want 4", count, err)
+	}
+
+	// Tampered logs are detected, and no key use is allowed anymore
+	data, _ := ioutil.ReadFile(path)
+	data = bytes.Replace(data, []byte(`"purpose":"announce"`), []byte(`"purpose":"consensus"`), 1)
+	ioutil.WriteFile(path, data, 0600)
+	if _, err := Verify(path); err == nil {
+		t.Fatalf("Tampered log verified")
+	}
+	a, _ = New(path, 0, 0, nil)
+	if err := a.Use(PurposeConsensus, common.HexToHash("0x05"), nil); err == nil {
+		t.Fatalf("Key use allowed with a tampered log")
+	}
+	a.Close()
+
+	// Without a log, the policy is still applied
+	a, _ = New("", 0, 0, []string{"decrypt"})
+	if err := a.Use(PurposeConsensus, common.Hash{}, nil); err != nil {
+		t.Errorf("Error on unrecorded key use: %v", err)
+	}
+	if err := a.Use(PurposeDecrypt, common.Hash{}, nil); err != ErrRefused {
+		t.Errorf("Refused key use error mismatch: have %v, want %v", err, ErrRefused)
+	}
+}
+
+func TestAuditorRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	// Every entry is larger than the maximum size, so each one is rotated, and all
+	// the rotated logs are kept
+	const entries = 5
+	a, _ := New(path, 1, 0, nil)
+	for i := 0; i < entries; i++ {
+		if err := a.Use(PurposeConsensus, common.BigToHash(big.NewInt(int64(i))), nil); err != nil {
+			t.Fatalf("Error recording key use %d: %v", i, err)
+		}
+	}
+	a.Close()
+	if rotated, err := rotatedLogs(path); err != nil || rotated != entries {
+		t.Errorf("Rotated logs mismatch: have %d (err %v), want %d", rotated, err, entries)
+	}
+
+	// The chain continues from the rotated logs after reopening
+	a, _ = New(path, 1, 0, nil)
+	if err := a.Use(PurposeSeal, common.Hash{}, nil); err != nil {
+		t.Fatalf("Error recording key use after reopening: %v", err)
+	}
+	a.Close()
+	if count, err := Verify(path); err != nil || count != entries+1 {
+		t.Fatalf("Verification mismatch: have %d entries (err %v), want %d", count, err, entries+1)
+	}
+	data, _ := ioutil.ReadFile(rotatedPath(path, entries+1))
+	if !bytes.Contains(data, []byte(`"seq":5,`)) {
+		t.Errorf("Sequence not continued after reopening: %s", data)
+	}
+
+	// Removing the oldest rotated log, or any other one, is detected
+	oldest, _ := ioutil.ReadFile(rotatedPath(path, 1))
+	os.Remove(rotatedPath(path, 1))
+	for i := 2; i <= entries+1; i++ {
+		os.Rename(rotatedPath(path, i), rotatedPath(path, i-1))
+	}
+	if _, err := Verify(path); err == nil {
+		t.Errorf("Log with a removed oldest rotated log verified")
+	}
+	for i := entries + 1; i >= 2; i-- {
+		os.Rename(rotatedPath(path, i-1), rotatedPath(path, i))
+	}
+	ioutil.WriteFile(rotatedPath(path, 1), oldest, 0600)
+	if _, err := Verify(path); err != nil {
+		t.Fatalf("Restored log not verified: %v", err)
+	}
+	os.Remove(rotatedPath(path, 2))
+	if _, err := Verify(path); err == nil {
+		t.Errorf("Log with a missing rotated log verified")
+	}
+	a, _ = New(path, 1, 0, nil)
+	if err := a.Use(PurposeSeal, common.Hash{}, nil); err == nil {
+		t.Errorf("Key use allowed with a missing rotated log")
+	}
+	a.Close()
+}
+
+func TestAuditorSyncInterval(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	// Key uses only schedule a sync, which is done on closing at the latest
+	a, _ := New(path, 0, time.Hour, nil)
+	for i := 0; i < 3; i++ {
+		if err := a.Use(PurposeConsensus, common.BigToHash(big.NewInt(int64(i))), nil); err != nil {
+			t.Fatalf("Error recording key use %d: %v", i, err)
+		}
+	}
+	if !a.syncPending {
+		t.Errorf("Sync of the log not scheduled")
+	}
+	if err := a.Close(); err != nil {
+		t.Fatalf("Error closing auditor: %v", err)
+	}
+	if a.syncPending {
+		t.Errorf("Sync of the log still pending after closing")
+	}
+	if count, err := Verify(path); err != nil || count != 3 {
+		t.Fatalf("Verification mismatch: have %d entries (err %v), want 3", count, err)
+	}
+
+	// The scheduled sync is done after the interval
+	a, _ = New(path, 0, time.Millisecond, nil)
+	defer a.Close()
+	if err := a.Use(PurposeSeal, common.Hash{}, nil); err != nil {
+		t.Fatalf("Error recording key use: %v", err)
+	}
+	for i := 0; ; i++ {
+		a.mu.Lock()
+		pending := a.syncPending
+		a.mu.Unlock()
+		if !pending {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("Scheduled sync of the log not done")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
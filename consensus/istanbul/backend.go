@@ -28,6 +28,10 @@ import (
 	"github.com/ethereum/go-ethereum/event"
 )
 
+// DecryptFn is a decrypt callback function to request an ECIES ciphertext to be
+// decrypted by a backing account.
+type DecryptFn func(accounts.Account, []byte, []byte, []byte) ([]byte, error)
+
 // SignerFn is a signer callback function to request a header to be signed by a
 // backing account.
 type SignerFn func(accounts.Account, string, []byte) ([]byte, error)
@@ -99,5 +103,5 @@ type Backend interface {
 	RefreshValPeers(valset ValidatorSet)
 
 	// Authorize injects a private key into the consensus engine.
-	Authorize(address common.Address, signFn SignerFn, signHashBLSFn BLSSignerFn, signMessageBLSFn BLSMessageSignerFn)
+	Authorize(address common.Address, decryptFn DecryptFn, signFn SignerFn, signHashBLSFn BLSSignerFn, signMessageBLSFn BLSMessageSignerFn)
 }
@@ -17,7 +17,13 @@
 package backend
 
 import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"time"
@@ -25,7 +31,11 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/istanbul"
+	"github.com/ethereum/go-ethereum/consensus/istanbul/audit"
 	vet "github.com/ethereum/go-ethereum/consensus/istanbul/backend/internal/enodes"
+	comm_errors "github.com/ethereum/go-ethereum/contract_comm/errors"
+	"github.com/ethereum/go-ethereum/contract_comm/validators"
+	"github.com/ethereum/go-ethereum/crypto/ecies"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/rlp"
@@ -185,7 +195,9 @@ func (sb *Backend) shouldGenerateAndProcessAnnounce() (bool, error) {
 // The data structures that it prunes are:
 // 1)  cachedAnnounceMsgs
 // 2)  lastAnnounceGossiped
-// 3)  valEnodeTable
+// 3)  valPublicKeys
+// 4)  versionCertificates, announceSources, lastCertificateGossiped and pendingAnnounceRequests
+// 5)  valEnodeTable
 func (sb *Backend) pruneAnnounceDataStructures() error {
 	logger := sb.logger.New("func", "pruneAnnounceDataStructures")
 
@@ -214,6 +226,32 @@ func (sb *Backend) pruneAnnounceDataStructures() error {
 	}
 	sb.lastAnnounceGossipedMu.Unlock()
 
+	sb.valPublicKeysMu.Lock()
+	for remoteAddress := range sb.valPublicKeys {
+		if !regAndElectedVals[remoteAddress] {
+			logger.Trace("Deleting entry from valPublicKeys", "address", remoteAddress)
+			delete(sb.valPublicKeys, remoteAddress)
+		}
+	}
+	sb.valPublicKeysMu.Unlock()
+
+	sb.versionCertificatesMu.Lock()
+	for remoteAddress := range sb.versionCertificates {
+		if !regAndElectedVals[remoteAddress] {
+			logger.Trace("Deleting entry from versionCertificates", "address", remoteAddress)
+			delete(sb.versionCertificates, remoteAddress)
+			delete(sb.announceSources, remoteAddress)
+			delete(sb.lastCertificateGossiped, remoteAddress)
+		}
+	}
+	for request, pending := range sb.pendingAnnounceRequests {
+		if !regAndElectedVals[request.ValAddress] || time.Since(pending.requested) > 5*pendingAnnounceFetchTimeout {
+			logger.Trace("Deleting entry from pendingAnnounceRequests", "address", request.ValAddress, "destAddress", request.DestAddress)
+			delete(sb.pendingAnnounceRequests, request)
+		}
+	}
+	sb.versionCertificatesMu.Unlock()
+
 	if err := sb.valEnodeTable.PruneEntries(regAndElectedVals); err != nil {
 		logger.Trace("Error in pruning valEnodeTable", "err", err)
 		return err
@@ -226,6 +264,29 @@ func (sb *Backend) pruneAnnounceDataStructures() error {
 //
 // define the IstanbulAnnounce message format, the AnnounceMsgCache entries, the announce send function (both the gossip version and the "retrieve from cache" version), and the announce get function
 
+// minEncryptedAnnouncePeerVersion is the lowest eth protocol version of the peers that can
+// decode announce messages with an encrypted enode URL.  Peers with older versions are only
+// sent this node's own announce messages of the legacy format, if enabled for the transition
+// with the AnnounceLegacyEnodeURL setting.
+const minEncryptedAnnouncePeerVersion = 67
+
+// minCompactAnnouncePeerVersion is the lowest eth protocol version of the peers that gossip
+// signed announce versions (version certificates) instead of announce messages.  Announce
+// messages are only sent to those peers when they request them with a GetAnnounces message,
+// holding the record of the requesting validator only, so that they only travel towards the
+// validators that need them.
+const minCompactAnnouncePeerVersion = 68
+
+// pendingAnnounceFetchTimeout is the time after which an announce message being fetched
+// from a peer is requested again.
+const pendingAnnounceFetchTimeout = 1 * time.Minute
+
+// announceKeySize is the size of the AES key encrypting the enode URL of an announce message.
+const announceKeySize = 16
+
+// announceRecord is the entry of an announce message for a destination validator.  In the
+// legacy announce format, EncryptedEnodeURL holds the enode URL in plain text.  Otherwise it
+// holds the key of the enode URL, encrypted to the destination validator's public key.
 type announceRecord struct {
 	DestAddress       common.Address
 	EncryptedEnodeURL []byte
@@ -239,10 +300,20 @@ type announceData struct {
 	AnnounceRecords []*announceRecord
 	EnodeURLHash    common.Hash
 	Version         uint
+
+	// EncryptedEnodeURL is the enode URL encrypted with the key held by the announce records.
+	// It is nil for announce messages of the legacy format.
+	EncryptedEnodeURL []byte
 }
 
 func (ad *announceData) String() string {
-	return fmt.Sprintf("{Version: %v, EnodeURLHash: %v, AnnounceRecords: %v}", ad.Version, ad.EnodeURLHash.Hex(), ad.AnnounceRecords)
+	return fmt.Sprintf("{Version: %v, EnodeURLHash: %v, AnnounceRecords: %v, EncryptedEnodeURL length: %d}", ad.Version, ad.EnodeURLHash.Hex(), ad.AnnounceRecords, len(ad.EncryptedEnodeURL))
+}
+
+// legacy returns whether ad is of the legacy announce format, which peers of all versions can
+// decode.
+func (ad *announceData) legacy() bool {
+	return ad.EncryptedEnodeURL == nil
 }
 
 // ==============================================
@@ -270,7 +341,10 @@ func (ar *announceRecord) DecodeRLP(s *rlp.Stream) error {
 
 // EncodeRLP serializes ad into the Ethereum RLP format.
 func (ad *announceData) EncodeRLP(w io.Writer) error {
-	return rlp.Encode(w, []interface{}{ad.AnnounceRecords, ad.EnodeURLHash, ad.Version})
+	if ad.EncryptedEnodeURL == nil {
+		return rlp.Encode(w, []interface{}{ad.AnnounceRecords, ad.EnodeURLHash, ad.Version})
+	}
+	return rlp.Encode(w, []interface{}{ad.AnnounceRecords, ad.EnodeURLHash, ad.Version, ad.EncryptedEnodeURL})
 }
 
 // DecodeRLP implements rlp.Decoder, and load the ad fields from a RLP stream.
@@ -279,12 +353,28 @@ func (ad *announceData) DecodeRLP(s *rlp.Stream) error {
 		AnnounceRecords []*announceRecord
 		EnodeURLHash    common.Hash
 		Version         uint
+		Rest            []rlp.RawValue `rlp:"tail"`
 	}
 
 	if err := s.Decode(&msg); err != nil {
 		return err
 	}
 	ad.AnnounceRecords, ad.EnodeURLHash, ad.Version = msg.AnnounceRecords, msg.EnodeURLHash, msg.Version
+
+	// Announce messages with an encrypted enode URL have a fourth element
+	ad.EncryptedEnodeURL = nil
+	switch len(msg.Rest) {
+	case 0:
+	case 1:
+		if err := rlp.DecodeBytes(msg.Rest[0], &ad.EncryptedEnodeURL); err != nil {
+			return err
+		}
+		if ad.EncryptedEnodeURL == nil {
+			ad.EncryptedEnodeURL = []byte{}
+		}
+	default:
+		return errors.New("too many elements in announce data")
+	}
 	return nil
 }
 
@@ -293,20 +383,140 @@ func (ad *announceData) DecodeRLP(s *rlp.Stream) error {
 // by this node.  Note that it will only cache the announce msgs from registered or elected
 // validators.
 type announceMsgCachedEntry struct {
-	MsgVersion uint
-	MsgPayload []byte
+	MsgVersion       uint
+	MsgPayload       []byte // Announce msg with an encrypted enode URL, nil if not received
+	LegacyMsgPayload []byte // Announce msg of the legacy format, only generated for this node's own entry
+
+	// RecipientMsgPayloads are the announce msgs holding the record of a single destination
+	// validator, sent to the peers of the compact announce versions that request them.
+	RecipientMsgPayloads map[common.Address][]byte
+}
+
+// payload returns the payload of the given format, or nil if it wasn't received.
+func (entry *announceMsgCachedEntry) payload(legacy bool) []byte {
+	if legacy {
+		return entry.LegacyMsgPayload
+	}
+	return entry.MsgPayload
+}
+
+// withPayload returns a copy of entry holding payload as the announce msg with an encrypted
+// enode URL.
+func (entry *announceMsgCachedEntry) withPayload(version uint, payload []byte) *announceMsgCachedEntry {
+	cpy := &announceMsgCachedEntry{MsgVersion: version}
+	if entry != nil && entry.MsgVersion == version {
+		*cpy = *entry
+	}
+	cpy.MsgPayload = payload
+	return cpy
+}
+
+// withRecipientPayload returns a copy of entry holding payload as the announce msg for destAddress.
+func (entry *announceMsgCachedEntry) withRecipientPayload(version uint, destAddress common.Address, payload []byte) *announceMsgCachedEntry {
+	cpy := &announceMsgCachedEntry{MsgVersion: version}
+	if entry != nil && entry.MsgVersion == version {
+		*cpy = *entry
+	}
+	cpy.RecipientMsgPayloads = make(map[common.Address][]byte, len(cpy.RecipientMsgPayloads)+1)
+	if entry != nil && entry.MsgVersion == version {
+		for addr, recipientPayload := range entry.RecipientMsgPayloads {
+			cpy.RecipientMsgPayloads[addr] = recipientPayload
+		}
+	}
+	cpy.RecipientMsgPayloads[destAddress] = payload
+	return cpy
+}
+
+// payloadFor returns the announce msg to gossip to peer, or nil if peer can't decode any of the
+// cached ones.
+func (entry *announceMsgCachedEntry) payloadFor(peer consensus.Peer) []byte {
+	if peer.Version() >= minEncryptedAnnouncePeerVersion && entry.MsgPayload != nil {
+		return entry.MsgPayload
+	}
+	return entry.LegacyMsgPayload
+}
+
+// payloadTo returns the announce msg to send to peer when it requests the one for destAddress, or
+// nil if none of the cached ones can be sent.
+func (entry *announceMsgCachedEntry) payloadTo(peer consensus.Peer, destAddress common.Address) []byte {
+	if peer.Version() >= minCompactAnnouncePeerVersion {
+		if payload, ok := entry.RecipientMsgPayloads[destAddress]; ok {
+			return payload
+		}
+	}
+	return entry.payloadFor(peer)
+}
+
+// gossipTo returns whether the announce msg of the given format should be gossiped to peer.
+// Peers of the compact announce versions only receive announce msgs on request.
+func (entry *announceMsgCachedEntry) gossipTo(legacy bool) func(peer consensus.Peer) bool {
+	return func(peer consensus.Peer) bool {
+		if peer.Version() >= minCompactAnnouncePeerVersion {
+			return false
+		}
+		payload := entry.payloadFor(peer)
+		return payload != nil && bytes.Equal(payload, entry.payload(legacy))
+	}
+}
+
+// announceRequest is the request of a validator's announce msg.  Peers of the compact announce
+// versions request the announce msg holding the record of a single destination validator, or any
+// announce msg of the validator if DestAddress is the zero address.
+type announceRequest struct {
+	ValAddress  common.Address
+	DestAddress common.Address
+}
+
+// pendingAnnounceRequest holds the peers waiting for an announce msg this node is fetching.
+type pendingAnnounceRequest struct {
+	requested time.Time
+	peers     map[enode.ID]consensus.Peer
+}
+
+// encodeAnnounceRequests encodes the payload of a GetAnnounces message to peer.  Peers of the older
+// protocol versions are only sent the requested validator addresses.
+func encodeAnnounceRequests(peer consensus.Peer, requests []announceRequest) ([]byte, error) {
+	if peer.Version() >= minCompactAnnouncePeerVersion {
+		return rlp.EncodeToBytes(requests)
+	}
+	valAddresses := make([]common.Address, 0, len(requests))
+	requested := make(map[common.Address]bool)
+	for _, request := range requests {
+		if !requested[request.ValAddress] {
+			requested[request.ValAddress] = true
+			valAddresses = append(valAddresses, request.ValAddress)
+		}
+	}
+	return rlp.EncodeToBytes(valAddresses)
+}
+
+// decodeAnnounceRequests decodes the payload of a GetAnnounces message received from peer.
+func decodeAnnounceRequests(peer consensus.Peer, payload []byte) ([]announceRequest, error) {
+	var requests []announceRequest
+	if peer.Version() >= minCompactAnnouncePeerVersion {
+		err := rlp.DecodeBytes(payload, &requests)
+		return requests, err
+	}
+	var valAddresses []common.Address
+	if err := rlp.DecodeBytes(payload, &valAddresses); err != nil {
+		return nil, err
+	}
+	for _, valAddress := range valAddresses {
+		requests = append(requests, announceRequest{ValAddress: valAddress})
+	}
+	return requests, nil
 }
 
 // This function will request announce messages from a set of validator addresses from a peer
-func (sb *Backend) sendGetAnnounces(peer consensus.Peer, valAddresses []common.Address) error {
+func (sb *Backend) sendGetAnnounces(peer consensus.Peer, requests []announceRequest) error {
 	logger := sb.logger.New("func", "sendGetAnnounces")
-	valAddressesBytes, err := rlp.EncodeToBytes(valAddresses)
+	requestsBytes, err := encodeAnnounceRequests(peer, requests)
 	if err != nil {
-		logger.Error("Error encoding valAddresses", "valAddresses", common.ConvertToStringSlice(valAddresses), "err", err)
+		logger.Error("Error encoding announce requests", "requests", requests, "err", err)
 		return err
 	}
 
-	go peer.Send(istanbulGetAnnouncesMsg, valAddressesBytes)
+	sb.asyncSend(peer, istanbulGetAnnouncesMsg, requestsBytes)
 	return nil
 }
 
@@ -314,58 +524,161 @@ func (sb *Backend) sendGetAnnounces(peer consensus.Peer, valAddresses []common.A
 // from it's announceMsgCache.
 func (sb *Backend) handleGetAnnouncesMsg(peer consensus.Peer, payload []byte) error {
 	logger := sb.logger.New("func", "handleGetAnnouncesMsg")
-	var valAddresses []common.Address
 
-	if err := rlp.DecodeBytes(payload, &valAddresses); err != nil {
-		logger.Error("Error in decoding valAddresses", "err", err)
+	requests, err := decodeAnnounceRequests(peer, payload)
+	if err != nil {
+		logger.Error("Error in decoding announce requests", "err", err)
 		return err
 	}
 
 	sb.cachedAnnounceMsgsMu.RLock()
 	defer sb.cachedAnnounceMsgsMu.RUnlock()
+
+	// Announce msgs newer than the cached ones are fetched from the peers that sent their version
+	// certificates, and sent to the requesting peer once received.
+	fetches := make(map[enode.ID][]announceRequest)
+	sources := make(map[enode.ID]consensus.Peer)
 	// TODO:  Add support for the AnnounceMsg to contain multiple announce messages within it's payload
-	for _, valAddress := range valAddresses {
-		if cachedAnnounceMsgEntry, ok := sb.cachedAnnounceMsgs[valAddress]; ok {
-			logger.Trace("Sending announce msg", "peer", peer, "valAddress", valAddress)
-			go peer.Send(istanbulAnnounceMsg, cachedAnnounceMsgEntry.MsgPayload)
+	for _, request := range requests {
+		var (
+			cachedPayload []byte
+			cachedVersion uint
+		)
+		if cachedAnnounceMsgEntry, ok := sb.cachedAnnounceMsgs[request.ValAddress]; ok {
+			if cachedPayload = cachedAnnounceMsgEntry.payloadTo(peer, request.DestAddress); cachedPayload != nil {
+				cachedVersion = cachedAnnounceMsgEntry.MsgVersion
+			}
+		}
+		if source, fetching := sb.fetchAnnounce(request, cachedVersion, peer); fetching {
+			if source != nil {
+				logger.Trace("Fetching announce msg", "peer", peer, "request", request, "source", source)
+				fetches[source.Node().ID()] = append(fetches[source.Node().ID()], request)
+				sources[source.Node().ID()] = source
+			}
+			continue
+		}
+		if cachedPayload == nil {
+			logger.Trace("No announce msg to send to the peer", "peer", peer, "request", request)
+			continue
+		}
+		logger.Trace("Sending announce msg", "peer", peer, "request", request)
+		sb.asyncSend(peer, istanbulAnnounceMsg, cachedPayload)
+	}
+	for id, requests := range fetches {
+		if err := sb.sendGetAnnounces(sources[id], requests); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
+// fetchAnnounce returns whether the requested announce msg is being fetched, as this node has the
+// version certificate of an announce msg newer than cachedVersion.  In that case, peer is sent the
+// announce msg once received, and the returned source is the peer to request the announce msg from
+// if it's not requested already.
+func (sb *Backend) fetchAnnounce(request announceRequest, cachedVersion uint, peer consensus.Peer) (consensus.Peer, bool) {
+	sb.versionCertificatesMu.Lock()
+	defer sb.versionCertificatesMu.Unlock()
+
+	certificate, ok := sb.versionCertificates[request.ValAddress]
+	if !ok || certificate.AnnounceMsgVersion <= cachedVersion {
+		return nil, false
+	}
+	source := sb.announceSources[request.ValAddress]
+	if source == nil || source.Node().ID() == peer.Node().ID() {
+		return nil, false
+	}
+
+	pending, ok := sb.pendingAnnounceRequests[request]
+	if !ok {
+		pending = &pendingAnnounceRequest{peers: make(map[enode.ID]consensus.Peer)}
+		sb.pendingAnnounceRequests[request] = pending
+	}
+	pending.peers[peer.Node().ID()] = peer
+	if time.Since(pending.requested) < pendingAnnounceFetchTimeout {
+		return nil, true
+	}
+	pending.requested = time.Now()
+	return source, true
+}
+
+// deliverPendingAnnounce sends the announce msgs of entry to the peers that requested them while
+// they were being fetched.
+func (sb *Backend) deliverPendingAnnounce(valAddress common.Address, entry *announceMsgCachedEntry) {
+	sb.versionCertificatesMu.Lock()
+	if certificate, ok := sb.versionCertificates[valAddress]; ok && certificate.AnnounceMsgVersion > entry.MsgVersion {
+		// Still waiting for the announce msg of the certified version
+		sb.versionCertificatesMu.Unlock()
+		return
+	}
+	type delivery struct {
+		peer    consensus.Peer
+		payload []byte
+	}
+	var deliveries []delivery
+	for request, pending := range sb.pendingAnnounceRequests {
+		if request.ValAddress != valAddress {
+			continue
+		}
+		for id, peer := range pending.peers {
+			if payload := entry.payloadTo(peer, request.DestAddress); payload != nil {
+				deliveries = append(deliveries, delivery{peer: peer, payload: payload})
+				delete(pending.peers, id)
+			}
+		}
+		if len(pending.peers) == 0 {
+			delete(sb.pendingAnnounceRequests, request)
+		}
+	}
+	sb.versionCertificatesMu.Unlock()
+
+	for _, delivery := range deliveries {
+		sb.logger.Trace("Sending fetched announce msg", "func", "deliverPendingAnnounce", "peer", delivery.peer, "valAddress", valAddress)
+		sb.asyncSend(delivery.peer, istanbulAnnounceMsg, delivery.payload)
+	}
+}
+
 // generateAndGossipAnnounce will generate the lastest announce msg from this node (as opposed to retrieving from the announceMsgCache) and then broadcast it to it's peers,
 // which should then gossip the announce msg message throughout the p2p network, since this announce msg's timestamp should be the latest among all of this
 // validator's previous announce msgs.
 func (sb *Backend) generateAndGossipAnnounce() error {
 	logger := sb.logger.New("func", "generateAndGossipAnnounce")
 	logger.Trace("generateAndGossipAnnounce called")
-	istMsg, announceVersion, announceAddress, err := sb.generateAnnounce()
+	entry, announceData, err := sb.generateAnnounce()
 	if err != nil {
 		return err
 	}
 
-	if istMsg == nil {
+	if entry == nil {
 		return nil
 	}
 
-	// Convert to payload
-	payload, err := istMsg.Payload()
-	if err != nil {
-		logger.Error("Error in converting Istanbul Announce Message to payload", "AnnounceMsg", istMsg.String(), "err", err)
-		return err
-	}
-
 	// Add the generated announce message to this node's cache
 	sb.cachedAnnounceMsgsMu.Lock()
 	defer sb.cachedAnnounceMsgsMu.Unlock()
-	sb.cachedAnnounceMsgs[announceAddress] = &announceMsgCachedEntry{MsgVersion: announceVersion, MsgPayload: payload}
+	sb.cachedAnnounceMsgs[sb.Address()] = entry
 
-	return sb.Multicast(nil, payload, istanbulAnnounceMsg)
+	// Peers of the compact announce versions are only sent the certificate of the new version,
+	// and request the announce msg holding their record
+	if err := sb.generateAndGossipVersionCertificate(announceData.Version, announceData.EnodeURLHash); err != nil {
+		logger.Error("Error in gossiping the announce version certificate", "err", err)
+		return err
+	}
+
+	if err := sb.multicast(nil, entry.MsgPayload, istanbulAnnounceMsg, entry.gossipTo(false)); err != nil {
+		return err
+	}
+	if entry.LegacyMsgPayload == nil {
+		return nil
+	}
+	return sb.multicast(nil, entry.LegacyMsgPayload, istanbulAnnounceMsg, entry.gossipTo(true))
 }
 
-// This function is a helper function for generateAndGossipAnnounce.  It will create the latest announce msg for this node.
-func (sb *Backend) generateAnnounce() (*istanbul.Message, uint, common.Address, error) {
+// This function is a helper function for generateAndGossipAnnounce.  It will create the latest announce msgs for this node:
+// the announce msg with the records of all the remote validators, the announce msgs holding the record of a single remote
+// validator, and if enabled, the announce msg of the legacy format for the peers that can't decode encrypted enode URLs.
+func (sb *Backend) generateAnnounce() (*announceMsgCachedEntry, *announceData, error) {
 	logger := sb.logger.New("func", "generateAnnounce")
 	var enodeUrl string
 	if sb.config.Proxied {
@@ -373,7 +686,7 @@ func (sb *Backend) generateAnnounce() (*istanbul.Message, uint, common.Address,
 			enodeUrl = sb.proxyNode.externalNode.URLv4()
 		} else {
 			logger.Error("Proxied node is not connected to a proxy")
-			return nil, 0, common.Address{}, errNoProxyConnection
+			return nil, nil, errNoProxyConnection
 		}
 	} else {
 		enodeUrl = sb.p2pserver.Self().URLv4()
@@ -382,26 +695,96 @@ func (sb *Backend) generateAnnounce() (*istanbul.Message, uint, common.Address,
 	// Retrieve the set of remote validators' public key to encrypt the enodeUrl
 	regAndActiveVals, err := sb.retrieveRegisteredAndElectedValidators()
 	if err != nil {
-		return nil, 0, common.Address{}, err
+		return nil, nil, err
+	}
+	publicKeys := sb.retrieveValidatorPublicKeys(regAndActiveVals)
+
+	// The enode URL is encrypted with a random key, which is then encrypted to each remote
+	// validator's public key.  Validators whose public key is not known yet (as they are not
+	// registered, and this node didn't receive any announce message from them) are left out
+	// until the next announce.
+	key := make([]byte, announceKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, nil, err
+	}
+	encryptedEnodeURL, err := encryptEnodeURL(key, enodeUrl)
+	if err != nil {
+		logger.Error("Error encrypting the enode URL", "err", err)
+		return nil, nil, err
 	}
 
 	announceRecords := make([]*announceRecord, 0, len(regAndActiveVals))
+	var legacyAnnounceRecords []*announceRecord
 	for addr := range regAndActiveVals {
-		// TODO - Need to encrypt using the remote validator's validator key
-		announceRecords = append(announceRecords, &announceRecord{DestAddress: addr, EncryptedEnodeURL: []byte(enodeUrl)})
+		if addr == sb.Address() {
+			continue
+		}
+		if sb.config.AnnounceLegacyEnodeURL {
+			legacyAnnounceRecords = append(legacyAnnounceRecords, &announceRecord{DestAddress: addr, EncryptedEnodeURL: []byte(enodeUrl)})
+		}
+
+		publicKey, ok := publicKeys[addr]
+		if !ok {
+			logger.Trace("Public key of validator unknown, not adding it to the announce msg", "address", addr)
+			continue
+		}
+		encryptedKey, err := ecies.Encrypt(rand.Reader, ecies.ImportECDSAPublic(publicKey), key, nil, nil)
+		if err != nil {
+			logger.Warn("Error encrypting the enode URL key", "address", addr, "err", err)
+			continue
+		}
+		announceRecords = append(announceRecords, &announceRecord{DestAddress: addr, EncryptedEnodeURL: encryptedKey})
 	}
 
+	// Unix() returns a int64, but we need a uint for the golang rlp encoding implmentation. Warning: This timestamp value will be truncated in 2106.
+	version := uint(time.Now().Unix())
+	enodeURLHash := istanbul.RLPHash(enodeUrl)
+
+	// The announce msg of the legacy format exposes the enode URL to any peer, so it is only
+	// generated during the transition to the encrypted format if enabled
+	var legacyPayload []byte
+	if sb.config.AnnounceLegacyEnodeURL {
+		legacyAnnounceData := &announceData{
+			AnnounceRecords: legacyAnnounceRecords,
+			EnodeURLHash:    enodeURLHash,
+			Version:         version,
+		}
+		if legacyPayload, err = sb.signAnnounce(legacyAnnounceData); err != nil {
+			return nil, nil, err
+		}
+	}
 	announceData := &announceData{
-		AnnounceRecords: announceRecords,
-		EnodeURLHash:    istanbul.RLPHash(enodeUrl),
-		// Unix() returns a int64, but we need a uint for the golang rlp encoding implmentation. Warning: This timestamp value will be truncated in 2106.
-		Version: uint(time.Now().Unix()),
+		AnnounceRecords:   announceRecords,
+		EnodeURLHash:      enodeURLHash,
+		Version:           version,
+		EncryptedEnodeURL: encryptedEnodeURL,
+	}
+
+	entry := &announceMsgCachedEntry{MsgVersion: version, LegacyMsgPayload: legacyPayload, RecipientMsgPayloads: make(map[common.Address][]byte, len(announceRecords))}
+	if entry.MsgPayload, err = sb.signAnnounce(announceData); err != nil {
+		return nil, nil, err
+	}
+	for _, record := range announceRecords {
+		recipientAnnounceData := *announceData
+		recipientAnnounceData.AnnounceRecords = []*announceRecord{record}
+		if entry.RecipientMsgPayloads[record.DestAddress], err = sb.signAnnounce(&recipientAnnounceData); err != nil {
+			return nil, nil, err
+		}
 	}
 
+	logger.Debug("Generated an announce message", "AnnounceData", announceData.String())
+
+	return entry, announceData, nil
+}
+
+// signAnnounce returns the payload of the signed announce message holding announceData.
+func (sb *Backend) signAnnounce(announceData *announceData) ([]byte, error) {
+	logger := sb.logger.New("func", "signAnnounce")
+
 	announceBytes, err := rlp.EncodeToBytes(announceData)
 	if err != nil {
 		logger.Error("Error encoding announce content", "AnnounceData", announceData.String(), "err", err)
-		return nil, 0, common.Address{}, err
+		return nil, err
 	}
 
 	msg := &istanbul.Message{
@@ -412,14 +795,130 @@ func (sb *Backend) generateAnnounce() (*istanbul.Message, uint, common.Address,
 	}
 
 	// Sign the announce message
-	if err := msg.Sign(sb.Sign); err != nil {
+	if err := msg.Sign(sb.signer(audit.PurposeAnnounce)); err != nil {
 		logger.Error("Error in signing an Announce Message", "AnnounceMsg", msg.String(), "err", err)
-		return nil, 0, common.Address{}, err
+		return nil, err
 	}
 
-	logger.Debug("Generated an announce message", "IstanbulMsg", msg.String(), "AnnounceData", announceData.String())
+	// Convert to payload
+	payload, err := msg.Payload()
+	if err != nil {
+		logger.Error("Error in converting Istanbul Announce Message to payload", "AnnounceMsg", msg.String(), "err", err)
+		return nil, err
+	}
+	return payload, nil
+}
 
-	return msg, announceData.Version, msg.Address, nil
+// retrieveValidatorPublicKeys returns the public keys of the given validators to encrypt this node's
+// enode URL to.  The keys registered in the validators contract are used, falling back to the keys
+// recovered from the validators' announce msgs for the validators that aren't registered (such as
+// the validators of the genesis block).
+func (sb *Backend) retrieveValidatorPublicKeys(valAddresses map[common.Address]bool) map[common.Address]*ecdsa.PublicKey {
+	logger := sb.logger.New("func", "retrieveValidatorPublicKeys")
+	publicKeys := make(map[common.Address]*ecdsa.PublicKey, len(valAddresses))
+
+	currentBlock := sb.currentBlock()
+	if currentState, err := sb.stateAt(currentBlock.Hash()); err != nil {
+		logger.Warn("Error in retrieving the current state", "err", err)
+	} else if registeredKeys, err := validators.RetrieveRegisteredValidatorPublicKeys(currentBlock.Header(), currentState); err != nil {
+		if err != comm_errors.ErrSmartContractNotDeployed && err != comm_errors.ErrRegistryContractNotDeployed {
+			logger.Warn("Error in retrieving the registered validators' public keys", "err", err)
+		}
+	} else {
+		for addr, publicKey := range registeredKeys {
+			if valAddresses[addr] {
+				publicKeys[addr] = publicKey
+			}
+		}
+	}
+
+	sb.valPublicKeysMu.RLock()
+	defer sb.valPublicKeysMu.RUnlock()
+	for addr := range valAddresses {
+		if _, ok := publicKeys[addr]; ok {
+			continue
+		}
+		if publicKey, ok := sb.valPublicKeys[addr]; ok {
+			publicKeys[addr] = publicKey
+		}
+	}
+	return publicKeys
+}
+
+// encryptEnodeURL encrypts enodeURL with AES-GCM, prepending the random nonce to the ciphertext.
+func encryptEnodeURL(key []byte, enodeURL string) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, []byte(enodeURL), nil), nil
+}
+
+// decryptEnodeURL decrypts an enode URL encrypted by encryptEnodeURL.
+func decryptEnodeURL(key []byte, encryptedEnodeURL []byte) (string, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	if len(encryptedEnodeURL) < gcm.NonceSize() {
+		return "", errors.New("encrypted enode URL too short")
+	}
+	nonce, ciphertext := encryptedEnodeURL[:gcm.NonceSize()], encryptedEnodeURL[gcm.NonceSize():]
+	enodeURL, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(enodeURL), nil
+}
+
+// decryptAnnounceRecord returns the enode URL of an announce message for which record is the
+// entry of this node.
+func (sb *Backend) decryptAnnounceRecord(announceData *announceData, record *announceRecord) (string, error) {
+	// Legacy announce messages have the enode URL in plain text
+	if announceData.EncryptedEnodeURL == nil {
+		return string(record.EncryptedEnodeURL), nil
+	}
+	key, err := sb.Decrypt(record.EncryptedEnodeURL)
+	if err != nil {
+		return "", err
+	}
+	enodeUrl, err := decryptEnodeURL(key, announceData.EncryptedEnodeURL)
+	if err != nil {
+		return "", err
+	}
+	if istanbul.RLPHash(enodeUrl) != announceData.EnodeURLHash {
+		return "", errInvalidEnodeURLHash
+	}
+	return enodeUrl, nil
+}
+
+// storeValPublicKey records the public key of the validator that signed an announce message,
+// to encrypt this node's enode URL to that validator.
+func (sb *Backend) storeValPublicKey(msg *istanbul.Message) error {
+	payloadNoSig, err := msg.PayloadNoSig()
+	if err != nil {
+		return err
+	}
+	publicKey, err := istanbul.GetSignaturePublicKey(payloadNoSig, msg.Signature)
+	if err != nil {
+		return err
+	}
+	sb.valPublicKeysMu.Lock()
+	defer sb.valPublicKeysMu.Unlock()
+	sb.valPublicKeys[msg.Address] = publicKey
+	return nil
 }
 
 // This function will handle an announce message.
@@ -448,6 +947,12 @@ func (sb *Backend) handleAnnounceMsg(peer consensus.Peer, payload []byte) error
 		return errUnauthorizedAnnounceMessage
 	}
 
+	// Record the sender's public key, even if the message is an old one
+	if err := sb.storeValPublicKey(msg); err != nil {
+		logger.Warn("Error in recovering the public key of the announce message sender", "err", err)
+		return err
+	}
+
 	var announceData announceData
 	err = rlp.DecodeBytes(msg.Msg, &announceData)
 	if err != nil {
@@ -456,10 +961,24 @@ func (sb *Backend) handleAnnounceMsg(peer consensus.Peer, payload []byte) error
 	}
 
 	logger = logger.New("msgAddress", msg.Address, "msgVersion", announceData.Version)
+	legacy := announceData.legacy()
+
+	// Peers of the compact announce versions send the announce msgs holding the record of a single
+	// destination validator, which are not gossiped
+	recipient := !legacy && peer != nil && peer.Version() >= minCompactAnnouncePeerVersion && len(announceData.AnnounceRecords) == 1
+	cached := func(entry *announceMsgCachedEntry) bool {
+		if recipient {
+			_, ok := entry.RecipientMsgPayloads[announceData.AnnounceRecords[0].DestAddress]
+			return ok
+		}
+		return entry.payload(legacy) != nil
+	}
 
-	// Ignore the message if it's older than the one that this node currently has persisted and return from this function
+	// Ignore the message if it's older than the one that this node currently has persisted and return from this function.
+	// An announce msg of the same version is only accepted in the format that isn't cached yet.
 	sb.cachedAnnounceMsgsMu.Lock()
-	if cachedAnnounceMsgEntry, ok := sb.cachedAnnounceMsgs[msg.Address]; ok && cachedAnnounceMsgEntry.MsgVersion >= announceData.Version {
+	if cachedAnnounceMsgEntry, ok := sb.cachedAnnounceMsgs[msg.Address]; ok && (cachedAnnounceMsgEntry.MsgVersion > announceData.Version ||
+		cachedAnnounceMsgEntry.MsgVersion == announceData.Version && cached(cachedAnnounceMsgEntry)) {
 		sb.cachedAnnounceMsgsMu.Unlock()
 		logger.Trace("Received announce message that has older or same version than the one cached", "cached_timestamp", cachedAnnounceMsgEntry.MsgVersion)
 		return errOldAnnounceMessage
@@ -472,6 +991,15 @@ func (sb *Backend) handleAnnounceMsg(peer consensus.Peer, payload []byte) error
 		return err
 	}
 
+	// The announce msg must hold the enode URL certified for its version
+	sb.versionCertificatesMu.RLock()
+	certificate, ok := sb.versionCertificates[msg.Address]
+	sb.versionCertificatesMu.RUnlock()
+	if ok && certificate.AnnounceMsgVersion == announceData.Version && certificate.EnodeURLHash != announceData.EnodeURLHash {
+		logger.Warn("Announce message doesn't match the version certificate", "certificate", certificate)
+		return errInvalidEnodeURLHash
+	}
+
 	// If this is a registered or elected validator, then process the announce message
 	shouldProcessAnnounce, err := sb.shouldGenerateAndProcessAnnounce()
 	if err != nil {
@@ -483,8 +1011,11 @@ func (sb *Backend) handleAnnounceMsg(peer consensus.Peer, payload []byte) error
 		logger.Trace("Going to process an announce msg", "announce records", announceData.AnnounceRecords)
 		for _, announceRecord := range announceData.AnnounceRecords {
 			if announceRecord.DestAddress == sb.Address() {
-				// TODO: Decrypt the enodeURL using this validator's validator key after making changes to encrypt it
-				enodeUrl := string(announceRecord.EncryptedEnodeURL)
+				enodeUrl, err := sb.decryptAnnounceRecord(&announceData, announceRecord)
+				if err != nil {
+					logger.Warn("Error in decrypting the enodeURL", "err", err)
+					return err
+				}
 				node, err := enode.ParseV4(enodeUrl)
 				if err != nil {
 					logger.Error("Error in parsing enodeURL", "enodeUrl", enodeUrl)
@@ -501,12 +1032,35 @@ func (sb *Backend) handleAnnounceMsg(peer consensus.Peer, payload []byte) error
 		}
 	}
 
+	// Announce msgs of the legacy format expose the enode URL of their sender to any peer, so
+	// they are never cached, served or regossiped
+	if legacy {
+		return nil
+	}
+
 	sb.cachedAnnounceMsgsMu.Lock()
-	sb.cachedAnnounceMsgs[msg.Address] = &announceMsgCachedEntry{MsgVersion: announceData.Version, MsgPayload: payload}
+	cachedEntry := sb.cachedAnnounceMsgs[msg.Address]
+	newVersion := cachedEntry == nil || cachedEntry.MsgVersion < announceData.Version
+	var entry *announceMsgCachedEntry
+	if recipient {
+		entry = cachedEntry.withRecipientPayload(announceData.Version, announceData.AnnounceRecords[0].DestAddress, payload)
+	} else {
+		entry = cachedEntry.withPayload(announceData.Version, payload)
+	}
+	sb.cachedAnnounceMsgs[msg.Address] = entry
 	sb.cachedAnnounceMsgsMu.Unlock()
 
-	// Regossip this announce message
-	return sb.regossipAnnounce(msg, payload)
+	// Send this announce message to the peers that requested it, and regossip it to the peers
+	// of the older protocol versions.  The announce msg with all the records of a version is
+	// accepted only once, so it is gossiped regardless of the regossip interval.
+	sb.deliverPendingAnnounce(msg.Address, entry)
+	if recipient {
+		return nil
+	}
+	if !newVersion {
+		return sb.multicast(nil, payload, istanbulAnnounceMsg, entry.gossipTo(false))
+	}
+	return sb.regossipAnnounce(msg, payload, entry.gossipTo(false))
 }
 
 // validateAnnounce will do some validation to check the contents of the announce
@@ -550,7 +1104,7 @@ func (sb *Backend) validateAnnounce(announceData *announceData) (bool, error) {
 // Note that even if the registered/elected validator is not malicious, but changing their enode very frequently, the
 // other validators will eventually get that validator's latest enode, since all nodes will periodically check it's neighbors
 // for updated announce messages.
-func (sb *Backend) regossipAnnounce(msg *istanbul.Message, payload []byte) error {
+func (sb *Backend) regossipAnnounce(msg *istanbul.Message, payload []byte, sendTo func(consensus.Peer) bool) error {
 	logger := sb.logger.New("func", "regossipAnnounce", "announceSourceAddress", msg.Address)
 
 	sb.lastAnnounceGossipedMu.RLock()
@@ -564,7 +1118,7 @@ func (sb *Backend) regossipAnnounce(msg *istanbul.Message, payload []byte) error
 	sb.lastAnnounceGossipedMu.RUnlock()
 
 	logger.Trace("Regossiping the istanbul announce message", "IstanbulMsg", msg.String())
-	if err := sb.Multicast(nil, payload, istanbulAnnounceMsg); err != nil {
+	if err := sb.multicast(nil, payload, istanbulAnnounceMsg, sendTo); err != nil {
 		return err
 	}
 
@@ -579,14 +1133,22 @@ func (sb *Backend) regossipAnnounce(msg *istanbul.Message, payload []byte) error
 //
 // define the AnnounceVersion messge format, the getAnnounceVersions send and handle function, and the announceVersions handle function
 
+// announceVersion is the version of a validator's latest announce msg.  Announce versions
+// exchanged with peers of the compact announce versions are signed by the validator along
+// with the hash of its enode URL, and are gossiped as version certificates.
 type announceVersion struct {
 	ValAddress         common.Address
 	AnnounceMsgVersion uint
+	EnodeURLHash       common.Hash // Zero for unsigned announce versions
+	Signature          []byte      // Nil for unsigned announce versions
 }
 
 // EncodeRLP serializes announceVersion into the Ethereum RLP format.
 func (av *announceVersion) EncodeRLP(w io.Writer) error {
-	return rlp.Encode(w, []interface{}{av.ValAddress, av.AnnounceMsgVersion})
+	if av.Signature == nil {
+		return rlp.Encode(w, []interface{}{av.ValAddress, av.AnnounceMsgVersion})
+	}
+	return rlp.Encode(w, []interface{}{av.ValAddress, av.AnnounceMsgVersion, av.EnodeURLHash, av.Signature})
 }
 
 // DecodeRLP implements rlp.Decoder, and load the announceVerion fields from a RLP stream.
@@ -594,24 +1156,124 @@ func (av *announceVersion) DecodeRLP(s *rlp.Stream) error {
 	var msg struct {
 		ValAddress         common.Address
 		AnnounceMsgVersion uint
+		Rest               []rlp.RawValue `rlp:"tail"`
 	}
 
 	if err := s.Decode(&msg); err != nil {
 		return err
 	}
 	av.ValAddress, av.AnnounceMsgVersion = msg.ValAddress, msg.AnnounceMsgVersion
+
+	// Signed announce versions have the enode URL hash and the signature as third and fourth elements
+	av.EnodeURLHash, av.Signature = common.Hash{}, nil
+	switch len(msg.Rest) {
+	case 0:
+	case 2:
+		if err := rlp.DecodeBytes(msg.Rest[0], &av.EnodeURLHash); err != nil {
+			return err
+		}
+		if err := rlp.DecodeBytes(msg.Rest[1], &av.Signature); err != nil {
+			return err
+		}
+		if av.Signature == nil {
+			av.Signature = []byte{}
+		}
+	default:
+		return errors.New("invalid number of elements in announce version")
+	}
 	return nil
 }
 
 // String returns the string representation of announceVersion.
 func (av *announceVersion) String() string {
-	return fmt.Sprintf("{ValAddress: %s, AnnounceMsgTimstamp: %d}", av.ValAddress.String(), av.AnnounceMsgVersion)
+	return fmt.Sprintf("{ValAddress: %s, AnnounceMsgTimstamp: %d, Signed: %v}", av.ValAddress.String(), av.AnnounceMsgVersion, av.Signature != nil)
+}
+
+// versionCertificatePayload returns the data signed by a validator to certify the version of its
+// latest announce msg, and the enode URL it holds.
+func versionCertificatePayload(valAddress common.Address, version uint, enodeURLHash common.Hash) ([]byte, error) {
+	return rlp.EncodeToBytes([]interface{}{valAddress, version, enodeURLHash})
+}
+
+// verify checks that av is signed by its validator.
+func (av *announceVersion) verify() error {
+	payload, err := versionCertificatePayload(av.ValAddress, av.AnnounceMsgVersion, av.EnodeURLHash)
+	if err != nil {
+		return err
+	}
+	signer, err := istanbul.GetSignatureAddress(payload, av.Signature)
+	if err != nil {
+		return err
+	}
+	if signer != av.ValAddress {
+		return errInvalidVersionCertificate
+	}
+	return nil
+}
+
+// generateAndGossipVersionCertificate signs the version of this node's latest announce msg and the
+// hash of its enode URL, and sends it to the peers of the compact announce versions.
+func (sb *Backend) generateAndGossipVersionCertificate(version uint, enodeURLHash common.Hash) error {
+	payload, err := versionCertificatePayload(sb.Address(), version, enodeURLHash)
+	if err != nil {
+		return err
+	}
+	signature, err := sb.signWithPurpose(audit.PurposeVersionCertificate, payload)
+	if err != nil {
+		return err
+	}
+	certificate := &announceVersion{ValAddress: sb.Address(), AnnounceMsgVersion: version, EnodeURLHash: enodeURLHash, Signature: signature}
+
+	sb.versionCertificatesMu.Lock()
+	sb.versionCertificates[certificate.ValAddress] = certificate
+	delete(sb.announceSources, certificate.ValAddress)
+	sb.versionCertificatesMu.Unlock()
+
+	return sb.gossipVersionCertificates([]*announceVersion{certificate}, nil)
+}
+
+// storeVersionCertificate records a version certificate received from peer if it's newer than the
+// one this node has, and returns whether it should be regossiped.  Like announce msgs, the version
+// certificates of a validator are regossiped at most once every 5 minutes.  The more recent ones
+// are retrieved by the periodic announce versions check.
+func (sb *Backend) storeVersionCertificate(certificate *announceVersion, peer consensus.Peer) bool {
+	sb.versionCertificatesMu.Lock()
+	defer sb.versionCertificatesMu.Unlock()
+
+	if current, ok := sb.versionCertificates[certificate.ValAddress]; ok && current.AnnounceMsgVersion >= certificate.AnnounceMsgVersion {
+		return false
+	}
+	sb.versionCertificates[certificate.ValAddress] = certificate
+	sb.announceSources[certificate.ValAddress] = peer
+
+	if lastGossipTs, ok := sb.lastCertificateGossiped[certificate.ValAddress]; ok && time.Since(lastGossipTs) < 5*time.Minute {
+		return false
+	}
+	sb.lastCertificateGossiped[certificate.ValAddress] = time.Now()
+	return true
+}
+
+// gossipVersionCertificates sends version certificates to the peers of the compact announce
+// versions, except the one they were received from.
+func (sb *Backend) gossipVersionCertificates(certificates []*announceVersion, from consensus.Peer) error {
+	payload, err := rlp.EncodeToBytes(certificates)
+	if err != nil {
+		return err
+	}
+	for _, peer := range sb.broadcaster.FindPeers(nil, p2p.AnyPurpose) {
+		if peer.Version() < minCompactAnnouncePeerVersion || (from != nil && peer.Node().ID() == from.Node().ID()) {
+			continue
+		}
+		sb.logger.Trace("Gossiping version certificates", "func", "gossipVersionCertificates", "peer", peer, "certificates", certificates)
+		sb.asyncSend(peer, istanbulAnnounceVersionsMsg, payload)
+	}
+	return nil
 }
 
 // sendGetAnnounceVersions will send a GetAnnounceVersions message to a specific peer to request it's announceVersion set
 func (sb *Backend) sendGetAnnounceVersions(peer consensus.Peer) {
 	sb.logger.Trace("Sending a GetAnnounceVersions message", "func", "sendGetAnnounceVersions", "peer", peer)
-	go peer.Send(istanbulGetAnnounceVersionsMsg, []byte{})
+	sb.asyncSend(peer, istanbulGetAnnounceVersionsMsg, []byte{})
 }
 
 // handleGetAnnounceVersionsMsg will handle a GetAnnounceVersions message.  Specifically, it will return to the peer
@@ -623,12 +1285,28 @@ func (sb *Backend) handleGetAnnounceVersionsMsg(peer consensus.Peer, payload []b
 
 	sb.cachedAnnounceMsgsMu.RLock()
 	defer sb.cachedAnnounceMsgsMu.RUnlock()
+	sb.versionCertificatesMu.RLock()
+	defer sb.versionCertificatesMu.RUnlock()
 
-	announceVersions := make([]*announceVersion, 0, len(sb.cachedAnnounceMsgs))
+	// Peers of the compact announce versions are sent the version certificates, and the unsigned
+	// versions of the cached announce msgs that are newer
+	compact := peer.Version() >= minCompactAnnouncePeerVersion
+	announceVersions := make([]*announceVersion, 0, len(sb.cachedAnnounceMsgs)+len(sb.versionCertificates))
 
 	for valAddress, cachedAnnounceEntry := range sb.cachedAnnounceMsgs {
+		if cachedAnnounceEntry.payloadFor(peer) == nil {
+			continue
+		}
+		if certificate, ok := sb.versionCertificates[valAddress]; compact && ok && certificate.AnnounceMsgVersion >= cachedAnnounceEntry.MsgVersion {
+			continue
+		}
 		announceVersions = append(announceVersions, &announceVersion{ValAddress: valAddress, AnnounceMsgVersion: cachedAnnounceEntry.MsgVersion})
 	}
+	if compact {
+		for _, certificate := range sb.versionCertificates {
+			announceVersions = append(announceVersions, certificate)
+		}
+	}
 
 	announceVersionsBytes, err := rlp.EncodeToBytes(announceVersions)
 	if err != nil {
@@ -637,7 +1315,7 @@ func (sb *Backend) handleGetAnnounceVersionsMsg(peer consensus.Peer, payload []b
 	}
 
 	logger.Trace("Sending an AnnounceVersions message", "announceVersions", announceVersions, "peer", peer)
-	go peer.Send(istanbulAnnounceVersionsMsg, announceVersionsBytes)
+	sb.asyncSend(peer, istanbulAnnounceVersionsMsg, announceVersionsBytes)
 
 	return nil
 }
@@ -661,31 +1339,58 @@ func (sb *Backend) handleAnnounceVersionsMsg(peer consensus.Peer, payload []byte
 		return err
 	}
 
-	announcesToRequest := make([]common.Address, 0)
-	for _, announceVersion := range announceVersions {
+	// Only the validators request the announce msgs of signed versions.  The other nodes fetch
+	// them when their peers request them.
+	shouldProcessAnnounce, err := sb.shouldGenerateAndProcessAnnounce()
+	if err != nil {
+		return err
+	}
+
+	announcesToRequest := make([]announceRequest, 0)
+	newCertificates := make([]*announceVersion, 0)
+	for i := range announceVersions {
+		announceVersion := &announceVersions[i]
 		// Ignore this announceVersion entry if it's val address is not in the current registered or elected valset.
 		if !regAndActiveVals[announceVersion.ValAddress] {
 			logger.Trace("Ignoring announceVersion since it's not in active/elected valset", "valAddress", announceVersion.ValAddress)
 			continue
 		}
 
+		if announceVersion.Signature != nil {
+			if err := announceVersion.verify(); err != nil {
+				logger.Debug("Ignoring invalid version certificate", "valAddress", announceVersion.ValAddress, "err", err)
+				continue
+			}
+			if sb.storeVersionCertificate(announceVersion, peer) {
+				newCertificates = append(newCertificates, announceVersion)
+			}
+			if !shouldProcessAnnounce {
+				continue
+			}
+		}
+
+		// Validators request the announce msg holding their own record
+		request := announceRequest{ValAddress: announceVersion.ValAddress}
+		if shouldProcessAnnounce {
+			request.DestAddress = sb.Address()
+		}
 		sb.cachedAnnounceMsgsMu.RLock()
-		if cachedEntry, ok := sb.cachedAnnounceMsgs[announceVersion.ValAddress]; !ok || cachedEntry.MsgVersion < announceVersion.AnnounceMsgVersion {
-			announcesToRequest = append(announcesToRequest, announceVersion.ValAddress)
+		if cachedEntry, ok := sb.cachedAnnounceMsgs[announceVersion.ValAddress]; !ok || cachedEntry.MsgVersion < announceVersion.AnnounceMsgVersion ||
+			cachedEntry.payloadTo(peer, request.DestAddress) == nil {
+			announcesToRequest = append(announcesToRequest, request)
 		}
 		sb.cachedAnnounceMsgsMu.RUnlock()
 	}
 
-	if len(announcesToRequest) > 0 {
-		announcesToRequestBytes, err := rlp.EncodeToBytes(announcesToRequest)
-		if err != nil {
-			logger.Error("Error encoding announce to request array", "announcesToRequest", common.ConvertToStringSlice(announcesToRequest), "err", err)
-			return err
+	if len(newCertificates) > 0 {
+		if err := sb.gossipVersionCertificates(newCertificates, peer); err != nil {
+			logger.Warn("Error in gossiping version certificates", "err", err)
 		}
+	}
 
-		logger.Trace("Going to send a GetAnnounces", "announcesToRequest", common.ConvertToStringSlice(announcesToRequest), "peer", peer)
-
-		go peer.Send(istanbulGetAnnouncesMsg, announcesToRequestBytes)
+	if len(announcesToRequest) > 0 {
+		logger.Trace("Going to send a GetAnnounces", "announcesToRequest", announcesToRequest, "peer", peer)
+		return sb.sendGetAnnounces(peer, announcesToRequest)
 	}
 
 	return nil
@@ -1,11 +1,20 @@
 package backend
 
 import (
+	"bytes"
 	"net"
 	"testing"
+	"time"
 
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/consensustest"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/ecies"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/rlp"
 )
 
 func TestHandleIstAnnounce(t *testing.T) {
@@ -23,20 +32,50 @@ func TestHandleIstAnnounce(t *testing.T) {
 	val1Addr := getAddress()
 	val1P2pServer := &consensustest.MockP2PServer{Node: val1Node}
 
+	// Make the public key of val2 known to val1
+	val2Addr := valSet.GetByIndex(2).Address()
+	val2PrivateKey, _ := crypto.GenerateKey()
+	b.valPublicKeys[val2Addr] = &val2PrivateKey.PublicKey
+
 	// Set backend to val1
 	b.SetP2PServer(val1P2pServer)
-	b.Authorize(val1Addr, signerFn, signerBLSHashFn, signerBLSMessageFn)
+	b.Authorize(val1Addr, decryptFn, signerFn, signerBLSHashFn, signerBLSMessageFn)
 
 	// Generate an ist announce message using val1
-	istMsg, _, _, err := b.generateAnnounce()
+	generated, _, err := b.generateAnnounce()
 	if err != nil {
 		t.Fatalf("Error on generateAnnounce: %s", err)
 	}
-	istMsg.Sign(b.Sign)
-	payload, _ := istMsg.Payload()
+	payload := generated.MsgPayload
+	recipientPayload, ok := generated.RecipientMsgPayloads[val2Addr]
+	if !ok || len(generated.RecipientMsgPayloads) != 1 {
+		t.Fatalf("Announce messages for single validators mismatch: have %d", len(generated.RecipientMsgPayloads))
+	}
+
+	// The announce message must not contain val1's enode URL in plain text, and the
+	// announce message of the legacy format is only generated if enabled
+	if bytes.Contains(payload, []byte(val1Node.URLv4())) {
+		t.Fatalf("Announce message contains the enode URL in plain text")
+	}
+	if generated.LegacyMsgPayload != nil {
+		t.Fatalf("Legacy announce message generated by default")
+	}
+	b.config.AnnounceLegacyEnodeURL = true
+	legacyGenerated, _, err := b.generateAnnounce()
+	b.config.AnnounceLegacyEnodeURL = false
+	if err != nil {
+		t.Fatalf("Error on generateAnnounce: %s", err)
+	}
+	legacyPayload := legacyGenerated.LegacyMsgPayload
+	if !bytes.Contains(legacyPayload, []byte(val1Node.URLv4())) {
+		t.Fatalf("Legacy announce message doesn't contain the enode URL in plain text")
+	}
 
 	// Set backend to val2
-	b.address = valSet.GetByIndex(2).Address()
+	b.address = val2Addr
+	b.decryptFn = func(_ accounts.Account, c, s1, s2 []byte) ([]byte, error) {
+		return ecies.ImportECDSA(val2PrivateKey).Decrypt(c, s1, s2)
+	}
 
 	// Handle val1's announce message
 	if err = b.handleAnnounceMsg(nil, payload); err != nil {
@@ -50,4 +89,264 @@ func TestHandleIstAnnounce(t *testing.T) {
 	} else {
 		t.Errorf("Failed to save enode entry")
 	}
+
+	// The public key of val1 is known from its announce message
+	if publicKey, ok := b.valPublicKeys[val1Addr]; !ok || crypto.PubkeyToAddress(*publicKey) != val1Addr {
+		t.Errorf("Public key of val1 not recorded")
+	}
+
+	// The legacy announce message is processed, but never cached
+	if err = b.handleAnnounceMsg(nil, legacyPayload); err != nil {
+		t.Errorf("Legacy announce message rejected: %v", err)
+	}
+	if entry := b.cachedAnnounceMsgs[val1Addr]; entry.LegacyMsgPayload != nil {
+		t.Errorf("Legacy announce message cached")
+	}
+	// The announce message holding val2's record only is sent by the peers of the compact announce versions
+	compactPeer := newAnnounceTestPeer(minCompactAnnouncePeerVersion)
+	if err = b.handleAnnounceMsg(compactPeer, recipientPayload); err != nil {
+		t.Errorf("Announce message for val2 rejected: %v", err)
+	}
+	if err = b.handleAnnounceMsg(compactPeer, recipientPayload); err != errOldAnnounceMessage {
+		t.Errorf("Known announce message for val2 error mismatch: have %v, want %v", err, errOldAnnounceMessage)
+	}
+	entry := b.cachedAnnounceMsgs[val1Addr]
+	if !bytes.Equal(entry.MsgPayload, payload) || entry.LegacyMsgPayload != nil || !bytes.Equal(entry.RecipientMsgPayloads[val2Addr], recipientPayload) {
+		t.Errorf("Cached announce messages mismatch")
+	}
+	if !bytes.Equal(entry.payloadFor(newAnnounceTestPeer(minEncryptedAnnouncePeerVersion)), payload) ||
+		entry.payloadFor(newAnnounceTestPeer(minEncryptedAnnouncePeerVersion-1)) != nil ||
+		!bytes.Equal(entry.payloadTo(compactPeer, val2Addr), recipientPayload) ||
+		!bytes.Equal(entry.payloadTo(compactPeer, common.Address{}), payload) {
+		t.Errorf("Announce message format sent to peers mismatch")
+	}
+}
+
+func TestEnodeURLEncryption(t *testing.T) {
+	enodeURL := "enode://1dd9d65c4552b5eb43d5ad55a2ee3f56c6cbc1c64a5c8d659f51fcd51bace24351232b8d7821617d2b29b54b81cdefb9b3e9c37d7fd5f63270bcc9e1a6f6a439@1.2.3.4:30303"
+	key := make([]byte, announceKeySize)
+	encrypted, err := encryptEnodeURL(key, enodeURL)
+	if err != nil {
+		t.Fatalf("Error encrypting enode URL: %v", err)
+	}
+	decrypted, err := decryptEnodeURL(key, encrypted)
+	if err != nil {
+		t.Fatalf("Error decrypting enode URL: %v", err)
+	}
+	if decrypted != enodeURL {
+		t.Errorf("Decrypted enode URL mismatch: have %s, want %s", decrypted, enodeURL)
+	}
+
+	// Tampered ciphertexts are rejected
+	encrypted[len(encrypted)-1] ^= 0xff
+	if _, err := decryptEnodeURL(key, encrypted); err == nil {
+		t.Errorf("Tampered enode URL decrypted")
+	}
+}
+
+func TestAnnounceDataRLP(t *testing.T) {
+	records := []*announceRecord{{DestAddress: common.HexToAddress("0x01"), EncryptedEnodeURL: []byte("enode")}}
+
+	// Legacy announce messages keep their encoding
+	legacy := &announceData{AnnounceRecords: records, EnodeURLHash: common.HexToHash("0x02"), Version: 3}
+	enc, err := rlp.EncodeToBytes(legacy)
+	if err != nil {
+		t.Fatalf("Error encoding announce data: %v", err)
+	}
+	want, _ := rlp.EncodeToBytes([]interface{}{records, legacy.EnodeURLHash, legacy.Version})
+	if !bytes.Equal(enc, want) {
+		t.Errorf("Legacy encoding mismatch: have %x, want %x", enc, want)
+	}
+	var decoded announceData
+	if err := rlp.DecodeBytes(enc, &decoded); err != nil {
+		t.Fatalf("Error decoding announce data: %v", err)
+	}
+	if decoded.EncryptedEnodeURL != nil || !decoded.legacy() {
+		t.Errorf("Legacy announce data decoded with an encrypted enode URL")
+	}
+
+	// Announce messages with an encrypted enode URL
+	encrypted := &announceData{AnnounceRecords: records, EnodeURLHash: common.HexToHash("0x02"), Version: 3, EncryptedEnodeURL: []byte{}}
+	enc, err = rlp.EncodeToBytes(encrypted)
+	if err != nil {
+		t.Fatalf("Error encoding announce data: %v", err)
+	}
+	if err := rlp.DecodeBytes(enc, &decoded); err != nil {
+		t.Fatalf("Error decoding announce data: %v", err)
+	}
+	if decoded.EncryptedEnodeURL == nil || decoded.legacy() {
+		t.Errorf("Announce data decoded without an encrypted enode URL")
+	}
+}
+
+// announceTestPeer records the messages sent to it.
+type announceTestPeer struct {
+	node    *enode.Node
+	version int
+	sent    chan uint64
+}
+
+func newAnnounceTestPeer(version int) *announceTestPeer {
+	key, _ := crypto.GenerateKey()
+	return &announceTestPeer{
+		node:    enode.NewV4(&key.PublicKey, net.ParseIP("127.0.0.1"), 0, 0),
+		version: version,
+		sent:    make(chan uint64, 10),
+	}
+}
+
+func (p *announceTestPeer) Send(msgcode uint64, data interface{}) error {
+	p.sent <- msgcode
+	return nil
+}
+
+func (p *announceTestPeer) Node() *enode.Node { return p.node }
+
+func (p *announceTestPeer) Version() int { return p.version }
+
+func (p *announceTestPeer) expect(t *testing.T, msgcode uint64) {
+	t.Helper()
+	select {
+	case code := <-p.sent:
+		if code != msgcode {
+			t.Fatalf("message code mismatch: have %#x, want %#x", code, msgcode)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("message %#x not sent", msgcode)
+	}
+}
+
+func (p *announceTestPeer) expectNone(t *testing.T) {
+	t.Helper()
+	select {
+	case code := <-p.sent:
+		t.Fatalf("unexpected message %#x sent", code)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestVersionCertificate(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	enodeURLHash := common.HexToHash("0x03")
+	payload, _ := versionCertificatePayload(address, 10, enodeURLHash)
+	signature, _ := crypto.Sign(crypto.Keccak256(payload), key)
+
+	certificate := &announceVersion{ValAddress: address, AnnounceMsgVersion: 10, EnodeURLHash: enodeURLHash, Signature: signature}
+	if err := certificate.verify(); err != nil {
+		t.Fatalf("Valid version certificate rejected: %v", err)
+	}
+	for _, forged := range []*announceVersion{
+		{ValAddress: address, AnnounceMsgVersion: 11, EnodeURLHash: enodeURLHash, Signature: signature},
+		{ValAddress: address, AnnounceMsgVersion: 10, EnodeURLHash: common.HexToHash("0x04"), Signature: signature},
+	} {
+		if err := forged.verify(); err == nil {
+			t.Fatalf("Forged version certificate accepted: %v", forged)
+		}
+	}
+
+	// Unsigned announce versions keep their encoding, signed ones round trip
+	unsigned := &announceVersion{ValAddress: address, AnnounceMsgVersion: 10}
+	enc, _ := rlp.EncodeToBytes(unsigned)
+	want, _ := rlp.EncodeToBytes([]interface{}{address, uint(10)})
+	if !bytes.Equal(enc, want) {
+		t.Errorf("Unsigned announce version encoding mismatch: have %x, want %x", enc, want)
+	}
+	enc, _ = rlp.EncodeToBytes([]*announceVersion{unsigned, certificate})
+	var decoded []*announceVersion
+	if err := rlp.DecodeBytes(enc, &decoded); err != nil {
+		t.Fatalf("Error decoding announce versions: %v", err)
+	}
+	if decoded[0].Signature != nil || !bytes.Equal(decoded[1].Signature, signature) || decoded[1].EnodeURLHash != enodeURLHash {
+		t.Errorf("Decoded announce versions mismatch: have %v", decoded)
+	}
+}
+
+func TestFetchAnnounce(t *testing.T) {
+	b := &Backend{
+		logger:                  log.New(),
+		cachedAnnounceMsgs:      make(map[common.Address]*announceMsgCachedEntry),
+		versionCertificates:     make(map[common.Address]*announceVersion),
+		announceSources:         make(map[common.Address]consensus.Peer),
+		lastCertificateGossiped: make(map[common.Address]time.Time),
+		pendingAnnounceRequests: make(map[announceRequest]*pendingAnnounceRequest),
+	}
+	valAddress, destAddress := common.HexToAddress("0x01"), common.HexToAddress("0x02")
+	source := newAnnounceTestPeer(minCompactAnnouncePeerVersion)
+	requester1 := newAnnounceTestPeer(minCompactAnnouncePeerVersion)
+	requester2 := newAnnounceTestPeer(minEncryptedAnnouncePeerVersion)
+
+	// Peers of the compact announce versions request the announce msg of a destination validator
+	request1, _ := encodeAnnounceRequests(requester1, []announceRequest{{ValAddress: valAddress, DestAddress: destAddress}})
+	request2, _ := encodeAnnounceRequests(requester2, []announceRequest{{ValAddress: valAddress, DestAddress: destAddress}})
+	if requests, err := decodeAnnounceRequests(requester2, request2); err != nil || len(requests) != 1 || requests[0] != (announceRequest{ValAddress: valAddress}) {
+		t.Fatalf("Announce requests of older peers mismatch: have %v, %v", requests, err)
+	}
+
+	// Without a version certificate, there is nothing to send
+	b.handleGetAnnouncesMsg(requester1, request1)
+	source.expectNone(t)
+	requester1.expectNone(t)
+
+	// The announce msg of a certified version is fetched from the peer that sent the certificate, once per request
+	certificate := &announceVersion{ValAddress: valAddress, AnnounceMsgVersion: 10, Signature: []byte{}}
+	if !b.storeVersionCertificate(certificate, source) {
+		t.Fatalf("New version certificate not regossiped")
+	}
+	if b.storeVersionCertificate(certificate, requester1) {
+		t.Fatalf("Known version certificate regossiped")
+	}
+	b.handleGetAnnouncesMsg(requester1, request1)
+	source.expect(t, istanbulGetAnnouncesMsg)
+	b.handleGetAnnouncesMsg(requester1, request1)
+	source.expectNone(t)
+	b.handleGetAnnouncesMsg(requester2, request2)
+	source.expect(t, istanbulGetAnnouncesMsg)
+	requester1.expectNone(t)
+
+	// Older announce msgs don't satisfy the requests
+	b.deliverPendingAnnounce(valAddress, &announceMsgCachedEntry{MsgVersion: 9, MsgPayload: []byte{9}})
+	requester1.expectNone(t)
+
+	// The fetched announce msg of the destination validator is only sent to the peer that requested it
+	entry := (*announceMsgCachedEntry)(nil).withRecipientPayload(10, destAddress, []byte{12})
+	b.cachedAnnounceMsgs[valAddress] = entry
+	b.deliverPendingAnnounce(valAddress, entry)
+	requester1.expect(t, istanbulAnnounceMsg)
+	requester2.expectNone(t)
+
+	// The announce msg with all the records is sent to the other requesting peers
+	entry = entry.withPayload(10, []byte{10})
+	b.cachedAnnounceMsgs[valAddress] = entry
+	b.deliverPendingAnnounce(valAddress, entry)
+	requester1.expectNone(t)
+	requester2.expect(t, istanbulAnnounceMsg)
+	if len(b.pendingAnnounceRequests) != 0 {
+		t.Errorf("Pending announce requests left: %v", b.pendingAnnounceRequests)
+	}
+
+	// Further requests are answered from the cache
+	b.handleGetAnnouncesMsg(requester1, request1)
+	requester1.expect(t, istanbulAnnounceMsg)
+	source.expectNone(t)
+
+	// Announce msgs are only gossiped to the peers of the older protocol versions, in the
+	// format they can decode
+	legacyPeer := newAnnounceTestPeer(minEncryptedAnnouncePeerVersion - 1)
+	if gossipTo := entry.gossipTo(false); gossipTo(requester1) || !gossipTo(requester2) || gossipTo(legacyPeer) {
+		t.Errorf("Announce msg gossip peers mismatch")
+	}
+	if entry.gossipTo(true)(legacyPeer) {
+		t.Errorf("Missing legacy announce msg gossiped")
+	}
+	// Only the own announce msg is cached in the legacy format
+	ownEntry := *entry
+	ownEntry.LegacyMsgPayload = []byte{11}
+	entry = &ownEntry
+	if gossipTo := entry.gossipTo(true); gossipTo(requester1) || gossipTo(requester2) || !gossipTo(legacyPeer) {
+		t.Errorf("Legacy announce msg gossip peers mismatch")
+	}
+	if !bytes.Equal(entry.MsgPayload, []byte{10}) || !bytes.Equal(entry.LegacyMsgPayload, []byte{11}) || !bytes.Equal(entry.RecipientMsgPayloads[destAddress], []byte{12}) {
+		t.Errorf("Cached announce msgs mismatch: have %x, %x, %x", entry.MsgPayload, entry.LegacyMsgPayload, entry.RecipientMsgPayloads)
+	}
 }
@@ -17,6 +17,7 @@
 package backend
 
 import (
+	"crypto/ecdsa"
 	"errors"
 	"fmt"
 	"math/big"
@@ -29,6 +30,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/istanbul"
+	"github.com/ethereum/go-ethereum/consensus/istanbul/audit"
 	"github.com/ethereum/go-ethereum/consensus/istanbul/backend/internal/enodes"
 	istanbulCore "github.com/ethereum/go-ethereum/consensus/istanbul/core"
 	"github.com/ethereum/go-ethereum/consensus/istanbul/validator"
@@ -59,6 +61,9 @@ var (
 	// errInvalidSigningFn is returned when the consensus signing function is invalid.
 	errInvalidSigningFn = errors.New("invalid signing function for istanbul messages")
 
+	// errInvalidDecryptFn is returned when the decrypt function of the announce messages is invalid.
+	errInvalidDecryptFn = errors.New("invalid decrypt function for istanbul announce messages")
+
 	// errProxyAlreadySet is returned if a user tries to add a proxy that is already set.
 	// TODO - When we support multiple sentries per validator, this error will become irrelevant.
 	errProxyAlreadySet = errors.New("proxy already set")
@@ -72,6 +77,13 @@ var (
 	// errOldAnnounceMessage is returned when the received announce message's block number is earlier
 	// than a previous received message
 	errOldAnnounceMessage = errors.New("old announce message")
+
+	// errInvalidEnodeURLHash is returned when the decrypted enode URL of an announce message
+	// doesn't match the announced enode URL hash
+	errInvalidEnodeURLHash = errors.New("enode URL doesn't match the announced hash")
+
+	// errInvalidVersionCertificate is returned when an announce version is not signed by its validator
+	errInvalidVersionCertificate = errors.New("announce version not signed by the validator")
 )
 
 // Information about the proxy for a proxied validator
@@ -112,8 +124,14 @@ func New(config *istanbul.Config, db ethdb.Database) consensus.Istanbul {
 		announceThreadQuit:      make(chan struct{}),
 		lastAnnounceGossiped:    make(map[common.Address]time.Time),
 		cachedAnnounceMsgs:      make(map[common.Address]*announceMsgCachedEntry),
+		valPublicKeys:           make(map[common.Address]*ecdsa.PublicKey),
+		versionCertificates:     make(map[common.Address]*announceVersion),
+		announceSources:         make(map[common.Address]consensus.Peer),
+		lastCertificateGossiped: make(map[common.Address]time.Time),
+		pendingAnnounceRequests: make(map[announceRequest]*pendingAnnounceRequest),
 		valEnodesShareWg:        new(sync.WaitGroup),
 		valEnodesShareQuit:      make(chan struct{}),
+		sendQueues:              make(map[enode.ID]*peerSendQueue),
 		finalizationTimer:       metrics.NewRegisteredTimer("consensus/istanbul/backend/finalize", nil),
 		rewardDistributionTimer: metrics.NewRegisteredTimer("consensus/istanbul/backend/rewards", nil),
 	}
@@ -135,6 +153,13 @@ func New(config *istanbul.Config, db ethdb.Database) consensus.Istanbul {
 	}
 	backend.valEnodeTable = table
 
+	auditSyncInterval := time.Duration(config.AuditLogSyncInterval) * time.Millisecond
+	auditor, err := audit.New(config.AuditLogPath, config.AuditLogMaxSize, auditSyncInterval, config.RefusedSigningPurposes)
+	if err != nil {
+		logger.Crit("Can't create signer auditor", "err", err, "path", config.AuditLogPath)
+	}
+	backend.auditor = auditor
+
 	// Set the handler functions for each istanbul message type
 	backend.istanbulAnnounceMsgHandlers = make(map[uint64]announceMsgHandler)
 	backend.istanbulAnnounceMsgHandlers[istanbulGetAnnouncesMsg] = backend.handleGetAnnouncesMsg
@@ -153,10 +178,12 @@ type Backend struct {
 	istanbulEventMux *event.TypeMux
 
 	address          common.Address              // Ethereum address of the signing key
+	decryptFn        istanbul.DecryptFn          // Decrypt function to decrypt ECIES ciphertexts with
 	signFn           istanbul.SignerFn           // Signer function to authorize hashes with
 	signHashBLSFn    istanbul.BLSSignerFn        // Signer function to authorize hashes using BLS with
 	signMessageBLSFn istanbul.BLSMessageSignerFn // Signer function to authorize messages using BLS with
 	signFnMu         sync.RWMutex                // Protects the signer fields
+	auditor          *audit.Auditor              // Records the uses of the signing key, refusing the forbidden ones
 
 	core         istanbulCore.Engine
 	logger       log.Logger
@@ -202,6 +229,20 @@ type Backend struct {
 	cachedAnnounceMsgs   map[common.Address]*announceMsgCachedEntry
 	cachedAnnounceMsgsMu sync.RWMutex
 
+	// Map of the public keys of the registered/elected validators, recovered from their announce
+	// messages.  They are used to encrypt this node's enode URL in its announce messages.
+	valPublicKeys   map[common.Address]*ecdsa.PublicKey
+	valPublicKeysMu sync.RWMutex
+
+	// Latest signed announce versions of the registered/elected validators, the peers they were
+	// received from, the last time they were regossiped, and the peers waiting for announce
+	// messages this node is fetching.
+	versionCertificates     map[common.Address]*announceVersion
+	announceSources         map[common.Address]consensus.Peer
+	lastCertificateGossiped map[common.Address]time.Time
+	pendingAnnounceRequests map[announceRequest]*pendingAnnounceRequest
+	versionCertificatesMu   sync.RWMutex
+
 	valEnodesShareWg   *sync.WaitGroup
 	valEnodesShareQuit chan struct{}
 
@@ -212,6 +253,10 @@ type Backend struct {
 	// Proxy's validator
 	proxiedPeer consensus.Peer
 
+	// Outbound message queues of the registered peers
+	sendQueues   map[enode.ID]*peerSendQueue
+	sendQueuesMu sync.RWMutex
+
 	delegateSignFeed  event.Feed
 	delegateSignScope event.SubscriptionScope
 
@@ -236,7 +281,7 @@ func (sb *Backend) IsProxiedValidator() bool {
 	return sb.proxyNode != nil && sb.proxyNode.peer != nil
 }
 
-// SendDelegateSignMsgToProxy sends an istanbulDelegateSign message to a proxy
+// SendDelegateSignMsgToProxy queues an istanbulDelegateSign message to a proxy
 // if one exists
 func (sb *Backend) SendDelegateSignMsgToProxy(msg []byte) error {
 	if !sb.IsProxiedValidator() {
@@ -244,10 +289,11 @@ func (sb *Backend) SendDelegateSignMsgToProxy(msg []byte) error {
 		sb.logger.Error("SendDelegateSignMsgToProxy failed", "err", err)
 		return err
 	}
-	return sb.proxyNode.peer.Send(istanbulDelegateSign, msg)
+	sb.asyncSend(sb.proxyNode.peer, istanbulDelegateSign, msg)
+	return nil
 }
 
-// SendDelegateSignMsgToProxiedValidator sends an istanbulDelegateSign message to a
+// SendDelegateSignMsgToProxiedValidator queues an istanbulDelegateSign message to a
 // proxied validator if one exists
 func (sb *Backend) SendDelegateSignMsgToProxiedValidator(msg []byte) error {
 	if !sb.IsProxy() {
@@ -255,15 +301,17 @@ func (sb *Backend) SendDelegateSignMsgToProxiedValidator(msg []byte) error {
 		sb.logger.Error("SendDelegateSignMsgToProxiedValidator failed", "err", err)
 		return err
 	}
-	return sb.proxiedPeer.Send(istanbulDelegateSign, msg)
+	sb.asyncSend(sb.proxiedPeer, istanbulDelegateSign, msg)
+	return nil
 }
 
 // Authorize implements istanbul.Backend.Authorize
-func (sb *Backend) Authorize(address common.Address, signFn istanbul.SignerFn, signHashBLSFn istanbul.BLSSignerFn, signMessageBLSFn istanbul.BLSMessageSignerFn) {
+func (sb *Backend) Authorize(address common.Address, decryptFn istanbul.DecryptFn, signFn istanbul.SignerFn, signHashBLSFn istanbul.BLSSignerFn, signMessageBLSFn istanbul.BLSMessageSignerFn) {
 	sb.signFnMu.Lock()
 	defer sb.signFnMu.Unlock()
 
 	sb.address = address
+	sb.decryptFn = decryptFn
 	sb.signFn = signFn
 	sb.signHashBLSFn = signHashBLSFn
 	sb.signMessageBLSFn = signMessageBLSFn
@@ -278,6 +326,9 @@ func (sb *Backend) Address() common.Address {
 // Close the backend
 func (sb *Backend) Close() error {
 	sb.delegateSignScope.Close()
+	if err := sb.auditor.Close(); err != nil {
+		sb.logger.Error("Failed to close signer audit log", "err", err)
+	}
 	return sb.valEnodeTable.Close()
 }
 
@@ -403,6 +454,11 @@ func (sb *Backend) BroadcastConsensusMsg(destAddresses []common.Address, payload
 // If the destAddresses param is set to nil, then this function will send the message to all connected
 // peers.
 func (sb *Backend) Multicast(destAddresses []common.Address, payload []byte, ethMsgCode uint64) error {
+	return sb.multicast(destAddresses, payload, ethMsgCode, nil)
+}
+
+// multicast is Multicast restricted to the peers for which sendTo returns true, if it's not nil.
+func (sb *Backend) multicast(destAddresses []common.Address, payload []byte, ethMsgCode uint64, sendTo func(consensus.Peer) bool) error {
 	logger := sb.logger.New("func", "Multicast")
 
 	// Get peers to send.
@@ -419,6 +475,10 @@ func (sb *Backend) Multicast(destAddresses []common.Address, payload []byte, eth
 
 	if len(peers) > 0 {
 		for _, p := range peers {
+			if sendTo != nil && !sendTo(p) {
+				logger.Trace("Message not meant for the peer.  Not sending it to peer", "peer", p, "version", p.Version())
+				continue
+			}
 			if ethMsgCode == istanbulAnnounceMsg {
 				nodePubKey := p.Node().Pubkey()
 				nodeAddr := crypto.PubkeyToAddress(*nodePubKey)
@@ -440,7 +500,7 @@ func (sb *Backend) Multicast(destAddresses []common.Address, payload []byte, eth
 			}
 			logger.Trace("Sending istanbul message to peer", "peer", p)
 
-			go p.Send(ethMsgCode, payload)
+			sb.asyncSend(p, ethMsgCode, payload)
 		}
 	}
 	return nil
@@ -628,18 +688,50 @@ func (sb *Backend) verifyValSetDiff(proposal istanbul.Proposal, block *types.Blo
 
 // Sign implements istanbul.Backend.Sign
 func (sb *Backend) Sign(data []byte) ([]byte, error) {
+	return sb.signWithPurpose(audit.PurposeConsensus, data)
+}
+
+// signer returns a function signing the data for the given purpose.
+func (sb *Backend) signer(purpose audit.Purpose) func([]byte) ([]byte, error) {
+	return func(data []byte) ([]byte, error) {
+		return sb.signWithPurpose(purpose, data)
+	}
+}
+
+// signWithPurpose signs the data with the backend's private key, if the audit
+// log records the use and the signing policy allows it.
+func (sb *Backend) signWithPurpose(purpose audit.Purpose, data []byte) ([]byte, error) {
 	if sb.signFn == nil {
 		return nil, errInvalidSigningFn
 	}
+	if err := sb.auditKeyUse(purpose, data); err != nil {
+		return nil, err
+	}
 	sb.signFnMu.RLock()
 	defer sb.signFnMu.RUnlock()
 	return sb.signFn(accounts.Account{Address: sb.address}, accounts.MimetypeIstanbul, data)
 }
 
+// Decrypt decrypts an ECIES ciphertext with the backend's private key
+func (sb *Backend) Decrypt(ciphertext []byte) ([]byte, error) {
+	if sb.decryptFn == nil {
+		return nil, errInvalidDecryptFn
+	}
+	if err := sb.auditKeyUse(audit.PurposeDecrypt, ciphertext); err != nil {
+		return nil, err
+	}
+	sb.signFnMu.RLock()
+	defer sb.signFnMu.RUnlock()
+	return sb.decryptFn(accounts.Account{Address: sb.address}, ciphertext, nil, nil)
+}
+
 func (sb *Backend) SignBlockHeader(data []byte) (blscrypto.SerializedSignature, error) {
 	if sb.signHashBLSFn == nil {
 		return blscrypto.SerializedSignature{}, errInvalidSigningFn
 	}
+	if err := sb.auditKeyUse(audit.PurposeCommittedSeal, data); err != nil {
+		return blscrypto.SerializedSignature{}, err
+	}
 	sb.signFnMu.RLock()
 	defer sb.signFnMu.RUnlock()
 	return sb.signHashBLSFn(accounts.Account{Address: sb.address}, data)
@@ -649,6 +741,9 @@ func (sb *Backend) SignBLSWithCompositeHash(data []byte) (blscrypto.SerializedSi
 	if sb.signMessageBLSFn == nil {
 		return blscrypto.SerializedSignature{}, errInvalidSigningFn
 	}
+	if err := sb.auditKeyUse(audit.PurposeEpochSeal, data); err != nil {
+		return blscrypto.SerializedSignature{}, err
+	}
 	sb.signFnMu.RLock()
 	defer sb.signFnMu.RUnlock()
 	// Currently, ExtraData is unused. In the future, it could include data that could be used to introduce
@@ -658,6 +753,27 @@ func (sb *Backend) SignBLSWithCompositeHash(data []byte) (blscrypto.SerializedSi
 	return sb.signMessageBLSFn(accounts.Account{Address: sb.address}, data, []byte{})
 }
 
+// auditKeyUse records a use of the backend's private key on the data in the
+// audit log. The consensus messages are recorded with the current view, as they
+// are signed by the core.
+func (sb *Backend) auditKeyUse(purpose audit.Purpose, data []byte) error {
+	var view *istanbul.View
+	switch purpose {
+	case audit.PurposeConsensus, audit.PurposeCommittedSeal, audit.PurposeEpochSeal:
+		if sb.core != nil {
+			view = sb.core.CurrentView()
+		}
+	}
+	return sb.auditor.Use(purpose, crypto.Keccak256Hash(data), view)
+}
+
+// AuditKeyUse records a use of the backend's private key outside of consensus,
+// on the message with the given hash, returning an error if the key must not be
+// used.
+func (sb *Backend) AuditKeyUse(purpose audit.Purpose, msgHash common.Hash) error {
+	return sb.auditor.Use(purpose, msgHash, nil)
+}
+
 // CheckSignature implements istanbul.Backend.CheckSignature
 func (sb *Backend) CheckSignature(data []byte, address common.Address, sig []byte) error {
 	signer, err := istanbul.GetSignatureAddress(data, sig)
@@ -792,6 +908,26 @@ func (sb *Backend) removeProxy(node *enode.Node) {
 	}
 }
 
+// SetProxy replaces the proxy of a proxied validator and its configured proxy
+// enodes. If the validator is running, it peers with the new proxy right away.
+func (sb *Backend) SetProxy(node, externalNode *enode.Node) error {
+	if !sb.config.Proxied {
+		return errors.New("Can't set proxy for node that is not configured to be proxied")
+	}
+	sb.coreMu.Lock()
+	defer sb.coreMu.Unlock()
+
+	sb.config.ProxyInternalFacingNode = node
+	sb.config.ProxyExternalFacingNode = externalNode
+	if !sb.coreStarted {
+		return nil
+	}
+	if sb.proxyNode != nil {
+		sb.removeProxy(sb.proxyNode.node)
+	}
+	return sb.addProxy(node, externalNode)
+}
+
 // RefreshValPeers will create 'validator' type peers to all the valset validators, and disconnect from the
 // peers that are not part of the valset.
 // It will also disconnect all validator connections if this node is not a validator.
@@ -18,7 +18,10 @@ package backend
 
 import (
 	"crypto/ecdsa"
+	"io/ioutil"
 	"math/big"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -27,10 +30,13 @@ import (
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus/istanbul"
+	"github.com/ethereum/go-ethereum/consensus/istanbul/audit"
 	"github.com/ethereum/go-ethereum/consensus/istanbul/validator"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	blscrypto "github.com/ethereum/go-ethereum/crypto/bls"
+	"github.com/ethereum/go-ethereum/crypto/ecies"
 )
 
 func TestSign(t *testing.T) {
@@ -50,6 +56,39 @@ func TestSign(t *testing.T) {
 	}
 }
 
+func TestSignRefusedPurpose(t *testing.T) {
+	dir, err := ioutil.TempDir("", "istanbul-audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := *istanbul.DefaultConfig
+	config.ValidatorEnodeDBPath = ""
+	config.RoundStateDBPath = ""
+	config.AuditLogPath = filepath.Join(dir, "audit.log")
+	config.RefusedSigningPurposes = []string{string(audit.PurposeEthstats)}
+	b := New(&config, rawdb.NewMemoryDatabase()).(*Backend)
+	defer b.Close()
+	key, _ := generatePrivateKey()
+	b.Authorize(crypto.PubkeyToAddress(key.PublicKey), decryptFn, signerFn, signerBLSHashFn, signerBLSMessageFn)
+
+	// Refusing ethstats reports doesn't prevent signing consensus messages and seals
+	data := []byte("Here is a string....")
+	if err := b.AuditKeyUse(audit.PurposeEthstats, crypto.Keccak256Hash(data)); err != audit.ErrRefused {
+		t.Errorf("ethstats error mismatch: have %v, want %v", err, audit.ErrRefused)
+	}
+	if _, err := b.Sign(data); err != nil {
+		t.Errorf("error signing consensus message: %v", err)
+	}
+	if _, err := b.signWithPurpose(audit.PurposeSeal, data); err != nil {
+		t.Errorf("error signing seal: %v", err)
+	}
+	if count, err := audit.Verify(config.AuditLogPath); err != nil || count != 3 {
+		t.Errorf("audit log mismatch: have %d entries (err %v), want 3", count, err)
+	}
+}
+
 func TestCheckSignature(t *testing.T) {
 	key, _ := generatePrivateKey()
 	data := []byte("Here is a string....")
@@ -238,6 +277,11 @@ func (slice Keys) Swap(i, j int) {
 	slice[i], slice[j] = slice[j], slice[i]
 }
 
+func decryptFn(_ accounts.Account, c, s1, s2 []byte) ([]byte, error) {
+	key, _ := generatePrivateKey()
+	return ecies.ImportECDSA(key).Decrypt(c, s1, s2)
+}
+
 func signerFn(_ accounts.Account, mimeType string, data []byte) ([]byte, error) {
 	key, _ := generatePrivateKey()
 	return crypto.Sign(crypto.Keccak256(data), key)
@@ -299,6 +343,6 @@ func newBackend() (b *Backend) {
 	_, b = newBlockChain(4, true)
 
 	key, _ := generatePrivateKey()
-	b.Authorize(crypto.PubkeyToAddress(key.PublicKey), signerFn, signerBLSHashFn, signerBLSMessageFn)
+	b.Authorize(crypto.PubkeyToAddress(key.PublicKey), decryptFn, signerFn, signerBLSHashFn, signerBLSMessageFn)
 	return
 }
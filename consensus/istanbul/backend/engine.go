@@ -26,8 +26,11 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/istanbul"
+	"github.com/ethereum/go-ethereum/consensus/istanbul/audit"
 	istanbulCore "github.com/ethereum/go-ethereum/consensus/istanbul/core"
 	"github.com/ethereum/go-ethereum/consensus/istanbul/validator"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/contract_comm/currency"
 	gpm "github.com/ethereum/go-ethereum/contract_comm/gasprice_minimum"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -188,6 +191,10 @@ func (sb *Backend) verifyCascadingFields(chain consensus.ChainReader, header *ty
 		if parent.Time+sb.config.BlockPeriod > header.Time {
 			return errInvalidTimestamp
 		}
+		// Verify the base fee against the parent's gas usage
+		if err := misc.VerifyBaseFee(chain.Config(), parent, header); err != nil {
+			return err
+		}
 		// Verify validators in extraData. Validators in snapshot and extraData should be the same.
 		if err := sb.verifySigner(chain, header, parents); err != nil {
 			return err
@@ -450,17 +457,26 @@ func (sb *Backend) Finalize(chain consensus.ChainReader, header *types.Header, s
 	start := time.Now()
 	defer sb.finalizationTimer.UpdateSince(start)
 
+	// Settle the fee currency credits accumulated by the block's transactions.
+	// Failed credits are reverted individually, so the rest must be kept.
+	if err := currency.CreditFees(header, state); err != nil {
+		log.Error("Failed to credit fee currency fees", "number", header.Number, "err", err)
+	}
+
 	snapshot := state.Snapshot()
 	err := sb.setInitialGoldTokenTotalSupplyIfUnset(header, state)
 	if err != nil {
 		state.RevertToSnapshot(snapshot)
 	}
 
-	// Trigger an update to the gas price minimum in the GasPriceMinimum contract based on block congestion
-	snapshot = state.Snapshot()
-	_, err = gpm.UpdateGasPriceMinimum(header, state)
-	if err != nil {
-		state.RevertToSnapshot(snapshot)
+	// Trigger an update to the gas price minimum in the GasPriceMinimum contract based on block congestion.
+	// Once the base fee fork is active the base fee in the header takes its place.
+	if !chain.Config().IsBaseFee(header.Number) {
+		snapshot = state.Snapshot()
+		_, err = gpm.UpdateGasPriceMinimum(header, state)
+		if err != nil {
+			state.RevertToSnapshot(snapshot)
+		}
 	}
 
 	sb.logger.Trace("Finalizing", "block", header.Number.Uint64(), "epochSize", sb.config.Epoch)
@@ -572,7 +588,7 @@ func (sb *Backend) SealHash(header *types.Header) common.Hash {
 func (sb *Backend) updateBlock(parent *types.Header, block *types.Block) (*types.Block, error) {
 	header := block.Header()
 	// sign the hash
-	seal, err := sb.Sign(sigHash(header).Bytes())
+	seal, err := sb.signWithPurpose(audit.PurposeSeal, sigHash(header).Bytes())
 	if err != nil {
 		return nil, err
 	}
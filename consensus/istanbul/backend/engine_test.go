@@ -112,7 +112,7 @@ func newBlockChain(n int, isFullChain bool) (*core.BlockChain, *Backend) {
 	}
 
 	b, _ := New(config, memDB).(*Backend)
-	b.Authorize(address, signerFn, signerBLSHashFn, signerBLSMessageFn)
+	b.Authorize(address, nil, signerFn, signerBLSHashFn, signerBLSMessageFn)
 
 	genesis.MustCommit(memDB)
 
@@ -203,7 +203,7 @@ func newBlockChain(n int, isFullChain bool) (*core.BlockChain, *Backend) {
 				return blscrypto.SerializedSignatureFromBytes(signatureBytes)
 			}
 
-			b.Authorize(address, signerFn, signerBLSHashFn, signerBLSMessageFn)
+			b.Authorize(address, nil, signerFn, signerBLSHashFn, signerBLSMessageFn)
 			break
 		}
 	}
@@ -50,6 +50,14 @@ const (
 	istanbulAnnounceVersionsMsg    = 0x18
 )
 
+// Codes of the consensus and announce messages, for tools observing the traffic
+// of the consensus protocol.
+const (
+	ConsensusMsgCode        = istanbulConsensusMsg
+	AnnounceMsgCode         = istanbulAnnounceMsg
+	AnnounceVersionsMsgCode = istanbulAnnounceVersionsMsg
+)
+
 func (sb *Backend) isIstanbulMsg(msg p2p.Msg) bool {
 	return msg.Code >= istanbulConsensusMsg && msg.Code <= istanbulAnnounceVersionsMsg
 }
@@ -156,7 +164,7 @@ func (sb *Backend) handleConsensusMsg(peer consensus.Peer, payload []byte) error
 		// Need to forward the message to the proxied validator
 		sb.logger.Trace("Forwarding consensus message to proxied validator")
 		if sb.proxiedPeer != nil {
-			go sb.proxiedPeer.Send(istanbulConsensusMsg, payload)
+			sb.asyncSend(sb.proxiedPeer, istanbulConsensusMsg, payload)
 		}
 	} else { // The case when this node is a validator
 		go sb.istanbulEventMux.Post(istanbul.MessageEvent{
@@ -265,6 +273,8 @@ func (sb *Backend) RegisterPeer(peer consensus.Peer, isProxiedPeer bool) {
 
 	sb.logger.Trace("RegisterPeer called", "peer", peer, "isProxiedPeer", isProxiedPeer)
 
+	sb.registerSendQueue(peer)
+
 	// Check to see if this connecting peer if a proxied validator
 	if sb.config.Proxy && isProxiedPeer {
 		sb.proxiedPeer = peer
@@ -282,6 +292,8 @@ func (sb *Backend) RegisterPeer(peer consensus.Peer, isProxiedPeer bool) {
 }
 
 func (sb *Backend) UnregisterPeer(peer consensus.Peer, isProxiedPeer bool) {
+	sb.unregisterSendQueue(peer)
+
 	if sb.config.Proxy && isProxiedPeer && reflect.DeepEqual(sb.proxiedPeer, peer) {
 		sb.proxiedPeer = nil
 	} else if sb.config.Proxied {
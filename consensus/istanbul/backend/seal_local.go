@@ -0,0 +1,200 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package backend
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+
+	bls "github.com/celo-org/bls-zexe/go"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/istanbul"
+	istanbulCore "github.com/ethereum/go-ethereum/consensus/istanbul/core"
+	"github.com/ethereum/go-ethereum/consensus/istanbul/validator"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	blscrypto "github.com/ethereum/go-ethereum/crypto/bls"
+)
+
+// The methods in this file seal blocks without running the consensus protocol,
+// for tools holding the keys of all validators, like the retesteth server.
+
+// PrepareLocal initializes the consensus fields of a header like Prepare, for a
+// block sealed with SealLocal. Unlike Prepare it keeps the timestamp of the header
+// unless it is closer than the block period to the parent, does not wait for the
+// timestamp and copies the aggregated seal of the parent as the parent seal. The
+// coinbase is set to the proposer of the first round.
+func (sb *Backend) PrepareLocal(chain consensus.ChainReader, header *types.Header) error {
+	number := header.Number.Uint64()
+	parent := chain.GetHeader(header.ParentHash, number-1)
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	header.Nonce = emptyNonce
+	header.MixDigest = types.IstanbulDigest
+	header.Difficulty = defaultDifficulty
+	if header.Time < parent.Time+sb.config.BlockPeriod {
+		header.Time = parent.Time + sb.config.BlockPeriod
+	}
+
+	// The proposer rotates according to the proposer policy, starting after the parent's author
+	valSet := sb.getOrderedValidators(number-1, header.ParentHash)
+	lastProposer, _ := sb.Author(parent)
+	proposer := validator.GetProposerSelector(sb.config.ProposerPolicy)(valSet, lastProposer, 0)
+	if proposer == nil {
+		return errUnauthorized
+	}
+	header.Coinbase = proposer.Address()
+
+	if err := writeEmptyIstanbulExtra(header); err != nil {
+		return err
+	}
+	// The genesis block carries no aggregated seal
+	if number <= 1 {
+		return nil
+	}
+	parentExtra, err := types.ExtractIstanbulExtra(parent)
+	if err != nil {
+		return err
+	}
+	return writeAggregatedSeal(header, parentExtra.AggregatedSeal, true)
+}
+
+// SealLocal seals a block prepared with PrepareLocal in the first round: the
+// proposer signs the block and every validator of the parent block commits it.
+// At the last block of an epoch, the validator set diff must already be written
+// and the validators also sign the next epoch's validator set. The keys of the
+// proposer and of all validators must be given.
+func (sb *Backend) SealLocal(chain consensus.ChainReader, block *types.Block, keys []*ecdsa.PrivateKey) (*types.Block, error) {
+	header := block.Header()
+	number := header.Number.Uint64()
+
+	byAddress := make(map[common.Address]*ecdsa.PrivateKey, len(keys))
+	for _, key := range keys {
+		byAddress[crypto.PubkeyToAddress(key.PublicKey)] = key
+	}
+	proposerKey := byAddress[header.Coinbase]
+	if proposerKey == nil {
+		return nil, fmt.Errorf("no key for proposer %s", header.Coinbase.Hex())
+	}
+	seal, err := crypto.Sign(crypto.Keccak256(sigHash(header).Bytes()), proposerKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeSeal(header, seal); err != nil {
+		return nil, err
+	}
+
+	// Commit the block with the keys of the whole validator set
+	snap, err := sb.snapshot(chain, number-1, header.ParentHash, nil)
+	if err != nil {
+		return nil, err
+	}
+	valSet := snap.ValSet
+	round := big.NewInt(0)
+	committedSeal := istanbulCore.PrepareCommittedSeal(header.Hash(), round)
+	bitmap := big.NewInt(0)
+	signatures := make([][]byte, 0, valSet.Size())
+	validatorKeys := make([]*ecdsa.PrivateKey, 0, valSet.Size())
+	for i, val := range valSet.List() {
+		key := byAddress[val.Address()]
+		if key == nil {
+			return nil, fmt.Errorf("no key for validator %s", val.Address().Hex())
+		}
+		signature, err := signBLS(key, committedSeal, []byte{}, false)
+		if err != nil {
+			return nil, err
+		}
+		bitmap.SetBit(bitmap, i, 1)
+		signatures = append(signatures, signature[:])
+		validatorKeys = append(validatorKeys, key)
+	}
+	aggregated, err := blscrypto.AggregateSignatures(signatures)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeAggregatedSeal(header, types.IstanbulAggregatedSeal{Bitmap: bitmap, Signature: aggregated, Round: round}, false); err != nil {
+		return nil, err
+	}
+	block = block.WithSeal(header)
+
+	if !istanbul.IsLastBlockOfEpoch(number, sb.config.Epoch) {
+		return block, nil
+	}
+	// Sign the validator set of the next epoch, as the validators do in their commits
+	extra, err := types.ExtractIstanbulExtra(header)
+	if err != nil {
+		return nil, err
+	}
+	added, err := istanbul.CombineIstanbulExtraToValidatorData(extra.AddedValidators, extra.AddedValidatorsPublicKeys)
+	if err != nil {
+		return nil, err
+	}
+	newValSet := valSet.Copy()
+	if !newValSet.RemoveValidators(extra.RemovedValidators) || !newValSet.AddValidators(added) {
+		return nil, errInvalidValidatorSetDiff
+	}
+	publicKeys := make([]blscrypto.SerializedPublicKey, 0, newValSet.Size())
+	for _, val := range newValSet.List() {
+		publicKeys = append(publicKeys, val.BLSPublicKey())
+	}
+	maxNonSignersPlusOne := uint32(newValSet.Size() - newValSet.MinQuorumSize() + 1)
+	epochData, err := blscrypto.EncodeEpochSnarkData(publicKeys, maxNonSignersPlusOne, uint16(istanbul.GetEpochNumber(number, sb.config.Epoch)))
+	if err != nil {
+		return nil, err
+	}
+	epochSignatures := make([][]byte, 0, len(validatorKeys))
+	for _, key := range validatorKeys {
+		signature, err := signBLS(key, epochData, []byte{}, true)
+		if err != nil {
+			return nil, err
+		}
+		epochSignatures = append(epochSignatures, signature[:])
+	}
+	epochSeal, err := blscrypto.AggregateSignatures(epochSignatures)
+	if err != nil {
+		return nil, err
+	}
+	return block.WithEpochSnarkData(&types.EpochSnarkData{Signature: epochSeal}), nil
+}
+
+// signBLS signs a message with the BLS key derived from an ECDSA key, the same
+// way the keystore signs for an unlocked account.
+func signBLS(key *ecdsa.PrivateKey, msg []byte, extraData []byte, useComposite bool) (blscrypto.SerializedSignature, error) {
+	privateKeyBytes, err := blscrypto.ECDSAToBLS(key)
+	if err != nil {
+		return blscrypto.SerializedSignature{}, err
+	}
+	privateKey, err := bls.DeserializePrivateKey(privateKeyBytes)
+	if err != nil {
+		return blscrypto.SerializedSignature{}, err
+	}
+	defer privateKey.Destroy()
+
+	signature, err := privateKey.SignMessage(msg, extraData, useComposite)
+	if err != nil {
+		return blscrypto.SerializedSignature{}, err
+	}
+	defer signature.Destroy()
+	signatureBytes, err := signature.Serialize()
+	if err != nil {
+		return blscrypto.SerializedSignature{}, err
+	}
+	return blscrypto.SerializedSignatureFromBytes(signatureBytes)
+}
@@ -0,0 +1,93 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package backend
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/istanbul"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+func TestSealLocal(t *testing.T) {
+	genesis, keys := getGenesisAndKeys(4, true)
+	db := rawdb.NewMemoryDatabase()
+	config := *istanbul.DefaultConfig
+	config.ValidatorEnodeDBPath = ""
+	config.RoundStateDBPath = ""
+	config.Epoch = genesis.Config.Istanbul.Epoch
+	engine := New(&config, db).(*Backend)
+	genesis.MustCommit(db)
+
+	chain, err := core.NewBlockChain(db, nil, genesis.Config, engine, vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+	engine.SetChain(chain, chain.CurrentBlock, func(hash common.Hash) (*state.StateDB, error) {
+		return chain.StateAt(chain.GetHeaderByHash(hash).Root)
+	})
+
+	// Seal a full epoch and the first block of the next one
+	proposers := make(map[common.Address]bool)
+	for i := uint64(0); i <= config.Epoch; i++ {
+		parent := chain.CurrentBlock()
+		header := makeHeader(parent, &config)
+		if err := engine.PrepareLocal(chain, header); err != nil {
+			t.Fatalf("block %d: failed to prepare: %v", header.Number, err)
+		}
+		statedb, err := chain.StateAt(parent.Root())
+		if err != nil {
+			t.Fatalf("block %d: failed to get state: %v", header.Number, err)
+		}
+		block, err := engine.FinalizeAndAssemble(chain, header, statedb, nil, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("block %d: failed to finalize: %v", header.Number, err)
+		}
+		if err := engine.UpdateValSetDiff(chain, block.MutableHeader(), statedb); err != nil {
+			t.Fatalf("block %d: failed to update the validator set diff: %v", header.Number, err)
+		}
+		if block, err = engine.SealLocal(chain, block, keys); err != nil {
+			t.Fatalf("block %d: failed to seal: %v", header.Number, err)
+		}
+		if _, err := chain.InsertChain(types.Blocks{block}); err != nil {
+			t.Fatalf("block %d: failed to import: %v", header.Number, err)
+		}
+		if engine.IsLastBlockOfEpoch(block.Header()) && len(block.EpochSnarkData().Signature) == 0 {
+			t.Errorf("block %d: missing epoch validator set seal", block.Number())
+		}
+		proposers[block.Coinbase()] = true
+	}
+	if len(proposers) != len(keys) {
+		t.Errorf("proposers mismatch: have %d, want %d", len(proposers), len(keys))
+	}
+
+	// Sealing fails without the keys of all validators
+	parent := chain.CurrentBlock()
+	header := makeHeader(parent, &config)
+	if err := engine.PrepareLocal(chain, header); err != nil {
+		t.Fatalf("failed to prepare: %v", err)
+	}
+	if _, err := engine.SealLocal(chain, types.NewBlockWithHeader(header), keys[:1]); err == nil {
+		t.Errorf("sealed a block without the keys of all validators")
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package backend
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// sendPriority is the priority class of an outbound istanbul message. Lower
+// values are sent first.
+type sendPriority int
+
+const (
+	priorityConsensus sendPriority = iota // Consensus messages
+	priorityForward                       // Messages forwarded between a proxy and its validator
+	priorityAnnounce                      // Announce messages and requests for them
+	priorityGossip                        // Enode sharing, announce version gossip and delegate signing
+
+	numSendPriorities
+)
+
+var (
+	// sendQueueLimits are the maximum number of messages queued per peer and class
+	sendQueueLimits = [numSendPriorities]int{1024, 512, 256, 128}
+
+	// sendQueueNames are the metric names of each priority class
+	sendQueueNames = [numSendPriorities]string{"consensus", "forward", "announce", "gossip"}
+)
+
+// Metrics for the outbound queues, aggregated over all peers
+var (
+	sendQueueDepthGauges   [numSendPriorities]metrics.Gauge
+	sendQueueLatencyTimers [numSendPriorities]metrics.Timer
+	sendQueueDropMeters    [numSendPriorities]metrics.Meter
+)
+
+func init() {
+	for i, name := range sendQueueNames {
+		sendQueueDepthGauges[i] = metrics.NewRegisteredGauge("consensus/istanbul/backend/sendqueue/"+name+"/depth", nil)
+		sendQueueLatencyTimers[i] = metrics.NewRegisteredTimer("consensus/istanbul/backend/sendqueue/"+name+"/latency", nil)
+		sendQueueDropMeters[i] = metrics.NewRegisteredMeter("consensus/istanbul/backend/sendqueue/"+name+"/drop", nil)
+	}
+}
+
+// msgPriority returns the priority class of an istanbul message code.
+func msgPriority(msgCode uint64) sendPriority {
+	switch msgCode {
+	case istanbulConsensusMsg:
+		return priorityConsensus
+	case istanbulFwdMsg:
+		return priorityForward
+	case istanbulAnnounceMsg, istanbulGetAnnouncesMsg:
+		return priorityAnnounce
+	default:
+		return priorityGossip
+	}
+}
+
+// queuedMsg is an outbound message waiting in a peer's send queue.
+type queuedMsg struct {
+	code    uint64
+	payload interface{}
+	queued  time.Time
+}
+
+// peerSendQueue schedules the outbound istanbul messages to a single peer. The
+// messages are written by a single goroutine, always picking the oldest message
+// of the highest priority class. When a class is full, new announce and gossip
+// messages are dropped, while the senders of consensus and forward messages wait
+// for room, as losing them could stall the consensus rounds.
+type peerSendQueue struct {
+	peer   consensus.Peer
+	queues [numSendPriorities][]*queuedMsg
+	closed bool
+	lock   sync.Mutex
+	space  *sync.Cond // Signals the senders waiting for room in a full queue
+
+	wake chan struct{} // Notifies the send loop of newly queued messages
+	quit chan struct{} // Terminates the send loop
+}
+
+// newPeerSendQueue creates a send queue for the given peer and starts its send loop.
+func newPeerSendQueue(peer consensus.Peer) *peerSendQueue {
+	q := &peerSendQueue{
+		peer: peer,
+		wake: make(chan struct{}, 1),
+		quit: make(chan struct{}),
+	}
+	q.space = sync.NewCond(&q.lock)
+	go q.loop()
+	return q
+}
+
+// enqueue schedules a message for sending, returning false if it was dropped.
+// Consensus and forward messages are never dropped while the queue is open: if
+// their class is full, enqueue blocks until the send loop makes room.
+func (q *peerSendQueue) enqueue(code uint64, payload interface{}) bool {
+	prio := msgPriority(code)
+
+	q.lock.Lock()
+	for !q.closed && len(q.queues[prio]) >= sendQueueLimits[prio] {
+		if prio > priorityForward {
+			sendQueueDropMeters[prio].Mark(1)
+			q.lock.Unlock()
+			return false
+		}
+		q.space.Wait()
+	}
+	if q.closed {
+		sendQueueDropMeters[prio].Mark(1)
+		q.lock.Unlock()
+		return false
+	}
+	msg := &queuedMsg{code: code, payload: payload, queued: time.Now()}
+	q.queues[prio] = append(q.queues[prio], msg)
+	sendQueueDepthGauges[prio].Inc(1)
+	q.lock.Unlock()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// next pops the next message to send, or returns nil if all queues are empty.
+func (q *peerSendQueue) next() (*queuedMsg, sendPriority) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	for prio := range q.queues {
+		if len(q.queues[prio]) > 0 {
+			msg := q.queues[prio][0]
+			q.queues[prio][0] = nil
+			q.queues[prio] = q.queues[prio][1:]
+			sendQueueDepthGauges[prio].Dec(1)
+			if prio <= int(priorityForward) {
+				q.space.Broadcast()
+			}
+			return msg, sendPriority(prio)
+		}
+	}
+	return nil, 0
+}
+
+// loop writes the queued messages to the peer until the queue is closed.
+func (q *peerSendQueue) loop() {
+	for {
+		select {
+		case <-q.wake:
+		case <-q.quit:
+			return
+		}
+		for {
+			msg, prio := q.next()
+			if msg == nil {
+				break
+			}
+			sendQueueLatencyTimers[prio].UpdateSince(msg.queued)
+			q.peer.Send(msg.code, msg.payload)
+
+			select {
+			case <-q.quit:
+				return
+			default:
+			}
+		}
+	}
+}
+
+// close stops the send loop, discards all queued messages and releases the
+// senders waiting for room.
+func (q *peerSendQueue) close() {
+	close(q.quit)
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	for prio := range q.queues {
+		sendQueueDepthGauges[prio].Dec(int64(len(q.queues[prio])))
+		q.queues[prio] = nil
+	}
+	q.closed = true
+	q.space.Broadcast()
+}
+
+// registerSendQueue creates the outbound queue of a newly connected peer.
+func (sb *Backend) registerSendQueue(peer consensus.Peer) {
+	sb.sendQueuesMu.Lock()
+	defer sb.sendQueuesMu.Unlock()
+
+	id := peer.Node().ID()
+	if old, ok := sb.sendQueues[id]; ok {
+		old.close()
+	}
+	sb.sendQueues[id] = newPeerSendQueue(peer)
+}
+
+// unregisterSendQueue stops the outbound queue of a disconnected peer.
+func (sb *Backend) unregisterSendQueue(peer consensus.Peer) {
+	sb.sendQueuesMu.Lock()
+	defer sb.sendQueuesMu.Unlock()
+
+	id := peer.Node().ID()
+	// The peer may have reconnected before the old session was unregistered
+	if q, ok := sb.sendQueues[id]; ok && q.peer == peer {
+		q.close()
+		delete(sb.sendQueues, id)
+	}
+}
+
+// asyncSend schedules an istanbul message to the peer through the peer's send
+// queue. Peers without a queue, which have not been registered with the engine,
+// are sent the message directly on a new goroutine.
+func (sb *Backend) asyncSend(peer consensus.Peer, msgCode uint64, payload interface{}) {
+	sb.sendQueuesMu.RLock()
+	q := sb.sendQueues[peer.Node().ID()]
+	sb.sendQueuesMu.RUnlock()
+
+	if q == nil || q.peer != peer {
+		go peer.Send(msgCode, payload)
+		return
+	}
+	if !q.enqueue(msgCode, payload) {
+		sb.logger.Trace("Dropped istanbul message on full send queue", "peer", peer, "code", msgCode)
+	}
+}
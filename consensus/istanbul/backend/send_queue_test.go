@@ -0,0 +1,126 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package backend
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// newManualSendQueue creates a send queue without starting its send loop, so
+// that messages can be popped manually.
+func newManualSendQueue() *peerSendQueue {
+	q := &peerSendQueue{peer: &MockPeer{}, wake: make(chan struct{}, 1), quit: make(chan struct{})}
+	q.space = sync.NewCond(&q.lock)
+	return q
+}
+
+// Tests that queued messages are sent by priority class, and that full queues
+// drop new gossip messages.
+func TestPeerSendQueuePriorities(t *testing.T) {
+	q := newManualSendQueue()
+
+	q.enqueue(istanbulValEnodesShareMsg, 1)
+	q.enqueue(istanbulAnnounceMsg, 2)
+	q.enqueue(istanbulFwdMsg, 3)
+	q.enqueue(istanbulConsensusMsg, 4)
+	q.enqueue(istanbulDelegateSign, 5)
+	q.enqueue(istanbulConsensusMsg, 6)
+
+	for _, want := range []int{4, 6, 3, 2, 1, 5} {
+		msg, _ := q.next()
+		if msg == nil {
+			t.Fatalf("queue drained early, want message %d", want)
+		}
+		if have := msg.payload.(int); have != want {
+			t.Fatalf("message order mismatch: have %d, want %d", have, want)
+		}
+	}
+	if msg, _ := q.next(); msg != nil {
+		t.Fatalf("unexpected message left in queue: %v", msg.payload)
+	}
+
+	// Fill up the gossip queue
+	for i := 0; i < sendQueueLimits[priorityGossip]; i++ {
+		if !q.enqueue(istanbulValEnodesShareMsg, i) {
+			t.Fatalf("gossip message %d dropped before queue full", i)
+		}
+	}
+	if q.enqueue(istanbulValEnodesShareMsg, -1) {
+		t.Fatalf("gossip message queued beyond limit")
+	}
+}
+
+// Tests that consensus messages are never evicted from a full queue, their
+// senders waiting for room instead.
+func TestPeerSendQueueConsensusBackpressure(t *testing.T) {
+	q := newManualSendQueue()
+
+	limit := sendQueueLimits[priorityConsensus]
+	for i := 0; i < limit; i++ {
+		if !q.enqueue(istanbulConsensusMsg, i) {
+			t.Fatalf("consensus message %d dropped", i)
+		}
+	}
+	// Queue two more messages, which must wait for room
+	queued := make(chan bool)
+	go func() {
+		queued <- q.enqueue(istanbulConsensusMsg, limit)
+		queued <- q.enqueue(istanbulConsensusMsg, limit+1)
+	}()
+	select {
+	case <-queued:
+		t.Fatalf("consensus message queued beyond limit")
+	case <-time.After(50 * time.Millisecond):
+	}
+	for i := 0; i < limit+2; i++ {
+		msg, _ := q.next()
+		if msg == nil || msg.payload.(int) != i {
+			t.Fatalf("consensus message %d missing, have %v", i, msg)
+		}
+		if i < 2 {
+			select {
+			case ok := <-queued:
+				if !ok {
+					t.Fatalf("waiting consensus message %d dropped", limit+i)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("waiting consensus message %d not queued", limit+i)
+			}
+		}
+	}
+	if msg, _ := q.next(); msg != nil {
+		t.Fatalf("unexpected message left in queue: %v", msg.payload)
+	}
+
+	// Closing the queue releases the waiting senders
+	for i := 0; i < limit; i++ {
+		q.enqueue(istanbulConsensusMsg, i)
+	}
+	go func() { queued <- q.enqueue(istanbulConsensusMsg, limit) }()
+	time.Sleep(10 * time.Millisecond)
+	q.close()
+	select {
+	case ok := <-queued:
+		if ok {
+			t.Fatalf("consensus message queued on a closed queue")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("waiting sender not released on close")
+	}
+}
@@ -314,7 +314,7 @@ func TestValSetChange(t *testing.T) {
 			return blscrypto.SerializedSignatureFromBytes(signatureBytes)
 		}
 
-		engine.Authorize(address, signerFn, signerBLSHashFn, signerBLSMessageFn)
+		engine.Authorize(address, nil, signerFn, signerBLSHashFn, signerBLSMessageFn)
 
 		chain.AddHeader(0, genesis.ToBlock(nil).Header())
 
@@ -25,6 +25,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/istanbul"
+	"github.com/ethereum/go-ethereum/consensus/istanbul/audit"
 	vet "github.com/ethereum/go-ethereum/consensus/istanbul/backend/internal/enodes"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p/enode"
@@ -152,7 +153,7 @@ func (sb *Backend) sendValEnodesShareMsg() error {
 	}
 
 	// Sign the validator enode share message
-	if err := msg.Sign(sb.Sign); err != nil {
+	if err := msg.Sign(sb.signer(audit.PurposeValEnodesShare)); err != nil {
 		sb.logger.Error("Error in signing an Istanbul ValEnodesShare Message", "ValEnodesShareMsg", msg.String(), "err", err)
 		return err
 	}
@@ -165,7 +166,7 @@ func (sb *Backend) sendValEnodesShareMsg() error {
 	}
 
 	sb.logger.Debug("Sending Istanbul Validator Enodes Share payload to proxy peer")
-	go sb.proxyNode.peer.Send(istanbulValEnodesShareMsg, payload)
+	sb.asyncSend(sb.proxyNode.peer, istanbulValEnodesShareMsg, payload)
 
 	return nil
 }
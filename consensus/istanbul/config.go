@@ -40,6 +40,10 @@ type Config struct {
 	LookbackWindow              uint64         `toml:",omitempty"` // The window of blocks in which a validator is forgived from voting
 	ValidatorEnodeDBPath        string         `toml:",omitempty"` // The location for the validator enodes DB
 	RoundStateDBPath            string         `toml:",omitempty"` // The location for the round states DB
+	AuditLogPath                string         `toml:",omitempty"` // The location for the audit log of the validator's key uses, disabled if empty
+	AuditLogMaxSize             uint64         `toml:",omitempty"` // The size in bytes above which the audit log is rotated, 0 for no limit
+	AuditLogSyncInterval        uint64         `toml:",omitempty"` // The interval in milliseconds between the syncs of the audit log to disk, 0 to sync it on every key use
+	RefusedSigningPurposes      []string       `toml:",omitempty"` // The key uses refused by the signing policy (e.g. "ethstats")
 
 	// Proxy Configs
 	Proxy                   bool           `toml:",omitempty"` // Specifies if this node is a proxy
@@ -53,6 +57,7 @@ type Config struct {
 	// Announce Configs
 	AnnounceGossipPeriod                 uint64 `toml:",omitempty"` // Time duration (in seconds) between gossiped announce messages
 	AnnounceAggressiveGossipOnEnablement bool   `toml:",omitempty"` // Specifies if this node should do aggressive gossip on announce enablement
+	AnnounceLegacyEnodeURL               bool   `toml:",omitempty"` // Specifies if this node should also announce its enode URL unencrypted to the peers of the older protocol versions
 }
 
 var DefaultConfig = &Config{
@@ -66,8 +71,11 @@ var DefaultConfig = &Config{
 	LookbackWindow:                       12,
 	ValidatorEnodeDBPath:                 "validatorenodes",
 	RoundStateDBPath:                     "roundstates",
+	AuditLogMaxSize:                      64 * 1024 * 1024,
+	AuditLogSyncInterval:                 0,
 	Proxy:                                false,
 	Proxied:                              false,
 	AnnounceGossipPeriod:                 600,
 	AnnounceAggressiveGossipOnEnablement: false,
+	AnnounceLegacyEnodeURL:               false,
 }
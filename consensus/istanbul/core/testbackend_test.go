@@ -73,7 +73,7 @@ type testCommittedMsgs struct {
 //
 // define the functions that needs to be provided for Istanbul.
 
-func (self *testSystemBackend) Authorize(address common.Address, _ istanbul.SignerFn, _ istanbul.BLSSignerFn, _ istanbul.BLSMessageSignerFn) {
+func (self *testSystemBackend) Authorize(address common.Address, _ istanbul.DecryptFn, _ istanbul.SignerFn, _ istanbul.BLSSignerFn, _ istanbul.BLSMessageSignerFn) {
 	self.address = address
 	self.engine.SetAddress(address)
 }
@@ -17,6 +17,7 @@
 package istanbul
 
 import (
+	"crypto/ecdsa"
 	"encoding/hex"
 	"errors"
 	"math/big"
@@ -40,16 +41,21 @@ func RLPHash(v interface{}) (h common.Hash) {
 
 // GetSignatureAddress gets the signer address from the signature
 func GetSignatureAddress(data []byte, sig []byte) (common.Address, error) {
-	// 1. Keccak data
-	hashData := crypto.Keccak256(data)
-	// 2. Recover public key
-	pubkey, err := crypto.SigToPub(hashData, sig)
+	pubkey, err := GetSignaturePublicKey(data, sig)
 	if err != nil {
 		return common.Address{}, err
 	}
 	return crypto.PubkeyToAddress(*pubkey), nil
 }
 
+// GetSignaturePublicKey recovers the public key of the signer of data
+func GetSignaturePublicKey(data []byte, sig []byte) (*ecdsa.PublicKey, error) {
+	// 1. Keccak data
+	hashData := crypto.Keccak256(data)
+	// 2. Recover public key
+	return crypto.SigToPub(hashData, sig)
+}
+
 func CheckValidatorSignature(valSet ValidatorSet, data []byte, sig []byte) (common.Address, error) {
 	// 1. Get signature address
 	signer, err := GetSignatureAddress(data, sig)
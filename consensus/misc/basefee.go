@@ -0,0 +1,88 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package misc
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	big1 = big.NewInt(1)
+
+	errMissingBaseFee    = errors.New("header is missing base fee")
+	errUnexpectedBaseFee = errors.New("header has base fee before the base fee fork")
+)
+
+// VerifyBaseFee verifies that the base fee of header is consistent with the
+// gas usage of its parent, or absent if the base fee fork is not yet active.
+func VerifyBaseFee(config *params.ChainConfig, parent, header *types.Header) error {
+	if !config.IsBaseFee(header.Number) {
+		if header.BaseFee != nil {
+			return errUnexpectedBaseFee
+		}
+		return nil
+	}
+	if header.BaseFee == nil {
+		return errMissingBaseFee
+	}
+	if expected := CalcBaseFee(config, parent); header.BaseFee.Cmp(expected) != 0 {
+		return fmt.Errorf("invalid base fee: have %v, want %v, parentBaseFee %v, parentGasUsed %d",
+			header.BaseFee, expected, parent.BaseFee, parent.GasUsed)
+	}
+	return nil
+}
+
+// CalcBaseFee returns the CELO denominated base fee of the block following
+// parent. The base fee moves by at most 1/BaseFeeChangeDenominator per block
+// towards keeping the gas used at 1/ElasticityMultiplier of the gas limit, and
+// never drops below MinimumBaseFee.
+func CalcBaseFee(config *params.ChainConfig, parent *types.Header) *big.Int {
+	// The first block after the fork starts from the initial base fee
+	if !config.IsBaseFee(parent.Number) || parent.BaseFee == nil {
+		return new(big.Int).SetUint64(params.InitialBaseFee)
+	}
+	var (
+		gasTarget   = parent.GasLimit / params.ElasticityMultiplier
+		baseFee     = new(big.Int).Set(parent.BaseFee)
+		denominator = new(big.Int).SetUint64(params.BaseFeeChangeDenominator)
+	)
+	switch {
+	case gasTarget == 0 || parent.GasUsed == gasTarget:
+		// Nothing to adjust against
+	case parent.GasUsed > gasTarget:
+		// baseFee += max(1, baseFee * (gasUsed - target) / target / denominator)
+		delta := new(big.Int).SetUint64(parent.GasUsed - gasTarget)
+		delta.Mul(delta, parent.BaseFee)
+		delta.Div(delta, new(big.Int).SetUint64(gasTarget))
+		delta.Div(delta, denominator)
+		baseFee.Add(baseFee, math.BigMax(delta, big1))
+	default:
+		// baseFee -= baseFee * (target - gasUsed) / target / denominator
+		delta := new(big.Int).SetUint64(gasTarget - parent.GasUsed)
+		delta.Mul(delta, parent.BaseFee)
+		delta.Div(delta, new(big.Int).SetUint64(gasTarget))
+		delta.Div(delta, denominator)
+		baseFee.Sub(baseFee, delta)
+	}
+	return math.BigMax(baseFee, new(big.Int).SetUint64(params.MinimumBaseFee))
+}
@@ -0,0 +1,70 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package misc
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the base fee follows the gas usage of the parent block.
+func TestCalcBaseFee(t *testing.T) {
+	config := *params.TestChainConfig
+	config.BaseFeeBlock = big.NewInt(5)
+
+	tests := []struct {
+		number        int64
+		parentBaseFee int64
+		parentGasUsed uint64
+		expected      int64
+	}{
+		{4, 0, 0, int64(params.InitialBaseFee)},         // fork block
+		{5, 1000000000, 10000000, 1000000000},           // usage == target
+		{5, 1000000000, 9000000, 987500000},             // usage below target
+		{5, 1000000000, 11000000, 1012500000},           // usage above target
+		{5, 1000000000, 0, 875000000},                   // empty block
+		{5, 100000000, 0, int64(params.MinimumBaseFee)}, // floored at the minimum
+	}
+	for i, test := range tests {
+		parent := &types.Header{
+			Number:   big.NewInt(test.number),
+			GasLimit: 20000000,
+			GasUsed:  test.parentGasUsed,
+		}
+		if test.parentBaseFee != 0 {
+			parent.BaseFee = big.NewInt(test.parentBaseFee)
+		}
+		if have := CalcBaseFee(&config, parent); have.Int64() != test.expected {
+			t.Errorf("test %d: base fee mismatch: have %v, want %v", i, have, test.expected)
+		}
+		header := &types.Header{Number: big.NewInt(test.number + 1), BaseFee: big.NewInt(test.expected)}
+		if err := VerifyBaseFee(&config, parent, header); err != nil {
+			t.Errorf("test %d: failed to verify base fee: %v", i, err)
+		}
+	}
+	// Headers before the fork must not carry a base fee
+	parent := &types.Header{Number: big.NewInt(2), GasLimit: 20000000}
+	if err := VerifyBaseFee(&config, parent, &types.Header{Number: big.NewInt(3), BaseFee: big.NewInt(1)}); err != errUnexpectedBaseFee {
+		t.Errorf("base fee before fork: have %v, want %v", err, errUnexpectedBaseFee)
+	}
+	if err := VerifyBaseFee(&config, parent, &types.Header{Number: big.NewInt(3)}); err != nil {
+		t.Errorf("missing base fee before fork: have %v, want nil", err)
+	}
+}
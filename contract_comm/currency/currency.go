@@ -24,8 +24,10 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/contract_comm"
 	"github.com/ethereum/go-ethereum/contract_comm/errors"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
 )
@@ -77,6 +79,45 @@ const (
                           "type": "function"
                          }]`
 
+	// This is the batched counterpart of creditTo which fee currencies must
+	// implement once fees are batched. It is not part of the StableToken ABI.
+	creditToManyABI = `[{"constant": false,
+	                     "inputs": [
+	                          {
+	                              "name": "to",
+	                              "type": "address[]"
+	                          },
+	                          {
+	                              "name": "value",
+	                              "type": "uint256[]"
+	                          }
+	                     ],
+	                     "name": "creditToMany",
+	                     "outputs": [],
+	                     "payable": false,
+	                     "stateMutability": "nonpayable",
+	                     "type": "function"
+	                    }]`
+
+	// This is taken from celo-monorepo/packages/protocol/build/<env>/contracts/StableToken.json
+	creditToABI = `[{"constant": false,
+	                 "inputs": [
+	                      {
+	                          "name": "to",
+	                          "type": "address"
+	                      },
+	                      {
+	                          "name": "value",
+	                          "type": "uint256"
+	                      }
+	                 ],
+	                 "name": "creditTo",
+	                 "outputs": [],
+	                 "payable": false,
+	                 "stateMutability": "nonpayable",
+	                 "type": "function"
+	                }]`
+
 	// This is taken from celo-monorepo/packages/protocol/build/<env>/contracts/FeeCurrency.json
 	getWhitelistABI = `[{"constant": true,
 	                     "inputs": [],
@@ -100,6 +141,13 @@ var (
 	medianRateFuncABI, _   = abi.JSON(strings.NewReader(medianRateABI))
 	balanceOfFuncABI, _    = abi.JSON(strings.NewReader(balanceOfABI))
 	getWhitelistFuncABI, _ = abi.JSON(strings.NewReader(getWhitelistABI))
+	creditToManyFuncABI, _ = abi.JSON(strings.NewReader(creditToManyABI))
+	creditToFuncABI, _     = abi.JSON(strings.NewReader(creditToABI))
+
+	// Topics of the events which may change the fee currency whitelist
+	feeCurrencyWhitelistedTopic      = crypto.Keccak256Hash([]byte("FeeCurrencyWhitelisted(address)"))
+	feeCurrencyWhitelistRemovedTopic = crypto.Keccak256Hash([]byte("FeeCurrencyWhitelistRemoved(address)"))
+	registryUpdatedTopic             = crypto.Keccak256Hash([]byte("RegistryUpdated(string,bytes32,address)"))
 )
 
 type exchangeRate struct {
@@ -126,8 +174,8 @@ func ConvertToGold(val *big.Int, currencyFrom *common.Address) (*big.Int, error)
 // NOTE (jarmg 4/24/19): values are rounded down which can cause
 // an estimate to be off by 1 (at most)
 func Convert(val *big.Int, currencyFrom *common.Address, currencyTo *common.Address) (*big.Int, error) {
-	exchangeRateFrom, err1 := getExchangeRate(currencyFrom)
-	exchangeRateTo, err2 := getExchangeRate(currencyTo)
+	exchangeRateFrom, err1 := getExchangeRate(currencyFrom, nil, nil)
+	exchangeRateTo, err2 := getExchangeRate(currencyTo, nil, nil)
 
 	if err1 != nil || err2 != nil {
 		log.Error("Convert - Error in retreiving currency exchange rates")
@@ -151,8 +199,8 @@ func Cmp(val1 *big.Int, currency1 *common.Address, val2 *big.Int, currency2 *com
 		return val1.Cmp(val2)
 	}
 
-	exchangeRate1, err1 := getExchangeRate(currency1)
-	exchangeRate2, err2 := getExchangeRate(currency2)
+	exchangeRate1, err1 := getExchangeRate(currency1, nil, nil)
+	exchangeRate2, err2 := getExchangeRate(currency2, nil, nil)
 
 	if err1 != nil || err2 != nil {
 		currency1Output := "nil"
@@ -176,7 +224,25 @@ func Cmp(val1 *big.Int, currency1 *common.Address, val2 *big.Int, currency2 *com
 	return leftSide.Cmp(rightSide)
 }
 
-func getExchangeRate(currencyAddress *common.Address) (*exchangeRate, error) {
+// ConvertFromGold converts a CELO denominated value into currencyTo, using the
+// oracle rate at the given header and state. A nil currencyTo denotes CELO.
+func ConvertFromGold(val *big.Int, currencyTo *common.Address, header *types.Header, state vm.StateDB) (*big.Int, error) {
+	if currencyTo == nil {
+		return new(big.Int).Set(val), nil
+	}
+	exchangeRateTo, err := getExchangeRate(currencyTo, header, state)
+	if err != nil {
+		return nil, err
+	}
+	if exchangeRateTo.Denominator.Sign() == 0 {
+		return nil, errors.ErrExchangeRateZero
+	}
+	// Given value of val and rate n/d, the function below does (val * n) / d
+	numerator := new(big.Int).Mul(val, exchangeRateTo.Numerator)
+	return numerator.Div(numerator, exchangeRateTo.Denominator), nil
+}
+
+func getExchangeRate(currencyAddress *common.Address, header *types.Header, state vm.StateDB) (*exchangeRate, error) {
 	var (
 		returnArray [2]*big.Int
 		leftoverGas uint64
@@ -185,7 +251,7 @@ func getExchangeRate(currencyAddress *common.Address) (*exchangeRate, error) {
 	if currencyAddress == nil {
 		return &exchangeRate{cgExchangeRateNum, cgExchangeRateDen}, nil
 	} else {
-		if leftoverGas, err := contract_comm.MakeStaticCall(params.SortedOraclesRegistryId, medianRateFuncABI, "medianRate", []interface{}{currencyAddress}, &returnArray, params.MaxGasForMedianRate, nil, nil); err != nil {
+		if leftoverGas, err := contract_comm.MakeStaticCall(params.SortedOraclesRegistryId, medianRateFuncABI, "medianRate", []interface{}{currencyAddress}, &returnArray, params.MaxGasForMedianRate, header, state); err != nil {
 			if err == errors.ErrSmartContractNotDeployed {
 				log.Warn("Registry address lookup failed", "err", err)
 				return &exchangeRate{big.NewInt(1), big.NewInt(1)}, err
@@ -216,6 +282,48 @@ func GetBalanceOf(accountOwner common.Address, contractAddress common.Address, g
 	}
 }
 
+// CreditFees settles the fee credits accumulated in state during the block,
+// making a single creditToMany call on every fee currency with outstanding
+// credits. If the batched call fails, the currency's credits are made one by
+// one with creditTo instead, so that they aren't lost.
+func CreditFees(header *types.Header, state *state.StateDB) error {
+	var lastErr error
+	for _, feeCurrency := range state.FeeCreditCurrencies() {
+		recipients, amounts := state.FeeCredits(feeCurrency)
+		gas := params.MaxGasForCreditToManyPerRecipient * uint64(len(recipients))
+
+		snapshot := state.Snapshot()
+		leftoverGas, err := contract_comm.MakeCallWithAddress(feeCurrency, creditToManyFuncABI, "creditToMany", []interface{}{recipients, amounts}, nil, gas, common.Big0, header, state, false)
+		if err != nil {
+			log.Error("creditToMany invocation error, crediting fees individually", "feeCurrency", feeCurrency.Hex(), "recipients", len(recipients), "leftoverGas", leftoverGas, "err", err)
+			state.RevertToSnapshot(snapshot)
+			if err := creditFeesIndividually(header, state, feeCurrency, recipients, amounts); err != nil {
+				lastErr = err
+			}
+		} else {
+			log.Trace("creditToMany invocation success", "feeCurrency", feeCurrency.Hex(), "recipients", len(recipients), "gasUsed", gas-leftoverGas)
+		}
+		state.ClearFeeCredits(feeCurrency)
+	}
+	return lastErr
+}
+
+// creditFeesIndividually credits the fees of feeCurrency with a creditTo call
+// per recipient, returning the last error encountered.
+func creditFeesIndividually(header *types.Header, state *state.StateDB, feeCurrency common.Address, recipients []common.Address, amounts []*big.Int) error {
+	var lastErr error
+	for i, recipient := range recipients {
+		snapshot := state.Snapshot()
+		leftoverGas, err := contract_comm.MakeCallWithAddress(feeCurrency, creditToFuncABI, "creditTo", []interface{}{recipient, amounts[i]}, nil, params.MaxGasForCreditToTransactions, common.Big0, header, state, false)
+		if err != nil {
+			log.Error("creditTo invocation error, fee credit lost", "feeCurrency", feeCurrency.Hex(), "recipient", recipient.Hex(), "amount", amounts[i], "leftoverGas", leftoverGas, "err", err)
+			state.RevertToSnapshot(snapshot)
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
 // ------------------------------
 // FeeCurrencyWhiteList Functions
 //-------------------------------
@@ -261,3 +369,20 @@ func CurrencyWhitelist(header *types.Header, state vm.StateDB) ([]common.Address
 	}
 	return whitelist, err
 }
+
+// IsWhitelistChange reports whether a log may signal a change of the fee currency
+// whitelist: a currency added to or removed from it, or a new FeeCurrencyWhitelist
+// contract registered. The emitting contract isn't checked, so the whitelist has
+// to be retrieved again to know whether it actually changed.
+func IsWhitelistChange(l *types.Log) bool {
+	if len(l.Topics) == 0 {
+		return false
+	}
+	switch l.Topics[0] {
+	case feeCurrencyWhitelistedTopic, feeCurrencyWhitelistRemovedTopic:
+		return true
+	case registryUpdatedTopic:
+		return len(l.Topics) > 1 && l.Topics[1] == common.Hash(params.FeeCurrencyWhitelistRegistryId)
+	}
+	return false
+}
@@ -9,4 +9,6 @@ var (
 	ErrSmartContractNotDeployed      = errors.New("Contract not in Registry")
 	ErrRegistryContractNotDeployed   = errors.New("Registry not deployed")
 	ErrNoInternalEvmHandlerSingleton = errors.New("No internalEvmHandlerSingleton set for contract communication")
+	// ErrExchangeRateZero is returned when the oracle reports a zero exchange rate for a currency
+	ErrExchangeRateZero = errors.New("Exchange rate is zero")
 )
@@ -19,6 +19,7 @@ package contract_comm
 import (
 	"math/big"
 	"reflect"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
@@ -36,7 +37,8 @@ var (
 	internalEvmHandlerSingleton *InternalEVMHandler
 
 	// Metrics timers to track the execution time of calls made from the system to core contracts.
-	systemCallTimers = make(map[string]metrics.Timer)
+	systemCallTimers   = make(map[string]metrics.Timer)
+	systemCallTimersMu sync.Mutex
 )
 
 // An EVM handler to make calls to smart contracts from within geth
@@ -60,6 +62,16 @@ func MakeStaticCallWithAddress(scAddress common.Address, abi abi.ABI, funcName s
 	return makeCallFromSystem(scAddress, abi, funcName, args, returnObj, gas, nil, header, state, true)
 }
 
+// MakeCallWithAddress calls the contract at scAddress from the system address,
+// finalising the state afterwards if the call succeeds and finaliseState is set.
+func MakeCallWithAddress(scAddress common.Address, abi abi.ABI, funcName string, args []interface{}, returnObj interface{}, gas uint64, value *big.Int, header *types.Header, state vm.StateDB, finaliseState bool) (uint64, error) {
+	gasLeft, err := makeCallFromSystem(scAddress, abi, funcName, args, returnObj, gas, value, header, state, false)
+	if err == nil && finaliseState {
+		state.Finalise(true)
+	}
+	return gasLeft, err
+}
+
 func GetRegisteredAddress(registryId [32]byte, header *types.Header, state vm.StateDB) (*common.Address, error) {
 	vmevm, err := createEVM(header, state)
 	if err != nil {
@@ -101,11 +113,13 @@ func createEVM(header *types.Header, state vm.StateDB) (*vm.EVM, error) {
 func makeCallFromSystem(scAddress common.Address, abi abi.ABI, funcName string, args []interface{}, returnObj interface{}, gas uint64, value *big.Int, header *types.Header, state vm.StateDB, static bool) (uint64, error) {
 	// Record a metrics data point about execution time.
 	start := time.Now()
+	systemCallTimersMu.Lock()
 	timer, ok := systemCallTimers[funcName]
 	if !ok {
 		timer = metrics.NewRegisteredTimer("contract_comm/systemcall/"+funcName, nil)
 		systemCallTimers[funcName] = timer
 	}
+	systemCallTimersMu.Unlock()
 	defer timer.UpdateSince(start)
 
 	vmevm, err := createEVM(header, state)
@@ -23,9 +23,11 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/contract_comm"
+	"github.com/ethereum/go-ethereum/contract_comm/currency"
 	"github.com/ethereum/go-ethereum/contract_comm/errors"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/params"
 )
 
@@ -82,14 +84,28 @@ var (
 	gasPriceMinimumABI, _            = abi.JSON(strings.NewReader(gasPriceMinimumABIString))
 	FallbackGasPriceMinimum *big.Int = big.NewInt(0) // gas price minimum to return if unable to fetch from contract
 	suggestionMultiplier    *big.Int = big.NewInt(5) // The multiplier that we apply to the minimum when suggesting gas price
+
+	// Topics of the events which may change the gas price minimum
+	gasPriceMinimumUpdatedTopic = crypto.Keccak256Hash([]byte("GasPriceMinimumUpdated(uint256)"))
+	registryUpdatedTopic        = crypto.Keccak256Hash([]byte("RegistryUpdated(string,bytes32,address)"))
 )
 
 func GetGasPriceSuggestion(currency *common.Address, header *types.Header, state vm.StateDB) (*big.Int, error) {
 	gasPriceMinimum, err := GetGasPriceMinimum(currency, header, state)
+	if gasPriceMinimum == nil {
+		return nil, err
+	}
 	return new(big.Int).Mul(gasPriceMinimum, suggestionMultiplier), err
 }
 
+// GetGasPriceMinimum returns the minimum gas price in the given currency. Once
+// the base fee fork is active it is the base fee stored in the header, otherwise
+// it is read from the GasPriceMinimum contract.
 func GetGasPriceMinimum(currency *common.Address, header *types.Header, state vm.StateDB) (*big.Int, error) {
+	if header != nil && header.BaseFee != nil {
+		return GetBaseFeeInCurrency(currency, header.BaseFee, header, state)
+	}
+
 	var currencyAddress *common.Address
 	var err error
 
@@ -134,6 +150,18 @@ func GetGasPriceMinimum(currency *common.Address, header *types.Header, state vm
 	return gasPriceMinimum, err
 }
 
+// GetBaseFeeInCurrency converts the CELO denominated base fee into the given
+// currency at the oracle rate of the given header and state. An error is
+// returned if the rate of a non-native currency can't be retrieved, since the
+// CELO amount is no minimum in any other currency.
+func GetBaseFeeInCurrency(feeCurrency *common.Address, baseFee *big.Int, header *types.Header, state vm.StateDB) (*big.Int, error) {
+	baseFeeInCurrency, err := currency.ConvertFromGold(baseFee, feeCurrency, header, state)
+	if err != nil {
+		return nil, err
+	}
+	return baseFeeInCurrency, nil
+}
+
 func UpdateGasPriceMinimum(header *types.Header, state vm.StateDB) (*big.Int, error) {
 	var updatedGasPriceMinimum *big.Int
 
@@ -155,3 +183,20 @@ func UpdateGasPriceMinimum(header *types.Header, state vm.StateDB) (*big.Int, er
 	}
 	return updatedGasPriceMinimum, err
 }
+
+// IsGasPriceMinimumUpdate reports whether a log may signal an update of the gas
+// price minimum: a new gas price minimum, or a new GasPriceMinimum contract
+// registered. The emitting contract isn't checked, so the gas price minimum has
+// to be retrieved again to know its value.
+func IsGasPriceMinimumUpdate(l *types.Log) bool {
+	if len(l.Topics) == 0 {
+		return false
+	}
+	switch l.Topics[0] {
+	case gasPriceMinimumUpdatedTopic:
+		return true
+	case registryUpdatedTopic:
+		return len(l.Topics) > 1 && l.Topics[1] == common.Hash(params.GasPriceMinimumRegistryId)
+	}
+	return false
+}
@@ -16,6 +16,7 @@
 package validators
 
 import (
+	"crypto/ecdsa"
 	"fmt"
 	"math/big"
 	"strings"
@@ -26,6 +27,7 @@ import (
 	"github.com/ethereum/go-ethereum/contract_comm"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
 	blscrypto "github.com/ethereum/go-ethereum/crypto/bls"
 	"github.com/ethereum/go-ethereum/params"
 )
@@ -229,6 +231,33 @@ func GetValidator(header *types.Header, state vm.StateDB, validatorAddress commo
 	return validator, nil
 }
 
+// RetrieveRegisteredValidatorPublicKeys returns the ECDSA public keys registered in the validators
+// contract, by validator signer.  Keys that don't match the signer are left out.
+func RetrieveRegisteredValidatorPublicKeys(header *types.Header, state vm.StateDB) (map[common.Address]*ecdsa.PublicKey, error) {
+	regVals, err := RetrieveRegisteredValidators(header, state)
+	if err != nil {
+		return nil, err
+	}
+	publicKeys := make(map[common.Address]*ecdsa.PublicKey, len(regVals))
+	for _, account := range regVals {
+		validator, err := GetValidator(header, state, account)
+		if err != nil {
+			return nil, err
+		}
+		// The contract stores the uncompressed key without the leading 0x04 byte
+		ecdsaPublicKey := validator.EcdsaPublicKey
+		if len(ecdsaPublicKey) == 64 {
+			ecdsaPublicKey = append([]byte{0x04}, ecdsaPublicKey...)
+		}
+		publicKey, err := crypto.UnmarshalPubkey(ecdsaPublicKey)
+		if err != nil || crypto.PubkeyToAddress(*publicKey) != validator.Signer {
+			continue
+		}
+		publicKeys[validator.Signer] = publicKey
+	}
+	return publicKeys, nil
+}
+
 func GetValidatorData(header *types.Header, state vm.StateDB, validatorAddresses []common.Address) ([]istanbul.ValidatorData, error) {
 	var validatorData []istanbul.ValidatorData
 	for _, addr := range validatorAddresses {
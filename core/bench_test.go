@@ -26,7 +26,11 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/contract_comm"
+	"github.com/ethereum/go-ethereum/contract_comm/currency"
+	"github.com/ethereum/go-ethereum/core/asm"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -307,3 +311,195 @@ func benchReadChain(b *testing.B, full bool, count uint64) {
 		db.Close()
 	}
 }
+
+// feeCurrencyBenchCode is a minimal fee currency token, keeping the balance of
+// every account in the storage slot keyed by its address.
+const feeCurrencyBenchCode = `
+	PUSH 0
+	CALLDATALOAD
+	PUSH 0xe0
+	SHR
+	DUP1
+	PUSH 0x70a08231
+	EQ
+	JUMPI @balanceOf
+	DUP1
+	PUSH 0x362a5f80
+	EQ
+	JUMPI @debitFrom
+	DUP1
+	PUSH 0x9951b90c
+	EQ
+	JUMPI @creditTo
+	DUP1
+	PUSH 0xca981c62
+	EQ
+	JUMPI @creditToMany
+	PUSH 0
+	DUP1
+	REVERT
+balanceOf:
+	PUSH 4
+	CALLDATALOAD
+	SLOAD
+	PUSH 0
+	MSTORE
+	PUSH 32
+	PUSH 0
+	RETURN
+debitFrom:
+	PUSH 36
+	CALLDATALOAD
+	PUSH 4
+	CALLDATALOAD
+	DUP1
+	SLOAD
+	DUP3
+	SWAP1
+	SUB
+	SWAP1
+	SSTORE
+	STOP
+creditTo:
+	PUSH 36
+	CALLDATALOAD
+	PUSH 4
+	CALLDATALOAD
+	DUP1
+	SLOAD
+	DUP3
+	ADD
+	SWAP1
+	SSTORE
+	STOP
+creditToMany:
+	PUSH 4
+	CALLDATALOAD
+	PUSH 4
+	ADD
+	PUSH 36
+	CALLDATALOAD
+	PUSH 4
+	ADD
+	DUP2
+	CALLDATALOAD
+loop:
+	DUP1
+	ISZERO
+	JUMPI @done
+	DUP1
+	PUSH 32
+	MUL
+	DUP1
+	DUP5
+	ADD
+	CALLDATALOAD
+	SWAP1
+	DUP4
+	ADD
+	CALLDATALOAD
+	DUP2
+	SLOAD
+	ADD
+	SWAP1
+	SSTORE
+	PUSH 1
+	SWAP1
+	SUB
+	JUMP @loop
+done:
+	STOP
+`
+
+func BenchmarkFeeCurrencyTxs_immediate(b *testing.B) {
+	benchFeeCurrencyTxs(b, false)
+}
+func BenchmarkFeeCurrencyTxs_batched(b *testing.B) {
+	benchFeeCurrencyTxs(b, true)
+}
+
+// benchFeeCurrencyTxs measures applying a block worth of transactions paying
+// their fees in a non-native currency, either crediting the fees with the
+// currency contract in every transaction or batching them per block.
+func benchFeeCurrencyTxs(b *testing.B, batched bool) {
+	config := *params.TestChainConfig
+	if batched {
+		config.BatchedFeesBlock = big.NewInt(0)
+	}
+	chain, base, header := newFeeCurrencyState(b, &config, compileCode(b, feeCurrencyBenchCode), ringAddrs[:200])
+	defer chain.Stop()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		statedb := base.Copy()
+		applyFeeCurrencyTxs(b, chain, statedb, header, ringAddrs[:200])
+		statedb.IntermediateRoot(true)
+	}
+}
+
+var (
+	feeCurrencyToken    = common.HexToAddress("0xfee")
+	feeCurrencyGateway  = common.HexToAddress("0x9a7e")
+	feeCurrencyCoinbase = common.HexToAddress("0xc0ba5e")
+)
+
+// newFeeCurrencyState creates a chain with the given config and a state on top
+// of its genesis, holding the fee currency token with the given code and
+// funding every sender with it.
+func newFeeCurrencyState(t testing.TB, config *params.ChainConfig, code []byte, senders []common.Address) (*BlockChain, *state.StateDB, *types.Header) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		genesis = (&Genesis{Config: config}).MustCommit(db)
+		funds   = new(big.Int).Mul(big.NewInt(1000), big.NewInt(params.Ether))
+	)
+	chain, err := NewBlockChain(db, nil, config, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	contract_comm.SetInternalEVMHandler(chain)
+
+	statedb, _ := state.New(genesis.Root(), state.NewDatabase(db))
+	statedb.SetCode(feeCurrencyToken, code)
+	for _, addr := range senders {
+		statedb.SetState(feeCurrencyToken, common.BytesToHash(addr.Bytes()), common.BigToHash(funds))
+	}
+	statedb.IntermediateRoot(true)
+
+	header := &types.Header{
+		ParentHash: genesis.Hash(),
+		Number:     big.NewInt(1),
+		GasLimit:   genesis.GasLimit(),
+		Coinbase:   feeCurrencyCoinbase,
+		Difficulty: big.NewInt(1),
+	}
+	return chain, statedb, header
+}
+
+// applyFeeCurrencyTxs applies a transaction paying its fees in the fee currency
+// token from every sender, then settles the batched fee credits if any.
+func applyFeeCurrencyTxs(t testing.TB, chain *BlockChain, statedb *state.StateDB, header *types.Header, senders []common.Address) {
+	gp := new(GasPool).AddGas(math.MaxUint64)
+	for _, from := range senders {
+		to := common.Address{}
+		msg := types.NewMessage(from, &to, 0, big.NewInt(0), 200000, big.NewInt(1), &feeCurrencyToken, &feeCurrencyGateway, big.NewInt(1), nil, false)
+		evm := vm.NewEVM(vm.NewEVMContext(msg, header, chain, nil), statedb, chain.Config(), vm.Config{})
+		if _, _, failed, err := ApplyMessage(evm, msg, gp); err != nil || failed {
+			t.Fatalf("failed to apply message: failed %v, err %v", failed, err)
+		}
+		statedb.Finalise(true)
+	}
+	if err := currency.CreditFees(header, statedb); err != nil {
+		t.Fatalf("failed to credit fees: %v", err)
+	}
+}
+
+func compileCode(t testing.TB, source string) []byte {
+	compiler := asm.NewCompiler(false)
+	compiler.Feed(asm.Lex([]byte(source), false))
+	code, errs := compiler.Compile()
+	if len(errs) != 0 {
+		t.Fatalf("failed to compile code: %v", errs)
+	}
+	return common.FromHex(code)
+}
@@ -2449,3 +2449,56 @@ func TestDeleteCreateRevert(t *testing.T) {
 		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
 	}
 }
+
+// Tests that the fees of a sponsored transaction are charged to and refunded
+// to the fee payer, while the sender only pays the value and bumps its nonce.
+func TestSponsoredTransactionFees(t *testing.T) {
+	var (
+		db           = rawdb.NewMemoryDatabase()
+		senderKey, _ = crypto.GenerateKey()
+		payerKey, _  = crypto.GenerateKey()
+		sender       = crypto.PubkeyToAddress(senderKey.PublicKey)
+		payer        = crypto.PubkeyToAddress(payerKey.PublicKey)
+		recipient    = common.HexToAddress("0xdeadbeef")
+		funds        = big.NewInt(1000000000)
+		config       = *params.TestChainConfig
+	)
+	config.TypedTxBlock = big.NewInt(0)
+	gspec := &Genesis{Config: &config, Alloc: GenesisAlloc{sender: {Balance: big.NewInt(1000)}, payer: {Balance: funds}}}
+	genesis := gspec.MustCommit(db)
+
+	signer := types.NewCeloSigner(config.ChainID)
+	blocks, _ := GenerateChain(&config, genesis, ethash.NewFaker(), db, 1, func(i int, gen *BlockGen) {
+		tx, err := types.SignTx(types.NewSponsoredTransaction(config.ChainID, gen.TxNonce(sender), &recipient, big.NewInt(1000), 100000, big.NewInt(10), nil, payer, nil, nil, nil), signer, senderKey)
+		if err != nil {
+			t.Fatalf("failed to sign tx: %v", err)
+		}
+		if tx, err = types.SignTxAsFeePayer(tx, signer, payerKey); err != nil {
+			t.Fatalf("failed to sign tx as fee payer: %v", err)
+		}
+		gen.AddTx(tx)
+	})
+	chain, err := NewBlockChain(db, nil, &config, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+	}
+	statedb, _ := chain.State()
+	if have := statedb.GetBalance(sender); have.Sign() != 0 {
+		t.Errorf("sender balance mismatch: have %v, want 0", have)
+	}
+	if have := statedb.GetNonce(sender); have != 1 {
+		t.Errorf("sender nonce mismatch: have %d, want 1", have)
+	}
+	if have := statedb.GetNonce(payer); have != 0 {
+		t.Errorf("fee payer nonce mismatch: have %d, want 0", have)
+	}
+	fee := new(big.Int).Mul(big.NewInt(int64(blocks[0].GasUsed())), big.NewInt(10))
+	if have, want := statedb.GetBalance(payer), new(big.Int).Sub(funds, fee); have.Cmp(want) != 0 {
+		t.Errorf("fee payer balance mismatch: have %v, want %v", have, want)
+	}
+}
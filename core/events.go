@@ -24,6 +24,15 @@ import (
 // NewTxsEvent is posted when a batch of transactions enter the transaction pool.
 type NewTxsEvent struct{ Txs []*types.Transaction }
 
+// FeeCurrencyWhitelistChangedEvent is posted when the transaction pool sees the
+// fee currency whitelist change at a new head.
+type FeeCurrencyWhitelistChangedEvent struct {
+	Header    *types.Header
+	Whitelist []common.Address
+	Added     []common.Address
+	Removed   []common.Address
+}
+
 // PendingLogsEvent is posted pre mining and notifies of pending logs.
 type PendingLogsEvent struct {
 	Logs []*types.Log
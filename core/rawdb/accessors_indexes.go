@@ -68,6 +68,25 @@ func DeleteTxLookupEntry(db ethdb.KeyValueWriter, hash common.Hash) {
 	db.Delete(txLookupKey(hash))
 }
 
+// ReadTxSenderEntry retrieves the hash of the transaction sent by an account
+// with a given nonce, or the zero hash if none is indexed. The entry may refer
+// to a transaction which is no longer canonical.
+func ReadTxSenderEntry(db ethdb.KeyValueReader, sender common.Address, nonce uint64) common.Hash {
+	data, _ := db.Get(txSenderKey(sender, nonce))
+	if len(data) != common.HashLength {
+		return common.Hash{}
+	}
+	return common.BytesToHash(data)
+}
+
+// WriteTxSenderEntry stores the hash of the transaction sent by an account with
+// a given nonce, enabling sender and nonce based transaction lookups.
+func WriteTxSenderEntry(db ethdb.KeyValueWriter, sender common.Address, nonce uint64, hash common.Hash) {
+	if err := db.Put(txSenderKey(sender, nonce), hash.Bytes()); err != nil {
+		log.Crit("Failed to store transaction sender entry", "err", err)
+	}
+}
+
 // ReadTransaction retrieves a specific transaction from the database, along with
 // its added positional metadata.
 func ReadTransaction(db ethdb.Reader, hash common.Hash) (*types.Transaction, common.Hash, uint64, uint64) {
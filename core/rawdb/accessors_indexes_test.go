@@ -106,3 +106,24 @@ func TestLookupStorage(t *testing.T) {
 		})
 	}
 }
+
+// Tests that sender and nonce lookup entries can be stored and retrieved.
+func TestTxSenderStorage(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	sender, other := common.Address{0x01}, common.Address{0x02}
+	hash := common.Hash{0x11}
+	if entry := ReadTxSenderEntry(db, sender, 1); entry != (common.Hash{}) {
+		t.Fatalf("non existent entry returned: %x", entry)
+	}
+	WriteTxSenderEntry(db, sender, 1, hash)
+	if entry := ReadTxSenderEntry(db, sender, 1); entry != hash {
+		t.Fatalf("entry mismatch: have %x, want %x", entry, hash)
+	}
+	if entry := ReadTxSenderEntry(db, sender, 2); entry != (common.Hash{}) {
+		t.Fatalf("entry of another nonce returned: %x", entry)
+	}
+	if entry := ReadTxSenderEntry(db, other, 1); entry != (common.Hash{}) {
+		t.Fatalf("entry of another sender returned: %x", entry)
+	}
+}
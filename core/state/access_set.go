@@ -0,0 +1,203 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// accessKind identifies which part of an account an access touched.
+type accessKind uint8
+
+const (
+	accessBalance accessKind = iota
+	accessNonce
+	accessCode
+	accessStorage
+)
+
+// accessKey identifies a single piece of state that can be read or written.
+// The slot is only meaningful for storage accesses.
+type accessKey struct {
+	addr common.Address
+	kind accessKind
+	slot common.Hash
+}
+
+// AccessSet records the state read and written while access tracking is
+// enabled on a StateDB. It is used by the parallel block processor to detect
+// conflicts between optimistically executed transactions and to merge their
+// results back into the canonical state.
+//
+// Balance changes made only through AddBalance/SubBalance, without the balance
+// having been read, are recorded as deltas. Deltas commute with each other, so
+// e.g. every transaction crediting the block proposer does not cause a conflict.
+type AccessSet struct {
+	reads    map[accessKey]struct{}
+	writes   map[accessKey]struct{}
+	deltas   map[common.Address]struct{}
+	created  map[common.Address]struct{}
+	suicided map[common.Address]struct{}
+}
+
+// NewAccessSet creates an empty access set.
+func NewAccessSet() *AccessSet {
+	return &AccessSet{
+		reads:    make(map[accessKey]struct{}),
+		writes:   make(map[accessKey]struct{}),
+		deltas:   make(map[common.Address]struct{}),
+		created:  make(map[common.Address]struct{}),
+		suicided: make(map[common.Address]struct{}),
+	}
+}
+
+func (a *AccessSet) read(addr common.Address, kind accessKind, slot common.Hash) {
+	a.reads[accessKey{addr, kind, slot}] = struct{}{}
+}
+
+func (a *AccessSet) readAccount(addr common.Address) {
+	a.read(addr, accessBalance, common.Hash{})
+	a.read(addr, accessNonce, common.Hash{})
+	a.read(addr, accessCode, common.Hash{})
+}
+
+func (a *AccessSet) write(addr common.Address, kind accessKind, slot common.Hash) {
+	a.writes[accessKey{addr, kind, slot}] = struct{}{}
+}
+
+func (a *AccessSet) writeAccount(addr common.Address) {
+	a.write(addr, accessBalance, common.Hash{})
+	a.write(addr, accessNonce, common.Hash{})
+	a.write(addr, accessCode, common.Hash{})
+}
+
+// seal converts balance deltas into regular writes for every account whose
+// balance was also read, since the final value then depends on the read.
+func (a *AccessSet) seal() {
+	for addr := range a.deltas {
+		key := accessKey{addr: addr, kind: accessBalance}
+		if _, ok := a.reads[key]; ok {
+			a.writes[key] = struct{}{}
+			delete(a.deltas, addr)
+		}
+	}
+}
+
+// Conflicts reports whether any state read recorded in a was modified by the
+// writes recorded in prior.
+func (a *AccessSet) Conflicts(prior *AccessSet) bool {
+	for key := range a.reads {
+		if _, ok := prior.writes[key]; ok {
+			return true
+		}
+		if _, ok := prior.created[key.addr]; ok {
+			return true
+		}
+		if _, ok := prior.suicided[key.addr]; ok {
+			return true
+		}
+		if key.kind == accessBalance {
+			if _, ok := prior.deltas[key.addr]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Merge adds the modifications recorded in other to a. Reads are not merged,
+// as the result is only meant to be used as the prior set in Conflicts.
+func (a *AccessSet) Merge(other *AccessSet) {
+	for key := range other.writes {
+		a.writes[key] = struct{}{}
+	}
+	for addr := range other.deltas {
+		a.deltas[addr] = struct{}{}
+	}
+	for addr := range other.created {
+		a.created[addr] = struct{}{}
+	}
+	for addr := range other.suicided {
+		a.suicided[addr] = struct{}{}
+	}
+}
+
+// StartAccessTracking begins recording every state read and write made
+// through the StateDB, discarding anything recorded previously.
+func (s *StateDB) StartAccessTracking() {
+	s.accesses = NewAccessSet()
+}
+
+// StopAccessTracking stops recording state accesses and returns the set
+// recorded since the last call to StartAccessTracking.
+func (s *StateDB) StopAccessTracking() *AccessSet {
+	accesses := s.accesses
+	s.accesses = nil
+	if accesses != nil {
+		accesses.seal()
+	}
+	return accesses
+}
+
+// ApplyAccesses copies the modifications recorded in accesses from src into s.
+// The src state must have been derived from base, and base must match s for
+// every piece of state read by src (i.e. accesses does not conflict with any
+// modifications made to s since base was taken). Balance deltas are computed
+// against base and added to the balances in s.
+func (s *StateDB) ApplyAccesses(src, base *StateDB, accesses *AccessSet) {
+	for addr := range accesses.created {
+		s.CreateAccount(addr)
+	}
+	for key := range accesses.writes {
+		switch key.kind {
+		case accessBalance:
+			s.SetBalance(key.addr, src.GetBalance(key.addr))
+		case accessNonce:
+			s.SetNonce(key.addr, src.GetNonce(key.addr))
+		case accessCode:
+			s.SetCode(key.addr, src.GetCode(key.addr))
+		case accessStorage:
+			s.SetState(key.addr, key.slot, src.GetState(key.addr, key.slot))
+		}
+	}
+	for addr := range accesses.deltas {
+		delta := new(big.Int).Sub(src.GetBalance(addr), base.GetBalance(addr))
+		if delta.Sign() >= 0 {
+			s.AddBalance(addr, delta)
+		} else {
+			s.SubBalance(addr, delta.Neg(delta))
+		}
+	}
+	for addr := range accesses.suicided {
+		// A suicide inside a reverted call leaves the account in place.
+		if !src.Exist(addr) {
+			s.Suicide(addr)
+		}
+	}
+	// Fee credits are only ever added to, so they commute just like deltas.
+	for feeCurrency, credits := range src.feeCredits {
+		for recipient, amount := range credits {
+			delta := new(big.Int).Set(amount)
+			if prev := base.feeCredits[feeCurrency][recipient]; prev != nil {
+				delta.Sub(delta, prev)
+			}
+			s.AddFeeCredit(feeCurrency, recipient, delta)
+		}
+	}
+}
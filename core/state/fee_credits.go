@@ -0,0 +1,92 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// AddFeeCredit records that amount of the given fee currency is owed to the
+// recipient. Fee credits are not part of the state trie: they are accumulated
+// in memory while the transactions of a block are processed, and settled with
+// the fee currency contracts when the block is finalized.
+func (s *StateDB) AddFeeCredit(feeCurrency, recipient common.Address, amount *big.Int) {
+	if amount.Sign() == 0 {
+		return
+	}
+	credits := s.feeCredits[feeCurrency]
+	if credits == nil {
+		credits = make(map[common.Address]*big.Int)
+		s.feeCredits[feeCurrency] = credits
+	}
+	prev := credits[recipient]
+	s.journal.append(feeCreditChange{feeCurrency: feeCurrency, recipient: recipient, prev: prev})
+
+	if prev == nil {
+		credits[recipient] = new(big.Int).Set(amount)
+	} else {
+		credits[recipient] = new(big.Int).Add(prev, amount)
+	}
+}
+
+// FeeCreditCurrencies returns the fee currencies with outstanding credits,
+// sorted by address.
+func (s *StateDB) FeeCreditCurrencies() []common.Address {
+	currencies := make([]common.Address, 0, len(s.feeCredits))
+	for feeCurrency := range s.feeCredits {
+		currencies = append(currencies, feeCurrency)
+	}
+	sortAddresses(currencies)
+	return currencies
+}
+
+// FeeCredits returns the outstanding credits in the given fee currency, with
+// the recipients sorted by address.
+func (s *StateDB) FeeCredits(feeCurrency common.Address) ([]common.Address, []*big.Int) {
+	credits := s.feeCredits[feeCurrency]
+
+	recipients := make([]common.Address, 0, len(credits))
+	for recipient := range credits {
+		recipients = append(recipients, recipient)
+	}
+	sortAddresses(recipients)
+
+	amounts := make([]*big.Int, len(recipients))
+	for i, recipient := range recipients {
+		amounts[i] = new(big.Int).Set(credits[recipient])
+	}
+	return recipients, amounts
+}
+
+// ClearFeeCredits drops the outstanding credits in the given fee currency,
+// once they have been settled.
+func (s *StateDB) ClearFeeCredits(feeCurrency common.Address) {
+	for recipient, amount := range s.feeCredits[feeCurrency] {
+		s.journal.append(feeCreditChange{feeCurrency: feeCurrency, recipient: recipient, prev: amount})
+	}
+	delete(s.feeCredits, feeCurrency)
+}
+
+func sortAddresses(addrs []common.Address) {
+	sort.Slice(addrs, func(i, j int) bool {
+		return bytes.Compare(addrs[i][:], addrs[j][:]) < 0
+	})
+}
@@ -129,6 +129,10 @@ type (
 	touchChange struct {
 		account *common.Address
 	}
+	feeCreditChange struct {
+		feeCurrency, recipient common.Address
+		prev                   *big.Int
+	}
 )
 
 func (ch createObjectChange) revert(s *StateDB) {
@@ -230,3 +234,21 @@ func (ch addPreimageChange) revert(s *StateDB) {
 func (ch addPreimageChange) dirtied() *common.Address {
 	return nil
 }
+
+func (ch feeCreditChange) revert(s *StateDB) {
+	if ch.prev == nil {
+		delete(s.feeCredits[ch.feeCurrency], ch.recipient)
+		if len(s.feeCredits[ch.feeCurrency]) == 0 {
+			delete(s.feeCredits, ch.feeCurrency)
+		}
+	} else {
+		if s.feeCredits[ch.feeCurrency] == nil {
+			s.feeCredits[ch.feeCurrency] = make(map[common.Address]*big.Int)
+		}
+		s.feeCredits[ch.feeCurrency][ch.recipient] = ch.prev
+	}
+}
+
+func (ch feeCreditChange) dirtied() *common.Address {
+	return nil
+}
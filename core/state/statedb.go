@@ -88,12 +88,18 @@ type StateDB struct {
 
 	preimages map[common.Hash][]byte
 
+	// Fee currency credits accumulated during the block, by currency and recipient.
+	feeCredits map[common.Address]map[common.Address]*big.Int
+
 	// Journal of state modifications. This is the backbone of
 	// Snapshot and RevertToSnapshot.
 	journal        *journal
 	validRevisions []revision
 	nextRevisionId int
 
+	// Reads and writes recorded for conflict detection, nil unless tracking.
+	accesses *AccessSet
+
 	// Measurements gathered during execution for debugging purposes
 	AccountReads   time.Duration
 	AccountHashes  time.Duration
@@ -119,6 +125,7 @@ func New(root common.Hash, db Database) (*StateDB, error) {
 		stateObjectsDirty:   make(map[common.Address]struct{}),
 		logs:                make(map[common.Hash][]*types.Log),
 		preimages:           make(map[common.Hash][]byte),
+		feeCredits:          make(map[common.Address]map[common.Address]*big.Int),
 		journal:             newJournal(),
 	}, nil
 }
@@ -151,6 +158,7 @@ func (s *StateDB) Reset(root common.Hash) error {
 	s.logs = make(map[common.Hash][]*types.Log)
 	s.logSize = 0
 	s.preimages = make(map[common.Hash][]byte)
+	s.feeCredits = make(map[common.Address]map[common.Address]*big.Int)
 	s.clearJournalAndRefund()
 	return nil
 }
@@ -212,18 +220,27 @@ func (s *StateDB) SubRefund(gas uint64) {
 // Exist reports whether the given account address exists in the state.
 // Notably this also returns true for suicided accounts.
 func (s *StateDB) Exist(addr common.Address) bool {
+	if s.accesses != nil {
+		s.accesses.readAccount(addr)
+	}
 	return s.getStateObject(addr) != nil
 }
 
 // Empty returns whether the state object is either non-existent
 // or empty according to the EIP161 specification (balance = nonce = code = 0)
 func (s *StateDB) Empty(addr common.Address) bool {
+	if s.accesses != nil {
+		s.accesses.readAccount(addr)
+	}
 	so := s.getStateObject(addr)
 	return so == nil || so.empty()
 }
 
 // Retrieve the balance from the given address or 0 if object not found
 func (s *StateDB) GetBalance(addr common.Address) *big.Int {
+	if s.accesses != nil {
+		s.accesses.read(addr, accessBalance, common.Hash{})
+	}
 	stateObject := s.getStateObject(addr)
 	if stateObject != nil {
 		return stateObject.Balance()
@@ -232,6 +249,9 @@ func (s *StateDB) GetBalance(addr common.Address) *big.Int {
 }
 
 func (s *StateDB) GetNonce(addr common.Address) uint64 {
+	if s.accesses != nil {
+		s.accesses.read(addr, accessNonce, common.Hash{})
+	}
 	stateObject := s.getStateObject(addr)
 	if stateObject != nil {
 		return stateObject.Nonce()
@@ -251,6 +271,9 @@ func (s *StateDB) BlockHash() common.Hash {
 }
 
 func (s *StateDB) GetCode(addr common.Address) []byte {
+	if s.accesses != nil {
+		s.accesses.read(addr, accessCode, common.Hash{})
+	}
 	stateObject := s.getStateObject(addr)
 	if stateObject != nil {
 		return stateObject.Code(s.db)
@@ -259,6 +282,9 @@ func (s *StateDB) GetCode(addr common.Address) []byte {
 }
 
 func (s *StateDB) GetCodeSize(addr common.Address) int {
+	if s.accesses != nil {
+		s.accesses.read(addr, accessCode, common.Hash{})
+	}
 	stateObject := s.getStateObject(addr)
 	if stateObject == nil {
 		return 0
@@ -274,6 +300,9 @@ func (s *StateDB) GetCodeSize(addr common.Address) int {
 }
 
 func (s *StateDB) GetCodeHash(addr common.Address) common.Hash {
+	if s.accesses != nil {
+		s.accesses.read(addr, accessCode, common.Hash{})
+	}
 	stateObject := s.getStateObject(addr)
 	if stateObject == nil {
 		return common.Hash{}
@@ -283,6 +312,9 @@ func (s *StateDB) GetCodeHash(addr common.Address) common.Hash {
 
 // GetState retrieves a value from the given account's storage trie.
 func (s *StateDB) GetState(addr common.Address, hash common.Hash) common.Hash {
+	if s.accesses != nil {
+		s.accesses.read(addr, accessStorage, hash)
+	}
 	stateObject := s.getStateObject(addr)
 	if stateObject != nil {
 		return stateObject.GetState(s.db, hash)
@@ -310,6 +342,9 @@ func (s *StateDB) GetStorageProof(a common.Address, key common.Hash) ([][]byte,
 
 // GetCommittedState retrieves a value from the given account's committed storage trie.
 func (s *StateDB) GetCommittedState(addr common.Address, hash common.Hash) common.Hash {
+	if s.accesses != nil {
+		s.accesses.read(addr, accessStorage, hash)
+	}
 	stateObject := s.getStateObject(addr)
 	if stateObject != nil {
 		return stateObject.GetCommittedState(s.db, hash)
@@ -347,6 +382,9 @@ func (s *StateDB) HasSuicided(addr common.Address) bool {
 
 // AddBalance adds amount to the account associated with addr.
 func (s *StateDB) AddBalance(addr common.Address, amount *big.Int) {
+	if s.accesses != nil {
+		s.accesses.deltas[addr] = struct{}{}
+	}
 	stateObject := s.GetOrNewStateObject(addr)
 	if stateObject != nil {
 		stateObject.AddBalance(amount)
@@ -355,6 +393,9 @@ func (s *StateDB) AddBalance(addr common.Address, amount *big.Int) {
 
 // SubBalance subtracts amount from the account associated with addr.
 func (s *StateDB) SubBalance(addr common.Address, amount *big.Int) {
+	if s.accesses != nil {
+		s.accesses.deltas[addr] = struct{}{}
+	}
 	stateObject := s.GetOrNewStateObject(addr)
 	if stateObject != nil {
 		stateObject.SubBalance(amount)
@@ -362,6 +403,9 @@ func (s *StateDB) SubBalance(addr common.Address, amount *big.Int) {
 }
 
 func (s *StateDB) SetBalance(addr common.Address, amount *big.Int) {
+	if s.accesses != nil {
+		s.accesses.write(addr, accessBalance, common.Hash{})
+	}
 	stateObject := s.GetOrNewStateObject(addr)
 	if stateObject != nil {
 		stateObject.SetBalance(amount)
@@ -369,6 +413,9 @@ func (s *StateDB) SetBalance(addr common.Address, amount *big.Int) {
 }
 
 func (s *StateDB) SetNonce(addr common.Address, nonce uint64) {
+	if s.accesses != nil {
+		s.accesses.write(addr, accessNonce, common.Hash{})
+	}
 	stateObject := s.GetOrNewStateObject(addr)
 	if stateObject != nil {
 		stateObject.SetNonce(nonce)
@@ -376,6 +423,9 @@ func (s *StateDB) SetNonce(addr common.Address, nonce uint64) {
 }
 
 func (s *StateDB) SetCode(addr common.Address, code []byte) {
+	if s.accesses != nil {
+		s.accesses.write(addr, accessCode, common.Hash{})
+	}
 	stateObject := s.GetOrNewStateObject(addr)
 	if stateObject != nil {
 		stateObject.SetCode(crypto.Keccak256Hash(code), code)
@@ -383,6 +433,9 @@ func (s *StateDB) SetCode(addr common.Address, code []byte) {
 }
 
 func (s *StateDB) SetState(addr common.Address, key, value common.Hash) {
+	if s.accesses != nil {
+		s.accesses.write(addr, accessStorage, key)
+	}
 	stateObject := s.GetOrNewStateObject(addr)
 	if stateObject != nil {
 		stateObject.SetState(s.db, key, value)
@@ -404,6 +457,11 @@ func (s *StateDB) SetStorage(addr common.Address, storage map[common.Hash]common
 // The account's state object is still available until the state is committed,
 // getStateObject will return a non-nil account after Suicide.
 func (s *StateDB) Suicide(addr common.Address) bool {
+	if s.accesses != nil {
+		s.accesses.readAccount(addr)
+		s.accesses.writeAccount(addr)
+		s.accesses.suicided[addr] = struct{}{}
+	}
 	stateObject := s.getStateObject(addr)
 	if stateObject == nil {
 		return false
@@ -530,6 +588,11 @@ func (s *StateDB) createObject(addr common.Address) (newobj, prev *stateObject)
 //
 // Carrying over the balance ensures that Ether doesn't disappear.
 func (s *StateDB) CreateAccount(addr common.Address) {
+	if s.accesses != nil {
+		s.accesses.readAccount(addr)
+		s.accesses.writeAccount(addr)
+		s.accesses.created[addr] = struct{}{}
+	}
 	newObj, prev := s.createObject(addr)
 	if prev != nil {
 		newObj.setBalance(prev.data.Balance)
@@ -579,6 +642,7 @@ func (s *StateDB) Copy() *StateDB {
 		logs:                make(map[common.Hash][]*types.Log, len(s.logs)),
 		logSize:             s.logSize,
 		preimages:           make(map[common.Hash][]byte, len(s.preimages)),
+		feeCredits:          make(map[common.Address]map[common.Address]*big.Int, len(s.feeCredits)),
 		journal:             newJournal(),
 	}
 	// Copy the dirty states, logs, and preimages
@@ -623,6 +687,13 @@ func (s *StateDB) Copy() *StateDB {
 	for hash, preimage := range s.preimages {
 		state.preimages[hash] = preimage
 	}
+	for feeCurrency, credits := range s.feeCredits {
+		cpy := make(map[common.Address]*big.Int, len(credits))
+		for recipient, amount := range credits {
+			cpy[recipient] = amount
+		}
+		state.feeCredits[feeCurrency] = cpy
+	}
 	return state
 }
 
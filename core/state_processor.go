@@ -75,15 +75,23 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 		// always true (EIP158)
 		statedb.IntermediateRoot(true)
 	}
-	// Iterate over and process the individual transactions
-	for i, tx := range block.Transactions() {
-		statedb.Prepare(tx.Hash(), block.Hash(), i)
-		receipt, err := ApplyTransaction(p.config, p.bc, nil, gp, statedb, header, tx, usedGas, cfg)
+	if p.useParallelExecution(block, cfg) {
+		var err error
+		receipts, allLogs, err = p.applyTransactionsParallel(block, statedb, usedGas, gp, cfg)
 		if err != nil {
 			return nil, nil, 0, err
 		}
-		receipts = append(receipts, receipt)
-		allLogs = append(allLogs, receipt.Logs...)
+	} else {
+		// Iterate over and process the individual transactions
+		for i, tx := range block.Transactions() {
+			statedb.Prepare(tx.Hash(), block.Hash(), i)
+			receipt, err := ApplyTransaction(p.config, p.bc, nil, gp, statedb, header, tx, usedGas, cfg)
+			if err != nil {
+				return nil, nil, 0, err
+			}
+			receipts = append(receipts, receipt)
+			allLogs = append(allLogs, receipt.Logs...)
+		}
 	}
 	// Finalize the block, applying any consensus engine specific extras (e.g. block rewards)
 	statedb.Prepare(common.Hash{}, block.Hash(), len(block.Transactions()))
@@ -0,0 +1,171 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var (
+	parallelTxMeter         = metrics.NewRegisteredMeter("chain/parallel/txs", nil)
+	parallelTxConflictMeter = metrics.NewRegisteredMeter("chain/parallel/conflicts", nil)
+)
+
+// parallelTxResult is the outcome of optimistically executing a transaction
+// on an isolated copy of the block's pre-transaction state.
+type parallelTxResult struct {
+	state    *state.StateDB
+	receipt  *types.Receipt
+	accesses *state.AccessSet
+	err      error
+}
+
+// useParallelExecution reports whether the transactions of the block should be
+// executed with applyTransactionsParallel.
+func (p *StateProcessor) useParallelExecution(block *types.Block, cfg vm.Config) bool {
+	// Tracers are not safe for concurrent use, and pre-Byzantium receipts need
+	// the intermediate state root after every transaction.
+	return cfg.ParallelTxExecution && !cfg.Debug && len(block.Transactions()) > 1 && p.config.IsByzantium(block.Number())
+}
+
+// applyTransactionsParallel applies the transactions of the block to statedb,
+// producing exactly the same state and receipts as applying them in order.
+//
+// Every transaction is first executed concurrently on its own copy of the
+// pre-transaction state while recording its state accesses. The results are
+// then merged in block order: a transaction whose reads do not overlap with the
+// writes of the transactions merged before it has its writes copied into
+// statedb, any other transaction is executed again on top of the merged state.
+//
+// Fee currency transactions credit their fees to the same recipients in the
+// storage of the fee currency contract, so before the batched fees fork they
+// all conflict with each other and are executed again sequentially. Once fee
+// credits are batched, they are accumulated outside the state and merged like
+// balance deltas, so only the debits of the senders are checked for conflicts.
+func (p *StateProcessor) applyTransactionsParallel(block *types.Block, statedb *state.StateDB, usedGas *uint64, gp *GasPool, cfg vm.Config) (types.Receipts, []*types.Log, error) {
+	var (
+		txs     = block.Transactions()
+		header  = block.Header()
+		base    = statedb.Copy()
+		results = make([]*parallelTxResult, len(txs))
+	)
+	// Execute all transactions optimistically against the pre-transaction state
+	var (
+		wg      sync.WaitGroup
+		next    = int32(-1)
+		workers = runtime.NumCPU()
+	)
+	if workers > len(txs) {
+		workers = len(txs)
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(atomic.AddInt32(&next, 1))
+				if i >= len(txs) {
+					return
+				}
+				results[i] = p.applyTransactionIsolated(block, header, base, txs[i], i, cfg)
+			}
+		}()
+	}
+	wg.Wait()
+	parallelTxMeter.Mark(int64(len(txs)))
+
+	// Merge the results in order, re-executing the ones that observed stale state
+	var (
+		receipts types.Receipts
+		allLogs  []*types.Log
+		written  = state.NewAccessSet()
+	)
+	for i, tx := range txs {
+		res := results[i]
+		statedb.Prepare(tx.Hash(), block.Hash(), i)
+
+		if res.err != nil || res.accesses.Conflicts(written) {
+			parallelTxConflictMeter.Mark(1)
+
+			statedb.StartAccessTracking()
+			receipt, err := ApplyTransaction(p.config, p.bc, nil, gp, statedb, header, tx, usedGas, cfg)
+			accesses := statedb.StopAccessTracking()
+			if err != nil {
+				return nil, nil, err
+			}
+			written.Merge(accesses)
+			receipts = append(receipts, receipt)
+			allLogs = append(allLogs, receipt.Logs...)
+			continue
+		}
+		// Mirror the gas pool accounting of the state transition
+		if gp.Gas() < tx.Gas() {
+			return nil, nil, ErrGasLimitReached
+		}
+		if err := gp.SubGas(res.receipt.GasUsed); err != nil {
+			return nil, nil, err
+		}
+		statedb.ApplyAccesses(res.state, base, res.accesses)
+		for _, l := range res.state.GetLogs(tx.Hash()) {
+			cpy := *l
+			statedb.AddLog(&cpy)
+		}
+		if cfg.EnablePreimageRecording {
+			for hash, preimage := range res.state.Preimages() {
+				statedb.AddPreimage(hash, preimage)
+			}
+		}
+		statedb.Finalise(true)
+		written.Merge(res.accesses)
+
+		*usedGas += res.receipt.GasUsed
+		receipt := res.receipt
+		receipt.CumulativeGasUsed = *usedGas
+		receipt.Logs = statedb.GetLogs(tx.Hash())
+		receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+
+		receipts = append(receipts, receipt)
+		allLogs = append(allLogs, receipt.Logs...)
+	}
+	return receipts, allLogs, nil
+}
+
+// applyTransactionIsolated executes tx on a private copy of base, with its own
+// gas pool and gas counter, recording every state access it makes.
+func (p *StateProcessor) applyTransactionIsolated(block *types.Block, header *types.Header, base *state.StateDB, tx *types.Transaction, index int, cfg vm.Config) *parallelTxResult {
+	var (
+		statedb = base.Copy()
+		gp      = new(GasPool).AddGas(block.GasLimit())
+		usedGas = new(uint64)
+	)
+	statedb.Prepare(tx.Hash(), block.Hash(), index)
+	statedb.StartAccessTracking()
+	receipt, err := ApplyTransaction(p.config, p.bc, nil, gp, statedb, header, tx, usedGas, cfg)
+	return &parallelTxResult{
+		state:    statedb,
+		receipt:  receipt,
+		accesses: statedb.StopAccessTracking(),
+		err:      err,
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/contract_comm"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that executing the transactions of a block in parallel yields exactly
+// the same state, receipts and logs as executing them sequentially, both for
+// independent transactions and for ones conflicting with each other.
+func TestParallelTxExecution(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		config  = params.TestChainConfig
+		signer  = types.NewEIP155Signer(config.ChainID)
+		funds   = new(big.Int).Mul(big.NewInt(1000), big.NewInt(params.Ether))
+		keys    = make([]*ecdsa.PrivateKey, 16)
+		addrs   = make([]common.Address, len(keys))
+		counter = common.HexToAddress("0xc0ffee")
+		bomb    = common.HexToAddress("0xb0b")
+		alloc   = GenesisAlloc{
+			// counter increments storage slot 0 and emits an empty log on every call
+			counter: {Code: common.FromHex("0x60005460010160005560006000a000"), Balance: big.NewInt(0)},
+			// bomb self destructs, sending its balance to the counter contract
+			bomb: {Code: common.FromHex("0x7300000000000000000000000000000000c0ffeeff"), Balance: big.NewInt(1000)},
+		}
+	)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+		alloc[addrs[i]] = GenesisAccount{Balance: funds}
+	}
+	gspec := &Genesis{Config: config, Alloc: alloc}
+	genesis := gspec.MustCommit(db)
+
+	transfer := func(gen *BlockGen, from int, to common.Address, data []byte) {
+		tx, err := types.SignTx(types.NewTransaction(gen.TxNonce(addrs[from]), to, big.NewInt(1), 100000, nil, nil, nil, nil, data), signer, keys[from])
+		if err != nil {
+			t.Fatalf("failed to sign tx: %v", err)
+		}
+		gen.AddTx(tx)
+	}
+	blocks, _ := GenerateChain(config, genesis, ethash.NewFaker(), db, 3, func(i int, gen *BlockGen) {
+		switch i {
+		case 0:
+			// Only independent transfers to fresh accounts
+			for j := range keys {
+				transfer(gen, j, common.BigToAddress(big.NewInt(int64(1000+j))), nil)
+			}
+		case 1:
+			// Chained transfers between the same accounts, and repeated senders
+			for j := 0; j < len(keys)-1; j++ {
+				transfer(gen, j, addrs[j+1], nil)
+			}
+			transfer(gen, 0, addrs[0], nil)
+			transfer(gen, 0, addrs[len(addrs)-1], nil)
+		case 2:
+			// Storage conflicts, logs and a self destruct among independent transfers
+			for j := range keys {
+				if j%3 == 0 {
+					transfer(gen, j, counter, nil)
+				} else {
+					transfer(gen, j, common.BigToAddress(big.NewInt(int64(2000+j))), nil)
+				}
+			}
+			transfer(gen, 1, bomb, nil)
+			transfer(gen, 2, counter, nil)
+		}
+	})
+
+	checkParallelExecution(t, db, config, genesis, blocks)
+}
+
+// Tests that fee currency transactions are executed in parallel with the same
+// results as sequentially, whether the fees are credited to the recipients by
+// every transaction or batched per block.
+func TestParallelFeeCurrencyTxExecution(t *testing.T) {
+	t.Run("immediate", func(t *testing.T) { testParallelFeeCurrencyTxExecution(t, false) })
+	t.Run("batched", func(t *testing.T) { testParallelFeeCurrencyTxExecution(t, true) })
+}
+
+func testParallelFeeCurrencyTxExecution(t *testing.T, batched bool) {
+	config := *params.TestChainConfig
+	if batched {
+		config.BatchedFeesBlock = big.NewInt(0)
+	}
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		signer  = types.NewEIP155Signer(config.ChainID)
+		funds   = new(big.Int).Mul(big.NewInt(1000), big.NewInt(params.Ether))
+		keys    = make([]*ecdsa.PrivateKey, 8)
+		token   = common.HexToAddress("0xfee")
+		gateway = common.HexToAddress("0x9a7e")
+		storage = make(map[common.Hash]common.Hash)
+		alloc   = GenesisAlloc{}
+	)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addr := crypto.PubkeyToAddress(keys[i].PublicKey)
+		alloc[addr] = GenesisAccount{Balance: funds}
+		storage[common.BytesToHash(addr.Bytes())] = common.BigToHash(funds)
+	}
+	alloc[token] = GenesisAccount{Code: compileCode(t, feeCurrencyBenchCode), Storage: storage, Balance: big.NewInt(0)}
+	genesis := (&Genesis{Config: &config, Alloc: alloc}).MustCommit(db)
+
+	// The fee currency calls need a chain to create their EVMs
+	chain, err := NewBlockChain(db, nil, &config, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	contract_comm.SetInternalEVMHandler(chain)
+	chain.Stop()
+
+	blocks, receipts := GenerateChain(&config, genesis, ethash.NewFaker(), db, 2, func(i int, gen *BlockGen) {
+		gen.SetCoinbase(common.Address{0xc0})
+		for j, key := range keys {
+			from := crypto.PubkeyToAddress(key.PublicKey)
+			to := common.BigToAddress(big.NewInt(int64(1000 + j)))
+			if i == 1 && j > 0 {
+				// Transfers to the previous sender, paying fees in the native token
+				to = crypto.PubkeyToAddress(keys[j-1].PublicKey)
+			}
+			feeCurrency := &token
+			if i == 1 && j%2 == 1 {
+				feeCurrency = nil
+			}
+			tx, err := types.SignTx(types.NewTransaction(gen.TxNonce(from), to, big.NewInt(1), 200000, big.NewInt(1), feeCurrency, &gateway, big.NewInt(1), nil), signer, key)
+			if err != nil {
+				t.Fatalf("failed to sign tx: %v", err)
+			}
+			gen.AddTx(tx)
+		}
+	})
+	for i := range receipts {
+		for j, receipt := range receipts[i] {
+			if receipt.Status != types.ReceiptStatusSuccessful {
+				t.Fatalf("block %d: tx %d failed", i+1, j)
+			}
+		}
+	}
+	// The fees were paid in the fee currency
+	statedb, _ := state.New(blocks[0].Root(), state.NewDatabase(db))
+	sender := crypto.PubkeyToAddress(keys[0].PublicKey)
+	if balance := statedb.GetState(token, common.BytesToHash(sender.Bytes())).Big(); balance.Cmp(funds) >= 0 {
+		t.Fatalf("fee currency balance not debited: have %v", balance)
+	}
+	checkParallelExecution(t, db, &config, genesis, blocks)
+}
+
+// checkParallelExecution processes the blocks both sequentially and in parallel,
+// checking that the states, receipts, logs and fee credits are the same.
+func checkParallelExecution(t *testing.T, db ethdb.Database, config *params.ChainConfig, genesis *types.Block, blocks types.Blocks) {
+	chain, err := NewBlockChain(db, nil, config, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	parent := genesis
+	for _, block := range blocks {
+		sequential, _ := state.New(parent.Root(), state.NewDatabase(db))
+		seqReceipts, seqLogs, seqGas, err := chain.Processor().Process(block, sequential, vm.Config{})
+		if err != nil {
+			t.Fatalf("block %d: sequential processing failed: %v", block.NumberU64(), err)
+		}
+		parallel, _ := state.New(parent.Root(), state.NewDatabase(db))
+		parReceipts, parLogs, parGas, err := chain.Processor().Process(block, parallel, vm.Config{ParallelTxExecution: true})
+		if err != nil {
+			t.Fatalf("block %d: parallel processing failed: %v", block.NumberU64(), err)
+		}
+		if root := sequential.IntermediateRoot(true); root != block.Root() {
+			t.Fatalf("block %d: sequential state root mismatch: have %x, want %x", block.NumberU64(), root, block.Root())
+		}
+		if root := parallel.IntermediateRoot(true); root != block.Root() {
+			t.Errorf("block %d: parallel state root mismatch: have %x, want %x", block.NumberU64(), root, block.Root())
+		}
+		if seqGas != parGas {
+			t.Errorf("block %d: gas used mismatch: have %d, want %d", block.NumberU64(), parGas, seqGas)
+		}
+		if have, want := types.DeriveSha(parReceipts), types.DeriveSha(seqReceipts); have != want {
+			t.Errorf("block %d: receipt root mismatch: have %x, want %x", block.NumberU64(), have, want)
+		}
+		if len(parLogs) != len(seqLogs) {
+			t.Fatalf("block %d: log count mismatch: have %d, want %d", block.NumberU64(), len(parLogs), len(seqLogs))
+		}
+		for i := range seqLogs {
+			if have, want := parLogs[i], seqLogs[i]; have.Index != want.Index || have.TxIndex != want.TxIndex || have.TxHash != want.TxHash {
+				t.Errorf("block %d: log %d mismatch: have %+v, want %+v", block.NumberU64(), i, have, want)
+			}
+		}
+		for _, feeCurrency := range sequential.FeeCreditCurrencies() {
+			seqRecipients, seqAmounts := sequential.FeeCredits(feeCurrency)
+			parRecipients, parAmounts := parallel.FeeCredits(feeCurrency)
+			if !reflect.DeepEqual(seqRecipients, parRecipients) || !reflect.DeepEqual(seqAmounts, parAmounts) {
+				t.Errorf("block %d: fee credits mismatch: have %v %v, want %v %v", block.NumberU64(), parRecipients, parAmounts, seqRecipients, seqAmounts)
+			}
+		}
+		if have, want := len(parallel.FeeCreditCurrencies()), len(sequential.FeeCreditCurrencies()); have != want {
+			t.Errorf("block %d: fee credit currencies mismatch: have %d, want %d", block.NumberU64(), have, want)
+		}
+		if _, err := chain.InsertChain(types.Blocks{block}); err != nil {
+			t.Fatalf("block %d: failed to insert: %v", block.NumberU64(), err)
+		}
+		parent = block
+	}
+}
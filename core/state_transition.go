@@ -194,7 +194,16 @@ func (st *StateTransition) useGas(amount uint64) error {
 	return nil
 }
 
-// payFees deducts gas and gateway fees from sender balance and adds the purchased amount of gas to the state.
+// feePayer returns the account paying the fees of the message: the fee payer
+// of a sponsored transaction, or the sender otherwise.
+func (st *StateTransition) feePayer() common.Address {
+	if payer := st.msg.FeePayer(); payer != nil {
+		return *payer
+	}
+	return st.msg.From()
+}
+
+// payFees deducts gas and gateway fees from the fee payer balance and adds the purchased amount of gas to the state.
 func (st *StateTransition) payFees() error {
 	feeVal := new(big.Int).Mul(new(big.Int).SetUint64(st.msg.Gas()), st.gasPrice)
 
@@ -208,7 +217,7 @@ func (st *StateTransition) payFees() error {
 		return errNonWhitelistedFeeCurrency
 	}
 
-	if !st.canPayFee(st.msg.From(), feeVal, st.msg.FeeCurrency()) {
+	if !st.canPayFee(st.feePayer(), feeVal, st.msg.FeeCurrency()) {
 		return errInsufficientBalanceForFees
 	}
 	if err := st.gp.SubGas(st.msg.Gas()); err != nil {
@@ -217,7 +226,7 @@ func (st *StateTransition) payFees() error {
 
 	st.initialGas = st.msg.Gas()
 	st.gas += st.msg.Gas()
-	err := st.debitFee(st.msg.From(), feeVal, st.msg.FeeCurrency())
+	err := st.debitFee(st.feePayer(), feeVal, st.msg.FeeCurrency())
 	return err
 }
 
@@ -296,6 +305,18 @@ func (st *StateTransition) creditFee(to common.Address, amount *big.Int, feeCurr
 	}
 }
 
+// creditFeeRecipient credits a fee to one of its recipients. Once the batched
+// fees fork is active, fee currency credits are accumulated in the state and
+// settled with the currency contract when the block is finalized.
+func (st *StateTransition) creditFeeRecipient(to common.Address, amount *big.Int, feeCurrency *common.Address) error {
+	if feeCurrency != nil && st.evm.ChainConfig().IsBatchedFees(st.evm.BlockNumber) {
+		log.Trace("Accumulating fee credit", "recipient", to, "amount", amount, "feeCurrency", *feeCurrency)
+		st.state.AddFeeCredit(*feeCurrency, to, amount)
+		return nil
+	}
+	return st.creditFee(to, amount, feeCurrency)
+}
+
 func (st *StateTransition) preCheck() error {
 	// Make sure this transaction's nonce is correct.
 	if st.msg.CheckNonce() {
@@ -307,7 +328,12 @@ func (st *StateTransition) preCheck() error {
 		}
 	}
 
-	// Make sure this transaction's gas price is valid.
+	// Make sure this transaction's gas price is valid. The minimum is unknown if
+	// the base fee can't be converted into the fee currency.
+	if st.gasPriceMinimum == nil {
+		log.Error("Tx gas price minimum is unknown", "feeCurrency", st.msg.FeeCurrency())
+		return ErrGasPriceDoesNotExceedMinimum
+	}
 	if st.gasPrice.Cmp(st.gasPriceMinimum) < 0 {
 		log.Error("Tx gas price is less than minimum", "minimum", st.gasPriceMinimum, "price", st.gasPrice)
 		return ErrGasPriceDoesNotExceedMinimum
@@ -403,14 +429,14 @@ func (st *StateTransition) distributeTxFees() error {
 	// Pay gateway fee to the specified recipient.
 	if st.msg.GatewayFeeRecipient() != nil {
 		log.Trace("Crediting gateway fee", "recipient", *st.msg.GatewayFeeRecipient(), "amount", st.msg.GatewayFee(), "feeCurrency", st.msg.FeeCurrency())
-		if err := st.creditFee(*st.msg.GatewayFeeRecipient(), st.msg.GatewayFee(), st.msg.FeeCurrency()); err != nil {
+		if err := st.creditFeeRecipient(*st.msg.GatewayFeeRecipient(), st.msg.GatewayFee(), st.msg.FeeCurrency()); err != nil {
 			log.Error("Failed to credit gateway fee", "err", err)
 			return err
 		}
 	}
 
 	log.Trace("Crediting gas fee tip", "recipient", st.evm.Coinbase, "amount", tipTxFee, "feeCurrency", st.msg.FeeCurrency())
-	if err := st.creditFee(st.evm.Coinbase, tipTxFee, st.msg.FeeCurrency()); err != nil {
+	if err := st.creditFeeRecipient(st.evm.Coinbase, tipTxFee, st.msg.FeeCurrency()); err != nil {
 		return err
 	}
 
@@ -420,17 +446,17 @@ func (st *StateTransition) distributeTxFees() error {
 		if err != commerrs.ErrSmartContractNotDeployed && err != commerrs.ErrRegistryContractNotDeployed {
 			return err
 		}
-		log.Trace("Cannot credit gas fee to community fund: refunding fee to sender", "error", err, "fee", baseTxFee)
+		log.Trace("Cannot credit gas fee to community fund: refunding fee to fee payer", "error", err, "fee", baseTxFee)
 		refund.Add(refund, baseTxFee)
 	} else {
 		log.Trace("Crediting gas fee tip", "recipient", *governanceAddress, "amount", baseTxFee, "feeCurrency", st.msg.FeeCurrency())
-		if err = st.creditFee(*governanceAddress, baseTxFee, st.msg.FeeCurrency()); err != nil {
+		if err = st.creditFeeRecipient(*governanceAddress, baseTxFee, st.msg.FeeCurrency()); err != nil {
 			return err
 		}
 	}
 
-	log.Trace("Crediting refund", "recipient", st.msg.From(), "amount", refund, "feeCurrency", st.msg.FeeCurrency())
-	err = st.creditFee(st.msg.From(), refund, st.msg.FeeCurrency())
+	log.Trace("Crediting refund", "recipient", st.feePayer(), "amount", refund, "feeCurrency", st.msg.FeeCurrency())
+	err = st.creditFee(st.feePayer(), refund, st.msg.FeeCurrency())
 	if err != nil {
 		log.Error("Failed to refund gas", "err", err)
 		return err
@@ -0,0 +1,69 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that batching the fee credits of a block leaves the same fee currency
+// balances as crediting them in every transaction, including when the fee
+// currency doesn't implement creditToMany and the credits are made one by one.
+func TestFeeCurrencyBatchedCredits(t *testing.T) {
+	var (
+		senders  = ringAddrs[:10]
+		accounts = append([]common.Address{feeCurrencyCoinbase, feeCurrencyGateway}, senders...)
+		code     = compileCode(t, feeCurrencyBenchCode)
+		// Dispatch creditToMany to an unknown selector, making the call revert
+		legacyCode = compileCode(t, strings.Replace(feeCurrencyBenchCode, "PUSH 0xca981c62", "PUSH 0xffffffff", 1))
+	)
+	balances := func(batched bool, code []byte) map[common.Address]common.Hash {
+		config := *params.TestChainConfig
+		if batched {
+			config.BatchedFeesBlock = big.NewInt(0)
+		}
+		chain, statedb, header := newFeeCurrencyState(t, &config, code, senders)
+		defer chain.Stop()
+
+		applyFeeCurrencyTxs(t, chain, statedb, header, senders)
+		if currencies := statedb.FeeCreditCurrencies(); len(currencies) != 0 {
+			t.Fatalf("fee credits left unsettled: %v", currencies)
+		}
+		result := make(map[common.Address]common.Hash)
+		for _, addr := range accounts {
+			result[addr] = statedb.GetState(feeCurrencyToken, common.BytesToHash(addr.Bytes()))
+		}
+		return result
+	}
+	want := balances(false, code)
+	if (want[feeCurrencyCoinbase] == common.Hash{}) || (want[feeCurrencyGateway] == common.Hash{}) {
+		t.Fatalf("fees not credited: coinbase %x, gateway %x", want[feeCurrencyCoinbase], want[feeCurrencyGateway])
+	}
+	for name, code := range map[string][]byte{"creditToMany": code, "creditTo": legacyCode} {
+		have := balances(true, code)
+		for _, addr := range accounts {
+			if have[addr] != want[addr] {
+				t.Errorf("%s: balance mismatch for %x: have %x, want %x", name, addr, have[addr], want[addr])
+			}
+		}
+	}
+}
@@ -298,12 +298,12 @@ func (l *txList) Filter(costLimit *big.Int, gasLimit uint64) (types.Transactions
 
 	// Filter out all the transactions above the account's funds
 	removed := l.txs.Filter(func(tx *types.Transaction) bool {
-		if tx.FeeCurrency() == nil {
+		if tx.FeeCurrency() == nil && tx.FeePayer() == nil {
 			log.Trace("Transaction Filter", "hash", tx.Hash(), "Fee currency", tx.FeeCurrency(), "Cost", tx.Cost(), "Cost Limit", costLimit, "Gas", tx.Gas(), "Gas Limit", gasLimit)
 			return tx.Cost().Cmp(costLimit) > 0 || tx.Gas() > gasLimit
 		} else {
-			// If the fees are being paid in the non-native currency, ensure that the `tx.Value` is less than costLimit
-			// as the fees will be deducted in the non-native currency.
+			// If the fees are being paid in the non-native currency or by a fee payer, ensure that the `tx.Value`
+			// is less than costLimit as the fees will not be deducted from the sender's native balance.
 			log.Trace("Transaction Filter", "hash", tx.Hash(), "Fee currency", tx.FeeCurrency(), "Value", tx.Value(), "Cost Limit", costLimit, "Gas", tx.Gas(), "Gas Limit", gasLimit)
 			return tx.Value().Cmp(costLimit) > 0 || tx.Gas() > gasLimit
 		}
@@ -17,6 +17,7 @@
 package core
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"math"
@@ -28,6 +29,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/prque"
 	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/misc"
 	"github.com/ethereum/go-ethereum/contract_comm/currency"
 	ccerrors "github.com/ethereum/go-ethereum/contract_comm/errors"
 	gpm "github.com/ethereum/go-ethereum/contract_comm/gasprice_minimum"
@@ -84,6 +86,14 @@ var (
 
 	// ErrNonWhitelistedFeeCurrency is returned if the txn fee currency is not white listed
 	ErrNonWhitelistedFeeCurrency = errors.New("non-whitelisted fee currency")
+
+	// ErrTxTypeNotSupported is returned if a typed transaction is received before
+	// the typed transaction fork is active.
+	ErrTxTypeNotSupported = types.ErrTxTypeNotSupported
+
+	// ErrInvalidFeePayer is returned if a sponsored transaction does not carry a
+	// valid signature of its fee payer.
+	ErrInvalidFeePayer = errors.New("invalid fee payer")
 )
 
 var (
@@ -133,6 +143,7 @@ type blockChain interface {
 	StateAt(root common.Hash) (*state.StateDB, error)
 
 	SubscribeChainHeadEvent(ch chan<- ChainHeadEvent) event.Subscription
+	SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription
 
 	// Engine retrieves the chain's consensus engine.
 	Engine() consensus.Engine
@@ -237,10 +248,20 @@ type TxPool struct {
 	mu          sync.RWMutex
 
 	istanbul bool // Fork indicator whether we are in the istanbul stage.
+	typedTx  bool // Fork indicator whether typed transactions are accepted.
+
+	currentState   *state.StateDB // Current state in the blockchain head
+	pendingNonces  *txNoncer      // Pending state tracking virtual nonces
+	currentMaxGas  uint64         // Current gas limit for transaction caps
+	pendingBaseFee *big.Int       // Base fee of the pending block, nil before the base fee fork
+
+	whitelist       map[common.Address]bool           // Fee currency whitelist at the current head, nil if unknown
+	whitelistStale  *big.Int                          // Number of the block changing the whitelist, nil if the whitelist is fresh
+	whitelistChange *FeeCurrencyWhitelistChangedEvent // Whitelist change to notify once the reorg is done
+	whitelistFeed   event.Feed
 
-	currentState  *state.StateDB // Current state in the blockchain head
-	pendingNonces *txNoncer      // Pending state tracking virtual nonces
-	currentMaxGas uint64         // Current gas limit for transaction caps
+	gasPriceMinimums     map[common.Address]*big.Int // Gas price minimum of each fee currency at the current head, the native one at the zero address
+	gasPriceMinimumStale *big.Int                    // Number of the block updating the gas price minimum, nil if the cached ones are fresh
 
 	locals  *accountSet // Set of local transaction to exempt from eviction rules
 	journal *txJournal  // Journal of local transaction to back up to disk
@@ -253,6 +274,8 @@ type TxPool struct {
 
 	chainHeadCh     chan ChainHeadEvent
 	chainHeadSub    event.Subscription
+	logsCh          chan []*types.Log
+	logsSub         event.Subscription
 	reqResetCh      chan *txpoolResetRequest
 	reqPromoteCh    chan *accountSet
 	queueTxEventCh  chan *types.Transaction
@@ -276,18 +299,22 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		config:          config,
 		chainconfig:     chainconfig,
 		chain:           chain,
-		signer:          types.NewEIP155Signer(chainconfig.ChainID),
+		signer:          types.NewCeloSigner(chainconfig.ChainID),
 		pending:         make(map[common.Address]*txList),
 		queue:           make(map[common.Address]*txList),
 		beats:           make(map[common.Address]time.Time),
 		all:             newTxLookup(),
 		chainHeadCh:     make(chan ChainHeadEvent, chainHeadChanSize),
+		logsCh:          make(chan []*types.Log, chainHeadChanSize),
 		reqResetCh:      make(chan *txpoolResetRequest),
 		reqPromoteCh:    make(chan *accountSet),
 		queueTxEventCh:  make(chan *types.Transaction),
 		reorgDoneCh:     make(chan chan struct{}),
 		reorgShutdownCh: make(chan struct{}),
 		gasPrice:        new(big.Int).SetUint64(config.PriceLimit),
+		whitelistStale:  new(big.Int),
+
+		gasPriceMinimums: make(map[common.Address]*big.Int),
 	}
 	pool.locals = newAccountSet(pool.signer)
 	for _, addr := range config.Locals {
@@ -316,6 +343,7 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 
 	// Subscribe events from blockchain and start the main event loop.
 	pool.chainHeadSub = pool.chain.SubscribeChainHeadEvent(pool.chainHeadCh)
+	pool.logsSub = pool.chain.SubscribeLogsEvent(pool.logsCh)
 	pool.wg.Add(1)
 	go pool.loop()
 
@@ -350,6 +378,29 @@ func (pool *TxPool) loop() {
 				head = ev.Block
 			}
 
+		// Handle fee currency whitelist and gas price minimum changes
+		case logs := <-pool.logsCh:
+			var included bool
+			pool.mu.Lock()
+			for _, l := range logs {
+				number := new(big.Int).SetUint64(l.BlockNumber)
+				switch {
+				case currency.IsWhitelistChange(l):
+					pool.whitelistStale = laterBlock(pool.whitelistStale, number)
+				case gpm.IsGasPriceMinimumUpdate(l):
+					pool.gasPriceMinimumStale = laterBlock(pool.gasPriceMinimumStale, number)
+				default:
+					continue
+				}
+				included = included || head.Number().Cmp(number) >= 0
+			}
+			pool.mu.Unlock()
+
+			// Refresh at once if the head already includes a change
+			if included {
+				pool.requestReset(head.Header(), head.Header())
+			}
+
 		// System shutdown.
 		case <-pool.chainHeadSub.Err():
 			close(pool.reorgShutdownCh)
@@ -404,6 +455,7 @@ func (pool *TxPool) Stop() {
 
 	// Unsubscribe subscriptions registered from blockchain
 	pool.chainHeadSub.Unsubscribe()
+	pool.logsSub.Unsubscribe()
 	pool.wg.Wait()
 
 	if pool.journal != nil {
@@ -418,6 +470,12 @@ func (pool *TxPool) SubscribeNewTxsEvent(ch chan<- NewTxsEvent) event.Subscripti
 	return pool.scope.Track(pool.txFeed.Subscribe(ch))
 }
 
+// SubscribeFeeCurrencyWhitelistChangedEvent registers a subscription of
+// FeeCurrencyWhitelistChangedEvent and starts sending event to the given channel.
+func (pool *TxPool) SubscribeFeeCurrencyWhitelistChangedEvent(ch chan<- FeeCurrencyWhitelistChangedEvent) event.Subscription {
+	return pool.scope.Track(pool.whitelistFeed.Subscribe(ch))
+}
+
 // GasPrice returns the current gas price enforced by the transaction pool.
 func (pool *TxPool) GasPrice() *big.Int {
 	pool.mu.RLock()
@@ -439,6 +497,33 @@ func (pool *TxPool) SetGasPrice(price *big.Int) {
 	log.Info("Transaction pool price threshold updated", "price", price)
 }
 
+// SetLimits updates the price bump, slot limits and queue lifetime of the
+// transaction pool, and drops the transactions exceeding the new limits.
+func (pool *TxPool) SetLimits(config TxPoolConfig) {
+	config = (&config).sanitize()
+
+	pool.mu.Lock()
+	pool.config.PriceBump = config.PriceBump
+	pool.config.AccountSlots = config.AccountSlots
+	pool.config.GlobalSlots = config.GlobalSlots
+	pool.config.AccountQueue = config.AccountQueue
+	pool.config.GlobalQueue = config.GlobalQueue
+	pool.config.Lifetime = config.Lifetime
+
+	queued := make([]common.Address, 0, len(pool.queue))
+	for addr := range pool.queue {
+		queued = append(queued, addr)
+	}
+	pool.mu.Unlock()
+
+	// Enforce the new limits with a promotion run over all queued accounts
+	<-pool.requestPromoteExecutables(newAccountSet(pool.signer, queued...))
+
+	log.Info("Transaction pool limits updated", "pricebump", config.PriceBump,
+		"accountslots", config.AccountSlots, "globalslots", config.GlobalSlots,
+		"accountqueue", config.AccountQueue, "globalqueue", config.GlobalQueue, "lifetime", config.Lifetime)
+}
+
 // Nonce returns the next nonce of an account, with all transactions executable
 // by the pool already applied on top.
 func (pool *TxPool) Nonce(addr common.Address) uint64 {
@@ -529,6 +614,10 @@ func (pool *TxPool) local() map[common.Address]types.Transactions {
 // validateTx checks whether a transaction is valid according to the consensus
 // rules and adheres to some heuristic limits of the local node (price and size).
 func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
+	// Reject typed transactions until the fork activating them
+	if tx.Type() != types.LegacyTxType && !pool.typedTx {
+		return ErrTxTypeNotSupported
+	}
 	// Heuristic limit, reject transactions over MaxCodeSize to prevent DOS attacks
 	if tx.Size() > params.MaxCodeSize {
 		return ErrOversizedData
@@ -548,9 +637,15 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if err != nil {
 		return ErrInvalidSender
 	}
+	// Sponsored transactions must also be signed by their fee payer
+	if tx.Type() == types.SponsoredTxType {
+		if _, err := types.FeePayer(pool.signer, tx); err != nil {
+			return ErrInvalidFeePayer
+		}
+	}
 
 	// Ensure the fee currency is native or whitelisted.
-	if tx.FeeCurrency() != nil && !currency.IsWhitelisted(*tx.FeeCurrency(), nil, nil) {
+	if tx.FeeCurrency() != nil && !pool.isWhitelisted(*tx.FeeCurrency()) {
 		return ErrNonWhitelistedFeeCurrency
 	}
 
@@ -578,10 +673,19 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 		return ErrIntrinsicGas
 	}
 
-	gasPriceMinimum, err := gpm.GetGasPriceMinimum(tx.FeeCurrency(), nil, nil)
-	if err != nil && err != ccerrors.ErrSmartContractNotDeployed && err != ccerrors.ErrRegistryContractNotDeployed {
-		log.Debug("unable to fetch gas price minimum", "err", err)
-		return err
+	var gasPriceMinimum *big.Int
+	if pool.pendingBaseFee != nil {
+		gasPriceMinimum, err = gpm.GetBaseFeeInCurrency(tx.FeeCurrency(), pool.pendingBaseFee, nil, nil)
+		if err != nil {
+			log.Debug("unable to convert base fee", "feeCurrency", tx.FeeCurrency(), "err", err)
+			return err
+		}
+	} else {
+		gasPriceMinimum, err = pool.gasPriceMinimum(tx.FeeCurrency())
+		if err != nil {
+			log.Debug("unable to fetch gas price minimum", "err", err)
+			return err
+		}
 	}
 
 	if tx.GasPrice().Cmp(gasPriceMinimum) == -1 {
@@ -1045,7 +1149,10 @@ func (pool *TxPool) scheduleReorgLoop() {
 func (pool *TxPool) runReorg(done chan struct{}, reset *txpoolResetRequest, dirtyAccounts *accountSet, events map[common.Address]*txSortedMap) {
 	defer close(done)
 
-	var promoteAddrs []common.Address
+	var (
+		promoteAddrs    []common.Address
+		whitelistChange *FeeCurrencyWhitelistChangedEvent
+	)
 	if dirtyAccounts != nil {
 		promoteAddrs = dirtyAccounts.flatten()
 	}
@@ -1053,6 +1160,7 @@ func (pool *TxPool) runReorg(done chan struct{}, reset *txpoolResetRequest, dirt
 	if reset != nil {
 		// Reset from the old head to the new, rescheduling any reorged transactions
 		pool.reset(reset.oldHead, reset.newHead)
+		whitelistChange, pool.whitelistChange = pool.whitelistChange, nil
 
 		// Nonces were reset, discard any events that became stale
 		for addr := range events {
@@ -1101,6 +1209,9 @@ func (pool *TxPool) runReorg(done chan struct{}, reset *txpoolResetRequest, dirt
 		}
 		pool.txFeed.Send(NewTxsEvent{txs})
 	}
+	if whitelistChange != nil {
+		pool.whitelistFeed.Send(*whitelistChange)
+	}
 }
 
 // reset retrieves the current state of the blockchain and ensures the content
@@ -1181,6 +1292,26 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	pool.pendingNonces = newTxNoncer(statedb)
 	pool.currentMaxGas = newHead.GasLimit
 
+	// Refresh the fee currency whitelist on reorgs and once the block changing it
+	// is the head, before reinjecting transactions
+	reorged := oldHead != nil && oldHead.Hash() != newHead.Hash() && oldHead.Hash() != newHead.ParentHash
+	if reorged || (pool.whitelistStale != nil && newHead.Number.Cmp(pool.whitelistStale) >= 0) {
+		pool.refreshWhitelist(newHead, statedb)
+	}
+	// Likewise drop the cached gas price minimums. Before the base fee fork, they
+	// are also updated by the finalization of every block, whose logs aren't in
+	// the receipts.
+	advanced := oldHead == nil || oldHead.Hash() != newHead.Hash()
+	if reorged || (advanced && !pool.chainconfig.IsBaseFee(newHead.Number)) || (pool.gasPriceMinimumStale != nil && newHead.Number.Cmp(pool.gasPriceMinimumStale) >= 0) {
+		pool.gasPriceMinimums = make(map[common.Address]*big.Int)
+		pool.gasPriceMinimumStale = nil
+	}
+	if pool.chainconfig.IsBaseFee(new(big.Int).Add(newHead.Number, big.NewInt(1))) {
+		pool.pendingBaseFee = misc.CalcBaseFee(pool.chainconfig, newHead)
+	} else {
+		pool.pendingBaseFee = nil
+	}
+
 	// Inject any transactions discarded due to reorgs
 	log.Debug("Reinjecting stale transactions", "count", len(reinject))
 	senderCacher.recover(pool.signer, reinject)
@@ -1189,6 +1320,102 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	// Update all fork indicator by next pending block number.
 	next := new(big.Int).Add(newHead.Number, big.NewInt(1))
 	pool.istanbul = pool.chainconfig.IsIstanbul(next)
+	pool.typedTx = pool.chainconfig.IsTypedTx(next)
+}
+
+// gasPriceMinimum returns the gas price minimum of a fee currency at the current
+// head, retrieving it if it isn't cached.
+func (pool *TxPool) gasPriceMinimum(feeCurrency *common.Address) (*big.Int, error) {
+	var key common.Address
+	if feeCurrency != nil {
+		key = *feeCurrency
+	}
+	if gasPriceMinimum, ok := pool.gasPriceMinimums[key]; ok {
+		return gasPriceMinimum, nil
+	}
+	gasPriceMinimum, err := gpm.GetGasPriceMinimum(feeCurrency, nil, nil)
+	if err != nil && err != ccerrors.ErrSmartContractNotDeployed && err != ccerrors.ErrRegistryContractNotDeployed {
+		return nil, err
+	}
+	pool.gasPriceMinimums[key] = gasPriceMinimum
+	return gasPriceMinimum, nil
+}
+
+// laterBlock returns the later of two block numbers, the first of which may be nil.
+func laterBlock(number, other *big.Int) *big.Int {
+	if number == nil || number.Cmp(other) < 0 {
+		return other
+	}
+	return number
+}
+
+// isWhitelisted reports whether a currency can pay for fees at the current head.
+// The whitelist is queried if it isn't known.
+func (pool *TxPool) isWhitelisted(feeCurrency common.Address) bool {
+	if pool.whitelist == nil {
+		return currency.IsWhitelisted(feeCurrency, nil, nil)
+	}
+	return pool.whitelist[feeCurrency]
+}
+
+// refreshWhitelist retrieves the fee currency whitelist at the new head. If it
+// can't be retrieved, it's queried for every transaction instead.
+func (pool *TxPool) refreshWhitelist(head *types.Header, statedb *state.StateDB) {
+	pool.whitelistStale = nil
+
+	whitelist, err := currency.CurrencyWhitelist(head, statedb.Copy())
+	if err != nil {
+		pool.whitelist = nil
+		return
+	}
+	pool.setWhitelist(head, whitelist)
+}
+
+// setWhitelist updates the fee currency whitelist. If it changed, the transactions
+// paying fees in the currencies removed are dropped and the change is notified.
+func (pool *TxPool) setWhitelist(head *types.Header, whitelist []common.Address) {
+	old := pool.whitelist
+	pool.whitelist = make(map[common.Address]bool, len(whitelist))
+	for _, feeCurrency := range whitelist {
+		pool.whitelist[feeCurrency] = true
+	}
+	if old == nil {
+		return
+	}
+	change := &FeeCurrencyWhitelistChangedEvent{Header: head, Whitelist: whitelist}
+	for _, feeCurrency := range whitelist {
+		if !old[feeCurrency] {
+			change.Added = append(change.Added, feeCurrency)
+		}
+	}
+	for feeCurrency := range old {
+		if !pool.whitelist[feeCurrency] {
+			change.Removed = append(change.Removed, feeCurrency)
+		}
+	}
+	if len(change.Added) == 0 && len(change.Removed) == 0 {
+		return
+	}
+	sort.Slice(change.Removed, func(i, j int) bool {
+		return bytes.Compare(change.Removed[i][:], change.Removed[j][:]) < 0
+	})
+	log.Info("Fee currency whitelist changed", "number", head.Number, "added", change.Added, "removed", change.Removed)
+
+	// Transactions in the removed currencies can't be executed anymore
+	if len(change.Removed) > 0 {
+		var drops []common.Hash
+		pool.all.Range(func(hash common.Hash, tx *types.Transaction) bool {
+			if tx.FeeCurrency() != nil && old[*tx.FeeCurrency()] && !pool.whitelist[*tx.FeeCurrency()] {
+				drops = append(drops, hash)
+			}
+			return true
+		})
+		for _, hash := range drops {
+			pool.removeTx(hash, true)
+		}
+		log.Debug("Dropped transactions in removed fee currencies", "count", len(drops))
+	}
+	pool.whitelistChange = change
 }
 
 // promoteExecutables moves transactions that have become processable from the
@@ -1198,6 +1425,10 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 	// Track the promoted transactions to broadcast them at once
 	var promoted []*types.Transaction
 
+	// Track the funds committed by pending transactions, as a fee payer may
+	// sponsor the transactions of many senders. Only gathered when needed.
+	var committed feeCommitments
+
 	// Iterate over all accounts and promote any executable transactions
 	for _, addr := range accounts {
 		list := pool.queue[addr]
@@ -1222,11 +1453,29 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 
 		// Gather all executable transactions and promote them
 		readies := list.Ready(pool.pendingNonces.get(addr))
-		for _, tx := range readies {
+		for i, tx := range readies {
+			if tx.FeePayer() != nil && *tx.FeePayer() != addr {
+				if committed == nil {
+					committed = pool.pendingFeeCommitments()
+				}
+				// Keep the rest of the account's transactions queued until the
+				// fee payer can cover them along with its pending commitments
+				if !pool.feePayerCovers(committed, tx) {
+					log.Trace("Fee payer can't cover queued transaction", "hash", tx.Hash(), "feePayer", *tx.FeePayer())
+					for _, tx := range readies[i:] {
+						list.Add(tx, pool.config.PriceBump)
+					}
+					readies = readies[:i]
+					break
+				}
+			}
 			hash := tx.Hash()
 			if pool.promoteTx(addr, hash, tx) {
 				log.Trace("Promoting queued transaction", "hash", hash)
 				promoted = append(promoted, tx)
+				if committed != nil {
+					committed.add(addr, tx)
+				}
 			}
 		}
 		queuedGauge.Dec(int64(len(readies)))
@@ -1256,6 +1505,73 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 	return promoted
 }
 
+// feeBalance identifies the balance of an account in a fee currency, the zero
+// address being the native currency.
+type feeBalance struct {
+	account     common.Address
+	feeCurrency common.Address
+}
+
+// feeCommitments tracks the funds of every account committed by transactions.
+type feeCommitments map[feeBalance]*big.Int
+
+// add commits the value of tx from its sender and its fee from its fee payer.
+func (c feeCommitments) add(from common.Address, tx *types.Transaction) {
+	payer := from
+	if tx.FeePayer() != nil {
+		payer = *tx.FeePayer()
+	}
+	c.commit(feeBalance{account: from}, tx.Value())
+	c.commit(feeBalanceOf(payer, tx), tx.Fee())
+}
+
+func (c feeCommitments) commit(key feeBalance, amount *big.Int) {
+	if c[key] == nil {
+		c[key] = new(big.Int)
+	}
+	c[key].Add(c[key], amount)
+}
+
+// feeBalanceOf returns the balance account pays the fee of tx from.
+func feeBalanceOf(account common.Address, tx *types.Transaction) feeBalance {
+	key := feeBalance{account: account}
+	if tx.FeeCurrency() != nil {
+		key.feeCurrency = *tx.FeeCurrency()
+	}
+	return key
+}
+
+// pendingFeeCommitments gathers the funds committed by all pending transactions.
+func (pool *TxPool) pendingFeeCommitments() feeCommitments {
+	committed := make(feeCommitments)
+	for addr, list := range pool.pending {
+		for _, tx := range list.txs.items {
+			committed.add(addr, tx)
+		}
+	}
+	return committed
+}
+
+// feePayerCovers reports whether the fee payer of the sponsored tx can pay its
+// fee on top of the funds already committed by pending transactions.
+func (pool *TxPool) feePayerCovers(committed feeCommitments, tx *types.Transaction) bool {
+	payer := *tx.FeePayer()
+
+	balance := pool.currentState.GetBalance(payer)
+	if tx.FeeCurrency() != nil {
+		var err error
+		if balance, _, err = currency.GetBalanceOf(payer, *tx.FeeCurrency(), params.MaxGasToReadErc20Balance, nil, nil); err != nil {
+			log.Debug("Failed to get fee payer balance", "feePayer", payer, "feeCurrency", tx.FeeCurrency(), "err", err)
+			return false
+		}
+	}
+	cost := new(big.Int).Set(tx.Fee())
+	if amount := committed[feeBalanceOf(payer, tx)]; amount != nil {
+		cost.Add(cost, amount)
+	}
+	return balance.Cmp(cost) >= 0
+}
+
 // truncatePending removes transactions from the pending queue if the pool is above the
 // pending limit. The algorithm tries to reduce transaction counts by an approximately
 // equal number for all for accounts with many pending transactions.
@@ -1441,24 +1757,38 @@ func (pool *TxPool) demoteUnexecutables() {
 }
 
 // ValidateTransactorBalanceCoversTx validates transactor has enough funds to cover transaction cost: V + GP * GL.
+// For sponsored transactions the sender only needs to cover V, while the fee payer covers GP * GL.
 func ValidateTransactorBalanceCoversTx(tx *types.Transaction, from common.Address, currentState *state.StateDB) error {
-	if tx.FeeCurrency() == nil && currentState.GetBalance(from).Cmp(tx.Cost()) < 0 {
+	payer := from
+	if tx.FeePayer() != nil {
+		payer = *tx.FeePayer()
+	}
+	if tx.FeeCurrency() == nil && payer == from && currentState.GetBalance(from).Cmp(tx.Cost()) < 0 {
 		log.Debug("Insufficient funds",
 			"from", from, "Transaction cost", tx.Cost(), "to", tx.To(),
 			"gas", tx.Gas(), "gas price", tx.GasPrice(), "nonce", tx.Nonce(),
 			"value", tx.Value(), "fee currency", tx.FeeCurrency(), "balance", currentState.GetBalance(from))
 		return ErrInsufficientFunds
+	} else if tx.FeeCurrency() == nil && payer != from {
+		if currentState.GetBalance(payer).Cmp(tx.Fee()) < 0 {
+			log.Debug("validateTx insufficient fee payer funds", "feePayer", payer, "fee", tx.Fee(), "balance", currentState.GetBalance(payer))
+			return ErrInsufficientFunds
+		}
+
+		if currentState.GetBalance(from).Cmp(tx.Value()) < 0 {
+			log.Debug("validateTx insufficient funds", "balance", currentState.GetBalance(from).String())
+			return ErrInsufficientFunds
+		}
 	} else if tx.FeeCurrency() != nil {
-		feeCurrencyBalance, _, err := currency.GetBalanceOf(from, *tx.FeeCurrency(), params.MaxGasToReadErc20Balance, nil, nil)
+		feeCurrencyBalance, _, err := currency.GetBalanceOf(payer, *tx.FeeCurrency(), params.MaxGasToReadErc20Balance, nil, nil)
 
 		if err != nil {
 			log.Debug("validateTx error in getting fee currency balance", "feeCurrency", tx.FeeCurrency(), "error", err)
 			return err
 		}
 
-		gasFee := new(big.Int).Mul(tx.GasPrice(), big.NewInt(int64(tx.Gas())))
-		if feeCurrencyBalance.Cmp(new(big.Int).Add(gasFee, tx.GatewayFee())) < 0 {
-			log.Debug("validateTx insufficient fee currency", "feeCurrency", tx.FeeCurrency(), "feeCurrencyBalance", feeCurrencyBalance)
+		if feeCurrencyBalance.Cmp(tx.Fee()) < 0 {
+			log.Debug("validateTx insufficient fee currency", "feeCurrency", tx.FeeCurrency(), "feePayer", payer, "feeCurrencyBalance", feeCurrencyBalance)
 			return ErrInsufficientFunds
 		}
 
@@ -29,6 +29,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/contract_comm"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -71,6 +72,13 @@ func (bc *testBlockChain) SubscribeChainHeadEvent(ch chan<- ChainHeadEvent) even
 	return bc.chainHeadFeed.Subscribe(ch)
 }
 
+func (bc *testBlockChain) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
 func (bc *testBlockChain) Engine() consensus.Engine {
 	return ethash.NewFaker()
 }
@@ -291,6 +299,115 @@ func TestInvalidTransactions(t *testing.T) {
 	}
 }
 
+// Tests that typed transactions are only accepted once their fork is active.
+func TestTypedTransactionFork(t *testing.T) {
+	t.Parallel()
+
+	config := *params.TestChainConfig
+	config.TypedTxBlock = big.NewInt(0)
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	legacyPool := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain)
+	defer legacyPool.Stop()
+	typedPool := NewTxPool(testTxPoolConfig, &config, blockchain)
+	defer typedPool.Stop()
+
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	statedb.AddBalance(from, big.NewInt(1000000))
+
+	tx, _ := types.SignTx(types.NewCeloTransaction(config.ChainID, 0, &common.Address{}, big.NewInt(100), 100000, big.NewInt(1), nil, nil, nil, nil), types.NewCeloSigner(config.ChainID), key)
+	if err := legacyPool.AddRemote(tx); err != ErrTxTypeNotSupported {
+		t.Errorf("expected %v before the fork, got %v", ErrTxTypeNotSupported, err)
+	}
+	if err := typedPool.AddRemote(tx); err != nil {
+		t.Errorf("expected typed transaction to be accepted after the fork, got %v", err)
+	}
+}
+
+// Tests that sponsored transactions need a valid fee payer signature, and that
+// the fees are checked against the fee payer balance instead of the sender's.
+func TestSponsoredTransactionValidation(t *testing.T) {
+	t.Parallel()
+
+	config := *params.TestChainConfig
+	config.TypedTxBlock = big.NewInt(0)
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	pool := NewTxPool(testTxPoolConfig, &config, blockchain)
+	defer pool.Stop()
+
+	senderKey, _ := crypto.GenerateKey()
+	payerKey, _ := crypto.GenerateKey()
+	sender := crypto.PubkeyToAddress(senderKey.PublicKey)
+	payer := crypto.PubkeyToAddress(payerKey.PublicKey)
+	statedb.AddBalance(sender, big.NewInt(100))
+
+	signer := types.NewCeloSigner(config.ChainID)
+	sponsored := func(nonce uint64) *types.Transaction {
+		tx, _ := types.SignTx(types.NewSponsoredTransaction(config.ChainID, nonce, &common.Address{}, big.NewInt(100), 100000, big.NewInt(1), nil, payer, nil, nil, nil), signer, senderKey)
+		return tx
+	}
+	if err := pool.AddRemote(sponsored(0)); err != ErrInvalidFeePayer {
+		t.Errorf("expected %v without fee payer signature, got %v", ErrInvalidFeePayer, err)
+	}
+	tx, _ := types.SignTxAsFeePayer(sponsored(0), signer, payerKey)
+	if err := pool.AddRemote(tx); err != ErrInsufficientFunds {
+		t.Errorf("expected %v with unfunded fee payer, got %v", ErrInsufficientFunds, err)
+	}
+	// The sender only needs to cover the value once the fee payer is funded
+	statedb.AddBalance(payer, big.NewInt(100000))
+	if err := pool.AddRemote(tx); err != nil {
+		t.Errorf("expected sponsored transaction to be accepted, got %v", err)
+	}
+}
+
+// Tests that a fee payer sponsoring the transactions of several senders must
+// cover all of their fees together before they are promoted.
+func TestSponsoredTransactionCumulativeFees(t *testing.T) {
+	t.Parallel()
+
+	config := *params.TestChainConfig
+	config.TypedTxBlock = big.NewInt(0)
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	pool := NewTxPool(testTxPoolConfig, &config, blockchain)
+	defer pool.Stop()
+
+	payerKey, _ := crypto.GenerateKey()
+	payer := crypto.PubkeyToAddress(payerKey.PublicKey)
+	statedb.AddBalance(payer, big.NewInt(150000))
+
+	signer := types.NewCeloSigner(config.ChainID)
+	for i := 0; i < 2; i++ {
+		senderKey, _ := crypto.GenerateKey()
+		tx, _ := types.SignTx(types.NewSponsoredTransaction(config.ChainID, 0, &common.Address{}, big.NewInt(0), 100000, big.NewInt(1), nil, payer, nil, nil, nil), signer, senderKey)
+		tx, _ = types.SignTxAsFeePayer(tx, signer, payerKey)
+		if err := pool.addRemoteSync(tx); err != nil {
+			t.Fatalf("failed to add sponsored transaction %d: %v", i, err)
+		}
+	}
+	// The fee payer covers either transaction, but not both
+	if pending, queued := pool.Stats(); pending != 1 || queued != 1 {
+		t.Fatalf("pending/queued mismatch: have %d/%d, want 1/1", pending, queued)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	// Funding the fee payer promotes the held back transaction
+	statedb.AddBalance(payer, big.NewInt(50000))
+	<-pool.requestReset(nil, nil)
+	if pending, queued := pool.Stats(); pending != 2 || queued != 0 {
+		t.Fatalf("pending/queued mismatch: have %d/%d, want 2/0", pending, queued)
+	}
+}
+
 func TestTransactionQueue(t *testing.T) {
 	t.Parallel()
 
@@ -583,6 +700,238 @@ func TestTransactionDropping(t *testing.T) {
 	}
 }
 
+// Tests that the transactions paying fees in currencies removed from the fee
+// currency whitelist are dropped, and that the change is notified.
+func TestTransactionFeeCurrencyWhitelistChange(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	var (
+		removed = common.Address{0x01}
+		kept    = common.Address{0x02}
+		added   = common.Address{0x03}
+		head    = &types.Header{Number: big.NewInt(1)}
+	)
+	feeCurrencyTransaction := func(nonce uint64, feeCurrency *common.Address) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, common.Address{}, big.NewInt(100), 100000, big.NewInt(1), feeCurrency, nil, nil, nil), pool.signer, key)
+		return tx
+	}
+	var (
+		txRemoved = feeCurrencyTransaction(10, &removed)
+		txKept    = feeCurrencyTransaction(11, &kept)
+		txNative  = feeCurrencyTransaction(12, nil)
+	)
+	pool.mu.Lock()
+	pool.setWhitelist(head, []common.Address{removed, kept})
+	if pool.whitelistChange != nil {
+		t.Fatalf("initial whitelist notified as a change")
+	}
+	pool.enqueueTx(txRemoved.Hash(), txRemoved)
+	pool.enqueueTx(txKept.Hash(), txKept)
+	pool.enqueueTx(txNative.Hash(), txNative)
+
+	pool.setWhitelist(head, []common.Address{kept, added})
+	change := pool.whitelistChange
+	pool.mu.Unlock()
+
+	if change == nil || len(change.Added) != 1 || change.Added[0] != added || len(change.Removed) != 1 || change.Removed[0] != removed {
+		t.Fatalf("whitelist change mismatch: have %+v", change)
+	}
+	if pool.all.Get(txRemoved.Hash()) != nil {
+		t.Errorf("transaction in removed currency not dropped")
+	}
+	if pool.all.Get(txKept.Hash()) == nil || pool.all.Get(txNative.Hash()) == nil {
+		t.Errorf("transactions in whitelisted currencies dropped")
+	}
+	if err := pool.AddRemote(feeCurrencyTransaction(13, &removed)); err != ErrNonWhitelistedFeeCurrency {
+		t.Errorf("transaction in removed currency error mismatch: have %v, want %v", err, ErrNonWhitelistedFeeCurrency)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// testLogsChain is a testBlockChain whose logs can be fed to its subscribers.
+type testLogsChain struct {
+	*testBlockChain
+	logsFeed event.Feed
+}
+
+func (bc *testLogsChain) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription {
+	return bc.logsFeed.Subscribe(ch)
+}
+
+// setupLogsTxPool creates a pool on a chain whose logs can be fed, along with
+// the chain the internal EVM calls of the pool need.
+func setupLogsTxPool(statedb *state.StateDB) (*TxPool, *testLogsChain) {
+	db := rawdb.NewMemoryDatabase()
+	(&Genesis{Config: params.TestChainConfig}).MustCommit(db)
+	chain, _ := NewBlockChain(db, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil)
+	contract_comm.SetInternalEVMHandler(chain)
+	chain.Stop()
+
+	blockchain := &testLogsChain{testBlockChain: &testBlockChain{statedb, 1000000, new(event.Feed)}}
+	return NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain), blockchain
+}
+
+// setFeeCurrencyWhitelist deploys to the state a registry and a FeeCurrencyWhitelist
+// contract returning the given currencies.
+func setFeeCurrencyWhitelist(statedb *state.StateDB, currencies ...common.Address) {
+	whitelist := common.HexToAddress("0x8000")
+
+	// The registry returns the address stored at the slot of the registry id
+	statedb.SetCode(params.RegistrySmartContractAddress, []byte{
+		byte(vm.PUSH1), 4, byte(vm.CALLDATALOAD), byte(vm.SLOAD),
+		byte(vm.PUSH1), 0, byte(vm.MSTORE), byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.RETURN),
+	})
+	statedb.SetState(params.RegistrySmartContractAddress, params.FeeCurrencyWhitelistRegistryId, common.BytesToHash(whitelist.Bytes()))
+
+	// The whitelist returns the encoded currencies following its code
+	data := append(common.BigToHash(big.NewInt(32)).Bytes(), common.BigToHash(big.NewInt(int64(len(currencies)))).Bytes()...)
+	for _, feeCurrency := range currencies {
+		data = append(data, common.BytesToHash(feeCurrency.Bytes()).Bytes()...)
+	}
+	code := []byte{
+		byte(vm.PUSH1), byte(len(data)), byte(vm.PUSH1), 12, byte(vm.PUSH1), 0, byte(vm.CODECOPY),
+		byte(vm.PUSH1), byte(len(data)), byte(vm.PUSH1), 0, byte(vm.RETURN),
+	}
+	statedb.SetCode(whitelist, append(code, data...))
+}
+
+// Tests that the fee currency whitelist is refreshed once the head includes the
+// logs changing it.
+func TestTransactionFeeCurrencyWhitelistLogs(t *testing.T) {
+	t.Parallel()
+
+	var (
+		first  = common.Address{0x01}
+		second = common.Address{0x02}
+	)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	setFeeCurrencyWhitelist(statedb, first)
+
+	pool, blockchain := setupLogsTxPool(statedb)
+	defer pool.Stop()
+
+	changes := make(chan FeeCurrencyWhitelistChangedEvent, 1)
+	sub := pool.SubscribeFeeCurrencyWhitelistChangedEvent(changes)
+	defer sub.Unsubscribe()
+
+	pool.mu.RLock()
+	if !pool.whitelist[first] || len(pool.whitelist) != 1 {
+		t.Fatalf("initial whitelist mismatch: have %v", pool.whitelist)
+	}
+	pool.mu.RUnlock()
+
+	// A currency whitelisted in the head is refreshed at once
+	setFeeCurrencyWhitelist(statedb, first, second)
+	blockchain.logsFeed.Send([]*types.Log{{
+		Topics:      []common.Hash{crypto.Keccak256Hash([]byte("FeeCurrencyWhitelisted(address)"))},
+		BlockNumber: 0,
+	}})
+	select {
+	case change := <-changes:
+		if len(change.Added) != 1 || change.Added[0] != second || len(change.Removed) != 0 {
+			t.Fatalf("whitelist change mismatch: have %+v", change)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("whitelist change not notified")
+	}
+
+	// A new whitelist registered in the next block is only refreshed once it's
+	// the head, and unrelated logs are ignored
+	setFeeCurrencyWhitelist(statedb, second)
+	blockchain.logsFeed.Send([]*types.Log{
+		{
+			Topics:      []common.Hash{crypto.Keccak256Hash([]byte("RegistryUpdated(string,bytes32,address)")), params.FeeCurrencyWhitelistRegistryId},
+			BlockNumber: 1,
+		},
+		{
+			Topics:      []common.Hash{crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))},
+			BlockNumber: 2,
+		},
+	})
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		pool.mu.RLock()
+		stale := pool.whitelistStale
+		pool.mu.RUnlock()
+		if stale != nil && stale.Uint64() == 1 {
+			break
+		}
+		if time.Since(start) > time.Second {
+			t.Fatalf("stale whitelist mismatch: have %v, want 1", stale)
+		}
+	}
+	select {
+	case change := <-changes:
+		t.Fatalf("whitelist refreshed before the head includes the change: %+v", change)
+	case <-time.After(50 * time.Millisecond):
+	}
+	head := types.NewBlock(&types.Header{Number: big.NewInt(1), ParentHash: blockchain.CurrentBlock().Hash(), GasLimit: 1000000}, nil, nil, nil, nil)
+	blockchain.chainHeadFeed.Send(ChainHeadEvent{Block: head})
+
+	select {
+	case change := <-changes:
+		if len(change.Removed) != 1 || change.Removed[0] != first || len(change.Added) != 0 || change.Header.Hash() != head.Hash() {
+			t.Fatalf("whitelist change mismatch: have %+v", change)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("whitelist change not notified")
+	}
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+	if pool.whitelistStale != nil {
+		t.Errorf("whitelist still stale at block %v", pool.whitelistStale)
+	}
+}
+
+// Tests that the gas price minimums are cached until an update of the gas price
+// minimum is included in the head.
+func TestTransactionGasPriceMinimumLogs(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	pool, blockchain := setupLogsTxPool(statedb)
+	defer pool.Stop()
+
+	key, _ := crypto.GenerateKey()
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+	pool.mu.Lock()
+	pool.gasPriceMinimums[common.Address{}] = big.NewInt(10)
+	pool.mu.Unlock()
+
+	tx := pricedTransaction(0, 100000, big.NewInt(5), key)
+	if err := pool.AddRemote(tx); err != ErrGasPriceDoesNotExceedMinimum {
+		t.Fatalf("cached gas price minimum error mismatch: have %v, want %v", err, ErrGasPriceDoesNotExceedMinimum)
+	}
+	blockchain.logsFeed.Send([]*types.Log{{
+		Topics:      []common.Hash{crypto.Keccak256Hash([]byte("GasPriceMinimumUpdated(uint256)"))},
+		BlockNumber: 0,
+	}})
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		pool.mu.RLock()
+		_, cached := pool.gasPriceMinimums[common.Address{}]
+		pool.mu.RUnlock()
+		if !cached {
+			break
+		}
+		if time.Since(start) > time.Second {
+			t.Fatalf("gas price minimum not refreshed")
+		}
+	}
+	if err := pool.AddRemote(tx); err != nil {
+		t.Fatalf("failed to add transaction after the refresh: %v", err)
+	}
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+	if _, cached := pool.gasPriceMinimums[common.Address{}]; !cached {
+		t.Errorf("refreshed gas price minimum not cached")
+	}
+}
+
 // Tests that if a transaction is dropped from the current pending pool (e.g. out
 // of fund), all consecutive (still valid, but not executable) transactions are
 // postponed back into the future queue to prevent broadcasting them.
@@ -1035,6 +1384,40 @@ func TestTransactionPendingGlobalLimiting(t *testing.T) {
 	}
 }
 
+// Tests that lowering the pool limits at runtime drops the transactions exceeding them.
+func TestTransactionPoolSetLimits(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	account := crypto.PubkeyToAddress(key.PublicKey)
+	pool.currentState.AddBalance(account, big.NewInt(1000000))
+
+	txs := types.Transactions{}
+	for i := uint64(0); i < testTxPoolConfig.AccountSlots; i++ {
+		txs = append(txs, transaction(i, 100000, key))
+	}
+	// Leave a nonce gap so the rest of the transactions are queued
+	for i := testTxPoolConfig.AccountSlots + 1; i < testTxPoolConfig.AccountSlots+9; i++ {
+		txs = append(txs, transaction(i, 100000, key))
+	}
+	pool.AddRemotesSync(txs)
+	if pending, queued := pool.Stats(); pending != int(testTxPoolConfig.AccountSlots) || queued != 8 {
+		t.Fatalf("pool stats mismatch: have %d pending %d queued, want %d pending 8 queued", pending, queued, testTxPoolConfig.AccountSlots)
+	}
+	config := testTxPoolConfig
+	config.AccountQueue = 4
+	pool.SetLimits(config)
+
+	if _, queued := pool.Stats(); queued != 4 {
+		t.Fatalf("queued transactions mismatch: have %d, want %d", queued, 4)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
 // Tests that if transactions start being capped, transactions are also removed from 'all'
 func TestTransactionCapClearsFromAll(t *testing.T) {
 	t.Parallel()
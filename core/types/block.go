@@ -86,6 +86,89 @@ type Header struct {
 	Extra       []byte         `json:"extraData"        gencodec:"required"`
 	MixDigest   common.Hash    `json:"mixHash"          gencodec:"required"`
 	Nonce       BlockNonce     `json:"nonce"            gencodec:"required"`
+
+	// BaseFee is the CELO denominated base fee of the block. It is only set
+	// once the base fee fork is active, and omitted from the RLP encoding
+	// otherwise so that legacy header hashes are unchanged.
+	BaseFee *big.Int `json:"baseFee,omitempty" rlp:"-"`
+}
+
+// rlpHeader is the RLP layout of a header. The base fee is encoded as an
+// optional trailing element.
+type rlpHeader struct {
+	ParentHash  common.Hash
+	UncleHash   common.Hash
+	Coinbase    common.Address
+	Root        common.Hash
+	TxHash      common.Hash
+	ReceiptHash common.Hash
+	Bloom       Bloom
+	Difficulty  *big.Int
+	Number      *big.Int
+	GasLimit    uint64
+	GasUsed     uint64
+	Time        uint64
+	Extra       []byte
+	MixDigest   common.Hash
+	Nonce       BlockNonce
+	BaseFee     []*big.Int `rlp:"tail"`
+}
+
+// EncodeRLP serializes h into the Ethereum RLP header format.
+func (h *Header) EncodeRLP(w io.Writer) error {
+	enc := rlpHeader{
+		ParentHash:  h.ParentHash,
+		UncleHash:   h.UncleHash,
+		Coinbase:    h.Coinbase,
+		Root:        h.Root,
+		TxHash:      h.TxHash,
+		ReceiptHash: h.ReceiptHash,
+		Bloom:       h.Bloom,
+		Difficulty:  h.Difficulty,
+		Number:      h.Number,
+		GasLimit:    h.GasLimit,
+		GasUsed:     h.GasUsed,
+		Time:        h.Time,
+		Extra:       h.Extra,
+		MixDigest:   h.MixDigest,
+		Nonce:       h.Nonce,
+	}
+	if h.BaseFee != nil {
+		enc.BaseFee = []*big.Int{h.BaseFee}
+	}
+	return rlp.Encode(w, &enc)
+}
+
+// DecodeRLP implements rlp.Decoder, and loads the RLP header fields into h.
+func (h *Header) DecodeRLP(s *rlp.Stream) error {
+	var dec rlpHeader
+	if err := s.Decode(&dec); err != nil {
+		return err
+	}
+	if len(dec.BaseFee) > 1 {
+		return fmt.Errorf("rlp: too many header fields (%d extra)", len(dec.BaseFee))
+	}
+	*h = Header{
+		ParentHash:  dec.ParentHash,
+		UncleHash:   dec.UncleHash,
+		Coinbase:    dec.Coinbase,
+		Root:        dec.Root,
+		TxHash:      dec.TxHash,
+		ReceiptHash: dec.ReceiptHash,
+		Bloom:       dec.Bloom,
+		Difficulty:  dec.Difficulty,
+		Number:      dec.Number,
+		GasLimit:    dec.GasLimit,
+		GasUsed:     dec.GasUsed,
+		Time:        dec.Time,
+		Extra:       dec.Extra,
+		MixDigest:   dec.MixDigest,
+		Nonce:       dec.Nonce,
+	}
+	if len(dec.BaseFee) == 1 {
+		h.BaseFee = dec.BaseFee[0]
+	}
+	return nil
 }
 
 // field type overrides for gencodec
@@ -96,6 +179,7 @@ type headerMarshaling struct {
 	GasUsed    hexutil.Uint64
 	Time       hexutil.Uint64
 	Extra      hexutil.Bytes
+	BaseFee    *hexutil.Big
 	Hash       common.Hash `json:"hash"` // adds call to Hash() in MarshalJSON
 }
 
@@ -147,6 +231,16 @@ func rlpHash(x interface{}) (h common.Hash) {
 	return h
 }
 
+// prefixedRlpHash writes the prefix into the hasher before rlp-encoding x.
+// It's used for typed transactions.
+func prefixedRlpHash(prefix byte, x interface{}) (h common.Hash) {
+	hw := sha3.NewLegacyKeccak256()
+	hw.Write([]byte{prefix})
+	rlp.Encode(hw, x)
+	hw.Sum(h[:0])
+	return h
+}
+
 type Randomness struct {
 	Revealed  common.Hash
 	Committed common.Hash
@@ -319,6 +413,9 @@ func CopyHeader(h *Header) *Header {
 	if cpy.Number = new(big.Int); h.Number != nil {
 		cpy.Number.Set(h.Number)
 	}
+	if h.BaseFee != nil {
+		cpy.BaseFee = new(big.Int).Set(h.BaseFee)
+	}
 	if len(h.Extra) > 0 {
 		cpy.Extra = make([]byte, len(h.Extra))
 		copy(cpy.Extra, h.Extra)
@@ -393,6 +490,13 @@ func (b *Block) ReceiptHash() common.Hash { return b.header.ReceiptHash }
 func (b *Block) UncleHash() common.Hash   { return b.header.UncleHash }
 func (b *Block) Extra() []byte            { return common.CopyBytes(b.header.Extra) }
 
+func (b *Block) BaseFee() *big.Int {
+	if b.header.BaseFee == nil {
+		return nil
+	}
+	return new(big.Int).Set(b.header.BaseFee)
+}
+
 func (b *Block) Header() *Header        { return CopyHeader(b.header) }
 func (b *Block) MutableHeader() *Header { return b.header }
 
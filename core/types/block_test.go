@@ -18,6 +18,7 @@ package types
 
 import (
 	"bytes"
+	"encoding/json"
 	"math/big"
 	"reflect"
 	"testing"
@@ -63,6 +64,59 @@ func TestBlockEncoding(t *testing.T) {
 	}
 }
 
+// Tests that headers without a base fee keep their legacy RLP and JSON
+// encodings, and that the base fee survives an encoding round trip once set.
+func TestHeaderBaseFeeEncoding(t *testing.T) {
+	header := &Header{
+		Difficulty: big.NewInt(1),
+		Number:     big.NewInt(10),
+		GasLimit:   20000000,
+		Extra:      []byte("celo"),
+	}
+	legacy, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		t.Fatal("encode error: ", err)
+	}
+	want, _ := rlp.EncodeToBytes([]interface{}{
+		header.ParentHash, header.UncleHash, header.Coinbase, header.Root, header.TxHash, header.ReceiptHash, header.Bloom,
+		header.Difficulty, header.Number, header.GasLimit, header.GasUsed, header.Time, header.Extra, header.MixDigest, header.Nonce,
+	})
+	if !bytes.Equal(legacy, want) {
+		t.Errorf("legacy header encoding mismatch:\ngot:  %x\nwant: %x", legacy, want)
+	}
+	if blob, _ := json.Marshal(header); bytes.Contains(blob, []byte("baseFee")) {
+		t.Errorf("legacy header JSON contains base fee: %s", blob)
+	}
+	header.BaseFee = big.NewInt(500000000)
+	if blob, _ := json.Marshal(header); !bytes.Contains(blob, []byte(`"baseFee":"0x1dcd6500"`)) {
+		t.Errorf("header JSON misses base fee: %s", blob)
+	}
+	enc, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		t.Fatal("encode error: ", err)
+	}
+	if bytes.Equal(enc, legacy) {
+		t.Fatal("base fee not included in encoding")
+	}
+	for _, data := range [][]byte{legacy, enc} {
+		var dec Header
+		if err := rlp.DecodeBytes(data, &dec); err != nil {
+			t.Fatal("decode error: ", err)
+		}
+		reenc, _ := rlp.EncodeToBytes(&dec)
+		if !bytes.Equal(reenc, data) {
+			t.Errorf("encoding round trip mismatch:\ngot:  %x\nwant: %x", reenc, data)
+		}
+	}
+	var dec Header
+	if err := rlp.DecodeBytes(enc, &dec); err != nil {
+		t.Fatal("decode error: ", err)
+	}
+	if dec.BaseFee == nil || dec.BaseFee.Cmp(header.BaseFee) != 0 {
+		t.Errorf("base fee mismatch: got %v, want %v", dec.BaseFee, header.BaseFee)
+	}
+}
+
 func TestUncleHash(t *testing.T) {
 	uncles := make([]*Header, 0)
 	h := CalcUncleHash(uncles)
@@ -16,27 +16,45 @@ var _ = (*txdataMarshaling)(nil)
 // MarshalJSON marshals as JSON.
 func (t txdata) MarshalJSON() ([]byte, error) {
 	type txdata struct {
-		AccountNonce hexutil.Uint64  `json:"nonce"    gencodec:"required"`
-		Price        *hexutil.Big    `json:"gasPrice" gencodec:"required"`
-		GasLimit     hexutil.Uint64  `json:"gas"      gencodec:"required"`
-		Recipient    *common.Address `json:"to"       rlp:"nil"`
-		Amount       *hexutil.Big    `json:"value"    gencodec:"required"`
-		Payload      hexutil.Bytes   `json:"input"    gencodec:"required"`
-		V            *hexutil.Big    `json:"v" gencodec:"required"`
-		R            *hexutil.Big    `json:"r" gencodec:"required"`
-		S            *hexutil.Big    `json:"s" gencodec:"required"`
-		Hash         *common.Hash    `json:"hash" rlp:"-"`
+		AccountNonce        hexutil.Uint64  `json:"nonce"    gencodec:"required"`
+		Price               *hexutil.Big    `json:"gasPrice" gencodec:"required"`
+		GasLimit            hexutil.Uint64  `json:"gas"      gencodec:"required"`
+		FeeCurrency         *common.Address `json:"feeCurrency" rlp:"nil"`
+		GatewayFeeRecipient *common.Address `json:"gatewayFeeRecipient" rlp:"nil"`
+		GatewayFee          *hexutil.Big    `json:"gatewayFee" rlp:"nil"`
+		Recipient           *common.Address `json:"to"       rlp:"nil"`
+		Amount              *hexutil.Big    `json:"value"    gencodec:"required"`
+		Payload             hexutil.Bytes   `json:"input"    gencodec:"required"`
+		V                   *hexutil.Big    `json:"v" gencodec:"required"`
+		R                   *hexutil.Big    `json:"r" gencodec:"required"`
+		S                   *hexutil.Big    `json:"s" gencodec:"required"`
+		Type                hexutil.Uint64  `json:"type"              rlp:"-"`
+		ChainID             *hexutil.Big    `json:"chainId,omitempty" rlp:"-"`
+		FeePayer            *common.Address `json:"feePayer,omitempty" rlp:"-"`
+		PayerV              *hexutil.Big    `json:"payerV,omitempty"   rlp:"-"`
+		PayerR              *hexutil.Big    `json:"payerR,omitempty"   rlp:"-"`
+		PayerS              *hexutil.Big    `json:"payerS,omitempty"   rlp:"-"`
+		Hash                *common.Hash    `json:"hash" rlp:"-"`
 	}
 	var enc txdata
 	enc.AccountNonce = hexutil.Uint64(t.AccountNonce)
 	enc.Price = (*hexutil.Big)(t.Price)
 	enc.GasLimit = hexutil.Uint64(t.GasLimit)
+	enc.FeeCurrency = t.FeeCurrency
+	enc.GatewayFeeRecipient = t.GatewayFeeRecipient
+	enc.GatewayFee = (*hexutil.Big)(t.GatewayFee)
 	enc.Recipient = t.Recipient
 	enc.Amount = (*hexutil.Big)(t.Amount)
 	enc.Payload = t.Payload
 	enc.V = (*hexutil.Big)(t.V)
 	enc.R = (*hexutil.Big)(t.R)
 	enc.S = (*hexutil.Big)(t.S)
+	enc.Type = hexutil.Uint64(t.Type)
+	enc.ChainID = (*hexutil.Big)(t.ChainID)
+	enc.FeePayer = t.FeePayer
+	enc.PayerV = (*hexutil.Big)(t.PayerV)
+	enc.PayerR = (*hexutil.Big)(t.PayerR)
+	enc.PayerS = (*hexutil.Big)(t.PayerS)
 	enc.Hash = t.Hash
 	return json.Marshal(&enc)
 }
@@ -44,16 +62,25 @@ func (t txdata) MarshalJSON() ([]byte, error) {
 // UnmarshalJSON unmarshals from JSON.
 func (t *txdata) UnmarshalJSON(input []byte) error {
 	type txdata struct {
-		AccountNonce *hexutil.Uint64 `json:"nonce"    gencodec:"required"`
-		Price        *hexutil.Big    `json:"gasPrice" gencodec:"required"`
-		GasLimit     *hexutil.Uint64 `json:"gas"      gencodec:"required"`
-		Recipient    *common.Address `json:"to"       rlp:"nil"`
-		Amount       *hexutil.Big    `json:"value"    gencodec:"required"`
-		Payload      *hexutil.Bytes  `json:"input"    gencodec:"required"`
-		V            *hexutil.Big    `json:"v" gencodec:"required"`
-		R            *hexutil.Big    `json:"r" gencodec:"required"`
-		S            *hexutil.Big    `json:"s" gencodec:"required"`
-		Hash         *common.Hash    `json:"hash" rlp:"-"`
+		AccountNonce        *hexutil.Uint64 `json:"nonce"    gencodec:"required"`
+		Price               *hexutil.Big    `json:"gasPrice" gencodec:"required"`
+		GasLimit            *hexutil.Uint64 `json:"gas"      gencodec:"required"`
+		FeeCurrency         *common.Address `json:"feeCurrency" rlp:"nil"`
+		GatewayFeeRecipient *common.Address `json:"gatewayFeeRecipient" rlp:"nil"`
+		GatewayFee          *hexutil.Big    `json:"gatewayFee" rlp:"nil"`
+		Recipient           *common.Address `json:"to"       rlp:"nil"`
+		Amount              *hexutil.Big    `json:"value"    gencodec:"required"`
+		Payload             *hexutil.Bytes  `json:"input"    gencodec:"required"`
+		V                   *hexutil.Big    `json:"v" gencodec:"required"`
+		R                   *hexutil.Big    `json:"r" gencodec:"required"`
+		S                   *hexutil.Big    `json:"s" gencodec:"required"`
+		Type                *hexutil.Uint64 `json:"type"              rlp:"-"`
+		ChainID             *hexutil.Big    `json:"chainId,omitempty" rlp:"-"`
+		FeePayer            *common.Address `json:"feePayer,omitempty" rlp:"-"`
+		PayerV              *hexutil.Big    `json:"payerV,omitempty"   rlp:"-"`
+		PayerR              *hexutil.Big    `json:"payerR,omitempty"   rlp:"-"`
+		PayerS              *hexutil.Big    `json:"payerS,omitempty"   rlp:"-"`
+		Hash                *common.Hash    `json:"hash" rlp:"-"`
 	}
 	var dec txdata
 	if err := json.Unmarshal(input, &dec); err != nil {
@@ -71,6 +98,15 @@ func (t *txdata) UnmarshalJSON(input []byte) error {
 		return errors.New("missing required field 'gas' for txdata")
 	}
 	t.GasLimit = uint64(*dec.GasLimit)
+	if dec.FeeCurrency != nil {
+		t.FeeCurrency = dec.FeeCurrency
+	}
+	if dec.GatewayFeeRecipient != nil {
+		t.GatewayFeeRecipient = dec.GatewayFeeRecipient
+	}
+	if dec.GatewayFee != nil {
+		t.GatewayFee = (*big.Int)(dec.GatewayFee)
+	}
 	if dec.Recipient != nil {
 		t.Recipient = dec.Recipient
 	}
@@ -94,6 +130,24 @@ func (t *txdata) UnmarshalJSON(input []byte) error {
 		return errors.New("missing required field 's' for txdata")
 	}
 	t.S = (*big.Int)(dec.S)
+	if dec.Type != nil {
+		t.Type = uint8(*dec.Type)
+	}
+	if dec.ChainID != nil {
+		t.ChainID = (*big.Int)(dec.ChainID)
+	}
+	if dec.FeePayer != nil {
+		t.FeePayer = dec.FeePayer
+	}
+	if dec.PayerV != nil {
+		t.PayerV = (*big.Int)(dec.PayerV)
+	}
+	if dec.PayerR != nil {
+		t.PayerR = (*big.Int)(dec.PayerR)
+	}
+	if dec.PayerS != nil {
+		t.PayerS = (*big.Int)(dec.PayerS)
+	}
 	if dec.Hash != nil {
 		t.Hash = dec.Hash
 	}
@@ -32,7 +32,23 @@ import (
 //go:generate gencodec -type txdata -field-override txdataMarshaling -out gen_tx_json.go
 
 var (
-	ErrInvalidSig = errors.New("invalid transaction v, r, s values")
+	ErrInvalidSig         = errors.New("invalid transaction v, r, s values")
+	ErrTxTypeNotSupported = errors.New("transaction type not supported")
+	ErrInvalidFeePayerSig = errors.New("invalid fee payer signature")
+	errEmptyTypedTx       = errors.New("empty typed transaction bytes")
+)
+
+// Transaction types.
+const (
+	// LegacyTxType is the original RLP list transaction format.
+	LegacyTxType = 0x00
+	// CeloTxType is a typed transaction envelope carrying the Celo fee fields
+	// and an explicit chain id.
+	CeloTxType = 0x7c
+	// SponsoredTxType is a typed transaction envelope whose gas and gateway
+	// fees are paid by a fee payer instead of the sender. The fee payer signs
+	// over the transaction including the sender's signature.
+	SponsoredTxType = 0x7d
 )
 
 type Transaction struct {
@@ -41,6 +57,8 @@ type Transaction struct {
 	hash atomic.Value
 	size atomic.Value
 	from atomic.Value
+	// fee payer cache, only used for sponsored transactions
+	payer atomic.Value
 }
 
 type txdata struct {
@@ -59,22 +77,71 @@ type txdata struct {
 	R *big.Int `json:"r" gencodec:"required"`
 	S *big.Int `json:"s" gencodec:"required"`
 
+	// Envelope values, not part of the legacy RLP encoding
+	Type    uint8    `json:"type"              rlp:"-"`
+	ChainID *big.Int `json:"chainId,omitempty" rlp:"-"` // only set for typed transactions
+
+	// Fee payer values, only set for sponsored transactions
+	FeePayer *common.Address `json:"feePayer,omitempty" rlp:"-"`
+	PayerV   *big.Int        `json:"payerV,omitempty"   rlp:"-"`
+	PayerR   *big.Int        `json:"payerR,omitempty"   rlp:"-"`
+	PayerS   *big.Int        `json:"payerS,omitempty"   rlp:"-"`
+
 	// This is only used when marshaling to JSON.
 	Hash *common.Hash `json:"hash" rlp:"-"`
 }
 
 type txdataMarshaling struct {
-	AccountNonce        hexutil.Uint64
-	Price               *hexutil.Big
-	GasLimit            hexutil.Uint64
-	FeeCurrency         *hexutil.Big
-	GatewayFeeRecipient *hexutil.Big
-	GatewayFee          *hexutil.Big
-	Amount              *hexutil.Big
-	Payload             hexutil.Bytes
-	V                   *hexutil.Big
-	R                   *hexutil.Big
-	S                   *hexutil.Big
+	AccountNonce hexutil.Uint64
+	Price        *hexutil.Big
+	GasLimit     hexutil.Uint64
+	GatewayFee   *hexutil.Big
+	Amount       *hexutil.Big
+	Payload      hexutil.Bytes
+	V            *hexutil.Big
+	R            *hexutil.Big
+	S            *hexutil.Big
+	Type         hexutil.Uint64
+	ChainID      *hexutil.Big
+	PayerV       *hexutil.Big
+	PayerR       *hexutil.Big
+	PayerS       *hexutil.Big
+}
+
+// celoTxdata is the RLP payload of a CeloTxType transaction envelope. The V
+// signature value is the recovery id (0 or 1), as the chain id is explicit.
+type celoTxdata struct {
+	ChainID             *big.Int
+	AccountNonce        uint64
+	Price               *big.Int
+	GasLimit            uint64
+	FeeCurrency         *common.Address `rlp:"nil"`
+	GatewayFeeRecipient *common.Address `rlp:"nil"`
+	GatewayFee          *big.Int        `rlp:"nil"`
+	Recipient           *common.Address `rlp:"nil"`
+	Amount              *big.Int
+	Payload             []byte
+
+	V, R, S *big.Int
+}
+
+// sponsoredTxdata is the RLP payload of a SponsoredTxType transaction
+// envelope. Both V signature values are recovery ids (0 or 1).
+type sponsoredTxdata struct {
+	ChainID             *big.Int
+	AccountNonce        uint64
+	Price               *big.Int
+	GasLimit            uint64
+	FeeCurrency         *common.Address `rlp:"nil"`
+	GatewayFeeRecipient *common.Address `rlp:"nil"`
+	GatewayFee          *big.Int        `rlp:"nil"`
+	Recipient           *common.Address `rlp:"nil"`
+	Amount              *big.Int
+	Payload             []byte
+	FeePayer            common.Address
+
+	V, R, S                *big.Int
+	PayerV, PayerR, PayerS *big.Int
 }
 
 func NewTransaction(nonce uint64, to common.Address, amount *big.Int, gasLimit uint64, gasPrice *big.Int, feeCurrency, gatewayFeeRecipient *common.Address, gatewayFee *big.Int, data []byte) *Transaction {
@@ -116,13 +183,49 @@ func newTransaction(nonce uint64, to *common.Address, amount *big.Int, gasLimit
 	return &Transaction{data: d}
 }
 
+// NewCeloTransaction creates an unsigned CeloTxType transaction for the given
+// chain. A nil recipient creates a contract.
+func NewCeloTransaction(chainID *big.Int, nonce uint64, to *common.Address, amount *big.Int, gasLimit uint64, gasPrice *big.Int, feeCurrency, gatewayFeeRecipient *common.Address, gatewayFee *big.Int, data []byte) *Transaction {
+	tx := newTransaction(nonce, to, amount, gasLimit, gasPrice, feeCurrency, gatewayFeeRecipient, gatewayFee, data)
+	tx.data.Type = CeloTxType
+	tx.data.ChainID = new(big.Int)
+	if chainID != nil {
+		tx.data.ChainID.Set(chainID)
+	}
+	return tx
+}
+
+// NewSponsoredTransaction creates an unsigned SponsoredTxType transaction for
+// the given chain, whose fees are paid by feePayer. A nil recipient creates a
+// contract.
+func NewSponsoredTransaction(chainID *big.Int, nonce uint64, to *common.Address, amount *big.Int, gasLimit uint64, gasPrice *big.Int, feeCurrency *common.Address, feePayer common.Address, gatewayFeeRecipient *common.Address, gatewayFee *big.Int, data []byte) *Transaction {
+	tx := NewCeloTransaction(chainID, nonce, to, amount, gasLimit, gasPrice, feeCurrency, gatewayFeeRecipient, gatewayFee, data)
+	tx.data.Type = SponsoredTxType
+	tx.data.FeePayer = &feePayer
+	tx.data.PayerV = new(big.Int)
+	tx.data.PayerR = new(big.Int)
+	tx.data.PayerS = new(big.Int)
+	return tx
+}
+
+// Type returns the transaction type.
+func (tx *Transaction) Type() uint8 {
+	return tx.data.Type
+}
+
 // ChainId returns which chain id this transaction was signed for (if at all)
 func (tx *Transaction) ChainId() *big.Int {
+	if tx.data.Type != LegacyTxType {
+		return new(big.Int).Set(tx.data.ChainID)
+	}
 	return deriveChainId(tx.data.V)
 }
 
 // Protected returns whether the transaction is protected from replay protection.
 func (tx *Transaction) Protected() bool {
+	if tx.data.Type != LegacyTxType {
+		return true
+	}
 	return isProtectedV(tx.data.V)
 }
 
@@ -135,22 +238,196 @@ func isProtectedV(V *big.Int) bool {
 	return true
 }
 
-// EncodeRLP implements rlp.Encoder
+// EncodeRLP implements rlp.Encoder. Typed transactions are encoded as an RLP
+// string holding the type byte followed by the payload.
 func (tx *Transaction) EncodeRLP(w io.Writer) error {
-	return rlp.Encode(w, &tx.data)
+	if tx.data.Type == LegacyTxType {
+		return rlp.Encode(w, &tx.data)
+	}
+	enc, err := tx.encodeTyped()
+	if err != nil {
+		return err
+	}
+	return rlp.Encode(w, enc)
 }
 
 // DecodeRLP implements rlp.Decoder
 func (tx *Transaction) DecodeRLP(s *rlp.Stream) error {
-	_, size, _ := s.Kind()
-	err := s.Decode(&tx.data)
-	if err == nil {
+	kind, size, err := s.Kind()
+	if err == nil && kind != rlp.List {
+		enc, err := s.Bytes()
+		if err != nil {
+			return err
+		}
+		return tx.decodeTyped(enc)
+	}
+	var data txdata
+	if err = s.Decode(&data); err == nil {
+		tx.data = data
 		tx.size.Store(common.StorageSize(rlp.ListSize(size)))
 	}
-
 	return err
 }
 
+// MarshalBinary returns the canonical encoding of the transaction: the RLP
+// list for legacy transactions, and the type byte followed by the payload for
+// typed transactions.
+func (tx *Transaction) MarshalBinary() ([]byte, error) {
+	if tx.data.Type == LegacyTxType {
+		return rlp.EncodeToBytes(&tx.data)
+	}
+	return tx.encodeTyped()
+}
+
+// UnmarshalBinary decodes the canonical encoding of a transaction.
+func (tx *Transaction) UnmarshalBinary(b []byte) error {
+	if len(b) > 0 && b[0] > 0x7f {
+		var data txdata
+		if err := rlp.DecodeBytes(b, &data); err != nil {
+			return err
+		}
+		tx.data = data
+		tx.size.Store(common.StorageSize(len(b)))
+		return nil
+	}
+	return tx.decodeTyped(b)
+}
+
+// encodeTyped returns the envelope of a typed transaction.
+func (tx *Transaction) encodeTyped() ([]byte, error) {
+	inner := tx.typedPayload()
+	if inner == nil {
+		return nil, ErrTxTypeNotSupported
+	}
+	payload, err := rlp.EncodeToBytes(inner)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{tx.data.Type}, payload...), nil
+}
+
+// decodeTyped decodes the envelope of a typed transaction.
+func (tx *Transaction) decodeTyped(b []byte) error {
+	if len(b) == 0 {
+		return errEmptyTypedTx
+	}
+	switch b[0] {
+	case CeloTxType:
+		return tx.decodeCeloTxdata(b)
+	case SponsoredTxType:
+		return tx.decodeSponsoredTxdata(b)
+	default:
+		return ErrTxTypeNotSupported
+	}
+}
+
+func (tx *Transaction) decodeCeloTxdata(b []byte) error {
+	var dec celoTxdata
+	if err := rlp.DecodeBytes(b[1:], &dec); err != nil {
+		return err
+	}
+	tx.data = txdata{
+		AccountNonce:        dec.AccountNonce,
+		Price:               dec.Price,
+		GasLimit:            dec.GasLimit,
+		FeeCurrency:         dec.FeeCurrency,
+		GatewayFeeRecipient: dec.GatewayFeeRecipient,
+		GatewayFee:          dec.GatewayFee,
+		Recipient:           dec.Recipient,
+		Amount:              dec.Amount,
+		Payload:             dec.Payload,
+		V:                   dec.V,
+		R:                   dec.R,
+		S:                   dec.S,
+		Type:                b[0],
+		ChainID:             dec.ChainID,
+	}
+	tx.size.Store(common.StorageSize(len(b)))
+	return nil
+}
+
+func (tx *Transaction) decodeSponsoredTxdata(b []byte) error {
+	var dec sponsoredTxdata
+	if err := rlp.DecodeBytes(b[1:], &dec); err != nil {
+		return err
+	}
+	tx.data = txdata{
+		AccountNonce:        dec.AccountNonce,
+		Price:               dec.Price,
+		GasLimit:            dec.GasLimit,
+		FeeCurrency:         dec.FeeCurrency,
+		GatewayFeeRecipient: dec.GatewayFeeRecipient,
+		GatewayFee:          dec.GatewayFee,
+		Recipient:           dec.Recipient,
+		Amount:              dec.Amount,
+		Payload:             dec.Payload,
+		V:                   dec.V,
+		R:                   dec.R,
+		S:                   dec.S,
+		Type:                b[0],
+		ChainID:             dec.ChainID,
+		FeePayer:            &dec.FeePayer,
+		PayerV:              dec.PayerV,
+		PayerR:              dec.PayerR,
+		PayerS:              dec.PayerS,
+	}
+	tx.size.Store(common.StorageSize(len(b)))
+	return nil
+}
+
+// typedPayload returns the RLP payload of a typed transaction, or nil if the
+// transaction type is unknown.
+func (tx *Transaction) typedPayload() interface{} {
+	switch tx.data.Type {
+	case CeloTxType:
+		return tx.celoTxdata()
+	case SponsoredTxType:
+		return tx.sponsoredTxdata()
+	default:
+		return nil
+	}
+}
+
+func (tx *Transaction) sponsoredTxdata() *sponsoredTxdata {
+	return &sponsoredTxdata{
+		ChainID:             tx.data.ChainID,
+		AccountNonce:        tx.data.AccountNonce,
+		Price:               tx.data.Price,
+		GasLimit:            tx.data.GasLimit,
+		FeeCurrency:         tx.data.FeeCurrency,
+		GatewayFeeRecipient: tx.data.GatewayFeeRecipient,
+		GatewayFee:          tx.data.GatewayFee,
+		Recipient:           tx.data.Recipient,
+		Amount:              tx.data.Amount,
+		Payload:             tx.data.Payload,
+		FeePayer:            *tx.data.FeePayer,
+		V:                   tx.data.V,
+		R:                   tx.data.R,
+		S:                   tx.data.S,
+		PayerV:              tx.data.PayerV,
+		PayerR:              tx.data.PayerR,
+		PayerS:              tx.data.PayerS,
+	}
+}
+
+func (tx *Transaction) celoTxdata() *celoTxdata {
+	return &celoTxdata{
+		ChainID:             tx.data.ChainID,
+		AccountNonce:        tx.data.AccountNonce,
+		Price:               tx.data.Price,
+		GasLimit:            tx.data.GasLimit,
+		FeeCurrency:         tx.data.FeeCurrency,
+		GatewayFeeRecipient: tx.data.GatewayFeeRecipient,
+		GatewayFee:          tx.data.GatewayFee,
+		Recipient:           tx.data.Recipient,
+		Amount:              tx.data.Amount,
+		Payload:             tx.data.Payload,
+		V:                   tx.data.V,
+		R:                   tx.data.R,
+		S:                   tx.data.S,
+	}
+}
+
 // MarshalJSON encodes the web3 RPC transaction format.
 func (tx *Transaction) MarshalJSON() ([]byte, error) {
 	hash := tx.Hash()
@@ -166,10 +443,41 @@ func (tx *Transaction) UnmarshalJSON(input []byte) error {
 		return err
 	}
 
+	switch dec.Type {
+	case LegacyTxType:
+		dec.ChainID = nil
+	case CeloTxType, SponsoredTxType:
+		if dec.ChainID == nil {
+			return errors.New("missing chainId for typed transaction")
+		}
+	default:
+		return ErrTxTypeNotSupported
+	}
+	if dec.Type == SponsoredTxType {
+		if dec.FeePayer == nil {
+			return errors.New("missing feePayer for sponsored transaction")
+		}
+		if dec.PayerV == nil || dec.PayerR == nil || dec.PayerS == nil {
+			return errors.New("missing fee payer signature for sponsored transaction")
+		}
+		if dec.PayerV.Sign() != 0 || dec.PayerR.Sign() != 0 || dec.PayerS.Sign() != 0 {
+			if dec.PayerV.BitLen() > 1 || !crypto.ValidateSignatureValues(byte(dec.PayerV.Uint64()), dec.PayerR, dec.PayerS, false) {
+				return ErrInvalidFeePayerSig
+			}
+		}
+	} else {
+		dec.FeePayer, dec.PayerV, dec.PayerR, dec.PayerS = nil, nil, nil, nil
+	}
+
 	withSignature := dec.V.Sign() != 0 || dec.R.Sign() != 0 || dec.S.Sign() != 0
 	if withSignature {
 		var V byte
-		if isProtectedV(dec.V) {
+		if dec.Type != LegacyTxType {
+			if dec.V.BitLen() > 1 {
+				return ErrInvalidSig
+			}
+			V = byte(dec.V.Uint64())
+		} else if isProtectedV(dec.V) {
 			chainID := deriveChainId(dec.V).Uint64()
 			V = byte(dec.V.Uint64() - 35 - 2*chainID)
 		} else {
@@ -194,6 +502,16 @@ func (tx *Transaction) Value() *big.Int                      { return new(big.In
 func (tx *Transaction) Nonce() uint64                        { return tx.data.AccountNonce }
 func (tx *Transaction) CheckNonce() bool                     { return true }
 
+// FeePayer returns the account paying the fees of a sponsored transaction.
+// It returns nil if the fees are paid by the sender.
+func (tx *Transaction) FeePayer() *common.Address {
+	if tx.data.FeePayer == nil {
+		return nil
+	}
+	payer := *tx.data.FeePayer
+	return &payer
+}
+
 // To returns the recipient address of the transaction.
 // It returns nil if the transaction is a contract creation.
 func (tx *Transaction) To() *common.Address {
@@ -204,13 +522,18 @@ func (tx *Transaction) To() *common.Address {
 	return &to
 }
 
-// Hash hashes the RLP encoding of tx.
-// It uniquely identifies the transaction.
+// Hash hashes the RLP encoding of tx, prefixed by the type byte for typed
+// transactions. It uniquely identifies the transaction.
 func (tx *Transaction) Hash() common.Hash {
 	if hash := tx.hash.Load(); hash != nil {
 		return hash.(common.Hash)
 	}
-	v := rlpHash(tx)
+	var v common.Hash
+	if tx.data.Type == LegacyTxType {
+		v = rlpHash(tx)
+	} else {
+		v = prefixedRlpHash(tx.data.Type, tx.typedPayload())
+	}
 	tx.hash.Store(v)
 	return v
 }
@@ -222,7 +545,12 @@ func (tx *Transaction) Size() common.StorageSize {
 		return size.(common.StorageSize)
 	}
 	c := writeCounter(0)
-	rlp.Encode(&c, &tx.data)
+	if tx.data.Type == LegacyTxType {
+		rlp.Encode(&c, &tx.data)
+	} else {
+		c.Write([]byte{tx.data.Type})
+		rlp.Encode(&c, tx.typedPayload())
+	}
 	tx.size.Store(common.StorageSize(c))
 	return common.StorageSize(c)
 }
@@ -248,7 +576,17 @@ func (tx *Transaction) AsMessage(s Signer) (Message, error) {
 
 	var err error
 	msg.from, err = Sender(s, tx)
-	return msg, err
+	if err != nil {
+		return msg, err
+	}
+	if tx.data.Type == SponsoredTxType {
+		payer, err := FeePayer(s, tx)
+		if err != nil {
+			return msg, err
+		}
+		msg.feePayer = &payer
+	}
+	return msg, nil
 }
 
 // WithSignature returns a new transaction with the given signature.
@@ -263,10 +601,28 @@ func (tx *Transaction) WithSignature(signer Signer, sig []byte) (*Transaction, e
 	return cpy, nil
 }
 
+// WithFeePayerSignature returns a new sponsored transaction with the given fee
+// payer signature. This signature needs to be in the [R || S || V] format
+// where V is 0 or 1.
+func (tx *Transaction) WithFeePayerSignature(signer CeloSigner, sig []byte) (*Transaction, error) {
+	r, s, v, err := signer.FeePayerSignatureValues(tx, sig)
+	if err != nil {
+		return nil, err
+	}
+	cpy := &Transaction{data: tx.data}
+	cpy.data.PayerR, cpy.data.PayerS, cpy.data.PayerV = r, s, v
+	return cpy, nil
+}
+
 // Cost returns amount + gasprice * gaslimit + gatewayfee.
 func (tx *Transaction) Cost() *big.Int {
+	return new(big.Int).Add(tx.Fee(), tx.data.Amount)
+}
+
+// Fee returns gasprice * gaslimit + gatewayfee, the amount paid in the fee
+// currency.
+func (tx *Transaction) Fee() *big.Int {
 	total := new(big.Int).Mul(tx.data.Price, new(big.Int).SetUint64(tx.data.GasLimit))
-	total.Add(total, tx.data.Amount)
 	total.Add(total, tx.data.GatewayFee)
 	return total
 }
@@ -277,6 +633,12 @@ func (tx *Transaction) RawSignatureValues() (v, r, s *big.Int) {
 	return tx.data.V, tx.data.R, tx.data.S
 }
 
+// RawFeePayerSignatureValues returns the V, R, S fee payer signature values of
+// a sponsored transaction. The return values should not be modified by the caller.
+func (tx *Transaction) RawFeePayerSignatureValues() (v, r, s *big.Int) {
+	return tx.data.PayerV, tx.data.PayerR, tx.data.PayerS
+}
+
 // Transactions is a Transaction slice type for basic sorting.
 type Transactions []*Transaction
 
@@ -430,6 +792,7 @@ type Message struct {
 	feeCurrency         *common.Address
 	gatewayFeeRecipient *common.Address
 	gatewayFee          *big.Int
+	feePayer            *common.Address
 	data                []byte
 	checkNonce          bool
 }
@@ -456,6 +819,7 @@ func (m Message) GasPrice() *big.Int                   { return m.gasPrice }
 func (m Message) FeeCurrency() *common.Address         { return m.feeCurrency }
 func (m Message) GatewayFeeRecipient() *common.Address { return m.gatewayFeeRecipient }
 func (m Message) GatewayFee() *big.Int                 { return m.gatewayFee }
+func (m Message) FeePayer() *common.Address            { return m.feePayer }
 func (m Message) Value() *big.Int                      { return m.amount }
 func (m Message) Gas() uint64                          { return m.gasLimit }
 func (m Message) Nonce() uint64                        { return m.nonce }
@@ -42,6 +42,8 @@ type sigCache struct {
 func MakeSigner(config *params.ChainConfig, blockNumber *big.Int) Signer {
 	var signer Signer
 	switch {
+	case config.IsTypedTx(blockNumber):
+		signer = NewCeloSigner(config.ChainID)
 	case config.IsEIP155(blockNumber):
 		signer = NewEIP155Signer(config.ChainID)
 	case config.IsHomestead(blockNumber):
@@ -88,6 +90,39 @@ func Sender(signer Signer, tx *Transaction) (common.Address, error) {
 	return addr, nil
 }
 
+// SignTxAsFeePayer adds the fee payer signature to a sponsored transaction
+// already signed by its sender.
+func SignTxAsFeePayer(tx *Transaction, s CeloSigner, prv *ecdsa.PrivateKey) (*Transaction, error) {
+	h := s.FeePayerHash(tx)
+	sig, err := crypto.Sign(h[:], prv)
+	if err != nil {
+		return nil, err
+	}
+	return tx.WithFeePayerSignature(s, sig)
+}
+
+// FeePayer returns the fee payer of a sponsored transaction, derived from the
+// fee payer signature. Like Sender, it caches the address along with the
+// signer used to derive it. Only a CeloSigner can derive fee payers.
+func FeePayer(signer Signer, tx *Transaction) (common.Address, error) {
+	if sc := tx.payer.Load(); sc != nil {
+		sigCache := sc.(sigCache)
+		if sigCache.signer.Equal(signer) {
+			return sigCache.from, nil
+		}
+	}
+	celo, ok := signer.(CeloSigner)
+	if !ok {
+		return common.Address{}, ErrTxTypeNotSupported
+	}
+	addr, err := celo.FeePayer(tx)
+	if err != nil {
+		return common.Address{}, err
+	}
+	tx.payer.Store(sigCache{signer: signer, from: addr})
+	return addr, nil
+}
+
 // Signer encapsulates transaction signature handling. Note that this interface is not a
 // stable API and may change at any time to accommodate new protocol rules.
 type Signer interface {
@@ -102,6 +137,123 @@ type Signer interface {
 	Equal(Signer) bool
 }
 
+// CeloSigner implements Signer for CeloTxType transactions, and falls back to
+// the EIP155 rules for legacy transactions.
+type CeloSigner struct{ EIP155Signer }
+
+func NewCeloSigner(chainId *big.Int) CeloSigner {
+	return CeloSigner{NewEIP155Signer(chainId)}
+}
+
+func (s CeloSigner) Equal(s2 Signer) bool {
+	celo, ok := s2.(CeloSigner)
+	return ok && celo.chainId.Cmp(s.chainId) == 0
+}
+
+func (s CeloSigner) Sender(tx *Transaction) (common.Address, error) {
+	switch tx.Type() {
+	case LegacyTxType:
+		return s.EIP155Signer.Sender(tx)
+	case CeloTxType, SponsoredTxType:
+	default:
+		return common.Address{}, ErrTxTypeNotSupported
+	}
+	if tx.ChainId().Cmp(s.chainId) != 0 {
+		return common.Address{}, ErrInvalidChainId
+	}
+	// The recovery id is stored as is, recoverPlain expects it offset by 27
+	V := new(big.Int).Add(tx.data.V, big27)
+	return recoverPlain(s.Hash(tx), tx.data.R, tx.data.S, V, true)
+}
+
+// SignatureValues returns signature values. This signature
+// needs to be in the [R || S || V] format where V is 0 or 1.
+func (s CeloSigner) SignatureValues(tx *Transaction, sig []byte) (R, S, V *big.Int, err error) {
+	switch tx.Type() {
+	case LegacyTxType:
+		return s.EIP155Signer.SignatureValues(tx, sig)
+	case CeloTxType, SponsoredTxType:
+	default:
+		return nil, nil, nil, ErrTxTypeNotSupported
+	}
+	if tx.ChainId().Cmp(s.chainId) != 0 {
+		return nil, nil, nil, ErrInvalidChainId
+	}
+	R, S, _, err = HomesteadSigner{}.SignatureValues(tx, sig)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	V = big.NewInt(int64(sig[64]))
+	return R, S, V, nil
+}
+
+// Hash returns the hash to be signed by the sender.
+// It does not uniquely identify the transaction.
+func (s CeloSigner) Hash(tx *Transaction) common.Hash {
+	if tx.Type() == LegacyTxType {
+		return s.EIP155Signer.Hash(tx)
+	}
+	return prefixedRlpHash(tx.Type(), s.signingFields(tx))
+}
+
+// signingFields returns the fields of a typed transaction covered by the
+// sender signature. For sponsored transactions it includes the fee payer.
+func (s CeloSigner) signingFields(tx *Transaction) []interface{} {
+	fields := []interface{}{
+		s.chainId,
+		tx.data.AccountNonce,
+		tx.data.Price,
+		tx.data.GasLimit,
+		tx.data.FeeCurrency,
+		tx.data.GatewayFeeRecipient,
+		tx.data.GatewayFee,
+		tx.data.Recipient,
+		tx.data.Amount,
+		tx.data.Payload,
+	}
+	if tx.Type() == SponsoredTxType {
+		fields = append(fields, tx.data.FeePayer)
+	}
+	return fields
+}
+
+// FeePayerHash returns the hash to be signed by the fee payer of a sponsored
+// transaction. It covers the sender signature, so the fee payer commits to a
+// single transaction of the sender and cannot be replayed.
+func (s CeloSigner) FeePayerHash(tx *Transaction) common.Hash {
+	return prefixedRlpHash(tx.Type(), append(s.signingFields(tx), tx.data.V, tx.data.R, tx.data.S))
+}
+
+// FeePayer returns the fee payer address of a sponsored transaction, derived
+// from the fee payer signature. The recovered address must match the fee payer
+// the sender signed for.
+func (s CeloSigner) FeePayer(tx *Transaction) (common.Address, error) {
+	if tx.Type() != SponsoredTxType {
+		return common.Address{}, ErrTxTypeNotSupported
+	}
+	if tx.ChainId().Cmp(s.chainId) != 0 {
+		return common.Address{}, ErrInvalidChainId
+	}
+	V := new(big.Int).Add(tx.data.PayerV, big27)
+	payer, err := recoverPlain(s.FeePayerHash(tx), tx.data.PayerR, tx.data.PayerS, V, true)
+	if err != nil {
+		return common.Address{}, ErrInvalidFeePayerSig
+	}
+	if payer != *tx.data.FeePayer {
+		return common.Address{}, ErrInvalidFeePayerSig
+	}
+	return payer, nil
+}
+
+// FeePayerSignatureValues returns the fee payer signature values. This
+// signature needs to be in the [R || S || V] format where V is 0 or 1.
+func (s CeloSigner) FeePayerSignatureValues(tx *Transaction, sig []byte) (R, S, V *big.Int, err error) {
+	if tx.Type() != SponsoredTxType {
+		return nil, nil, nil, ErrTxTypeNotSupported
+	}
+	return s.SignatureValues(tx, sig)
+}
+
 // EIP155Transaction implements Signer using the EIP155 rules.
 type EIP155Signer struct {
 	chainId, chainIdMul *big.Int
@@ -122,9 +274,15 @@ func (s EIP155Signer) Equal(s2 Signer) bool {
 	return ok && eip155.chainId.Cmp(s.chainId) == 0
 }
 
-var big8 = big.NewInt(8)
+var (
+	big8  = big.NewInt(8)
+	big27 = big.NewInt(27)
+)
 
 func (s EIP155Signer) Sender(tx *Transaction) (common.Address, error) {
+	if tx.Type() != LegacyTxType {
+		return common.Address{}, ErrTxTypeNotSupported
+	}
 	if !tx.Protected() {
 		return HomesteadSigner{}.Sender(tx)
 	}
@@ -139,6 +297,9 @@ func (s EIP155Signer) Sender(tx *Transaction) (common.Address, error) {
 // SignatureValues returns signature values. This signature
 // needs to be in the [R || S || V] format where V is 0 or 1.
 func (s EIP155Signer) SignatureValues(tx *Transaction, sig []byte) (R, S, V *big.Int, err error) {
+	if tx.Type() != LegacyTxType {
+		return nil, nil, nil, ErrTxTypeNotSupported
+	}
 	R, S, V, err = HomesteadSigner{}.SignatureValues(tx, sig)
 	if err != nil {
 		return nil, nil, nil, err
@@ -183,6 +344,9 @@ func (hs HomesteadSigner) SignatureValues(tx *Transaction, sig []byte) (r, s, v
 }
 
 func (hs HomesteadSigner) Sender(tx *Transaction) (common.Address, error) {
+	if tx.Type() != LegacyTxType {
+		return common.Address{}, ErrTxTypeNotSupported
+	}
 	return recoverPlain(hs.Hash(tx), tx.data.R, tx.data.S, tx.data.V, true)
 }
 
@@ -222,6 +386,9 @@ func (fs FrontierSigner) Hash(tx *Transaction) common.Hash {
 }
 
 func (fs FrontierSigner) Sender(tx *Transaction) (common.Address, error) {
+	if tx.Type() != LegacyTxType {
+		return common.Address{}, ErrTxTypeNotSupported
+	}
 	return recoverPlain(fs.Hash(tx), tx.data.R, tx.data.S, tx.data.V, false)
 }
 
@@ -131,3 +131,99 @@ func TestChainId(t *testing.T) {
 		t.Error("expected no error")
 	}
 }
+
+func TestCeloSigning(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	signer := NewCeloSigner(big.NewInt(18))
+	tx, err := SignTx(NewCeloTransaction(big.NewInt(18), 0, &addr, new(big.Int), 0, new(big.Int), nil, nil, nil, nil), signer, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tx.Protected() {
+		t.Fatal("expected tx to be protected")
+	}
+	if v, _, _ := tx.RawSignatureValues(); v.Uint64() > 1 {
+		t.Errorf("expected recovery id as v, got %d", v)
+	}
+	from, err := Sender(signer, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from != addr {
+		t.Errorf("exected from and address to be equal. Got %x want %x", from, addr)
+	}
+	// Typed transactions must be rejected by the legacy signers and by other chains
+	if _, err := Sender(NewEIP155Signer(big.NewInt(18)), tx); err != ErrTxTypeNotSupported {
+		t.Errorf("expected %v from EIP155 signer, got %v", ErrTxTypeNotSupported, err)
+	}
+	if _, err := Sender(NewCeloSigner(big.NewInt(19)), tx); err != ErrInvalidChainId {
+		t.Errorf("expected %v from signer of other chain, got %v", ErrInvalidChainId, err)
+	}
+	// Legacy transactions keep the EIP155 rules
+	legacy, err := SignTx(NewTransaction(0, addr, new(big.Int), 0, new(big.Int), nil, nil, nil, nil), signer, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from, err := Sender(NewEIP155Signer(big.NewInt(18)), legacy); err != nil || from != addr {
+		t.Errorf("legacy sender mismatch: have %x (%v), want %x", from, err, addr)
+	}
+}
+
+func TestSponsoredSigning(t *testing.T) {
+	senderKey, _ := crypto.GenerateKey()
+	payerKey, _ := crypto.GenerateKey()
+	sender := crypto.PubkeyToAddress(senderKey.PublicKey)
+	payer := crypto.PubkeyToAddress(payerKey.PublicKey)
+
+	signer := NewCeloSigner(big.NewInt(18))
+	tx, err := SignTx(NewSponsoredTransaction(big.NewInt(18), 0, &sender, new(big.Int), 21000, big.NewInt(1), nil, payer, nil, nil, nil), signer, senderKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The fee payer signature is missing until the fee payer countersigns
+	if _, err := FeePayer(signer, tx); err != ErrInvalidFeePayerSig {
+		t.Errorf("expected %v without fee payer signature, got %v", ErrInvalidFeePayerSig, err)
+	}
+	tx, err = SignTxAsFeePayer(tx, signer, payerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from, err := Sender(signer, tx); err != nil || from != sender {
+		t.Errorf("sender mismatch: have %x (%v), want %x", from, err, sender)
+	}
+	if have, err := FeePayer(signer, tx); err != nil || have != payer {
+		t.Errorf("fee payer mismatch: have %x (%v), want %x", have, err, payer)
+	}
+	msg, err := tx.AsMessage(signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.FeePayer() == nil || *msg.FeePayer() != payer {
+		t.Errorf("message fee payer mismatch: have %v, want %x", msg.FeePayer(), payer)
+	}
+	// A fee payer other than the one the sender signed for is rejected
+	forged, err := SignTxAsFeePayer(tx, signer, senderKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := FeePayer(signer, forged); err != ErrInvalidFeePayerSig {
+		t.Errorf("expected %v for forged fee payer, got %v", ErrInvalidFeePayerSig, err)
+	}
+	// The encoding round trip preserves both signatures
+	bin, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Transaction
+	if err := decoded.UnmarshalBinary(bin); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Hash() != tx.Hash() {
+		t.Errorf("hash mismatch after decoding: have %x, want %x", decoded.Hash(), tx.Hash())
+	}
+	if have, err := FeePayer(signer, &decoded); err != nil || have != payer {
+		t.Errorf("decoded fee payer mismatch: have %x (%v), want %x", have, err, payer)
+	}
+}
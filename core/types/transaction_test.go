@@ -308,3 +308,70 @@ func TestTransactionJSON(t *testing.T) {
 		}
 	}
 }
+
+// Tests that typed transactions survive the network, canonical and JSON
+// encodings, and that their hash covers the type byte.
+func TestCeloTransactionEncode(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signer := NewCeloSigner(common.Big1)
+
+	to := common.HexToAddress("b94f5374fce5edbc8e2a8697c15331677e6ebf0b")
+	feeCurrency := common.HexToAddress("0xfee")
+	tx, err := SignTx(NewCeloTransaction(common.Big1, 3, &to, big.NewInt(10), 2000, big.NewInt(1), &feeCurrency, nil, nil, common.FromHex("5544")), signer, key)
+	if err != nil {
+		t.Fatalf("could not sign transaction: %v", err)
+	}
+	bin, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatalf("binary encode error: %v", err)
+	}
+	if bin[0] != CeloTxType {
+		t.Fatalf("type byte mismatch: have %#x, want %#x", bin[0], CeloTxType)
+	}
+	if have, want := tx.Hash(), crypto.Keccak256Hash(bin); have != want {
+		t.Errorf("hash mismatch: have %x, want %x", have, want)
+	}
+	if have, want := tx.Size(), common.StorageSize(len(bin)); have != want {
+		t.Errorf("size mismatch: have %v, want %v", have, want)
+	}
+
+	var decoded Transaction
+	if err := decoded.UnmarshalBinary(bin); err != nil {
+		t.Fatalf("binary decode error: %v", err)
+	}
+	// Typed transactions are embedded in blocks as RLP strings
+	enc, err := rlp.EncodeToBytes(Transactions{tx, rightvrsTx})
+	if err != nil {
+		t.Fatalf("rlp encode error: %v", err)
+	}
+	var txs Transactions
+	if err := rlp.DecodeBytes(enc, &txs); err != nil {
+		t.Fatalf("rlp decode error: %v", err)
+	}
+	data, err := json.Marshal(tx)
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+	var parsed Transaction
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		t.Fatalf("json.Unmarshal failed: %v", err)
+	}
+
+	for i, have := range []*Transaction{&decoded, txs[0], &parsed} {
+		if have.Type() != CeloTxType {
+			t.Errorf("decoding %d: type mismatch: have %d, want %d", i, have.Type(), CeloTxType)
+		}
+		if have.Hash() != tx.Hash() {
+			t.Errorf("decoding %d: hash mismatch: have %x, want %x", i, have.Hash(), tx.Hash())
+		}
+		if *have.FeeCurrency() != feeCurrency {
+			t.Errorf("decoding %d: fee currency mismatch: have %x, want %x", i, have.FeeCurrency(), feeCurrency)
+		}
+		if from, err := Sender(signer, have); err != nil || from != crypto.PubkeyToAddress(key.PublicKey) {
+			t.Errorf("decoding %d: sender mismatch: have %x (%v)", i, from, err)
+		}
+	}
+	if txs[1].Type() != LegacyTxType || txs[1].Hash() != rightvrsTx.Hash() {
+		t.Errorf("legacy transaction mangled by decoding: have %v", txs[1])
+	}
+}
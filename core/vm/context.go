@@ -41,6 +41,9 @@ type Message interface {
 	FeeCurrency() *common.Address
 	GatewayFeeRecipient() *common.Address
 	GatewayFee() *big.Int
+	// FeePayer specifies the account paying the gas and gateway fees instead
+	// of the sender. nil means the sender pays.
+	FeePayer() *common.Address
 	Value() *big.Int
 
 	Nonce() uint64
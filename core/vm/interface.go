@@ -62,6 +62,7 @@ type StateDB interface {
 
 	AddLog(*types.Log)
 	AddPreimage(common.Hash, []byte)
+	AddFeeCredit(feeCurrency, recipient common.Address, amount *big.Int)
 
 	ForEachStorage(common.Address, func(common.Hash, common.Hash) bool) error
 
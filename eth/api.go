@@ -75,6 +75,54 @@ func (api *PublicEthereumAPI) ChainId() hexutil.Uint64 {
 	return (hexutil.Uint64)(chainID.Uint64())
 }
 
+// FeeCurrencyWhitelistChange is a change of the fee currency whitelist, sent to
+// the subscribers of the feeCurrencyWhitelistChanged notifications.
+type FeeCurrencyWhitelistChange struct {
+	BlockNumber hexutil.Uint64   `json:"blockNumber"`
+	BlockHash   common.Hash      `json:"blockHash"`
+	Whitelist   []common.Address `json:"whitelist"`
+	Added       []common.Address `json:"added"`
+	Removed     []common.Address `json:"removed"`
+}
+
+// FeeCurrencyWhitelistChanged sends a notification each time the fee currency
+// whitelist changes, as seen by the transaction pool.
+func (api *PublicEthereumAPI) FeeCurrencyWhitelistChanged(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	// Subscribe at once so that no change is missed once the subscription is returned
+	changes := make(chan core.FeeCurrencyWhitelistChangedEvent)
+	changesSub := api.e.TxPool().SubscribeFeeCurrencyWhitelistChangedEvent(changes)
+
+	go func() {
+		for {
+			select {
+			case ev := <-changes:
+				notifier.Notify(rpcSub.ID, &FeeCurrencyWhitelistChange{
+					BlockNumber: hexutil.Uint64(ev.Header.Number.Uint64()),
+					BlockHash:   ev.Header.Hash(),
+					Whitelist:   ev.Whitelist,
+					Added:       ev.Added,
+					Removed:     ev.Removed,
+				})
+			case <-rpcSub.Err():
+				changesSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				changesSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
 // PublicMinerAPI provides an API to control the miner.
 // It offers only methods that operate on data that pose no security risk when it is publicly accessible.
 type PublicMinerAPI struct {
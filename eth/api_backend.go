@@ -242,6 +242,10 @@ func (b *EthAPIBackend) GetTransaction(ctx context.Context, txHash common.Hash)
 	return tx, blockHash, blockNumber, index, nil
 }
 
+func (b *EthAPIBackend) GetTransactionBySenderAndNonce(ctx context.Context, sender common.Address, nonce uint64) (*types.Transaction, common.Hash, uint64, uint64, error) {
+	return b.eth.transactionBySenderAndNonce(sender, nonce)
+}
+
 func (b *EthAPIBackend) GetPoolNonce(ctx context.Context, addr common.Address) (uint64, error) {
 	return b.eth.txPool.Nonce(addr), nil
 }
@@ -309,6 +313,10 @@ func (b *EthAPIBackend) GatewayFeeRecipient() common.Address {
 	return b.eth.GatewayFeeRecipient()
 }
 
-func (b *EthAPIBackend) GatewayFee() *big.Int {
-	return b.eth.GatewayFee()
+func (b *EthAPIBackend) GatewayFee() (*big.Int, error) {
+	return b.eth.GatewayFee(), nil
+}
+
+func (b *EthAPIBackend) DefaultFeeCurrency() *common.Address {
+	return b.eth.config.DefaultFeeCurrency
 }
@@ -18,17 +18,26 @@ package eth
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"math/big"
 	"reflect"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/contract_comm"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 var dumper = spew.ConfigState{Indent: "    "}
@@ -245,3 +254,102 @@ func TestStorageRangeAt(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the changes of the fee currency whitelist made by the blocks are
+// notified to the RPC subscribers.
+func TestFeeCurrencyWhitelistChanged(t *testing.T) {
+	var (
+		db        = rawdb.NewMemoryDatabase()
+		engine    = ethash.NewFaker()
+		signer    = types.HomesteadSigner{}
+		whitelist = common.HexToAddress("0x8000")
+		removed   = common.HexToAddress("0x9000")
+		added     = common.HexToAddress("0xa000")
+	)
+	// The registry returns the address stored at the slot of the registry id
+	registryCode := []byte{
+		byte(vm.PUSH1), 4, byte(vm.CALLDATALOAD), byte(vm.SLOAD),
+		byte(vm.PUSH1), 0, byte(vm.MSTORE), byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.RETURN),
+	}
+	// The whitelist contains the currency stored at its first slot, which is
+	// replaced by the one sent to it
+	setCode := append([]byte{
+		byte(vm.PUSH1), 0, byte(vm.CALLDATALOAD), byte(vm.PUSH1), 0, byte(vm.SSTORE), byte(vm.PUSH32),
+	}, crypto.Keccak256([]byte("FeeCurrencyWhitelisted(address)"))...)
+	setCode = append(setCode, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.LOG1), byte(vm.STOP))
+	whitelistCode := []byte{byte(vm.CALLDATASIZE), byte(vm.PUSH1), 4, byte(vm.EQ), byte(vm.PUSH1), byte(7 + len(setCode)), byte(vm.JUMPI)}
+	whitelistCode = append(whitelistCode, setCode...)
+	whitelistCode = append(whitelistCode,
+		byte(vm.JUMPDEST), byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.MSTORE), byte(vm.PUSH1), 1, byte(vm.PUSH1), 32, byte(vm.MSTORE),
+		byte(vm.PUSH1), 0, byte(vm.SLOAD), byte(vm.PUSH1), 64, byte(vm.MSTORE), byte(vm.PUSH1), 96, byte(vm.PUSH1), 0, byte(vm.RETURN),
+	)
+	genesis := (&core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			testBank: {Balance: big.NewInt(1000000000)},
+			params.RegistrySmartContractAddress: {
+				Balance: new(big.Int),
+				Code:    registryCode,
+				Storage: map[common.Hash]common.Hash{params.FeeCurrencyWhitelistRegistryId: common.BytesToHash(whitelist.Bytes())},
+			},
+			whitelist: {
+				Balance: new(big.Int),
+				Code:    whitelistCode,
+				Storage: map[common.Hash]common.Hash{{}: common.BytesToHash(removed.Bytes())},
+			},
+		},
+	}).MustCommit(db)
+
+	blockchain, err := core.NewBlockChain(db, nil, params.TestChainConfig, engine, vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer blockchain.Stop()
+	contract_comm.SetInternalEVMHandler(blockchain)
+
+	config := core.DefaultTxPoolConfig
+	config.Journal = ""
+	pool := core.NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	// Subscribe to the whitelist changes over RPC
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("eth", NewPublicEthereumAPI(&Ethereum{txPool: pool})); err != nil {
+		t.Fatalf("failed to register API: %v", err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	changes := make(chan *FeeCurrencyWhitelistChange)
+	sub, err := client.EthSubscribe(context.Background(), changes, "feeCurrencyWhitelistChanged")
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	// Replace the whitelisted currency in a block
+	chain, _ := core.GenerateChain(params.TestChainConfig, genesis, engine, db, 1, func(i int, block *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(0, whitelist, new(big.Int), 100000, big.NewInt(1), nil, nil, nil, common.BytesToHash(added.Bytes()).Bytes()), signer, testBankKey)
+		block.AddTx(tx)
+	})
+	if _, err := blockchain.InsertChain(chain); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	select {
+	case change := <-changes:
+		if uint64(change.BlockNumber) != 1 || change.BlockHash != chain[0].Hash() {
+			t.Errorf("change block mismatch: have #%d %x, want #1 %x", change.BlockNumber, change.BlockHash, chain[0].Hash())
+		}
+		if len(change.Whitelist) != 1 || change.Whitelist[0] != added {
+			t.Errorf("whitelist mismatch: have %v, want [%v]", change.Whitelist, added)
+		}
+		if len(change.Added) != 1 || change.Added[0] != added || len(change.Removed) != 1 || change.Removed[0] != removed {
+			t.Errorf("change mismatch: have added %v removed %v", change.Added, change.Removed)
+		}
+	case err := <-sub.Err():
+		t.Fatalf("subscription failed: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("whitelist change not notified")
+	}
+}
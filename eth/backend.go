@@ -63,6 +63,8 @@ type LesServer interface {
 	Protocols() []p2p.Protocol
 	SetBloomBitsIndexer(bbIndexer *core.ChainIndexer)
 	SetContractBackend(bind.ContractBackend)
+	SetServingLimits(lightServ, lightPeers int)
+	SetGatewayFee(gatewayFee *big.Int)
 }
 
 // Ethereum implements the Ethereum full node service.
@@ -88,6 +90,8 @@ type Ethereum struct {
 	bloomRequests chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
 	bloomIndexer  *core.ChainIndexer             // Bloom indexer operating during block imports
 
+	txSenderIndexer *core.ChainIndexer // Sender and nonce transaction indexer, nil if disabled
+
 	APIBackend *EthAPIBackend
 
 	miner      *miner.Miner
@@ -107,6 +111,25 @@ func (s *Ethereum) AddLesServer(ls LesServer) {
 	ls.SetBloomBitsIndexer(s.bloomIndexer)
 }
 
+// SetLightServing updates the serving limits and the minimum gateway fee of the
+// light server. It fails if light serving was not enabled on startup, as
+// enabling or disabling it requires a restart.
+func (s *Ethereum) SetLightServing(lightServ, lightPeers int, gatewayFee *big.Int) error {
+	if s.lesServer == nil {
+		return errors.New("light serving is disabled")
+	}
+	if lightServ <= 0 {
+		return errors.New("disabling light serving requires a restart")
+	}
+	s.lesServer.SetServingLimits(lightServ, lightPeers)
+	s.lesServer.SetGatewayFee(gatewayFee)
+
+	s.lock.Lock()
+	s.gatewayFee = gatewayFee
+	s.lock.Unlock()
+	return nil
+}
+
 // SetClient sets a rpc client which connecting to our local node.
 func (s *Ethereum) SetContractBackend(backend bind.ContractBackend) {
 	// Pass the rpc client to les server if it is enabled.
@@ -185,6 +208,7 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 	var (
 		vmConfig = vm.Config{
 			EnablePreimageRecording: config.EnablePreimageRecording,
+			ParallelTxExecution:     config.ParallelTxExecution,
 			EWASMInterpreter:        config.EWASMInterpreter,
 			EVMInterpreter:          config.EVMInterpreter,
 		}
@@ -207,6 +231,10 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 		rawdb.WriteChainConfig(chainDb, genesisHash, chainConfig)
 	}
 	eth.bloomIndexer.Start(eth.blockchain)
+	if config.TxSenderIndex {
+		eth.txSenderIndexer = NewTxSenderIndexer(chainDb, chainConfig, fullHeaderChainAvailable)
+		eth.txSenderIndexer.Start(eth.blockchain)
+	}
 
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = ctx.ResolvePath(config.TxPool.Journal)
@@ -551,7 +579,7 @@ func (s *Ethereum) StartMining(threads int) error {
 				log.Error("BLSbase account unavailable locally", "err", err)
 				return fmt.Errorf("BLS signer missing: %v", err)
 			}
-			istanbul.Authorize(eb, wallet.SignData, blswallet.SignHashBLS, blswallet.SignMessageBLS)
+			istanbul.Authorize(eb, wallet.Decrypt, wallet.SignData, blswallet.SignHashBLS, blswallet.SignMessageBLS)
 		}
 
 		// If mining is started, we can disable the transaction rejection mechanism
@@ -620,6 +648,11 @@ func (s *Ethereum) Protocols() []p2p.Protocol {
 		protos[i] = s.protocolManager.makeProtocol(vsn, i == 0)
 		protos[i].Attributes = []enr.Entry{s.currentEthEntry()}
 	}
+	if _, ok := s.engine.(consensus.Handler); ok {
+		for _, vsn := range ConsensusProtocolVersions {
+			protos = append(protos, s.protocolManager.makeConsensusProtocol(vsn))
+		}
+	}
 	if s.lesServer != nil {
 		protos = append(protos, s.lesServer.Protocols()...)
 	}
@@ -662,6 +695,9 @@ func (s *Ethereum) Start(srvr *p2p.Server) error {
 // Ethereum protocol.
 func (s *Ethereum) Stop() error {
 	s.bloomIndexer.Close()
+	if s.txSenderIndexer != nil {
+		s.txSenderIndexer.Close()
+	}
 	s.blockchain.Stop()
 	s.engine.Close()
 	s.protocolManager.Stop()
@@ -0,0 +1,259 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/p2p"
+)
+
+const (
+	// maxCompactBlockCache is the number of recently propagated blocks kept around
+	// to serve requests for the transactions of compact blocks.
+	maxCompactBlockCache = 16
+
+	// compactBlockTimeout is the time a peer has to deliver the missing
+	// transactions of a compact block before it is retrieved by the fetcher.
+	compactBlockTimeout = 5 * time.Second
+)
+
+// pendingCompactBlock is a compact block which is waiting for the transactions
+// that were not found in the local pool to be delivered by the announcing peer.
+type pendingCompactBlock struct {
+	request    *compactBlockData
+	txs        []*types.Transaction // Transactions of the block, nil where still missing
+	missing    []uint64             // Indexes of the requested transactions
+	receivedAt time.Time
+	requested  time.Time // Time the missing transactions were requested at
+}
+
+// compactTxLookup indexes the pending transactions of the pool by short
+// identifier. It is rebuilt from the pool when the chain head changes, and
+// extended with the transactions added to the pool in between.
+type compactTxLookup struct {
+	head common.Hash
+	txs  map[shortTxID]*types.Transaction
+	lock sync.Mutex
+}
+
+// add indexes transactions newly added to the pool.
+func (l *compactTxLookup) add(txs []*types.Transaction) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	// Nothing to extend until built, the pool is read on first use
+	if l.txs == nil {
+		return
+	}
+	for _, tx := range txs {
+		l.txs[txShortID(tx.Hash())] = tx
+	}
+}
+
+// newCompactBlockData creates the compact block announcement of a block.
+func newCompactBlockData(block *types.Block, td *big.Int) *compactBlockData {
+	txs := block.Transactions()
+	ids := make([]shortTxID, len(txs))
+	for i, tx := range txs {
+		ids[i] = txShortID(tx.Hash())
+	}
+	return &compactBlockData{
+		Header:         block.Header(),
+		TxIDs:          ids,
+		Randomness:     block.Randomness(),
+		EpochSnarkData: block.EpochSnarkData(),
+		TD:             td,
+	}
+}
+
+// handleCompactBlock rebuilds an announced compact block from the transactions
+// in the local pool, requesting any missing ones from the announcing peer.
+func (pm *ProtocolManager) handleCompactBlock(p *peer, msg p2p.Msg) error {
+	var request compactBlockData
+	if err := msg.Decode(&request); err != nil {
+		return errResp(ErrDecode, "%v: %v", msg, err)
+	}
+	if err := request.sanityCheck(); err != nil {
+		return err
+	}
+	hash := request.Header.Hash()
+	p.MarkBlock(hash)
+
+	if pm.blockchain.HasBlock(hash, request.Header.Number.Uint64()) {
+		return nil
+	}
+	// Resolve the transactions against the pool
+	block := &pendingCompactBlock{
+		request:    &request,
+		txs:        make([]*types.Transaction, len(request.TxIDs)),
+		receivedAt: msg.ReceivedAt,
+	}
+	if err := pm.resolveCompactTxs(block); err != nil {
+		return err
+	}
+	if len(block.missing) == 0 {
+		pm.assembleCompactBlock(p, block)
+		return nil
+	}
+	// Some transactions are unknown, request them from the peer
+	pm.expireCompactBlocks(p)
+	if _, ok := p.compactBlocks[hash]; !ok && len(p.compactBlocks) >= maxPendingCompactBlocks {
+		p.Log().Debug("Too many pending compact blocks, falling back to fetcher", "hash", hash)
+		pm.fetchCompactBlock(p, &request)
+		return nil
+	}
+	block.requested = time.Now()
+	p.compactBlocks[hash] = block
+	return p.RequestBlockTxs(hash, block.missing)
+}
+
+// resolveCompactTxs fills in the transactions of a compact block found in the
+// pool, recording the indexes of the missing ones.
+func (pm *ProtocolManager) resolveCompactTxs(block *pendingCompactBlock) error {
+	lookup := &pm.compactTxs
+	lookup.lock.Lock()
+	defer lookup.lock.Unlock()
+
+	if head := pm.blockchain.CurrentBlock().Hash(); lookup.txs == nil || lookup.head != head {
+		pending, err := pm.txpool.Pending()
+		if err != nil {
+			return err
+		}
+		lookup.head, lookup.txs = head, make(map[shortTxID]*types.Transaction)
+		for _, txs := range pending {
+			for _, tx := range txs {
+				lookup.txs[txShortID(tx.Hash())] = tx
+			}
+		}
+	}
+	for i, id := range block.request.TxIDs {
+		if tx, ok := lookup.txs[id]; ok {
+			block.txs[i] = tx
+		} else {
+			block.missing = append(block.missing, uint64(i))
+		}
+	}
+	return nil
+}
+
+// expireCompactBlocks retrieves the compact blocks whose missing transactions
+// the peer failed to deliver in time through the fetcher instead.
+func (pm *ProtocolManager) expireCompactBlocks(p *peer) {
+	for hash, block := range p.compactBlocks {
+		if time.Since(block.requested) > compactBlockTimeout {
+			p.Log().Debug("Compact block transactions timed out, falling back to fetcher", "hash", hash)
+			delete(p.compactBlocks, hash)
+			pm.fetchCompactBlock(p, block.request)
+		}
+	}
+}
+
+// handleGetBlockTxs serves the transactions of a recently propagated block.
+func (pm *ProtocolManager) handleGetBlockTxs(p *peer, msg p2p.Msg) error {
+	var request getBlockTxsData
+	if err := msg.Decode(&request); err != nil {
+		return errResp(ErrDecode, "%v: %v", msg, err)
+	}
+	var block *types.Block
+	if cached, ok := pm.compactBlockCache.Get(request.Hash); ok {
+		block = cached.(*types.Block)
+	} else if block = pm.blockchain.GetBlockByHash(request.Hash); block == nil {
+		// We don't know the block, send an empty response so the peer can fall back
+		return p.SendBlockTxs(request.Hash, nil)
+	}
+	// The indexes are requested in ascending order, so no transaction can be served
+	// more than once in a single response
+	txs := block.Transactions()
+	if len(request.Indexes) > len(txs) {
+		return errResp(ErrDecode, "%d transactions requested from block with %d", len(request.Indexes), len(txs))
+	}
+	response := make([]*types.Transaction, 0, len(request.Indexes))
+	for i, index := range request.Indexes {
+		if index >= uint64(len(txs)) {
+			return errResp(ErrDecode, "transaction index %d out of range", index)
+		}
+		if i > 0 && index <= request.Indexes[i-1] {
+			return errResp(ErrDecode, "transaction index %d not ascending", index)
+		}
+		response = append(response, txs[index])
+	}
+	return p.SendBlockTxs(request.Hash, response)
+}
+
+// handleBlockTxs completes a pending compact block with the delivered transactions.
+func (pm *ProtocolManager) handleBlockTxs(p *peer, msg p2p.Msg) error {
+	var response blockTxsData
+	if err := msg.Decode(&response); err != nil {
+		return errResp(ErrDecode, "%v: %v", msg, err)
+	}
+	pm.expireCompactBlocks(p)
+	block, ok := p.compactBlocks[response.Hash]
+	if !ok {
+		// Unrequested or already completed, ignore
+		return nil
+	}
+	delete(p.compactBlocks, response.Hash)
+
+	if len(response.Txs) != len(block.missing) {
+		p.Log().Debug("Incomplete compact block transactions, falling back to fetcher", "hash", response.Hash, "have", len(response.Txs), "want", len(block.missing))
+		pm.fetchCompactBlock(p, block.request)
+		return nil
+	}
+	for i, index := range block.missing {
+		if response.Txs[i] == nil {
+			return errResp(ErrDecode, "transaction %d is nil", i)
+		}
+		block.txs[index] = response.Txs[i]
+	}
+	pm.assembleCompactBlock(p, block)
+	return nil
+}
+
+// assembleCompactBlock builds the full block out of a compact block with all its
+// transactions resolved, and schedules it for import. If the transactions do not
+// match the header, e.g. due to a short identifier collision, the block is
+// retrieved through the fetcher instead.
+func (pm *ProtocolManager) assembleCompactBlock(p *peer, compact *pendingCompactBlock) {
+	request := compact.request
+	if types.DeriveSha(types.Transactions(compact.txs)) != request.Header.TxHash {
+		p.Log().Debug("Compact block transactions mismatch, falling back to fetcher", "hash", request.Header.Hash())
+		pm.fetchCompactBlock(p, request)
+		return
+	}
+	block := types.NewBlockWithHeader(request.Header).WithBody(compact.txs, nil, request.Randomness, request.EpochSnarkData)
+	block.ReceivedAt = compact.receivedAt
+	block.ReceivedFrom = p
+
+	pm.importPropagatedBlock(p, block, request.TD)
+}
+
+// fetchCompactBlock schedules the announced block for retrieval by the fetcher,
+// as if only its hash had been announced.
+func (pm *ProtocolManager) fetchCompactBlock(p *peer, request *compactBlockData) {
+	pm.fetcher.Notify(p.id, request.Header.Hash(), request.Header.Number.Uint64(), time.Now(), p.RequestOneHeader, p.RequestBodies)
+}
+
+// cacheCompactBlock remembers a block announced in compact form, so requests for
+// its transactions can be served before it is imported.
+func (pm *ProtocolManager) cacheCompactBlock(block *types.Block) {
+	pm.compactBlockCache.Add(block.Hash(), block)
+}
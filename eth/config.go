@@ -99,6 +99,9 @@ type Config struct {
 	NoPruning  bool // Whether to disable pruning and flush everything to disk
 	NoPrefetch bool // Whether to disable prefetching and only load state on demand
 
+	// Whether to index the transactions by sender and nonce
+	TxSenderIndex bool `toml:",omitempty"`
+
 	// Whitelist of required block number -> hash values to accept
 	Whitelist map[uint64]common.Hash `toml:"-"`
 
@@ -107,6 +110,12 @@ type Config struct {
 	LightIngress int `toml:",omitempty"` // Incoming bandwidth limit for light servers
 	LightEgress  int `toml:",omitempty"` // Outgoing bandwidth limit for light servers
 	LightPeers   int `toml:",omitempty"` // Maximum number of LES client peers
+	// List of light servers a light client prefers to connect to
+	LightServers []string `toml:",omitempty"`
+	// Maximum gateway fee a light client accepts to pay for its transactions
+	MaxGatewayFee *big.Int `toml:",omitempty"`
+	// Fee currency of the transactions that don't specify one. Nil means the native token.
+	DefaultFeeCurrency *common.Address `toml:",omitempty"`
 	// Minimum gateway fee value to serve a transaction from a light client
 	GatewayFee *big.Int `toml:",omitempty"`
 	// Etherbase is the GatewayFeeRecipient light clients need to specify in order for their transactions to be accepted by this node.
@@ -141,6 +150,9 @@ type Config struct {
 	// Enables tracking of SHA3 preimages in the VM
 	EnablePreimageRecording bool
 
+	// Enables optimistic parallel execution of block transactions
+	ParallelTxExecution bool
+
 	// Istanbul options
 	Istanbul istanbul.Config
 
@@ -23,11 +23,18 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		SyncMode                downloader.SyncMode
 		NoPruning               bool
 		NoPrefetch              bool
+		TxSenderIndex           bool                   `toml:",omitempty"`
 		Whitelist               map[uint64]common.Hash `toml:"-"`
 		LightServ               int                    `toml:",omitempty"`
 		LightIngress            int                    `toml:",omitempty"`
 		LightEgress             int                    `toml:",omitempty"`
 		LightPeers              int                    `toml:",omitempty"`
+		LightServers            []string               `toml:",omitempty"`
+		MaxGatewayFee           *big.Int               `toml:",omitempty"`
+		DefaultFeeCurrency      *common.Address        `toml:",omitempty"`
+		GatewayFee              *big.Int               `toml:",omitempty"`
+		Etherbase               common.Address         `toml:",omitempty"`
+		BLSbase                 common.Address         `toml:",omitempty"`
 		UltraLightServers       []string               `toml:",omitempty"`
 		UltraLightFraction      int                    `toml:",omitempty"`
 		UltraLightOnlyAnnounce  bool                   `toml:",omitempty"`
@@ -42,6 +49,7 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		Ethash                  ethash.Config
 		TxPool                  core.TxPoolConfig
 		EnablePreimageRecording bool
+		ParallelTxExecution     bool
 		DocRoot                 string `toml:"-"`
 		EWASMInterpreter        string
 		EVMInterpreter          string
@@ -49,6 +57,7 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		RPCGasCap               *big.Int                       `toml:",omitempty"`
 		Checkpoint              *params.TrustedCheckpoint      `toml:",omitempty"`
 		CheckpointOracle        *params.CheckpointOracleConfig `toml:",omitempty"`
+		OverrideIstanbul        *big.Int
 	}
 	var enc Config
 	enc.Genesis = c.Genesis
@@ -56,11 +65,18 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.SyncMode = c.SyncMode
 	enc.NoPruning = c.NoPruning
 	enc.NoPrefetch = c.NoPrefetch
+	enc.TxSenderIndex = c.TxSenderIndex
 	enc.Whitelist = c.Whitelist
 	enc.LightServ = c.LightServ
 	enc.LightIngress = c.LightIngress
 	enc.LightEgress = c.LightEgress
 	enc.LightPeers = c.LightPeers
+	enc.LightServers = c.LightServers
+	enc.MaxGatewayFee = c.MaxGatewayFee
+	enc.DefaultFeeCurrency = c.DefaultFeeCurrency
+	enc.GatewayFee = c.GatewayFee
+	enc.Etherbase = c.Etherbase
+	enc.BLSbase = c.BLSbase
 	enc.UltraLightServers = c.UltraLightServers
 	enc.UltraLightFraction = c.UltraLightFraction
 	enc.UltraLightOnlyAnnounce = c.UltraLightOnlyAnnounce
@@ -75,6 +91,7 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.Ethash = c.Ethash
 	enc.TxPool = c.TxPool
 	enc.EnablePreimageRecording = c.EnablePreimageRecording
+	enc.ParallelTxExecution = c.ParallelTxExecution
 	enc.Istanbul = c.Istanbul
 	enc.DocRoot = c.DocRoot
 	enc.EWASMInterpreter = c.EWASMInterpreter
@@ -82,6 +99,7 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.RPCGasCap = c.RPCGasCap
 	enc.Checkpoint = c.Checkpoint
 	enc.CheckpointOracle = c.CheckpointOracle
+	enc.OverrideIstanbul = c.OverrideIstanbul
 	return &enc, nil
 }
 
@@ -93,11 +111,18 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		SyncMode                *downloader.SyncMode
 		NoPruning               *bool
 		NoPrefetch              *bool
+		TxSenderIndex           *bool                  `toml:",omitempty"`
 		Whitelist               map[uint64]common.Hash `toml:"-"`
 		LightServ               *int                   `toml:",omitempty"`
 		LightIngress            *int                   `toml:",omitempty"`
 		LightEgress             *int                   `toml:",omitempty"`
 		LightPeers              *int                   `toml:",omitempty"`
+		LightServers            []string               `toml:",omitempty"`
+		MaxGatewayFee           *big.Int               `toml:",omitempty"`
+		DefaultFeeCurrency      *common.Address        `toml:",omitempty"`
+		GatewayFee              *big.Int               `toml:",omitempty"`
+		Etherbase               *common.Address        `toml:",omitempty"`
+		BLSbase                 *common.Address        `toml:",omitempty"`
 		UltraLightServers       []string               `toml:",omitempty"`
 		UltraLightFraction      *int                   `toml:",omitempty"`
 		UltraLightOnlyAnnounce  *bool                  `toml:",omitempty"`
@@ -112,6 +137,7 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		Ethash                  *ethash.Config
 		TxPool                  *core.TxPoolConfig
 		EnablePreimageRecording *bool
+		ParallelTxExecution     *bool
 		DocRoot                 *string `toml:"-"`
 		EWASMInterpreter        *string
 		EVMInterpreter          *string
@@ -119,6 +145,7 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		RPCGasCap               *big.Int                       `toml:",omitempty"`
 		Checkpoint              *params.TrustedCheckpoint      `toml:",omitempty"`
 		CheckpointOracle        *params.CheckpointOracleConfig `toml:",omitempty"`
+		OverrideIstanbul        *big.Int
 	}
 	var dec Config
 	if err := unmarshal(&dec); err != nil {
@@ -139,6 +166,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.NoPrefetch != nil {
 		c.NoPrefetch = *dec.NoPrefetch
 	}
+	if dec.TxSenderIndex != nil {
+		c.TxSenderIndex = *dec.TxSenderIndex
+	}
 	if dec.Whitelist != nil {
 		c.Whitelist = dec.Whitelist
 	}
@@ -154,6 +184,24 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.LightPeers != nil {
 		c.LightPeers = *dec.LightPeers
 	}
+	if dec.LightServers != nil {
+		c.LightServers = dec.LightServers
+	}
+	if dec.MaxGatewayFee != nil {
+		c.MaxGatewayFee = dec.MaxGatewayFee
+	}
+	if dec.DefaultFeeCurrency != nil {
+		c.DefaultFeeCurrency = dec.DefaultFeeCurrency
+	}
+	if dec.GatewayFee != nil {
+		c.GatewayFee = dec.GatewayFee
+	}
+	if dec.Etherbase != nil {
+		c.Etherbase = *dec.Etherbase
+	}
+	if dec.BLSbase != nil {
+		c.BLSbase = *dec.BLSbase
+	}
 	if dec.UltraLightServers != nil {
 		c.UltraLightServers = dec.UltraLightServers
 	}
@@ -196,6 +244,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.EnablePreimageRecording != nil {
 		c.EnablePreimageRecording = *dec.EnablePreimageRecording
 	}
+	if dec.ParallelTxExecution != nil {
+		c.ParallelTxExecution = *dec.ParallelTxExecution
+	}
 	if dec.Istanbul != nil {
 		c.Istanbul = *dec.Istanbul
 	}
@@ -217,5 +268,8 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.CheckpointOracle != nil {
 		c.CheckpointOracle = dec.CheckpointOracle
 	}
+	if dec.OverrideIstanbul != nil {
+		c.OverrideIstanbul = dec.OverrideIstanbul
+	}
 	return nil
 }
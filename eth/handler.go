@@ -42,6 +42,7 @@ import (
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/trie"
+	lru "github.com/hashicorp/golang-lru"
 )
 
 const (
@@ -103,6 +104,9 @@ type ProtocolManager struct {
 
 	server      *p2p.Server
 	proxyServer *p2p.Server
+
+	compactBlockCache *lru.Cache      // Recently propagated blocks, for serving compact block transactions
+	compactTxs        compactTxLookup // Pending transactions by short identifier, for resolving compact blocks
 }
 
 // NewProtocolManager returns a new Ethereum sub protocol manager. The Ethereum sub protocol manages peers capable
@@ -127,6 +131,7 @@ func NewProtocolManager(config *params.ChainConfig, checkpoint *params.TrustedCh
 		server:      server,
 		proxyServer: proxyServer,
 	}
+	manager.compactBlockCache, _ = lru.New(maxCompactBlockCache)
 
 	if handler, ok := manager.engine.(consensus.Handler); ok {
 		handler.SetBroadcaster(manager)
@@ -242,6 +247,79 @@ func (pm *ProtocolManager) makeProtocol(version uint, primary bool) p2p.Protocol
 	}
 }
 
+// makeConsensusProtocol creates the dedicated consensus sub-protocol. It is only
+// meaningful if the consensus engine implements consensus.Handler.
+func (pm *ProtocolManager) makeConsensusProtocol(version uint) p2p.Protocol {
+	return p2p.Protocol{
+		Name:      ConsensusProtocolName,
+		Version:   version,
+		Length:    consensusProtocolLength,
+		Auxiliary: true,
+		Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+			pm.wg.Add(1)
+			defer pm.wg.Done()
+			return pm.handleConsensus(p, rw)
+		},
+		NodeInfo: func() interface{} {
+			return nil
+		},
+		PeerInfo: func(id enode.ID) interface{} {
+			return nil
+		},
+	}
+}
+
+// handleConsensus is the callback invoked to manage the life cycle of a consensus
+// sub-protocol stream. The stream is attached to the peer's eth session, and its
+// messages are read on their own goroutine and handed to the consensus engine.
+func (pm *ProtocolManager) handleConsensus(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+	handler, ok := pm.engine.(consensus.Handler)
+	if !ok {
+		return p2p.DiscUselessPeer
+	}
+	id := fmt.Sprintf("%x", p.ID().Bytes()[:8])
+	if err := pm.peers.RegisterConsensus(id, rw); err != nil {
+		return err
+	}
+	defer pm.peers.UnregisterConsensus(id)
+
+	addr := crypto.PubkeyToAddress(*p.Node().Pubkey())
+	for {
+		if err := pm.handleConsensusMsg(handler, addr, id, rw); err != nil {
+			p.Log().Debug("Consensus message handling failed", "err", err)
+			return err
+		}
+	}
+}
+
+// handleConsensusMsg is invoked whenever an inbound message is received over the
+// consensus sub-protocol. The remote connection is torn down upon returning any error.
+func (pm *ProtocolManager) handleConsensusMsg(handler consensus.Handler, addr common.Address, id string, rw p2p.MsgReadWriter) error {
+	msg, err := rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	if msg.Size > consensusMaxMsgSize {
+		return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, consensusMaxMsgSize)
+	}
+	defer msg.Discard()
+
+	if msg.Code < firstConsensusMsg {
+		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
+	}
+	// Messages may only be handled once the peer completed the eth handshake
+	p := pm.peers.Peer(id)
+	if p == nil {
+		log.Trace("Dropping consensus message from unregistered peer", "peer", id, "code", msg.Code)
+		return nil
+	}
+	handled, err := handler.HandleMsg(addr, msg, p)
+	if !handled {
+		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
+	}
+	return err
+}
+
 func (pm *ProtocolManager) removePeer(id string) {
 	// Short circuit if the peer was already removed
 	peer := pm.peers.Peer(id)
@@ -741,28 +819,16 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		request.Block.ReceivedAt = msg.ReceivedAt
 		request.Block.ReceivedFrom = p
 
-		// Mark the peer as owning the block and schedule it for import
-		p.MarkBlock(request.Block.Hash())
-		pm.fetcher.Enqueue(p.id, request.Block)
+		pm.importPropagatedBlock(p, request.Block, request.TD)
 
-		// Assuming the block is importable by the peer, but possibly not yet done so,
-		// calculate the head hash and TD that the peer truly must have.
-		var (
-			trueHead = request.Block.ParentHash()
-			trueTD   = new(big.Int).Sub(request.TD, request.Block.Difficulty())
-		)
-		// Update the peer's total difficulty if better than the previous
-		if _, td := p.Head(); trueTD.Cmp(td) > 0 {
-			p.SetHead(trueHead, trueTD)
-
-			// Schedule a sync if above ours. Note, this will not fire a sync for a gap of
-			// a single block (as the true TD is below the propagated block), however this
-			// scenario should easily be covered by the fetcher.
-			currentBlock := pm.blockchain.CurrentBlock()
-			if trueTD.Cmp(pm.blockchain.GetTd(currentBlock.Hash(), currentBlock.NumberU64())) > 0 {
-				go pm.synchronise(p)
-			}
-		}
+	case p.version >= celo66 && msg.Code == CompactBlockMsg:
+		return pm.handleCompactBlock(p, msg)
+
+	case p.version >= celo66 && msg.Code == GetBlockTxsMsg:
+		return pm.handleGetBlockTxs(p, msg)
+
+	case p.version >= celo66 && msg.Code == BlockTxsMsg:
+		return pm.handleBlockTxs(p, msg)
 
 	case msg.Code == TxMsg:
 		// Transactions arrived, make sure we have a valid and fresh chain to handle them
@@ -789,6 +855,33 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 	return nil
 }
 
+// importPropagatedBlock schedules a block propagated by a peer for import, and
+// updates the peer's head if the block's total difficulty is better.
+func (pm *ProtocolManager) importPropagatedBlock(p *peer, block *types.Block, td *big.Int) {
+	// Mark the peer as owning the block and schedule it for import
+	p.MarkBlock(block.Hash())
+	pm.fetcher.Enqueue(p.id, block)
+
+	// Assuming the block is importable by the peer, but possibly not yet done so,
+	// calculate the head hash and TD that the peer truly must have.
+	var (
+		trueHead = block.ParentHash()
+		trueTD   = new(big.Int).Sub(td, block.Difficulty())
+	)
+	// Update the peer's total difficulty if better than the previous
+	if _, td := p.Head(); trueTD.Cmp(td) > 0 {
+		p.SetHead(trueHead, trueTD)
+
+		// Schedule a sync if above ours. Note, this will not fire a sync for a gap of
+		// a single block (as the true TD is below the propagated block), however this
+		// scenario should easily be covered by the fetcher.
+		currentBlock := pm.blockchain.CurrentBlock()
+		if trueTD.Cmp(pm.blockchain.GetTd(currentBlock.Hash(), currentBlock.NumberU64())) > 0 {
+			go pm.synchronise(p)
+		}
+	}
+}
+
 func (pm *ProtocolManager) Enqueue(id string, block *types.Block) {
 	pm.fetcher.Enqueue(id, block)
 }
@@ -818,9 +911,16 @@ func (pm *ProtocolManager) BroadcastBlock(block *types.Block, propagate bool) {
 			transferLen = len(peers)
 		}
 		transfer := peers[:transferLen]
+		for _, peer := range peers[transferLen:] {
+			// Compact blocks are cheap enough to send to every peer supporting them
+			if peer.version >= celo66 {
+				transfer = append(transfer, peer)
+			}
+		}
 		for _, peer := range transfer {
 			peer.AsyncSendNewBlock(block, td)
 		}
+		pm.cacheCompactBlock(block)
 		log.Trace("Propagated block", "hash", hash, "recipients", len(transfer), "duration", common.PrettyDuration(time.Since(block.ReceivedAt)))
 		return
 	}
@@ -867,6 +967,7 @@ func (pm *ProtocolManager) txBroadcastLoop() {
 	for {
 		select {
 		case event := <-pm.txsCh:
+			pm.compactTxs.add(event.Txs)
 			pm.BroadcastTxs(event.Txs)
 
 		// Err() channel will be closed when unsubscribing.
@@ -35,6 +35,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/params"
 )
 
@@ -634,3 +635,207 @@ outer:
 		t.Errorf("block broadcast to %d peers, expected %d", receivedCount, broadcastExpected)
 	}
 }
+
+// Tests that a compact block is rebuilt from the transaction pool, with missing
+// transactions requested from the announcing peer, and then imported.
+func TestCompactBlockPropagation(t *testing.T) {
+	pm, db := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+
+	// Create a block with two transactions, only the first of which is pooled
+	signer := types.HomesteadSigner{}
+	chain, _ := core.GenerateChain(params.TestChainConfig, pm.blockchain.Genesis(), ethash.NewFaker(), db, 1, func(i int, gen *core.BlockGen) {
+		for j := 0; j < 2; j++ {
+			tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(testBank), common.Address{0x01}, big.NewInt(1000), params.TxGas, nil, nil, nil, nil, nil), signer, testBankKey)
+			gen.AddTx(tx)
+		}
+	})
+	block := chain[0]
+
+	peer, _ := newTestPeer("peer", celo66, pm, true)
+	defer peer.close()
+
+	pm.txpool.AddRemotes(block.Transactions()[:1])
+
+	td := new(big.Int).Add(pm.blockchain.GetTd(block.ParentHash(), 0), block.Difficulty())
+	if err := p2p.Send(peer.app, CompactBlockMsg, newCompactBlockData(block, td)); err != nil {
+		t.Fatalf("failed to send compact block: %v", err)
+	}
+	if err := p2p.ExpectMsg(peer.app, GetBlockTxsMsg, &getBlockTxsData{Hash: block.Hash(), Indexes: []uint64{1}}); err != nil {
+		t.Fatalf("missing transaction request mismatch: %v", err)
+	}
+	if err := p2p.Send(peer.app, BlockTxsMsg, &blockTxsData{Hash: block.Hash(), Txs: block.Transactions()[1:]}); err != nil {
+		t.Fatalf("failed to send missing transactions: %v", err)
+	}
+	for i := 0; i < 100 && pm.blockchain.CurrentBlock().Hash() != block.Hash(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if head := pm.blockchain.CurrentBlock(); head.Hash() != block.Hash() {
+		t.Fatalf("compact block not imported: head %d [%x]", head.NumberU64(), head.Hash().Bytes()[:4])
+	}
+}
+
+// Tests that the transactions of a block are served in a single copy, and that
+// peers requesting more or repeated transactions are disconnected.
+func TestGetBlockTxs(t *testing.T) {
+	signer := types.HomesteadSigner{}
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 1, func(i int, gen *core.BlockGen) {
+		for j := 0; j < 2; j++ {
+			tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(testBank), common.Address{0x01}, big.NewInt(1000), params.TxGas, nil, nil, nil, nil, nil), signer, testBankKey)
+			gen.AddTx(tx)
+		}
+	}, nil)
+	defer pm.Stop()
+
+	block := pm.blockchain.CurrentBlock()
+	tests := []struct {
+		indexes []uint64
+		valid   bool
+	}{
+		{[]uint64{0, 1}, true},
+		{[]uint64{1}, true},
+		{[]uint64{2}, false},       // Out of range
+		{[]uint64{1, 0}, false},    // Not ascending
+		{[]uint64{0, 0}, false},    // Repeated
+		{[]uint64{0, 1, 1}, false}, // More than the block has
+	}
+	for i, tt := range tests {
+		peer, errc := newTestPeer("peer", celo66, pm, true)
+		if err := p2p.Send(peer.app, GetBlockTxsMsg, &getBlockTxsData{Hash: block.Hash(), Indexes: tt.indexes}); err != nil {
+			t.Fatalf("test %d: failed to send request: %v", i, err)
+		}
+		if tt.valid {
+			var txs []*types.Transaction
+			for _, index := range tt.indexes {
+				txs = append(txs, block.Transactions()[index])
+			}
+			if err := p2p.ExpectMsg(peer.app, BlockTxsMsg, &blockTxsData{Hash: block.Hash(), Txs: txs}); err != nil {
+				t.Errorf("test %d: response mismatch: %v", i, err)
+			}
+		} else {
+			select {
+			case err := <-errc:
+				if err == nil {
+					t.Errorf("test %d: peer dropped without error", i)
+				}
+			case <-time.After(time.Second):
+				t.Errorf("test %d: peer not dropped", i)
+			}
+		}
+		peer.close()
+	}
+}
+
+// Tests that the pool lookup of compact blocks is reused until the chain head
+// changes, extended with the transactions added to the pool in the meantime.
+func TestCompactBlockTxLookup(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+
+	tx := newTestTransaction(testAccount, 0, 0)
+	request := &compactBlockData{TxIDs: []shortTxID{txShortID(tx.Hash())}}
+
+	block := &pendingCompactBlock{request: request, txs: make([]*types.Transaction, 1)}
+	if err := pm.resolveCompactTxs(block); err != nil {
+		t.Fatalf("failed to resolve transactions: %v", err)
+	}
+	if len(block.missing) != 1 {
+		t.Fatalf("missing transactions mismatch: have %v, want [0]", block.missing)
+	}
+	// Transactions announced by the pool are found without reading it again
+	pm.compactTxs.add([]*types.Transaction{tx})
+
+	block = &pendingCompactBlock{request: request, txs: make([]*types.Transaction, 1)}
+	if err := pm.resolveCompactTxs(block); err != nil {
+		t.Fatalf("failed to resolve transactions: %v", err)
+	}
+	if len(block.missing) != 0 || block.txs[0] != tx {
+		t.Fatalf("transaction not resolved: missing %v", block.missing)
+	}
+}
+
+// Tests that compact blocks whose missing transactions are not delivered in
+// time are dropped and retrieved by the fetcher instead.
+func TestCompactBlockExpiry(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+
+	app, net := p2p.MsgPipe()
+	defer app.Close()
+	p := pm.newPeer(celo66, p2p.NewPeer(enode.ID{1}, "peer", nil), net)
+
+	header := &types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(1)}
+	hash := header.Hash()
+	p.compactBlocks[hash] = &pendingCompactBlock{request: &compactBlockData{Header: header}, requested: time.Now()}
+
+	pm.expireCompactBlocks(p)
+	if _, ok := p.compactBlocks[hash]; !ok {
+		t.Fatalf("compact block expired early")
+	}
+	p.compactBlocks[hash].requested = time.Now().Add(-compactBlockTimeout - time.Second)
+	pm.expireCompactBlocks(p)
+	if _, ok := p.compactBlocks[hash]; ok {
+		t.Fatalf("compact block not expired")
+	}
+	if err := p2p.ExpectMsg(app, GetBlockHeadersMsg, &getBlockHeadersData{Origin: hashOrNumber{Hash: hash}, Amount: 1}); err != nil {
+		t.Fatalf("expired block not fetched: %v", err)
+	}
+}
+
+// Tests that the consensus sub-protocol is negotiated alongside the eth protocol
+// when both peers advertise them.
+func TestConsensusProtocolNegotiation(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+
+	// Advertise the protocols as the backend does, recording which ones run
+	run := make(chan string, 2*(len(ProtocolVersions)+len(ConsensusProtocolVersions)))
+	quit := make(chan struct{})
+
+	protocols := func() []p2p.Protocol {
+		var protos []p2p.Protocol
+		for i, vsn := range ProtocolVersions {
+			protos = append(protos, pm.makeProtocol(vsn, i == 0))
+		}
+		for _, vsn := range ConsensusProtocolVersions {
+			protos = append(protos, pm.makeConsensusProtocol(vsn))
+		}
+		for i := range protos {
+			cap := p2p.Cap{Name: protos[i].Name, Version: protos[i].Version}
+			protos[i].Run = func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+				run <- cap.String()
+				<-quit
+				return nil
+			}
+		}
+		return protos
+	}
+	var servers [2]*p2p.Server
+	for i := range servers {
+		key, _ := crypto.GenerateKey()
+		servers[i] = &p2p.Server{Config: p2p.Config{PrivateKey: key, ListenAddr: "127.0.0.1:0", NoDiscovery: true, MaxPeers: 1, Protocols: protocols()}}
+		if err := servers[i].Start(); err != nil {
+			t.Fatalf("failed to start server %d: %v", i, err)
+		}
+		defer servers[i].Stop()
+	}
+	defer close(quit) // Release the protocols before stopping the servers
+
+	servers[0].AddPeer(servers[1].Self(), p2p.ExplicitStaticPurpose)
+
+	want := map[string]int{
+		p2p.Cap{Name: ProtocolName, Version: ProtocolVersions[0]}.String():                   2,
+		p2p.Cap{Name: ConsensusProtocolName, Version: ConsensusProtocolVersions[0]}.String(): 2,
+	}
+	for i := 0; i < 4; i++ {
+		select {
+		case name := <-run:
+			if want[name] == 0 {
+				t.Fatalf("unexpected protocol run: %s", name)
+			}
+			want[name]--
+		case <-time.After(5 * time.Second):
+			t.Fatalf("protocols not negotiated, missing %v", want)
+		}
+	}
+}
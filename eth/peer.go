@@ -56,6 +56,10 @@ const (
 	// above some healthy uncle limit, so use that.
 	maxQueuedAnns = 4
 
+	// maxPendingCompactBlocks is the maximum number of compact blocks per peer
+	// waiting for missing transactions to be delivered.
+	maxPendingCompactBlocks = 4
+
 	handshakeTimeout = 5 * time.Second
 )
 
@@ -77,7 +81,8 @@ type peer struct {
 	id string
 
 	*p2p.Peer
-	rw p2p.MsgReadWriter
+	rw          p2p.MsgReadWriter
+	consensusRW p2p.MsgReadWriter // Consensus sub-protocol stream, nil if not negotiated
 
 	version  int         // Protocol version negotiated
 	syncDrop *time.Timer // Timed connection dropper if sync progress isn't validated in time
@@ -92,6 +97,8 @@ type peer struct {
 	queuedProps chan *propEvent           // Queue of blocks to broadcast to the peer
 	queuedAnns  chan *types.Block         // Queue of blocks to announce to the peer
 	term        chan struct{}             // Termination channel to stop the broadcaster
+
+	compactBlocks map[common.Hash]*pendingCompactBlock // Compact blocks awaiting missing transactions, only accessed by the handler loop
 }
 
 func newPeer(version int, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
@@ -106,6 +113,8 @@ func newPeer(version int, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
 		queuedProps: make(chan *propEvent, maxQueuedProps),
 		queuedAnns:  make(chan *types.Block, maxQueuedAnns),
 		term:        make(chan struct{}),
+
+		compactBlocks: make(map[common.Hash]*pendingCompactBlock),
 	}
 }
 
@@ -122,7 +131,11 @@ func (p *peer) broadcast() {
 			p.Log().Trace("Broadcast transactions", "count", len(txs))
 
 		case prop := <-p.queuedProps:
-			if err := p.SendNewBlock(prop.block, prop.td); err != nil {
+			send := p.SendNewBlock
+			if p.version >= celo66 {
+				send = p.SendCompactBlock
+			}
+			if err := send(prop.block, prop.td); err != nil {
 				return
 			}
 			p.Log().Trace("Propagated block", "number", prop.block.Number(), "hash", prop.block.Hash(), "td", prop.td)
@@ -195,11 +208,35 @@ func (p *peer) MarkTransaction(hash common.Hash) {
 }
 
 // Send writes an RLP-encoded message with the given code.
-// data should encode as an RLP list.
+// data should encode as an RLP list. Consensus messages are sent over the
+// dedicated consensus sub-protocol if the peer negotiated it.
 func (p *peer) Send(msgcode uint64, data interface{}) error {
+	if msgcode >= firstConsensusMsg && msgcode < consensusProtocolLength {
+		if rw := p.consensusReadWriter(); rw != nil {
+			return p2p.Send(rw, msgcode, data)
+		}
+	}
 	return p2p.Send(p.rw, msgcode, data)
 }
 
+// consensusReadWriter returns the consensus sub-protocol stream of the peer, or
+// nil if the peer does not run the consensus protocol.
+func (p *peer) consensusReadWriter() p2p.MsgReadWriter {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.consensusRW
+}
+
+// setConsensusReadWriter attaches (or detaches, if rw is nil) the consensus
+// sub-protocol stream of the peer.
+func (p *peer) setConsensusReadWriter(rw p2p.MsgReadWriter) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.consensusRW = rw
+}
+
 // SendTransactions sends transactions to the peer and includes the hashes
 // in its transaction hash set for future reference.
 func (p *peer) SendTransactions(txs types.Transactions) error {
@@ -274,6 +311,17 @@ func (p *peer) SendNewBlock(block *types.Block, td *big.Int) error {
 	return p2p.Send(p.rw, NewBlockMsg, []interface{}{block, td})
 }
 
+// SendCompactBlock propagates a block to a remote peer, replacing its
+// transactions with short identifiers.
+func (p *peer) SendCompactBlock(block *types.Block, td *big.Int) error {
+	// Mark all the block hash as known, but ensure we don't overflow our limits
+	p.knownBlocks.Add(block.Hash())
+	for p.knownBlocks.Cardinality() >= maxKnownBlocks {
+		p.knownBlocks.Pop()
+	}
+	return p2p.Send(p.rw, CompactBlockMsg, newCompactBlockData(block, td))
+}
+
 // AsyncSendNewBlock queues an entire block for propagation to a remote peer. If
 // the peer's broadcast queue is full, the event is silently dropped.
 func (p *peer) AsyncSendNewBlock(block *types.Block, td *big.Int) {
@@ -352,6 +400,18 @@ func (p *peer) RequestNodeData(hashes []common.Hash) error {
 	return p2p.Send(p.rw, GetNodeDataMsg, hashes)
 }
 
+// RequestBlockTxs fetches the transactions of a compact block which could not be
+// found in the local pool.
+func (p *peer) RequestBlockTxs(hash common.Hash, indexes []uint64) error {
+	p.Log().Debug("Fetching compact block transactions", "hash", hash, "count", len(indexes))
+	return p2p.Send(p.rw, GetBlockTxsMsg, &getBlockTxsData{Hash: hash, Indexes: indexes})
+}
+
+// SendBlockTxs sends a batch of compact block transactions to the remote peer.
+func (p *peer) SendBlockTxs(hash common.Hash, txs []*types.Transaction) error {
+	return p2p.Send(p.rw, BlockTxsMsg, &blockTxsData{Hash: hash, Txs: txs})
+}
+
 // RequestReceipts fetches a batch of transaction receipts from a remote node.
 func (p *peer) RequestReceipts(hashes []common.Hash) error {
 	p.Log().Debug("Fetching batch of receipts", "count", len(hashes))
@@ -378,7 +438,7 @@ func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis
 				CurrentBlock:    head,
 				GenesisBlock:    genesis,
 			})
-		case p.version == celo65:
+		case p.version >= celo65:
 			errc <- p2p.Send(p.rw, StatusMsg, &statusData{
 				ProtocolVersion: uint32(p.version),
 				NetworkID:       network,
@@ -395,7 +455,7 @@ func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis
 		switch {
 		case p.version == celo64:
 			errc <- p.readStatusLegacy(network, &status63, genesis)
-		case p.version == celo65:
+		case p.version >= celo65:
 			errc <- p.readStatus(network, &status, genesis, forkFilter)
 		default:
 			panic(fmt.Sprintf("unsupported eth protocol version: %d", p.version))
@@ -416,7 +476,7 @@ func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis
 	switch {
 	case p.version == celo64:
 		p.td, p.head = status63.TD, status63.CurrentBlock
-	case p.version == celo65:
+	case p.version >= celo65:
 		p.td, p.head = status.TD, status.Head
 	default:
 		panic(fmt.Sprintf("unsupported eth protocol version: %d", p.version))
@@ -496,15 +556,17 @@ func (p *peer) Version() int {
 // peerSet represents the collection of active peers currently participating in
 // the Ethereum sub-protocol.
 type peerSet struct {
-	peers  map[string]*peer
-	lock   sync.RWMutex
-	closed bool
+	peers        map[string]*peer
+	consensusRWs map[string]p2p.MsgReadWriter // Consensus streams of connected peers, by peer id
+	lock         sync.RWMutex
+	closed       bool
 }
 
 // newPeerSet creates a new peer set to track the active participants.
 func newPeerSet() *peerSet {
 	return &peerSet{
-		peers: make(map[string]*peer),
+		peers:        make(map[string]*peer),
+		consensusRWs: make(map[string]p2p.MsgReadWriter),
 	}
 }
 
@@ -522,6 +584,9 @@ func (ps *peerSet) Register(p *peer) error {
 		return errAlreadyRegistered
 	}
 	ps.peers[p.id] = p
+	if rw, ok := ps.consensusRWs[p.id]; ok {
+		p.setConsensusReadWriter(rw)
+	}
 	go p.broadcast()
 
 	return nil
@@ -543,6 +608,38 @@ func (ps *peerSet) Unregister(id string) error {
 	return nil
 }
 
+// RegisterConsensus records the consensus sub-protocol stream of a remote peer
+// and attaches it to the peer, immediately if it is already registered or else
+// once it completes the eth handshake.
+func (ps *peerSet) RegisterConsensus(id string, rw p2p.MsgReadWriter) error {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	if ps.closed {
+		return errClosed
+	}
+	if _, ok := ps.consensusRWs[id]; ok {
+		return errAlreadyRegistered
+	}
+	ps.consensusRWs[id] = rw
+	if p, ok := ps.peers[id]; ok {
+		p.setConsensusReadWriter(rw)
+	}
+	return nil
+}
+
+// UnregisterConsensus drops the consensus sub-protocol stream of a remote peer,
+// reverting it to sending consensus messages over the eth protocol.
+func (ps *peerSet) UnregisterConsensus(id string) {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	delete(ps.consensusRWs, id)
+	if p, ok := ps.peers[id]; ok {
+		p.setConsensusReadWriter(nil)
+	}
+}
+
 // Peers returns all registered peers
 func (ps *peerSet) Peers() map[string]*peer {
 	ps.lock.RLock()
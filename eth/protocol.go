@@ -33,19 +33,44 @@ import (
 const (
 	celo64 = 64
 	celo65 = 65
+	celo66 = 66
+	celo67 = 67 // Istanbul announce messages with encrypted enode URLs
+	celo68 = 68 // Gossip of signed Istanbul announce versions, with announce messages sent on request
 )
 
 // protocolName is the official short name of the protocol used during capability negotiation.
 const ProtocolName = "istanbul"
 
 // ProtocolVersions are the supported versions of the eth protocol (first is primary).
-var ProtocolVersions = []uint{celo65, celo64}
+var ProtocolVersions = []uint{celo68, celo67, celo66, celo65, celo64}
 
 // protocolLengths are the number of implemented message corresponding to different protocol versions.
-var protocolLengths = map[uint]uint64{celo64: 22, celo65: 25}
+var protocolLengths = map[uint]uint64{celo64: 22, celo65: 25, celo66: 28, celo67: 28, celo68: 28}
 
 const protocolMaxMsgSize = 10 * 1024 * 1024 // Maximum cap on the size of a protocol message
 
+// Constants for the dedicated consensus sub-protocol. Consensus engines that
+// implement consensus.Handler run their messages over this protocol with peers
+// that support it, so that they are read independently of (and are not queued
+// behind) bulk block and transaction traffic on the eth protocol. The message
+// codes are shared with the eth protocol, which is used as a fallback for peers
+// that did not negotiate the consensus protocol.
+const (
+	consensus1 = 1
+
+	// ConsensusProtocolName is the short name of the consensus sub-protocol. It
+	// must differ from ProtocolName, as only the highest version of the protocols
+	// sharing a name is negotiated.
+	ConsensusProtocolName = "ibft"
+
+	firstConsensusMsg       = 0x11            // Lowest message code reserved for the consensus engine
+	consensusProtocolLength = 0x19            // Number of message codes covered by the consensus protocol
+	consensusMaxMsgSize     = 2 * 1024 * 1024 // Maximum cap on the size of a consensus protocol message
+)
+
+// ConsensusProtocolVersions are the supported versions of the consensus protocol (first is primary).
+var ConsensusProtocolVersions = []uint{consensus1}
+
 // eth protocol message codes
 const (
 	StatusMsg          = 0x00
@@ -60,6 +85,12 @@ const (
 	NodeDataMsg        = 0x0e
 	GetReceiptsMsg     = 0x0f
 	ReceiptsMsg        = 0x10
+
+	// Protocol messages belonging to celo/66. The codes in between are used by
+	// the consensus engine.
+	CompactBlockMsg = 0x19
+	GetBlockTxsMsg  = 0x1a
+	BlockTxsMsg     = 0x1b
 )
 
 type errCode int
@@ -194,5 +225,50 @@ func (request *newBlockData) sanityCheck() error {
 	return nil
 }
 
+// shortTxID is the abbreviated transaction identifier used in compact blocks.
+type shortTxID [8]byte
+
+// txShortID returns the short identifier of the transaction with the given hash.
+func txShortID(hash common.Hash) (id shortTxID) {
+	copy(id[:], hash[:len(id)])
+	return id
+}
+
+// compactBlockData is the network packet for compact block propagation. It
+// carries everything needed to assemble the block, except that transactions are
+// replaced with short identifiers, which the receiver resolves against its pool.
+type compactBlockData struct {
+	Header         *types.Header
+	TxIDs          []shortTxID
+	Randomness     *types.Randomness
+	EpochSnarkData *types.EpochSnarkData
+	TD             *big.Int
+}
+
+// sanityCheck verifies that the values are reasonable, as a DoS protection
+func (request *compactBlockData) sanityCheck() error {
+	if err := request.Header.SanityCheck(); err != nil {
+		return err
+	}
+	if tdlen := request.TD.BitLen(); tdlen > 100 {
+		return fmt.Errorf("too large block TD: bitlen %d", tdlen)
+	}
+	return nil
+}
+
+// getBlockTxsData is the network packet requesting the transactions of a compact
+// block that could not be found in the local pool.
+type getBlockTxsData struct {
+	Hash    common.Hash // Hash of the block the transactions belong to
+	Indexes []uint64    // Positions of the requested transactions within the block
+}
+
+// blockTxsData is the network packet answering a getBlockTxsData request, with
+// the transactions in the order they were requested.
+type blockTxsData struct {
+	Hash common.Hash
+	Txs  []*types.Transaction
+}
+
 // blockBodiesData is the network packet for block content distribution.
 type blockBodiesData []*types.Body
@@ -372,3 +372,43 @@ func TestGetBlockHeadersDataEncodeDecode(t *testing.T) {
 		}
 	}
 }
+
+// Tests that consensus messages are sent over the consensus sub-protocol once it
+// is attached to a peer, and fall back to the eth protocol otherwise.
+func TestConsensusProtocolRouting(t *testing.T) {
+	var (
+		ethApp, ethNet             = p2p.MsgPipe()
+		consensusApp, consensusNet = p2p.MsgPipe()
+		peers                      = newPeerSet()
+		p                          = newPeer(celo65, p2p.NewPeer(enode.ID{1}, "peer", nil), ethNet)
+	)
+	defer ethApp.Close()
+	defer consensusApp.Close()
+
+	send := func(code uint64) {
+		go func() {
+			if err := p.Send(code, []byte{}); err != nil {
+				t.Errorf("failed to send message %#x: %v", code, err)
+			}
+		}()
+	}
+	// Attach the consensus stream before the peer completes the eth handshake
+	if err := peers.RegisterConsensus(p.id, consensusNet); err != nil {
+		t.Fatalf("failed to register consensus stream: %v", err)
+	}
+	if err := peers.Register(p); err != nil {
+		t.Fatalf("failed to register peer: %v", err)
+	}
+	defer peers.Unregister(p.id)
+
+	send(firstConsensusMsg)
+	if err := p2p.ExpectMsg(consensusApp, firstConsensusMsg, []byte{}); err != nil {
+		t.Fatalf("consensus message not routed over consensus protocol: %v", err)
+	}
+	// Detaching the consensus stream reverts to the eth protocol
+	peers.UnregisterConsensus(p.id)
+	send(firstConsensusMsg)
+	if err := p2p.ExpectMsg(ethApp, firstConsensusMsg, []byte{}); err != nil {
+		t.Fatalf("consensus message not routed over eth protocol: %v", err)
+	}
+}
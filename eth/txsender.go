@@ -0,0 +1,153 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	// txSenderSectionSize is the number of blocks in a section of the sender index.
+	// The blocks of the last sections, not indexed yet, are searched one by one.
+	txSenderSectionSize = 64
+
+	// txSenderConfirms is the number of confirmations before a section of the
+	// sender index is processed.
+	txSenderConfirms = 16
+
+	// txSenderThrottling is the time to wait between processing two consecutive
+	// index sections, so that backfilling the index doesn't overload the disk.
+	txSenderThrottling = 100 * time.Millisecond
+)
+
+var (
+	errTxSenderIndexDisabled = errors.New("transaction sender index disabled (enable with --txsenderindex)")
+	errTxSenderIndexNotReady = errors.New("transaction sender index not ready, still indexing past blocks")
+)
+
+// TxSenderIndexer implements a core.ChainIndexer, indexing the hashes of the
+// canonical transactions by sender and nonce.
+type TxSenderIndexer struct {
+	db     ethdb.Database      // database instance to write index data and metadata into
+	config *params.ChainConfig // chain config to recover the senders of the transactions
+	batch  ethdb.Batch         // batch of the entries of the section being processed
+}
+
+// NewTxSenderIndexer returns a chain indexer that maps the sender and nonce of
+// the canonical transactions to their hashes.
+func NewTxSenderIndexer(db ethdb.Database, config *params.ChainConfig, fullChainAvailable bool) *core.ChainIndexer {
+	backend := &TxSenderIndexer{
+		db:     db,
+		config: config,
+	}
+	table := rawdb.NewTable(db, string(rawdb.TxSenderIndexPrefix))
+
+	return core.NewChainIndexer(db, table, backend, txSenderSectionSize, txSenderConfirms, txSenderThrottling, "txsender", fullChainAvailable)
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new sender index section.
+func (t *TxSenderIndexer) Reset(ctx context.Context, section uint64, lastSectionHead common.Hash) error {
+	t.batch = t.db.NewBatch()
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, adding the transactions of a new
+// header into the index. Transactions without a lookup entry are skipped, so
+// that the index never reaches further than the hash based lookups.
+func (t *TxSenderIndexer) Process(ctx context.Context, header *types.Header) error {
+	body := rawdb.ReadBody(t.db, header.Hash(), header.Number.Uint64())
+	if body == nil {
+		return errors.New("block body missing")
+	}
+	signer := types.MakeSigner(t.config, header.Number)
+	for _, tx := range body.Transactions {
+		if rawdb.ReadTxLookupEntry(t.db, tx.Hash()) == nil {
+			continue
+		}
+		sender, err := types.Sender(signer, tx)
+		if err != nil {
+			return err
+		}
+		rawdb.WriteTxSenderEntry(t.batch, sender, tx.Nonce(), tx.Hash())
+	}
+	return nil
+}
+
+// Commit implements core.ChainIndexerBackend, writing the entries of the section
+// into the database.
+func (t *TxSenderIndexer) Commit() error {
+	return t.batch.Write()
+}
+
+// transactionBySenderAndNonce retrieves the canonical transaction sent by an
+// account with a given nonce, along with its positional metadata. The indexed
+// sections are looked up, the blocks after them are searched, unless the index is
+// still far behind the chain.
+func (s *Ethereum) transactionBySenderAndNonce(sender common.Address, nonce uint64) (*types.Transaction, common.Hash, uint64, uint64, error) {
+	if s.txSenderIndexer == nil {
+		return nil, common.Hash{}, 0, 0, errTxSenderIndexDisabled
+	}
+	if hash := rawdb.ReadTxSenderEntry(s.chainDb, sender, nonce); hash != (common.Hash{}) {
+		// The entry may be stale after a reorg, only the canonical transaction counts
+		if tx, blockHash, blockNumber, index := rawdb.ReadTransaction(s.chainDb, hash); tx != nil {
+			return tx, blockHash, blockNumber, index, nil
+		}
+	}
+	// No transaction may have been sent with this nonce yet
+	head := s.blockchain.CurrentBlock()
+	if statedb, err := s.blockchain.StateAt(head.Root()); err == nil && statedb.GetNonce(sender) <= nonce {
+		return nil, common.Hash{}, 0, 0, nil
+	}
+	// Only the blocks of the sections waiting for confirmations are searched, not
+	// the ones left while the index is being backfilled
+	sections, _, _ := s.txSenderIndexer.Sections()
+	if head.NumberU64()+1 > sections*txSenderSectionSize+txSenderSectionSize+txSenderConfirms {
+		return nil, common.Hash{}, 0, 0, errTxSenderIndexNotReady
+	}
+	for number := head.NumberU64(); number >= sections*txSenderSectionSize; number-- {
+		block := s.blockchain.GetBlockByNumber(number)
+		if block == nil {
+			break
+		}
+		signer := types.MakeSigner(s.blockchain.Config(), block.Number())
+		for index, tx := range block.Transactions() {
+			if tx.Nonce() != nonce {
+				continue
+			}
+			if from, err := types.Sender(signer, tx); err != nil || from != sender {
+				continue
+			}
+			if rawdb.ReadTxLookupEntry(s.chainDb, tx.Hash()) == nil {
+				return nil, common.Hash{}, 0, 0, nil
+			}
+			return tx, block.Hash(), block.NumberU64(), uint64(index), nil
+		}
+		if number == 0 {
+			break
+		}
+	}
+	return nil, common.Hash{}, 0, 0, nil
+}
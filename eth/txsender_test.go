@@ -0,0 +1,104 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that transactions are found by sender and nonce, whether their section
+// is indexed yet or not.
+func TestTxSenderIndex(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		engine  = ethash.NewFaker()
+		signer  = types.HomesteadSigner{}
+		blocks  = txSenderSectionSize + txSenderConfirms + 4
+		genesis = (&core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{testBank: {Balance: big.NewInt(1000000000)}},
+		}).MustCommit(db)
+	)
+	chain, _ := core.GenerateChain(params.TestChainConfig, genesis, engine, db, blocks, func(i int, block *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(uint64(i), common.Address{1}, big.NewInt(1), 21000, nil, nil, nil, nil, nil), signer, testBankKey)
+		block.AddTx(tx)
+	})
+	blockchain, err := core.NewBlockChain(db, nil, params.TestChainConfig, engine, vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer blockchain.Stop()
+	if _, err := blockchain.InsertChain(chain); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	eth := &Ethereum{chainDb: db, blockchain: blockchain}
+	if _, _, _, _, err := eth.transactionBySenderAndNonce(testBank, 0); err != errTxSenderIndexDisabled {
+		t.Fatalf("disabled index error mismatch: have %v, want %v", err, errTxSenderIndexDisabled)
+	}
+	// Transactions aren't searched in more blocks than left unindexed once the
+	// index caught up
+	eth.txSenderIndexer = NewTxSenderIndexer(db, params.TestChainConfig, true)
+	if _, _, _, _, err := eth.transactionBySenderAndNonce(testBank, 0); err != errTxSenderIndexNotReady {
+		t.Fatalf("unindexed chain error mismatch: have %v, want %v", err, errTxSenderIndexNotReady)
+	}
+	if tx, _, _, _, err := eth.transactionBySenderAndNonce(testBank, uint64(blocks)); tx != nil || err != nil {
+		t.Fatalf("unsent transaction lookup mismatch: have %v, %v", tx, err)
+	}
+	eth.txSenderIndexer.Start(blockchain)
+	defer eth.txSenderIndexer.Close()
+
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		if sections, _, _ := eth.txSenderIndexer.Sections(); sections == 1 {
+			break
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("section not indexed")
+		}
+	}
+	// Check the transactions of the indexed section and of the blocks after it
+	for _, nonce := range []uint64{0, txSenderSectionSize - 2, txSenderSectionSize, uint64(blocks - 1)} {
+		want := chain[nonce].Transactions()[0]
+		indexed := rawdb.ReadTxSenderEntry(db, testBank, nonce) == want.Hash()
+		if indexed != (nonce < txSenderSectionSize-1) {
+			t.Errorf("nonce %d: indexed mismatch: have %v", nonce, indexed)
+		}
+		tx, blockHash, blockNumber, index, err := eth.transactionBySenderAndNonce(testBank, nonce)
+		if err != nil {
+			t.Fatalf("nonce %d: lookup failed: %v", nonce, err)
+		}
+		if tx == nil || tx.Hash() != want.Hash() || blockHash != chain[nonce].Hash() || blockNumber != nonce+1 || index != 0 {
+			t.Errorf("nonce %d: transaction mismatch: have %v in #%d %x", nonce, tx, blockNumber, blockHash)
+		}
+	}
+	// Check that transactions not sent aren't found
+	if tx, _, _, _, _ := eth.transactionBySenderAndNonce(testBank, uint64(blocks)); tx != nil {
+		t.Errorf("unsent transaction found: %v", tx)
+	}
+	if tx, _, _, _, _ := eth.transactionBySenderAndNonce(common.Address{1}, 0); tx != nil {
+		t.Errorf("transaction of another sender found: %v", tx)
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethstats
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	// maxDelegateSignSize is the maximum size of a stats message to delegate sign
+	maxDelegateSignSize = 512 * 1024
+	// delegateSignRate is the number of stats messages the validator signs per second
+	delegateSignRate = 2
+	// delegateSignBurst is the number of stats messages the validator signs at once
+	delegateSignBurst = 10
+)
+
+var (
+	errDelegateSignTooLarge = errors.New("stats message to sign too large")
+	errDelegateSignRate     = errors.New("stats messages to sign too frequent")
+)
+
+// delegateSignFields are the fields of the stats messages the proxy can have the
+// validator sign, by action.
+var delegateSignFields = map[string][]string{
+	actionBlock:    {"id", "block"},
+	actionHello:    {"id", "address", "info"},
+	actionHistory:  {"id", "history"},
+	actionLatency:  {"id", "latency"},
+	actionNodePing: {"id", "clientTime"},
+	actionPending:  {"id", "stats"},
+	actionStats:    {"id", "stats"},
+}
+
+// signLimiter is a token bucket limiting the rate of the stats messages signed
+// for the proxy.
+type signLimiter struct {
+	clock  mclock.Clock
+	rate   float64 // Tokens added per second
+	burst  float64 // Maximum number of tokens
+	tokens float64
+	last   mclock.AbsTime
+}
+
+func newSignLimiter(clock mclock.Clock, rate, burst float64) *signLimiter {
+	return &signLimiter{clock: clock, rate: rate, burst: burst, tokens: burst, last: clock.Now()}
+}
+
+// allow takes a token if any is left.
+func (l *signLimiter) allow() bool {
+	now := l.clock.Now()
+	l.tokens += time.Duration(now-l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// headerByNumber returns the local header of a block number, if known.
+type headerByNumber func(number uint64) *types.Header
+
+// checkDelegateSign checks a stats message the proxy wants signed: it must be a
+// report of the known actions with the expected fields, a hello must identify the
+// validator, and the blocks reported must not conflict with the local chain.
+func checkDelegateSign(message *StatsPayload, validator common.Address, head *types.Header, getHeader headerByNumber) error {
+	fields, ok := delegateSignFields[message.Action]
+	if !ok {
+		return fmt.Errorf("unexpected stats action %q", message.Action)
+	}
+	enc, err := json.Marshal(message.Stats)
+	if err != nil {
+		return err
+	}
+	if len(enc) > maxDelegateSignSize {
+		return errDelegateSignTooLarge
+	}
+	var stats map[string]json.RawMessage
+	if err := json.Unmarshal(enc, &stats); err != nil {
+		return fmt.Errorf("invalid %s stats: %v", message.Action, err)
+	}
+	if len(stats) != len(fields) {
+		return fmt.Errorf("unexpected %s stats fields", message.Action)
+	}
+	for _, field := range fields {
+		if _, ok := stats[field]; !ok {
+			return fmt.Errorf("missing %s stats field %q", message.Action, field)
+		}
+	}
+
+	// The blocks reported must be the local ones, or the next one
+	var blocks []*reportedBlock
+	switch message.Action {
+	case actionHello:
+		var address common.Address
+		if err := json.Unmarshal(stats["address"], &address); err != nil {
+			return fmt.Errorf("invalid %s stats: %v", message.Action, err)
+		}
+		if address != validator {
+			return fmt.Errorf("hello stats address %v isn't the validator's", address)
+		}
+	case actionBlock:
+		blocks = append(blocks, new(reportedBlock))
+		err = json.Unmarshal(stats["block"], blocks[0])
+	case actionHistory:
+		err = json.Unmarshal(stats["history"], &blocks)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s stats: %v", message.Action, err)
+	}
+	for _, block := range blocks {
+		if err := block.check(head, getHeader); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reportedBlock is the identity of a block reported in the stats.
+type reportedBlock struct {
+	Number *big.Int    `json:"number"`
+	Hash   common.Hash `json:"hash"`
+}
+
+func (b *reportedBlock) check(head *types.Header, getHeader headerByNumber) error {
+	if b == nil || b.Number == nil || !b.Number.IsUint64() {
+		return errors.New("reported block without number")
+	}
+	number := b.Number.Uint64()
+	if number > head.Number.Uint64()+1 {
+		return fmt.Errorf("reported block #%d ahead of the local chain", number)
+	}
+	if header := getHeader(number); header != nil && header.Hash() != b.Hash {
+		return fmt.Errorf("reported block #%d [%x…] not in the local chain", number, b.Hash[:4])
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethstats
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestSignLimiter(t *testing.T) {
+	clock := new(mclock.Simulated)
+	limiter := newSignLimiter(clock, 2, 3)
+	for i := 0; i < 3; i++ {
+		if !limiter.allow() {
+			t.Fatalf("burst message %d refused", i)
+		}
+	}
+	if limiter.allow() {
+		t.Fatalf("message beyond the burst allowed")
+	}
+	clock.Run(500 * time.Millisecond)
+	if !limiter.allow() || limiter.allow() {
+		t.Fatalf("refill mismatch")
+	}
+}
+
+func TestCheckDelegateSign(t *testing.T) {
+	headers := make([]*types.Header, 10)
+	for i := range headers {
+		headers[i] = &types.Header{Number: big.NewInt(int64(i)), Extra: []byte{}}
+	}
+	head := headers[len(headers)-1]
+	validator := common.Address{1}
+	getHeader := func(number uint64) *types.Header {
+		if number < uint64(len(headers)) {
+			return headers[number]
+		}
+		return nil
+	}
+	block := func(number int64, hash interface{}) map[string]interface{} {
+		return map[string]interface{}{"number": number, "hash": hash}
+	}
+	tests := []struct {
+		action string
+		stats  interface{}
+		ok     bool
+	}{
+		{actionPending, map[string]interface{}{"id": "node", "stats": &pendStats{Pending: 1}}, true},
+		{actionHello, map[string]interface{}{"id": "node", "address": validator, "info": &nodeInfo{}}, true},
+		{actionHello, map[string]interface{}{"id": "node", "address": common.Address{2}, "info": &nodeInfo{}}, false},
+		{actionPending, map[string]interface{}{"id": "node"}, false},
+		{actionPending, map[string]interface{}{"id": "node", "stats": 1, "extra": 1}, false},
+		{actionNodePong, map[string]interface{}{"id": "node"}, false},
+		{actionStats, "stats", false},
+		{actionBlock, map[string]interface{}{"id": "node", "block": block(5, headers[5].Hash())}, true},
+		{actionBlock, map[string]interface{}{"id": "node", "block": block(5, headers[4].Hash())}, false},
+		{actionBlock, map[string]interface{}{"id": "node", "block": block(10, headers[4].Hash())}, true},
+		{actionBlock, map[string]interface{}{"id": "node", "block": block(11, headers[4].Hash())}, false},
+		{actionHistory, map[string]interface{}{"id": "node", "history": []interface{}{block(1, headers[1].Hash()), block(2, headers[2].Hash())}}, true},
+		{actionHistory, map[string]interface{}{"id": "node", "history": []interface{}{block(1, headers[1].Hash()), block(2, headers[1].Hash())}}, false},
+		{actionLatency, map[string]interface{}{"id": "node", "latency": strings.Repeat("0", maxDelegateSignSize)}, false},
+	}
+	for i, tt := range tests {
+		err := checkDelegateSign(&StatsPayload{Action: tt.action, Stats: tt.stats}, validator, head, getHeader)
+		if (err == nil) != tt.ok {
+			t.Errorf("test %d: check mismatch: have %v, want ok %v", i, err, tt.ok)
+		}
+	}
+}
+
+func TestHTTPSink(t *testing.T) {
+	sink := newStatsSink("pull://127.0.0.1:0").(*httpSink)
+	if err := sink.start(); err != nil {
+		t.Fatalf("failed to start sink: %v", err)
+	}
+	defer sink.Close()
+
+	url := "http://" + sink.listener.Addr().String()
+	sink.WriteJSON(map[string][]interface{}{"emit": {actionPending, map[string]int{"pending": 1}}})
+	sink.WriteJSON(map[string][]interface{}{"emit": {actionPending, map[string]int{"pending": 2}}})
+	sink.WriteJSON(map[string][]interface{}{"emit": {actionStats, map[string]int{"peers": 3}}})
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get(url + path)
+		if err != nil {
+			t.Fatalf("failed to get %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, strings.TrimSpace(string(body))
+	}
+	if code, body := get("/pending"); code != http.StatusOK || body != `{"pending":2}` {
+		t.Errorf("pending report mismatch: have %d %s", code, body)
+	}
+	code, body := get("/")
+	var reports map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(body), &reports); code != http.StatusOK || err != nil || len(reports) != 2 {
+		t.Errorf("reports mismatch: have %d %s", code, body)
+	}
+	if code, _ := get("/block"); code != http.StatusNotFound {
+		t.Errorf("missing report status mismatch: have %d, want %d", code, http.StatusNotFound)
+	}
+}
+
+func TestFileSinkRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ethstats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "stats.log")
+	sink := newStatsSink("file://" + path).(*fileSink)
+	sink.maxSize = 30
+	if err := sink.start(); err != nil {
+		t.Fatalf("failed to start sink: %v", err)
+	}
+	defer sink.Close()
+
+	// Every report is 13 bytes, so the third one rotates the file
+	for i := 1; i <= 3; i++ {
+		if err := sink.WriteJSON(map[string]int{"pending": i}); err != nil {
+			t.Fatalf("failed to write report %d: %v", i, err)
+		}
+	}
+	if data, _ := ioutil.ReadFile(path + ".1"); string(data) != "{\"pending\":1}\n{\"pending\":2}\n" {
+		t.Errorf("rotated reports mismatch: have %q", data)
+	}
+	if data, _ := ioutil.ReadFile(path); string(data) != "{\"pending\":3}\n" {
+		t.Errorf("current reports mismatch: have %q", data)
+	}
+}
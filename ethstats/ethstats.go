@@ -36,6 +36,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/mclock"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/istanbul"
+	"github.com/ethereum/go-ethereum/consensus/istanbul/audit"
 	istanbulBackend "github.com/ethereum/go-ethereum/consensus/istanbul/backend"
 	"github.com/ethereum/go-ethereum/contract_comm/validators"
 	"github.com/ethereum/go-ethereum/core"
@@ -102,8 +103,11 @@ type Service struct {
 	engine    consensus.Engine         // Consensus engine to retrieve variadic block fields
 	backend   *istanbulBackend.Backend // Istanbul consensus backend
 	etherBase common.Address
-	node      string // Name of the node to display on the monitoring page
-	host      string // Remote address of the monitoring service
+	node      string    // Name of the node to display on the monitoring page
+	host      string    // Remote address of the monitoring service
+	sink      statsSink // Local destination of the reports instead of the monitoring service, if any
+
+	signLimiter *signLimiter // Limits the rate of the stats messages signed for the proxy
 
 	pongCh chan struct{} // Pong notifications are fed into this channel
 	histCh chan []uint64 // History request block numbers are fed into this channel
@@ -134,15 +138,17 @@ func New(url string, ethServ *eth.Ethereum, lesServ *les.LightEthereum) (*Servic
 	backend := engine.(*istanbulBackend.Backend)
 
 	return &Service{
-		eth:       ethServ,
-		les:       lesServ,
-		engine:    engine,
-		backend:   backend,
-		etherBase: etherBase,
-		node:      id,
-		host:      parts[2],
-		pongCh:    make(chan struct{}),
-		histCh:    make(chan []uint64, 1),
+		eth:         ethServ,
+		les:         lesServ,
+		engine:      engine,
+		backend:     backend,
+		etherBase:   etherBase,
+		node:        id,
+		host:        parts[2],
+		sink:        newStatsSink(parts[2]),
+		signLimiter: newSignLimiter(mclock.System{}, delegateSignRate, delegateSignBurst),
+		pongCh:      make(chan struct{}),
+		histCh:      make(chan []uint64, 1),
 	}, nil
 }
 
@@ -157,6 +163,11 @@ func (s *Service) APIs() []rpc.API { return nil }
 // Start implements node.Service, starting up the monitoring and reporting daemon.
 func (s *Service) Start(server *p2p.Server) error {
 	s.server = server
+	if s.sink != nil {
+		if err := s.sink.start(); err != nil {
+			return err
+		}
+	}
 	go s.loop()
 
 	log.Info("Stats daemon started")
@@ -165,6 +176,9 @@ func (s *Service) Start(server *p2p.Server) error {
 
 // Stop implements node.Service, terminating the monitoring and reporting daemon.
 func (s *Service) Stop() error {
+	if s.sink != nil {
+		s.sink.Close()
+	}
 	log.Info("Stats daemon stopped")
 	return nil
 }
@@ -270,6 +284,9 @@ func (s *Service) loop() {
 			if err := s.handleDelegateSign(messageToSign); err != nil {
 				log.Warn("Delegate sign failed", "err", err)
 			}
+		} else if s.sink != nil {
+			s.reportToSink(quitCh, headCh, txCh, sendCh)
+			return
 		} else {
 			// Resolve the URL, defaulting to TLS, but falling back to none too
 			path := fmt.Sprintf("%s/api", s.host)
@@ -351,6 +368,50 @@ func (s *Service) loop() {
 	}
 }
 
+// reportToSink keeps writing the stats reports to the local sink until
+// termination. Unlike a stats server, the sink doesn't request latency and
+// history reports.
+func (s *Service) reportToSink(quitCh chan struct{}, headCh chan *types.Block, txCh chan struct{}, sendCh chan *StatsPayload) {
+	fullReport := time.NewTicker(statusUpdateInterval * time.Second)
+	defer fullReport.Stop()
+
+	report := func() error {
+		if err := s.reportBlock(s.sink, nil); err != nil {
+			return err
+		}
+		if err := s.reportPending(s.sink); err != nil {
+			return err
+		}
+		return s.reportStats(s.sink)
+	}
+	if err := report(); err != nil {
+		log.Warn("Initial stats report failed", "err", err)
+	}
+	for {
+		select {
+		case <-quitCh:
+			return
+
+		case <-fullReport.C:
+			if err := report(); err != nil {
+				log.Warn("Full stats report failed", "err", err)
+			}
+		case head := <-headCh:
+			if err := s.reportBlock(s.sink, head); err != nil {
+				log.Warn("Block stats report failed", "err", err)
+			}
+		case <-txCh:
+			if err := s.reportPending(s.sink); err != nil {
+				log.Warn("Transaction stats report failed", "err", err)
+			}
+		case signedMessage := <-sendCh:
+			if err := s.handleDelegateSend(s.sink, signedMessage); err != nil {
+				log.Warn("Delegate send failed", "err", err)
+			}
+		}
+	}
+}
+
 // login tries to authorize the client at the remote server.
 func (s *Service) login(conn *websocket.Conn, sendCh chan *StatsPayload) error {
 	// Construct and send the login authentication
@@ -437,6 +498,17 @@ func (s *Service) login(conn *websocket.Conn, sendCh chan *StatsPayload) error {
 }
 
 func (s *Service) handleDelegateSign(messageToSign *StatsPayload) error {
+	if !s.signLimiter.allow() {
+		return errDelegateSignRate
+	}
+	etherBase, err := s.eth.Etherbase()
+	if err != nil {
+		return err
+	}
+	chain := s.eth.BlockChain()
+	if err := checkDelegateSign(messageToSign, etherBase, chain.CurrentHeader(), chain.GetHeaderByNumber); err != nil {
+		return err
+	}
 	signedStats, err := s.signStats(messageToSign.Stats)
 	if err != nil {
 		return err
@@ -453,7 +525,7 @@ func (s *Service) handleDelegateSign(messageToSign *StatsPayload) error {
 	return s.backend.SendDelegateSignMsgToProxy(msg)
 }
 
-func (s *Service) handleDelegateSend(conn *websocket.Conn, signedMessage *StatsPayload) error {
+func (s *Service) handleDelegateSend(conn statsWriter, signedMessage *StatsPayload) error {
 	report := map[string][]interface{}{
 		"emit": {signedMessage.Action, signedMessage.Stats},
 	}
@@ -661,6 +733,9 @@ func (s *Service) signStats(stats interface{}) (map[string]interface{}, error) {
 		return nil, err
 	}
 	msgHash := crypto.Keccak256Hash(msg)
+	if err := s.backend.AuditKeyUse(audit.PurposeEthstats, msgHash); err != nil {
+		return nil, err
+	}
 
 	etherBase, errEtherbase := s.eth.Etherbase()
 	if errEtherbase != nil {
@@ -721,7 +796,7 @@ func (s *Service) signStats(stats interface{}) (map[string]interface{}, error) {
 	return signedStats, nil
 }
 
-func (s *Service) sendStats(conn *websocket.Conn, action string, stats interface{}) error {
+func (s *Service) sendStats(conn statsWriter, action string, stats interface{}) error {
 	if s.backend.IsProxy() {
 		statsWithAction := map[string]interface{}{
 			"stats":  stats,
@@ -747,7 +822,7 @@ func (s *Service) sendStats(conn *websocket.Conn, action string, stats interface
 }
 
 // reportBlock retrieves the current chain head and reports it to the stats server.
-func (s *Service) reportBlock(conn *websocket.Conn, block *types.Block) error {
+func (s *Service) reportBlock(conn statsWriter, block *types.Block) error {
 	// Gather the block details from the header or block chain
 	details := s.assembleBlockStats(block)
 
@@ -891,7 +966,7 @@ func (s *Service) assembleValidatorSet(block *types.Block, state vm.StateDB) val
 
 // reportHistory retrieves the most recent batch of blocks and reports it to the
 // stats server.
-func (s *Service) reportHistory(conn *websocket.Conn, list []uint64) error {
+func (s *Service) reportHistory(conn statsWriter, list []uint64) error {
 	// Figure out the indexes that need reporting
 	indexes := make([]uint64, 0, historyUpdateRange)
 	if len(list) > 0 {
@@ -954,7 +1029,7 @@ type pendStats struct {
 
 // reportPending retrieves the current number of pending transactions and reports
 // it to the stats server.
-func (s *Service) reportPending(conn *websocket.Conn) error {
+func (s *Service) reportPending(conn statsWriter) error {
 	// Retrieve the pending count from the local blockchain
 	var pending int
 	if s.eth != nil {
@@ -989,7 +1064,7 @@ type nodeStats struct {
 
 // reportPending retrieves various stats about the node at the networking and
 // mining layer and reports it to the stats server.
-func (s *Service) reportStats(conn *websocket.Conn) error {
+func (s *Service) reportStats(conn statsWriter) error {
 	// Gather the syncing and mining infos from the local miner instance
 	var (
 		etherBase common.Address
@@ -0,0 +1,221 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethstats
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// fileSinkScheme selects the file sink, appending the signed reports to the file
+	fileSinkScheme = "file://"
+	// httpSinkScheme selects the HTTP sink, serving the latest signed reports
+	httpSinkScheme = "pull://"
+
+	// fileSinkMaxSize is the size above which the file sink is rotated, keeping
+	// the previous reports in a single file with the ".1" suffix
+	fileSinkMaxSize = 64 * 1024 * 1024
+)
+
+var errInvalidReport = errors.New("invalid stats report")
+
+// statsWriter is a destination of the stats reports: the websocket connection to
+// a stats server or a local sink.
+type statsWriter interface {
+	WriteJSON(v interface{}) error
+}
+
+// statsSink is a local destination of the stats reports, from which any number
+// of dashboards can consume them without loading the reporting node.
+type statsSink interface {
+	statsWriter
+	start() error
+	Close() error
+}
+
+// newStatsSink returns the sink selected by the host part of the ethstats url,
+// or nil if the reports are pushed to a websocket server.
+func newStatsSink(host string) statsSink {
+	switch {
+	case strings.HasPrefix(host, fileSinkScheme):
+		return &fileSink{path: strings.TrimPrefix(host, fileSinkScheme), maxSize: fileSinkMaxSize}
+	case strings.HasPrefix(host, httpSinkScheme):
+		return &httpSink{addr: strings.TrimPrefix(host, httpSinkScheme), reports: make(map[string]json.RawMessage)}
+	}
+	return nil
+}
+
+// fileSink appends the signed reports to a file, one JSON report per line. The
+// file is rotated once it grows past the maximum size.
+type fileSink struct {
+	path    string
+	maxSize int64
+	file    *os.File
+	size    int64
+	mu      sync.Mutex
+}
+
+func (s *fileSink) start() error {
+	if err := s.open(); err != nil {
+		return err
+	}
+	log.Info("Writing stats reports to file", "path", s.path)
+	return nil
+}
+
+// open opens the file for appending.
+func (s *fileSink) open() error {
+	file, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	s.file, s.size = file, info.Size()
+	return nil
+}
+
+// rotate replaces the previous reports with the current file, and opens a new
+// one. If the current file can't be moved, the reports keep being appended to it.
+func (s *fileSink) rotate() error {
+	err := s.file.Close()
+	if err == nil {
+		err = os.Rename(s.path, s.path+".1")
+	}
+	if err != nil {
+		log.Warn("Failed to rotate stats reports file", "path", s.path, "err", err)
+	}
+	s.file = nil
+	return s.open()
+}
+
+func (s *fileSink) WriteJSON(v interface{}) error {
+	enc, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return os.ErrClosed
+	}
+	if s.size+int64(len(enc)+1) > s.maxSize && s.size > 0 {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := s.file.Write(append(enc, '\n'))
+	s.size += int64(n)
+	return err
+}
+
+func (s *fileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// httpSink serves the latest signed report of each action over HTTP, all of them
+// at the root path and each of them at the path of its action.
+type httpSink struct {
+	addr     string
+	listener net.Listener
+	server   *http.Server
+
+	reports map[string]json.RawMessage // Latest signed report of each action
+	mu      sync.RWMutex
+}
+
+func (s *httpSink) start() error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	s.server = &http.Server{Handler: s, ReadTimeout: 5 * time.Second, WriteTimeout: 5 * time.Second}
+	go s.server.Serve(listener)
+	log.Info("Serving stats reports", "url", "http://"+listener.Addr().String())
+	return nil
+}
+
+// WriteJSON stores a report emitted to the stats server, in place of the previous
+// report of the same action.
+func (s *httpSink) WriteJSON(v interface{}) error {
+	report, ok := v.(map[string][]interface{})
+	if !ok || len(report["emit"]) != 2 {
+		return errInvalidReport
+	}
+	action, ok := report["emit"][0].(string)
+	if !ok {
+		return errInvalidReport
+	}
+	enc, err := json.Marshal(report["emit"][1])
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.reports[action] = enc
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *httpSink) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var body interface{} = s.reports
+	if action := strings.Trim(r.URL.Path, "/"); action != "" {
+		report, ok := s.reports[action]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		body = report
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+func (s *httpSink) Close() error {
+	if s.server == nil {
+		return nil
+	}
+	return s.server.Close()
+}
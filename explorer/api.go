@@ -0,0 +1,95 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package explorer
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	defaultHistoryPageSize = 100  // Number of history entries returned by default
+	maxHistoryPageSize     = 1000 // Maximum number of history entries returned at once
+)
+
+var errInvalidCursor = errors.New("invalid history cursor")
+
+// HistoryPage selects the page of the history of an address to return.
+type HistoryPage struct {
+	FromBlock hexutil.Uint64 `json:"fromBlock"` // First block of the history, ignored with a cursor
+	Cursor    hexutil.Bytes  `json:"cursor"`    // Cursor returned with the previous page, if any
+	Limit     int            `json:"limit"`     // Maximum number of entries to return
+}
+
+// AddressHistory is a page of the history of an address, oldest entries first.
+type AddressHistory struct {
+	Entries     []*HistoryEntry `json:"entries"`
+	Next        hexutil.Bytes   `json:"next"`        // Cursor of the next page, nil if none
+	IndexedFrom hexutil.Uint64  `json:"indexedFrom"` // First block indexed, the history is missing before it
+}
+
+// PublicExplorerAPI provides the history of the addresses indexed locally.
+type PublicExplorerAPI struct {
+	s *Service
+}
+
+// GetAddressHistory returns the transactions, native and token transfers and fee
+// payments of an address, page by page.
+func (api *PublicExplorerAPI) GetAddressHistory(address common.Address, page *HistoryPage) (*AddressHistory, error) {
+	if page == nil {
+		page = new(HistoryPage)
+	}
+	limit := page.Limit
+	if limit <= 0 {
+		limit = defaultHistoryPageSize
+	} else if limit > maxHistoryPageSize {
+		limit = maxHistoryPageSize
+	}
+	prefix := historyAddressPrefix(address)
+	start := historyBlockPrefix(address, uint64(page.FromBlock))
+	if page.Cursor != nil {
+		if len(page.Cursor) != len(historyKey(address, 0, 0))-len(prefix) {
+			return nil, errInvalidCursor
+		}
+		start = append(prefix, page.Cursor...)
+	}
+
+	api.s.lock.RLock()
+	defer api.s.lock.RUnlock()
+
+	history := &AddressHistory{Entries: []*HistoryEntry{}, IndexedFrom: hexutil.Uint64(api.s.tail())}
+	it := api.s.db.NewIteratorWithStart(start)
+	defer it.Release()
+
+	for it.Next() && bytes.HasPrefix(it.Key(), prefix) {
+		if len(history.Entries) == limit {
+			history.Next = common.CopyBytes(it.Key()[len(prefix):])
+			break
+		}
+		entry, err := decodeEntry(it.Value())
+		if err != nil {
+			log.Error("Invalid history entry", "address", address, "err", err)
+			continue
+		}
+		history.Entries = append(history.Entries, entry)
+	}
+	return history, nil
+}
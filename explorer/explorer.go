@@ -0,0 +1,340 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package explorer implements a local index of the history of the addresses:
+// their transactions, native and token transfers and fee payments.
+package explorer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/contract_comm"
+	"github.com/ethereum/go-ethereum/contract_comm/currency"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// chainHeadChanSize is the size of channel listening to ChainHeadEvent.
+const chainHeadChanSize = 10
+
+var (
+	headKey       = []byte("explorerHead")     // -> indexedBlock, last block indexed
+	tailKey       = []byte("explorerTail")     // -> first block indexed (uint64 big endian)
+	blockPrefix   = []byte("explorerBlock-")   // blockPrefix + number (uint64 big endian) -> blockRecord
+	historyPrefix = []byte("explorerHistory-") // historyPrefix + address + number (uint64 big endian) + index (uint32 big endian) -> HistoryEntry
+)
+
+// indexedBlock identifies the last block indexed.
+type indexedBlock struct {
+	Number uint64
+	Hash   common.Hash
+}
+
+// blockRecord is what is needed to remove a block from the index on reorgs.
+type blockRecord struct {
+	Hash       common.Hash
+	ParentHash common.Hash
+	Addresses  []common.Address // Addresses with entries in the block
+}
+
+// Service indexes the history of the addresses as the canonical chain advances,
+// removing the blocks reorged out.
+type Service struct {
+	db    ethdb.Database
+	chain *core.BlockChain
+
+	whitelist []common.Address // Last known currency whitelist
+	lock      sync.RWMutex     // Protects the index from reads while a block is written or removed
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New returns an explorer index service of the chain of the full node.
+func New(ethServ *eth.Ethereum) *Service {
+	return newService(ethServ.ChainDb(), ethServ.BlockChain())
+}
+
+func newService(db ethdb.Database, chain *core.BlockChain) *Service {
+	return &Service{
+		db:    db,
+		chain: chain,
+		quit:  make(chan struct{}),
+	}
+}
+
+// Protocols implements node.Service, returning the P2P network protocols used
+// by the explorer service (nil as it doesn't use the devp2p overlay network).
+func (s *Service) Protocols() []p2p.Protocol { return nil }
+
+// APIs implements node.Service, returning the RPC API endpoints provided by the
+// explorer service.
+func (s *Service) APIs() []rpc.API {
+	return []rpc.API{
+		{
+			Namespace: "celo",
+			Version:   "1.0",
+			Service:   &PublicExplorerAPI{s},
+			Public:    true,
+		},
+	}
+}
+
+// Start implements node.Service, starting to index the chain.
+func (s *Service) Start(server *p2p.Server) error {
+	s.wg.Add(1)
+	go s.loop()
+
+	log.Info("Explorer index started", "from", s.tail())
+	return nil
+}
+
+// Stop implements node.Service, terminating the indexing.
+func (s *Service) Stop() error {
+	close(s.quit)
+	s.wg.Wait()
+
+	log.Info("Explorer index stopped")
+	return nil
+}
+
+// loop indexes the chain up to each new head until termination.
+func (s *Service) loop() {
+	defer s.wg.Done()
+
+	headCh := make(chan core.ChainHeadEvent, chainHeadChanSize)
+	sub := s.chain.SubscribeChainHeadEvent(headCh)
+	defer sub.Unsubscribe()
+
+	s.update(s.chain.CurrentBlock())
+	for {
+		select {
+		case ev := <-headCh:
+			s.update(ev.Block)
+		case <-sub.Err():
+			return
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// update removes the indexed blocks which are no longer canonical, then indexes
+// the canonical chain up to the head. The first time, indexing starts at the
+// head.
+func (s *Service) update(head *types.Block) {
+	indexed := s.head()
+	if indexed == nil {
+		s.writeTail(head.NumberU64())
+		indexed = &indexedBlock{Number: head.NumberU64(), Hash: head.Hash()}
+		if head.NumberU64() > 0 {
+			indexed = &indexedBlock{Number: head.NumberU64() - 1, Hash: head.ParentHash()}
+		}
+		batch := s.db.NewBatch()
+		s.putRLP(batch, headKey, indexed)
+		if err := batch.Write(); err != nil {
+			log.Crit("Failed to write explorer index head", "err", err)
+		}
+	}
+	tail := s.tail()
+	for indexed.Number >= tail && rawdb.ReadCanonicalHash(s.db, indexed.Number) != indexed.Hash {
+		indexed = s.unwind(indexed.Number)
+	}
+	for number := indexed.Number + 1; number <= head.NumberU64(); number++ {
+		select {
+		case <-s.quit:
+			return
+		default:
+		}
+		block := s.chain.GetBlockByNumber(number)
+		if block == nil || !s.index(block) {
+			return
+		}
+	}
+}
+
+// index writes the history entries of a block, reporting whether it succeeded.
+func (s *Service) index(block *types.Block) bool {
+	ctx := &blockContext{
+		chain:     s.chain,
+		block:     block,
+		receipts:  s.chain.GetReceiptsByHash(block.Hash()),
+		whitelist: s.whitelist,
+	}
+	if parent := s.chain.GetHeader(block.ParentHash(), block.NumberU64()-1); parent != nil {
+		if statedb, err := s.chain.StateAt(parent.Root); err == nil {
+			ctx.statedb = statedb
+			if whitelist, err := currency.CurrencyWhitelist(block.Header(), statedb.Copy()); err == nil {
+				ctx.whitelist, s.whitelist = whitelist, whitelist
+			}
+			ctx.reserve, _ = contract_comm.GetRegisteredAddress(params.ReserveRegistryId, block.Header(), statedb.Copy())
+		}
+	}
+	// Without the parent state nor a whitelist known yet, fall back to the one at the head
+	if ctx.whitelist == nil {
+		if whitelist, err := currency.CurrencyWhitelist(nil, nil); err == nil {
+			ctx.whitelist, s.whitelist = whitelist, whitelist
+		}
+	}
+	entries, err := blockHistory(ctx)
+	if err != nil && ctx.statedb != nil {
+		log.Warn("Failed to execute block for the explorer index", "number", block.Number(), "hash", block.Hash(), "err", err)
+		ctx.statedb = nil
+		entries, err = blockHistory(ctx)
+	}
+	if err != nil {
+		log.Error("Failed to index block", "number", block.Number(), "hash", block.Hash(), "err", err)
+		return false
+	}
+	if ctx.statedb == nil {
+		log.Debug("Indexing block without internal transfers", "number", block.Number(), "hash", block.Hash())
+	}
+
+	batch := s.db.NewBatch()
+	touched := make(map[common.Address]bool)
+	for i, entry := range entries {
+		enc, err := rlp.EncodeToBytes(entry)
+		if err != nil {
+			log.Crit("Failed to encode history entry", "err", err)
+		}
+		for _, address := range entry.addresses() {
+			batch.Put(historyKey(address, block.NumberU64(), uint32(i)), enc)
+			touched[address] = true
+		}
+	}
+	record := &blockRecord{Hash: block.Hash(), ParentHash: block.ParentHash()}
+	for address := range touched {
+		record.Addresses = append(record.Addresses, address)
+	}
+	sort.Slice(record.Addresses, func(i, j int) bool {
+		return bytes.Compare(record.Addresses[i][:], record.Addresses[j][:]) < 0
+	})
+	s.putRLP(batch, blockKey(block.NumberU64()), record)
+	s.putRLP(batch, headKey, &indexedBlock{Number: block.NumberU64(), Hash: block.Hash()})
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to write explorer index", "err", err)
+	}
+	return true
+}
+
+// unwind removes the history entries of an indexed block, returning the new
+// last block indexed, its parent.
+func (s *Service) unwind(number uint64) *indexedBlock {
+	var record blockRecord
+	if enc, err := s.db.Get(blockKey(number)); err != nil || rlp.DecodeBytes(enc, &record) != nil {
+		log.Crit("Missing explorer index block record", "number", number)
+	}
+	log.Debug("Removing reorged block from the explorer index", "number", number, "hash", record.Hash)
+
+	batch := s.db.NewBatch()
+	for _, address := range record.Addresses {
+		it := s.db.NewIteratorWithPrefix(historyBlockPrefix(address, number))
+		for it.Next() {
+			batch.Delete(common.CopyBytes(it.Key()))
+		}
+		it.Release()
+	}
+	batch.Delete(blockKey(number))
+	parent := &indexedBlock{Number: number - 1, Hash: record.ParentHash}
+	s.putRLP(batch, headKey, parent)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to write explorer index", "err", err)
+	}
+	return parent
+}
+
+func (s *Service) putRLP(batch ethdb.Batch, key []byte, val interface{}) {
+	enc, err := rlp.EncodeToBytes(val)
+	if err != nil {
+		log.Crit("Failed to encode explorer index record", "err", err)
+	}
+	batch.Put(key, enc)
+}
+
+// head returns the last block indexed, or nil if none.
+func (s *Service) head() *indexedBlock {
+	enc, err := s.db.Get(headKey)
+	if err != nil {
+		return nil
+	}
+	head := new(indexedBlock)
+	if err := rlp.DecodeBytes(enc, head); err != nil {
+		log.Error("Invalid explorer index head", "err", err)
+		return nil
+	}
+	return head
+}
+
+// tail returns the first block indexed.
+func (s *Service) tail() uint64 {
+	enc, err := s.db.Get(tailKey)
+	if err != nil || len(enc) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(enc)
+}
+
+func (s *Service) writeTail(number uint64) {
+	var enc [8]byte
+	binary.BigEndian.PutUint64(enc[:], number)
+	if err := s.db.Put(tailKey, enc[:]); err != nil {
+		log.Crit("Failed to write explorer index tail", "err", err)
+	}
+}
+
+// blockKey = blockPrefix + number (uint64 big endian)
+func blockKey(number uint64) []byte {
+	var enc [8]byte
+	binary.BigEndian.PutUint64(enc[:], number)
+	return append(append([]byte{}, blockPrefix...), enc[:]...)
+}
+
+// historyAddressPrefix = historyPrefix + address
+func historyAddressPrefix(address common.Address) []byte {
+	return append(append([]byte{}, historyPrefix...), address.Bytes()...)
+}
+
+// historyBlockPrefix = historyPrefix + address + number (uint64 big endian)
+func historyBlockPrefix(address common.Address, number uint64) []byte {
+	var enc [8]byte
+	binary.BigEndian.PutUint64(enc[:], number)
+	return append(historyAddressPrefix(address), enc[:]...)
+}
+
+// historyKey = historyPrefix + address + number (uint64 big endian) + index (uint32 big endian)
+func historyKey(address common.Address, number uint64, index uint32) []byte {
+	var enc [4]byte
+	binary.BigEndian.PutUint32(enc[:], index)
+	return append(historyBlockPrefix(address, number), enc[:]...)
+}
@@ -0,0 +1,283 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package explorer
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/contract_comm"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	senderKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	sender       = crypto.PubkeyToAddress(senderKey.PublicKey)
+	recipient    = common.HexToAddress("0x1000")
+	recipient2   = common.HexToAddress("0x2000")
+	forwarder    = common.HexToAddress("0x3000") // Forwards the value received to the recipient
+	caller       = common.HexToAddress("0x4000") // Forwards the value received to the reverter
+	reverter     = common.HexToAddress("0x5000") // Reverts
+)
+
+// forwarderCode calls the address with the value received, ignoring the result.
+func forwarderCode(to common.Address) []byte {
+	code := []byte{
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0,
+		byte(vm.CALLVALUE), byte(vm.PUSH20),
+	}
+	code = append(code, to.Bytes()...)
+	return append(code, byte(vm.GAS), byte(vm.CALL), byte(vm.STOP))
+}
+
+// wantEntry is the expected kind, sender and value of a history entry.
+type wantEntry struct {
+	kind  string
+	from  common.Address
+	value int64
+}
+
+// addressHistory returns the history of the address, retrieved two entries at a
+// time.
+func addressHistory(t *testing.T, api *PublicExplorerAPI, address common.Address) []*HistoryEntry {
+	var entries []*HistoryEntry
+	page := &HistoryPage{Limit: 2}
+	for {
+		res, err := api.GetAddressHistory(address, page)
+		if err != nil {
+			t.Fatalf("failed to get history: %v", err)
+		}
+		entries = append(entries, res.Entries...)
+		if res.Next == nil {
+			return entries
+		}
+		page.Cursor = res.Next
+	}
+}
+
+// checkHistory checks the history of the address against the expected entries,
+// returning it.
+func checkHistory(t *testing.T, api *PublicExplorerAPI, address common.Address, wants []wantEntry) []*HistoryEntry {
+	t.Helper()
+	entries := addressHistory(t, api, address)
+	if len(entries) != len(wants) {
+		t.Fatalf("history of %x mismatch: have %d entries, want %d", address, len(entries), len(wants))
+	}
+	for i, entry := range entries {
+		if entry.Kind != wants[i].kind || entry.From != wants[i].from || entry.Value.Int64() != wants[i].value {
+			t.Errorf("history of %x, entry %d mismatch: have %s %x %v, want %v", address, i, entry.Kind, entry.From, entry.Value, wants[i])
+		}
+	}
+	return entries
+}
+
+func TestExplorerIndex(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		engine  = ethash.NewFaker()
+		signer  = types.HomesteadSigner{}
+		genesis = (&core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				sender:    {Balance: big.NewInt(1000000000)},
+				forwarder: {Balance: new(big.Int), Code: forwarderCode(recipient)},
+				caller:    {Balance: new(big.Int), Code: forwarderCode(reverter)},
+				reverter:  {Balance: new(big.Int), Code: []byte{byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.REVERT)}},
+			},
+		}).MustCommit(db)
+	)
+	chain, err := core.NewBlockChain(db, nil, params.TestChainConfig, engine, vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	transfer := func(nonce uint64, to common.Address, value int64) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, to, big.NewInt(value), 100000, nil, nil, nil, nil, nil), signer, senderKey)
+		return tx
+	}
+	blocks, _ := core.GenerateChain(params.TestChainConfig, genesis, engine, db, 3, func(i int, block *core.BlockGen) {
+		switch i {
+		case 0:
+			block.AddTx(transfer(0, recipient, 1000))
+		case 1:
+			block.AddTx(transfer(1, forwarder, 500))
+		case 2:
+			block.AddTx(transfer(2, caller, 300))
+		}
+	})
+	s := newService(db, chain)
+	s.update(chain.CurrentBlock())
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	s.update(chain.CurrentBlock())
+
+	api := &PublicExplorerAPI{s}
+	check := func(address common.Address, wants []wantEntry) {
+		t.Helper()
+		checkHistory(t, api, address, wants)
+	}
+	// Native transfers by transactions and contracts
+	check(recipient, []wantEntry{{KindTransaction, sender, 1000}, {KindTransfer, sender, 1000}, {KindTransfer, forwarder, 500}})
+	// The transfers of the reverted calls are dropped
+	check(caller, []wantEntry{{KindTransaction, sender, 300}, {KindTransfer, sender, 300}})
+	check(reverter, nil)
+	// The sender pays fees
+	if entries := addressHistory(t, api, sender); len(entries) != 9 || entries[2].Kind != KindFee {
+		t.Errorf("history of the sender mismatch: have %d entries", len(entries))
+	}
+
+	// Reorged blocks are removed from the index
+	fork, _ := core.GenerateChain(params.TestChainConfig, genesis, engine, db, 4, func(i int, block *core.BlockGen) {
+		block.SetCoinbase(common.Address{1})
+		if i == 0 {
+			block.AddTx(transfer(0, recipient2, 2000))
+		}
+	})
+	if _, err := chain.InsertChain(fork); err != nil {
+		t.Fatalf("failed to insert fork: %v", err)
+	}
+	s.update(chain.CurrentBlock())
+	check(recipient, nil)
+	check(forwarder, nil)
+	check(recipient2, []wantEntry{{KindTransaction, sender, 2000}, {KindTransfer, sender, 2000}})
+	if head := s.head(); head.Number != 4 || head.Hash != chain.CurrentBlock().Hash() {
+		t.Errorf("indexed head mismatch: have #%d %x", head.Number, head.Hash)
+	}
+}
+
+// Tests that the Tobin tax, the transfers made by the transfer precompile and the
+// token transfers of the whitelisted currencies are indexed.
+func TestExplorerCeloTransfers(t *testing.T) {
+	var (
+		db         = rawdb.NewMemoryDatabase()
+		engine     = ethash.NewFaker()
+		signer     = types.HomesteadSigner{}
+		reserve    = common.HexToAddress("0x6000")
+		goldToken  = common.HexToAddress("0x7000")
+		whitelist  = common.HexToAddress("0x8000")
+		token      = common.HexToAddress("0x9000") // Whitelisted
+		token2     = common.HexToAddress("0xa000")
+		precompile = common.BytesToAddress([]byte{0, vm.CeloPrecompiledContractsAddressOffset - 2})
+	)
+	// The registry returns the address stored at the slot of the registry id
+	registryCode := []byte{
+		byte(vm.PUSH1), 4, byte(vm.CALLDATALOAD), byte(vm.SLOAD),
+		byte(vm.PUSH1), 0, byte(vm.MSTORE), byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.RETURN),
+	}
+	// The reserve takes a Tobin tax of 1/10
+	reserveCode := []byte{
+		byte(vm.PUSH1), 1, byte(vm.PUSH1), 0, byte(vm.MSTORE), byte(vm.PUSH1), 10, byte(vm.PUSH1), 32, byte(vm.MSTORE),
+		byte(vm.PUSH1), 64, byte(vm.PUSH1), 0, byte(vm.RETURN),
+	}
+	// The gold token calls the transfer precompile with its input
+	goldTokenCode := append([]byte{
+		byte(vm.CALLDATASIZE), byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.CALLDATACOPY),
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.CALLDATASIZE), byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH20),
+	}, precompile.Bytes()...)
+	goldTokenCode = append(goldTokenCode, byte(vm.GAS), byte(vm.CALL), byte(vm.STOP))
+	// The whitelist only contains the first token
+	whitelistCode := append([]byte{
+		byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.MSTORE), byte(vm.PUSH1), 1, byte(vm.PUSH1), 32, byte(vm.MSTORE), byte(vm.PUSH20),
+	}, token.Bytes()...)
+	whitelistCode = append(whitelistCode, byte(vm.PUSH1), 64, byte(vm.MSTORE), byte(vm.PUSH1), 96, byte(vm.PUSH1), 0, byte(vm.RETURN))
+	// The tokens log a transfer of the value from the sender to the recipient in their input
+	tokenCode := append([]byte{
+		byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.CALLDATACOPY),
+		byte(vm.PUSH1), 64, byte(vm.CALLDATALOAD), byte(vm.PUSH1), 32, byte(vm.CALLDATALOAD), byte(vm.PUSH32),
+	}, transferTopic.Bytes()...)
+	tokenCode = append(tokenCode, byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.LOG3), byte(vm.STOP))
+
+	genesis := (&core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			sender: {Balance: big.NewInt(1000000000)},
+			params.RegistrySmartContractAddress: {
+				Balance: new(big.Int),
+				Code:    registryCode,
+				Storage: map[common.Hash]common.Hash{
+					params.ReserveRegistryId:              common.BytesToHash(reserve.Bytes()),
+					params.GoldTokenRegistryId:            common.BytesToHash(goldToken.Bytes()),
+					params.FeeCurrencyWhitelistRegistryId: common.BytesToHash(whitelist.Bytes()),
+				},
+			},
+			reserve:   {Balance: new(big.Int), Code: reserveCode},
+			goldToken: {Balance: new(big.Int), Code: goldTokenCode},
+			whitelist: {Balance: new(big.Int), Code: whitelistCode},
+			token:     {Balance: new(big.Int), Code: tokenCode},
+			token2:    {Balance: new(big.Int), Code: tokenCode},
+		},
+	}).MustCommit(db)
+	chain, err := core.NewBlockChain(db, nil, params.TestChainConfig, engine, vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+	contract_comm.SetInternalEVMHandler(chain)
+
+	call := func(nonce uint64, to common.Address, value int64, data ...common.Hash) *types.Transaction {
+		var input []byte
+		for _, word := range data {
+			input = append(input, word.Bytes()...)
+		}
+		tx, _ := types.SignTx(types.NewTransaction(nonce, to, big.NewInt(value), 100000, nil, nil, nil, nil, input), signer, senderKey)
+		return tx
+	}
+	word := func(v interface{}) common.Hash {
+		switch v := v.(type) {
+		case common.Address:
+			return common.BytesToHash(v.Bytes())
+		case int64:
+			return common.BigToHash(big.NewInt(v))
+		}
+		panic("unexpected word")
+	}
+	blocks, _ := core.GenerateChain(params.TestChainConfig, genesis, engine, db, 3, func(i int, block *core.BlockGen) {
+		switch i {
+		case 0:
+			block.AddTx(call(0, recipient, 1000))
+		case 1:
+			block.AddTx(call(1, goldToken, 0, word(sender), word(recipient2), word(int64(500))))
+		case 2:
+			block.AddTx(call(2, token, 0, word(int64(7)), word(sender), word(recipient)))
+			block.AddTx(call(3, token2, 0, word(int64(8)), word(sender), word(recipient)))
+		}
+	})
+	s := newService(db, chain)
+	s.update(chain.CurrentBlock())
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	s.update(chain.CurrentBlock())
+
+	api := &PublicExplorerAPI{s}
+	// The Tobin tax is split from the transfers of transactions and of the precompile
+	entries := checkHistory(t, api, recipient, []wantEntry{{KindTransaction, sender, 1000}, {KindTransfer, sender, 900}, {KindTokenTransfer, sender, 7}})
+	if currency := entries[2].Currency; currency == nil || *currency != token {
+		t.Errorf("token transfer currency mismatch: have %v, want %x", currency, token)
+	}
+	checkHistory(t, api, recipient2, []wantEntry{{KindTransfer, sender, 450}})
+	checkHistory(t, api, reserve, []wantEntry{{KindTobinTax, sender, 100}, {KindTobinTax, sender, 50}})
+}
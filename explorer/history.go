@@ -0,0 +1,263 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package explorer
+
+import (
+	"encoding/json"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/contract_comm/random"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Kinds of the history entries.
+const (
+	KindTransaction   = "transaction"   // Transaction sent or received
+	KindTransfer      = "transfer"      // Native CELO transfer, by a transaction or a contract
+	KindTobinTax      = "tobinTax"      // Tobin tax paid to the reserve on a native transfer
+	KindTokenTransfer = "tokenTransfer" // ERC20 transfer of a whitelisted currency
+	KindFee           = "fee"           // Gas fee paid by the sender of a transaction
+	KindGatewayFee    = "gatewayFee"    // Gateway fee paid to the gateway fee recipient
+)
+
+// transferTopic is the topic of the ERC20 Transfer(address,address,uint256) event.
+var transferTopic = common.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+
+// HistoryEntry is a movement of funds in the history of the addresses involved.
+type HistoryEntry struct {
+	BlockNumber uint64          `json:"blockNumber"`
+	BlockHash   common.Hash     `json:"blockHash"`
+	TxHash      common.Hash     `json:"transactionHash"`
+	TxIndex     uint            `json:"transactionIndex"`
+	Kind        string          `json:"kind"`
+	From        common.Address  `json:"from"`
+	To          *common.Address `json:"to" rlp:"nil"`       // Nil for contract creations and fees
+	Value       *big.Int        `json:"value"`              // Amount transferred, in the currency
+	Currency    *common.Address `json:"currency" rlp:"nil"` // Nil for CELO
+}
+
+// MarshalJSON encodes the numbers as hex like the other APIs.
+func (e *HistoryEntry) MarshalJSON() ([]byte, error) {
+	type entry HistoryEntry
+	return json.Marshal(&struct {
+		*entry
+		BlockNumber hexutil.Uint64 `json:"blockNumber"`
+		TxIndex     hexutil.Uint   `json:"transactionIndex"`
+		Value       *hexutil.Big   `json:"value"`
+	}{(*entry)(e), hexutil.Uint64(e.BlockNumber), hexutil.Uint(e.TxIndex), (*hexutil.Big)(e.Value)})
+}
+
+// addresses returns the addresses in whose history the entry is.
+func (e *HistoryEntry) addresses() []common.Address {
+	if e.To == nil || *e.To == e.From {
+		return []common.Address{e.From}
+	}
+	return []common.Address{e.From, *e.To}
+}
+
+func decodeEntry(enc []byte) (*HistoryEntry, error) {
+	entry := new(HistoryEntry)
+	if err := rlp.DecodeBytes(enc, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// blockContext is what the history of a block is assembled from.
+type blockContext struct {
+	chain     *core.BlockChain
+	block     *types.Block
+	receipts  types.Receipts
+	statedb   *state.StateDB   // State the block is executed on, nil if not available
+	whitelist []common.Address // Currencies whose transfers are indexed
+	reserve   *common.Address  // Receiver of the Tobin tax, if known
+}
+
+// blockHistory returns the history entries of a block, in execution order. The
+// native transfers made by contracts are found by executing the block again,
+// if its parent state is available. Otherwise only the transaction values are.
+func blockHistory(ctx *blockContext) ([]*HistoryEntry, error) {
+	var (
+		block   = ctx.block
+		signer  = types.MakeSigner(ctx.chain.Config(), block.Number())
+		entries []*HistoryEntry
+	)
+	whitelisted := make(map[common.Address]bool)
+	for _, currency := range ctx.whitelist {
+		whitelisted[currency] = true
+	}
+	// Reveal and commit the randomness before the transactions, like the state processor
+	if ctx.statedb != nil && random.IsRunning() {
+		if err := random.RevealAndCommit(block.Randomness().Revealed, block.Randomness().Committed, block.Coinbase(), block.Header(), ctx.statedb); err != nil {
+			return nil, err
+		}
+		ctx.statedb.IntermediateRoot(true)
+	}
+	for i, tx := range block.Transactions() {
+		msg, err := tx.AsMessage(signer)
+		if err != nil {
+			return nil, err
+		}
+		var receipt *types.Receipt
+		if i < len(ctx.receipts) {
+			receipt = ctx.receipts[i]
+		}
+		entry := func(kind string, from common.Address, to *common.Address, value *big.Int, currency *common.Address) *HistoryEntry {
+			return &HistoryEntry{
+				BlockNumber: block.NumberU64(),
+				BlockHash:   block.Hash(),
+				TxHash:      tx.Hash(),
+				TxIndex:     uint(i),
+				Kind:        kind,
+				From:        from,
+				To:          to,
+				Value:       value,
+				Currency:    currency,
+			}
+		}
+		entries = append(entries, entry(KindTransaction, msg.From(), tx.To(), tx.Value(), nil))
+
+		// Native transfers
+		var transfers []*nativeTransfer
+		if ctx.statedb != nil {
+			if transfers, err = executeTransfers(ctx, msg, tx, i); err != nil {
+				return nil, err
+			}
+		} else if receipt != nil && receipt.Status == types.ReceiptStatusSuccessful && tx.Value().Sign() > 0 && tx.To() != nil {
+			transfers = []*nativeTransfer{{from: msg.From(), to: *tx.To(), value: tx.Value()}}
+		}
+		for _, transfer := range transfers {
+			kind := KindTransfer
+			if transfer.tobinTax {
+				kind = KindTobinTax
+			}
+			to := transfer.to
+			entries = append(entries, entry(kind, transfer.from, &to, transfer.value, nil))
+		}
+		if receipt == nil {
+			continue
+		}
+		// Transfers of the whitelisted currencies
+		for _, l := range receipt.Logs {
+			if !whitelisted[l.Address] || len(l.Topics) != 3 || l.Topics[0] != transferTopic || len(l.Data) != 32 {
+				continue
+			}
+			to, currency := common.BytesToAddress(l.Topics[2].Bytes()), l.Address
+			entries = append(entries, entry(KindTokenTransfer, common.BytesToAddress(l.Topics[1].Bytes()), &to, new(big.Int).SetBytes(l.Data), &currency))
+		}
+		// Fees
+		fee := new(big.Int).Mul(new(big.Int).SetUint64(receipt.GasUsed), tx.GasPrice())
+		entries = append(entries, entry(KindFee, msg.From(), nil, fee, tx.FeeCurrency()))
+		if recipient := tx.GatewayFeeRecipient(); recipient != nil && tx.GatewayFee() != nil && tx.GatewayFee().Sign() > 0 {
+			entries = append(entries, entry(KindGatewayFee, msg.From(), recipient, tx.GatewayFee(), tx.FeeCurrency()))
+		}
+	}
+	return entries, nil
+}
+
+// executeTransfers executes a transaction on the state of the block context,
+// returning the native transfers it made.
+func executeTransfers(ctx *blockContext, msg types.Message, tx *types.Transaction, index int) ([]*nativeTransfer, error) {
+	tracer := new(transferTracer)
+	vmctx := vm.NewEVMContext(msg, ctx.block.Header(), ctx.chain, nil)
+	transfer := vmctx.Transfer
+	vmctx.Transfer = func(db vm.StateDB, from, to common.Address, value *big.Int) {
+		tracer.record(from, to, value, ctx.reserve)
+		transfer(db, from, to, value)
+	}
+	ctx.statedb.Prepare(tx.Hash(), ctx.block.Hash(), index)
+	vmenv := vm.NewEVM(vmctx, ctx.statedb, ctx.chain.Config(), vm.Config{Debug: true, Tracer: tracer})
+	_, _, failed, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.Gas()))
+	if err != nil {
+		return nil, err
+	}
+	ctx.statedb.Finalise(vmenv.ChainConfig().IsEIP158(ctx.block.Number()))
+	if failed {
+		return nil, nil
+	}
+	return tracer.transfers, nil
+}
+
+// nativeTransfer is a transfer of CELO made by a transaction.
+type nativeTransfer struct {
+	from, to common.Address
+	value    *big.Int
+	tobinTax bool // Whether the transfer is the Tobin tax of the previous one
+}
+
+// pendingCall is a call made by a contract, whose transfers are dropped if it
+// fails.
+type pendingCall struct {
+	depth int // Depth of the calling contract
+	start int // Number of transfers before the call
+}
+
+// transferTracer records the native transfers which aren't reverted.
+type transferTracer struct {
+	transfers []*nativeTransfer
+	calls     []pendingCall
+}
+
+// record records a transfer made by the EVM, recognizing the Tobin tax sent to
+// the reserve right after the taxed transfer.
+func (t *transferTracer) record(from, to common.Address, value *big.Int, reserve *common.Address) {
+	if value.Sign() == 0 {
+		return
+	}
+	transfer := &nativeTransfer{from: from, to: to, value: new(big.Int).Set(value)}
+	if n := len(t.transfers); n > 0 && reserve != nil && to == *reserve && t.transfers[n-1].from == from && !t.transfers[n-1].tobinTax {
+		transfer.tobinTax = true
+	}
+	t.transfers = append(t.transfers, transfer)
+}
+
+func (t *transferTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	return nil
+}
+
+// CaptureState drops the transfers of the calls which failed, once back in the
+// calling contract, and tracks the calls made.
+func (t *transferTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	for len(t.calls) > 0 && t.calls[len(t.calls)-1].depth >= depth {
+		call := t.calls[len(t.calls)-1]
+		t.calls = t.calls[:len(t.calls)-1]
+		// The result of the call is on top of the stack of the calling contract
+		if call.depth == depth && len(stack.Data()) > 0 && stack.Back(0).Sign() == 0 {
+			t.transfers = t.transfers[:call.start]
+		}
+	}
+	switch op {
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL, vm.CREATE, vm.CREATE2:
+		t.calls = append(t.calls, pendingCall{depth: depth, start: len(t.transfers)})
+	}
+	return nil
+}
+
+func (t *transferTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+func (t *transferTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	return nil
+}
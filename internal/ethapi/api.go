@@ -34,7 +34,9 @@ import (
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/consensus/misc"
 	"github.com/ethereum/go-ethereum/contract_comm/blockchain_parameters"
+	gpm "github.com/ethereum/go-ethereum/contract_comm/gasprice_minimum"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -45,6 +47,7 @@ import (
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
 	"github.com/tyler-smith/go-bip39"
 )
 
@@ -65,6 +68,22 @@ func (s *PublicEthereumAPI) GasPrice(ctx context.Context) (*hexutil.Big, error)
 	return (*hexutil.Big)(price), err
 }
 
+// BaseFee returns the base fee of the pending block, converted into the given
+// fee currency at the oracle rate of the latest block. If feeCurrency is
+// omitted the base fee is returned in CELO.
+func (s *PublicEthereumAPI) BaseFee(ctx context.Context, feeCurrency *common.Address) (*hexutil.Big, error) {
+	state, header, err := s.b.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	config := s.b.ChainConfig()
+	if !config.IsBaseFee(new(big.Int).Add(header.Number, common.Big1)) {
+		return nil, errors.New("base fee fork is not active")
+	}
+	baseFee, err := gpm.GetBaseFeeInCurrency(feeCurrency, misc.CalcBaseFee(config, header), header, state)
+	return (*hexutil.Big)(baseFee), err
+}
+
 // ProtocolVersion returns the current Ethereum protocol version this node supports
 func (s *PublicEthereumAPI) ProtocolVersion() hexutil.Uint {
 	return hexutil.Uint(s.b.ProtocolVersion())
@@ -394,7 +413,7 @@ func (s *PrivateAccountAPI) SignTransaction(ctx context.Context, args SendTxArgs
 		log.Warn("Failed transaction sign attempt", "from", args.From, "to", args.To, "value", args.Value.ToInt(), "err", err)
 		return nil, err
 	}
-	data, err := rlp.EncodeToBytes(signed)
+	data, err := signed.MarshalBinary()
 	if err != nil {
 		return nil, err
 	}
@@ -1035,7 +1054,7 @@ func FormatLogs(logs []vm.StructLog) []StructLogRes {
 // RPCMarshalHeader converts the given header to the RPC output .
 func RPCMarshalHeader(head *types.Header) map[string]interface{} {
 	// TODO(asa): Consider removing parentHash
-	return map[string]interface{}{
+	result := map[string]interface{}{
 		"number":           (*hexutil.Big)(head.Number),
 		"hash":             head.Hash(),
 		"parentHash":       head.ParentHash,
@@ -1054,6 +1073,10 @@ func RPCMarshalHeader(head *types.Header) map[string]interface{} {
 		"transactionsRoot": head.TxHash,
 		"receiptsRoot":     head.ReceiptHash,
 	}
+	if head.BaseFee != nil {
+		result["baseFee"] = (*hexutil.Big)(head.BaseFee)
+	}
+	return result
 }
 
 // RPCMarshalBlock converts the given block to the RPC output which depends on fullTx. If inclTx is true transactions are
@@ -1135,6 +1158,12 @@ type RPCTransaction struct {
 	V                   *hexutil.Big    `json:"v"`
 	R                   *hexutil.Big    `json:"r"`
 	S                   *hexutil.Big    `json:"s"`
+	Type                hexutil.Uint64  `json:"type"`
+	ChainID             *hexutil.Big    `json:"chainId,omitempty"`
+	FeePayer            *common.Address `json:"feePayer,omitempty"`
+	PayerV              *hexutil.Big    `json:"payerV,omitempty"`
+	PayerR              *hexutil.Big    `json:"payerR,omitempty"`
+	PayerS              *hexutil.Big    `json:"payerS,omitempty"`
 }
 
 // newRPCTransaction returns a transaction that will serialize to the RPC
@@ -1142,7 +1171,7 @@ type RPCTransaction struct {
 func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber uint64, index uint64) *RPCTransaction {
 	var signer types.Signer = types.FrontierSigner{}
 	if tx.Protected() {
-		signer = types.NewEIP155Signer(tx.ChainId())
+		signer = types.NewCeloSigner(tx.ChainId())
 	}
 	from, _ := types.Sender(signer, tx)
 	v, r, s := tx.RawSignatureValues()
@@ -1162,6 +1191,17 @@ func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber
 		V:                   (*hexutil.Big)(v),
 		R:                   (*hexutil.Big)(r),
 		S:                   (*hexutil.Big)(s),
+		Type:                hexutil.Uint64(tx.Type()),
+	}
+	if tx.Type() != types.LegacyTxType {
+		result.ChainID = (*hexutil.Big)(tx.ChainId())
+	}
+	if tx.Type() == types.SponsoredTxType {
+		payerV, payerR, payerS := tx.RawFeePayerSignatureValues()
+		result.FeePayer = tx.FeePayer()
+		result.PayerV = (*hexutil.Big)(payerV)
+		result.PayerR = (*hexutil.Big)(payerR)
+		result.PayerS = (*hexutil.Big)(payerS)
 	}
 	if blockHash != (common.Hash{}) {
 		result.BlockHash = &blockHash
@@ -1191,7 +1231,7 @@ func newRPCRawTransactionFromBlockIndex(b *types.Block, index uint64) hexutil.By
 	if index >= uint64(len(txs)) {
 		return nil
 	}
-	blob, _ := rlp.EncodeToBytes(txs[index])
+	blob, _ := txs[index].MarshalBinary()
 	return blob
 }
 
@@ -1317,8 +1357,32 @@ func (s *PublicTransactionPoolAPI) GetRawTransactionByHash(ctx context.Context,
 			return nil, nil
 		}
 	}
-	// Serialize to RLP and return
-	return rlp.EncodeToBytes(tx)
+	// Serialize to the canonical encoding and return
+	return tx.MarshalBinary()
+}
+
+// GetTransactionBySenderAndNonce returns the transaction sent by the given account
+// with the given nonce, finalized or pooled. It requires the sender index.
+func (s *PublicTransactionPoolAPI) GetTransactionBySenderAndNonce(ctx context.Context, sender common.Address, nonce hexutil.Uint64) (*RPCTransaction, error) {
+	// Try to return an already finalized transaction
+	tx, blockHash, blockNumber, index, err := s.b.GetTransactionBySenderAndNonce(ctx, sender, uint64(nonce))
+	if err != nil {
+		return nil, err
+	}
+	if tx != nil {
+		return newRPCTransaction(tx, blockHash, blockNumber, index), nil
+	}
+	// No finalized transaction, try to retrieve it from the pool
+	pending, queue := s.b.TxPoolContent()
+	for _, txs := range []types.Transactions{pending[sender], queue[sender]} {
+		for _, tx := range txs {
+			if tx.Nonce() == uint64(nonce) {
+				return newRPCPendingTransaction(tx), nil
+			}
+		}
+	}
+	// Transaction unknown, return as such
+	return nil, nil
 }
 
 // GetTransactionReceipt returns the transaction receipt for the given transaction hash.
@@ -1338,7 +1402,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 
 	var signer types.Signer = types.FrontierSigner{}
 	if tx.Protected() {
-		signer = types.NewEIP155Signer(tx.ChainId())
+		signer = types.NewCeloSigner(tx.ChainId())
 	}
 	from, _ := types.Sender(signer, tx)
 
@@ -1385,6 +1449,28 @@ func (s *PublicTransactionPoolAPI) sign(addr common.Address, tx *types.Transacti
 	return wallet.SignTx(account, tx, s.b.ChainConfig().ChainID)
 }
 
+// signAsFeePayer adds the fee payer signature to a sponsored transaction, using
+// the wallet containing the fee payer account.
+func (s *PublicTransactionPoolAPI) signAsFeePayer(tx *types.Transaction) (*types.Transaction, error) {
+	if tx.Type() != types.SponsoredTxType {
+		return nil, types.ErrTxTypeNotSupported
+	}
+	// Look up the wallet containing the fee payer
+	account := accounts.Account{Address: *tx.FeePayer()}
+
+	wallet, err := s.b.AccountManager().Find(account)
+	if err != nil {
+		return nil, err
+	}
+	signer := types.NewCeloSigner(s.b.ChainConfig().ChainID)
+	hash := signer.FeePayerHash(tx)
+	sig, err := wallet.SignHash(account, hash[:])
+	if err != nil {
+		return nil, err
+	}
+	return tx.WithFeePayerSignature(signer, sig)
+}
+
 // SendTxArgs represents the arguments to sumbit a new transaction into the transaction pool.
 type SendTxArgs struct {
 	From                common.Address  `json:"from"`
@@ -1400,10 +1486,19 @@ type SendTxArgs struct {
 	// newer name and should be preferred by clients.
 	Data  *hexutil.Bytes `json:"data"`
 	Input *hexutil.Bytes `json:"input"`
+	// Type selects the transaction envelope, legacy if unset. The chain id is
+	// only used by typed transactions and defaults to the one of the node.
+	Type    *hexutil.Uint64 `json:"type"`
+	ChainID *hexutil.Big    `json:"chainId"`
+	// FeePayer is the account paying the fees of a sponsored transaction.
+	FeePayer *common.Address `json:"feePayer"`
 }
 
 // setDefaults is a helper function that fills in default values for unspecified tx fields.
 func (args *SendTxArgs) setDefaults(ctx context.Context, b Backend) error {
+	if args.FeeCurrency == nil {
+		args.FeeCurrency = b.DefaultFeeCurrency()
+	}
 	if args.Gas == nil {
 		args.Gas = new(hexutil.Uint64)
 		defaultGas := uint64(90000)
@@ -1438,6 +1533,24 @@ func (args *SendTxArgs) setDefaults(ctx context.Context, b Backend) error {
 	if args.Value == nil {
 		args.Value = new(hexutil.Big)
 	}
+	if args.Type != nil {
+		switch *args.Type {
+		case types.LegacyTxType:
+		case types.CeloTxType, types.SponsoredTxType:
+			if args.ChainID == nil {
+				args.ChainID = (*hexutil.Big)(b.ChainConfig().ChainID)
+			}
+		default:
+			return types.ErrTxTypeNotSupported
+		}
+	}
+	sponsored := args.Type != nil && *args.Type == types.SponsoredTxType
+	if sponsored && args.FeePayer == nil {
+		return errors.New(`"feePayer" is required for sponsored transactions`)
+	}
+	if !sponsored && args.FeePayer != nil {
+		return errors.New(`"feePayer" is only supported by sponsored transactions`)
+	}
 	if args.Nonce == nil {
 		nonce, err := b.GetPoolNonce(ctx, args.From)
 		if err != nil {
@@ -1492,7 +1605,11 @@ func (args *SendTxArgs) setDefaults(ctx context.Context, b Backend) error {
 		log.Trace("Estimate gas usage automatically", "gas", args.Gas)
 	}
 	if args.GatewayFeeRecipient != nil && args.GatewayFee == nil {
-		args.GatewayFee = (*hexutil.Big)(b.GatewayFee())
+		gatewayFee, err := b.GatewayFee()
+		if err != nil {
+			return err
+		}
+		args.GatewayFee = (*hexutil.Big)(gatewayFee)
 	}
 	return nil
 }
@@ -1504,6 +1621,12 @@ func (args *SendTxArgs) toTransaction() *types.Transaction {
 	} else if args.Data != nil {
 		input = *args.Data
 	}
+	if args.Type != nil && *args.Type == types.SponsoredTxType {
+		return types.NewSponsoredTransaction((*big.Int)(args.ChainID), uint64(*args.Nonce), args.To, (*big.Int)(args.Value), uint64(*args.Gas), (*big.Int)(args.GasPrice), args.FeeCurrency, *args.FeePayer, args.GatewayFeeRecipient, (*big.Int)(args.GatewayFee), input)
+	}
+	if args.Type != nil && *args.Type == types.CeloTxType {
+		return types.NewCeloTransaction((*big.Int)(args.ChainID), uint64(*args.Nonce), args.To, (*big.Int)(args.Value), uint64(*args.Gas), (*big.Int)(args.GasPrice), args.FeeCurrency, args.GatewayFeeRecipient, (*big.Int)(args.GatewayFee), input)
+	}
 	if args.To == nil {
 		return types.NewContractCreation(uint64(*args.Nonce), (*big.Int)(args.Value), uint64(*args.Gas), (*big.Int)(args.GasPrice), args.FeeCurrency, args.GatewayFeeRecipient, (*big.Int)(args.GatewayFee), input)
 	}
@@ -1558,6 +1681,12 @@ func (s *PublicTransactionPoolAPI) SendTransaction(ctx context.Context, args Sen
 	if err != nil {
 		return common.Hash{}, err
 	}
+	// Sponsored transactions can only be submitted if the fee payer is local too
+	if signed.Type() == types.SponsoredTxType {
+		if signed, err = s.signAsFeePayer(signed); err != nil {
+			return common.Hash{}, err
+		}
+	}
 	return SubmitTransaction(ctx, s.b, signed)
 }
 
@@ -1568,9 +1697,9 @@ func (s *PublicTransactionPoolAPI) FillTransaction(ctx context.Context, args Sen
 	if err := args.setDefaults(ctx, s.b); err != nil {
 		return nil, err
 	}
-	// Assemble the transaction and obtain rlp
+	// Assemble the transaction and obtain its canonical encoding
 	tx := args.toTransaction()
-	data, err := rlp.EncodeToBytes(tx)
+	data, err := tx.MarshalBinary()
 	if err != nil {
 		return nil, err
 	}
@@ -1581,7 +1710,7 @@ func (s *PublicTransactionPoolAPI) FillTransaction(ctx context.Context, args Sen
 // The sender is responsible for signing the transaction and using the correct nonce.
 func (s *PublicTransactionPoolAPI) SendRawTransaction(ctx context.Context, encodedTx hexutil.Bytes) (common.Hash, error) {
 	tx := new(types.Transaction)
-	if err := rlp.DecodeBytes(encodedTx, tx); err != nil {
+	if err := tx.UnmarshalBinary(encodedTx); err != nil {
 		return common.Hash{}, err
 	}
 	return SubmitTransaction(ctx, s.b, tx)
@@ -1612,7 +1741,65 @@ func (s *PublicTransactionPoolAPI) Sign(addr common.Address, data hexutil.Bytes)
 	return signature, err
 }
 
-// SignTransactionResult represents a RLP encoded signed transaction.
+// SignTypedData calculates an ECDSA signature of EIP-712 typed data:
+// keccak256("\x19\x01" + domainSeparator + hashStruct(message)).
+//
+// The typed data can reference the Celo types without defining them. The chain
+// ID of the domain defaults to the one of the chain, and must match it if set.
+//
+// The account associated with addr must be unlocked.
+func (s *PublicTransactionPoolAPI) SignTypedData(addr common.Address, typedData apitypes.TypedData) (hexutil.Bytes, error) {
+	account := accounts.Account{Address: addr}
+
+	wallet, err := s.b.AccountManager().Find(account)
+	if err != nil {
+		return nil, err
+	}
+	_, rawData, err := s.typedDataAndHash(typedData)
+	if err != nil {
+		return nil, err
+	}
+	// The wallet signs the keccak256 hash of the data
+	signature, err := wallet.SignData(account, accounts.MimetypeTypedData, rawData)
+	if err == nil {
+		signature[64] += 27 // Transform V from 0/1 to 27/28 according to the yellow paper
+	}
+	return signature, err
+}
+
+// SignTypedDataBLS calculates a BLS signature of the EIP-712 hash of typed data,
+// as SignTypedData does with an ECDSA signature.
+//
+// The account associated with addr must be unlocked.
+func (s *PublicTransactionPoolAPI) SignTypedDataBLS(addr common.Address, typedData apitypes.TypedData) (hexutil.Bytes, error) {
+	account := accounts.Account{Address: addr}
+
+	wallet, err := s.b.AccountManager().Find(account)
+	if err != nil {
+		return nil, err
+	}
+	hash, _, err := s.typedDataAndHash(typedData)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := wallet.SignMessageBLS(account, hash, []byte{})
+	if err != nil {
+		return nil, err
+	}
+	return signature[:], nil
+}
+
+// typedDataAndHash returns the EIP-712 hash of typed data on this chain, and the
+// data it hashes.
+func (s *PublicTransactionPoolAPI) typedDataAndHash(typedData apitypes.TypedData) ([]byte, []byte, error) {
+	typedData.AddCeloTypes()
+	if err := typedData.SetChainID(s.b.ChainConfig().ChainID); err != nil {
+		return nil, nil, err
+	}
+	return apitypes.TypedDataAndHash(typedData)
+}
+
+// SignTransactionResult represents a signed transaction in its canonical encoding.
 type SignTransactionResult struct {
 	Raw hexutil.Bytes      `json:"raw"`
 	Tx  *types.Transaction `json:"tx"`
@@ -1638,7 +1825,34 @@ func (s *PublicTransactionPoolAPI) SignTransaction(ctx context.Context, args Sen
 	if err != nil {
 		return nil, err
 	}
-	data, err := rlp.EncodeToBytes(tx)
+	data, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return &SignTransactionResult{data, tx}, nil
+}
+
+// SignTransactionAsFeePayer adds the fee payer signature to the given encoded
+// sponsored transaction, which must already be signed by its sender. The node
+// needs to have the private key of the fee payer account and it needs to be
+// unlocked.
+func (s *PublicTransactionPoolAPI) SignTransactionAsFeePayer(ctx context.Context, input hexutil.Bytes) (*SignTransactionResult, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(input); err != nil {
+		return nil, err
+	}
+	if tx.Type() != types.SponsoredTxType {
+		return nil, types.ErrTxTypeNotSupported
+	}
+	// Only pay for transactions carrying a valid sender signature
+	if _, err := types.Sender(types.NewCeloSigner(s.b.ChainConfig().ChainID), tx); err != nil {
+		return nil, err
+	}
+	tx, err := s.signAsFeePayer(tx)
+	if err != nil {
+		return nil, err
+	}
+	data, err := tx.MarshalBinary()
 	if err != nil {
 		return nil, err
 	}
@@ -1662,7 +1876,7 @@ func (s *PublicTransactionPoolAPI) PendingTransactions() ([]*RPCTransaction, err
 	for _, tx := range pending {
 		var signer types.Signer = types.HomesteadSigner{}
 		if tx.Protected() {
-			signer = types.NewEIP155Signer(tx.ChainId())
+			signer = types.NewCeloSigner(tx.ChainId())
 		}
 		from, _ := types.Sender(signer, tx)
 		if _, exists := accounts[from]; exists {
@@ -1690,7 +1904,7 @@ func (s *PublicTransactionPoolAPI) Resend(ctx context.Context, sendArgs SendTxAr
 	for _, p := range pending {
 		var signer types.Signer = types.HomesteadSigner{}
 		if p.Protected() {
-			signer = types.NewEIP155Signer(p.ChainId())
+			signer = types.NewCeloSigner(p.ChainId())
 		}
 		wantSigHash := signer.Hash(matchTx)
 
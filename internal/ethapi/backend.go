@@ -69,6 +69,7 @@ type Backend interface {
 	// Transaction pool API
 	SendTx(ctx context.Context, signedTx *types.Transaction) error
 	GetTransaction(ctx context.Context, txHash common.Hash) (*types.Transaction, common.Hash, uint64, uint64, error)
+	GetTransactionBySenderAndNonce(ctx context.Context, sender common.Address, nonce uint64) (*types.Transaction, common.Hash, uint64, uint64, error)
 	GetPoolTransactions() (types.Transactions, error)
 	GetPoolTransaction(txHash common.Hash) *types.Transaction
 	GetPoolNonce(ctx context.Context, addr common.Address) (uint64, error)
@@ -87,7 +88,8 @@ type Backend interface {
 	CurrentBlock() *types.Block
 
 	GatewayFeeRecipient() common.Address
-	GatewayFee() *big.Int
+	GatewayFee() (*big.Int, error)
+	DefaultFeeCurrency() *common.Address
 }
 
 func GetAPIs(apiBackend Backend) []rpc.API {
@@ -20,6 +20,7 @@ package web3ext
 var Modules = map[string]string{
 	"accounting": AccountingJs,
 	"admin":      AdminJs,
+	"celo":       CeloJs,
 	"chequebook": ChequebookJs,
 	"clique":     CliqueJs,
 	"ethash":     EthashJs,
@@ -36,6 +37,20 @@ var Modules = map[string]string{
 	"les":        LESJs,
 }
 
+const CeloJs = `
+web3._extend({
+	property: 'celo',
+	methods: [
+		new web3._extend.Method({
+			name: 'getAddressHistory',
+			call: 'celo_getAddressHistory',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
+		}),
+	]
+});
+`
+
 const ChequebookJs = `
 web3._extend({
 	property: 'chequebook',
@@ -170,6 +185,25 @@ web3._extend({
 			call: 'admin_removeTrustedPeer',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'pinPeer',
+			call: 'admin_pinPeer',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'unpinPeer',
+			call: 'admin_unpinPeer',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'peerHistory',
+			call: 'admin_peerHistory',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'reloadConfig',
+			call: 'admin_reloadConfig',
+		}),
 		new web3._extend.Method({
 			name: 'exportChain',
 			call: 'admin_exportChain',
@@ -220,6 +254,10 @@ web3._extend({
 			name: 'peers',
 			getter: 'admin_peers'
 		}),
+		new web3._extend.Property({
+			name: 'peersByPurpose',
+			getter: 'admin_peersByPurpose'
+		}),
 		new web3._extend.Property({
 			name: 'datadir',
 			getter: 'admin_datadir'
@@ -492,6 +530,23 @@ web3._extend({
 			params: 1,
 			inputFormatter: [web3._extend.formatters.inputTransactionFormatter]
 		}),
+		new web3._extend.Method({
+			name: 'signTransactionAsFeePayer',
+			call: 'eth_signTransactionAsFeePayer',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'signTypedData',
+			call: 'eth_signTypedData',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'signTypedDataBLS',
+			call: 'eth_signTypedDataBLS',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
+		}),
 		new web3._extend.Method({
 			name: 'submitTransaction',
 			call: 'eth_submitTransaction',
@@ -504,6 +559,12 @@ web3._extend({
 			params: 1,
 			inputFormatter: [web3._extend.formatters.inputTransactionFormatter]
 		}),
+		new web3._extend.Method({
+			name: 'getTransactionBySenderAndNonce',
+			call: 'eth_getTransactionBySenderAndNonce',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.utils.fromDecimal]
+		}),
 		new web3._extend.Method({
 			name: 'getHeaderByNumber',
 			call: 'eth_getHeaderByNumber',
@@ -543,6 +604,13 @@ web3._extend({
 			params: 3,
 			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, web3._extend.formatters.inputBlockNumberFormatter]
 		}),
+		new web3._extend.Method({
+			name: 'baseFee',
+			call: 'eth_baseFee',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter],
+			outputFormatter: web3._extend.utils.toBigNumber
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -890,6 +958,11 @@ web3._extend({
 			call: 'les_addBalance',
 			params: 3
 		}),
+		new web3._extend.Method({
+			name: 'setNotificationFilter',
+			call: 'les_setNotificationFilter',
+			params: 1
+		}),
 	],
 	properties:
 	[
@@ -905,6 +978,10 @@ web3._extend({
 			name: 'serverInfo',
 			getter: 'les_serverInfo'
 		}),
+		new web3._extend.Property({
+			name: 'notificationFilter',
+			getter: 'les_notificationFilter'
+		}),
 	]
 });
 `
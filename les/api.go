@@ -17,6 +17,7 @@
 package les
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math"
@@ -25,6 +26,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/common/mclock"
 	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 var (
@@ -352,3 +354,54 @@ func (api *PrivateLightAPI) GetCheckpointContractAddress() (string, error) {
 	}
 	return api.backend.oracle.config.Address.Hex(), nil
 }
+
+// PrivateLightNotificationAPI provides an API to manage the notifications the
+// LES servers relay to the light client, stored while the client is offline.
+type PrivateLightNotificationAPI struct {
+	notifications *notificationClient
+}
+
+// NewPrivateLightNotificationAPI creates a new LES light client notification API.
+func NewPrivateLightNotificationAPI(notifications *notificationClient) *PrivateLightNotificationAPI {
+	return &PrivateLightNotificationAPI{notifications: notifications}
+}
+
+// NotificationFilter returns the filter selecting the transactions and logs
+// relayed by the servers.
+func (api *PrivateLightNotificationAPI) NotificationFilter() NotificationFilter {
+	return api.notifications.Filter()
+}
+
+// SetNotificationFilter sets the filter selecting the transactions and logs
+// relayed by the servers. An empty filter stops the notifications.
+func (api *PrivateLightNotificationAPI) SetNotificationFilter(filter NotificationFilter) error {
+	return api.notifications.SetFilter(filter)
+}
+
+// Notifications creates a subscription to the transactions and logs relayed by
+// the servers, including those stored while the client was offline.
+func (api *PrivateLightNotificationAPI) Notifications(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		notifications := make(chan *Notification, 128)
+		sub := api.notifications.SubscribeNotifications(notifications)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case notification := <-notifications:
+				notifier.Notify(rpcSub.ID, notification)
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
@@ -40,6 +40,12 @@ import (
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
+var (
+	errGatewayFeeTooHigh   = errors.New("gateway fee exceeds the configured maximum")
+	errMaxGatewayFeeTooLow = errors.New("configured maximum gateway fee is below the minimum of the light servers")
+	errNoTxSenderIndex     = errors.New("transaction sender index not available on light clients")
+)
+
 type LesApiBackend struct {
 	extRPCEnabled bool
 	eth           *LightEthereum
@@ -175,6 +181,9 @@ func (b *LesApiBackend) GetEVM(ctx context.Context, msg vm.Message, header *type
 }
 
 func (b *LesApiBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
+	if max := b.eth.config.MaxGatewayFee; max != nil && signedTx.GatewayFee() != nil && signedTx.GatewayFee().Cmp(max) > 0 {
+		return errGatewayFeeTooHigh
+	}
 	return b.eth.txPool.Add(ctx, signedTx)
 }
 
@@ -194,6 +203,10 @@ func (b *LesApiBackend) GetTransaction(ctx context.Context, txHash common.Hash)
 	return light.GetTransaction(ctx, b.eth.odr, txHash)
 }
 
+func (b *LesApiBackend) GetTransactionBySenderAndNonce(ctx context.Context, sender common.Address, nonce uint64) (*types.Transaction, common.Hash, uint64, uint64, error) {
+	return nil, common.Hash{}, 0, 0, errNoTxSenderIndex
+}
+
 func (b *LesApiBackend) GetPoolNonce(ctx context.Context, addr common.Address) (uint64, error) {
 	return b.eth.txPool.GetNonce(ctx, addr)
 }
@@ -288,7 +301,16 @@ func (b *LesApiBackend) GatewayFeeRecipient() common.Address {
 	return b.eth.GetRandomPeerEtherbase()
 }
 
-func (b *LesApiBackend) GatewayFee() *big.Int {
+func (b *LesApiBackend) GatewayFee() (*big.Int, error) {
 	// TODO(nategraf): Create a method to fetch the gateway fee values of peers along with the coinbase.
-	return eth.DefaultConfig.GatewayFee
+	// Light servers reject transactions paying less than their minimum, so the configured maximum
+	// can't be used in its place.
+	if max := b.eth.config.MaxGatewayFee; max != nil && max.Cmp(eth.DefaultConfig.GatewayFee) < 0 {
+		return nil, errMaxGatewayFeeTooLow
+	}
+	return eth.DefaultConfig.GatewayFee, nil
+}
+
+func (b *LesApiBackend) DefaultFeeCurrency() *common.Address {
+	return b.eth.config.DefaultFeeCurrency
 }
@@ -0,0 +1,51 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/eth"
+)
+
+func TestLesApiBackendGatewayFee(t *testing.T) {
+	min := eth.DefaultConfig.GatewayFee
+	tests := []struct {
+		max  *big.Int
+		want *big.Int
+		err  error
+	}{
+		{nil, min, nil},
+		{new(big.Int).Add(min, big.NewInt(1)), min, nil},
+		{new(big.Int).Set(min), min, nil},
+		{new(big.Int).Sub(min, big.NewInt(1)), nil, errMaxGatewayFeeTooLow},
+	}
+	for i, tt := range tests {
+		config := eth.DefaultConfig
+		config.MaxGatewayFee = tt.max
+		b := &LesApiBackend{eth: &LightEthereum{lesCommons: lesCommons{config: &config}}}
+
+		fee, err := b.GatewayFee()
+		if err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+		if (fee == nil) != (tt.want == nil) || fee != nil && fee.Cmp(tt.want) != 0 {
+			t.Errorf("test %d: gateway fee mismatch: have %v, want %v", i, fee, tt.want)
+		}
+	}
+}
@@ -95,6 +95,9 @@ func New(ctx *node.ServiceContext, config *eth.Config) (*LightEthereum, error) {
 	}
 	log.Info("Initialised chain configuration", "config", chainConfig)
 
+	// Light servers the client prefers are dialed like trusted ultra light servers
+	trustedServers := append(append([]string{}, config.UltraLightServers...), config.LightServers...)
+
 	peers := newPeerSet()
 	leth := &LightEthereum{
 		lesCommons: lesCommons{
@@ -113,7 +116,7 @@ func New(ctx *node.ServiceContext, config *eth.Config) (*LightEthereum, error) {
 		networkId:      config.NetworkId,
 		bloomRequests:  make(chan chan *bloombits.Retrieval),
 		bloomIndexer:   eth.NewBloomIndexer(chainDb, params.BloomBitsBlocksClient, params.HelperTrieConfirmations, fullChainAvailable),
-		serverPool:     newServerPool(chainDb, config.UltraLightServers),
+		serverPool:     newServerPool(chainDb, trustedServers),
 	}
 
 	if syncMode == downloader.LightestSync && chainConfig.Istanbul == nil {
@@ -239,6 +242,11 @@ func (s *LightEthereum) APIs() []rpc.API {
 			Version:   "1.0",
 			Service:   NewPrivateLightAPI(&s.lesCommons),
 			Public:    false,
+		}, {
+			Namespace: "les",
+			Version:   "1.0",
+			Service:   NewPrivateLightNotificationAPI(s.handler.notifications),
+			Public:    false,
 		},
 	}...)
 }
@@ -286,6 +294,31 @@ func (s *LightEthereum) GetRandomPeerEtherbase() common.Address {
 	return s.peers.randomPeerEtherbase()
 }
 
+// SyncedEvent is posted when the local chain caught up with the head of a server.
+type SyncedEvent struct{ Head *types.Header }
+
+// Pause throttles the networking of the light client, e.g. while the app embedding
+// it runs in the background. The client disconnects from its servers, stops dialing
+// new ones and cancels the running sync. The server statistics and the servers
+// connected on pause are persisted along with the chain, so that Resume, or a
+// restart, reconnects to them and continues syncing from the local head.
+func (s *LightEthereum) Pause() {
+	s.serverPool.setPaused(true)
+	s.handler.downloader.Cancel()
+	log.Info("Light ethereum paused")
+}
+
+// Resume reconnects a paused light client to its last good servers.
+func (s *LightEthereum) Resume() {
+	s.serverPool.setPaused(false)
+	log.Info("Light ethereum resumed")
+}
+
+// SubscribeSyncedEvent registers a subscription of SyncedEvent.
+func (s *LightEthereum) SubscribeSyncedEvent(ch chan<- SyncedEvent) event.Subscription {
+	return s.handler.syncedFeed.Subscribe(ch)
+}
+
 // Stop implements node.Service, terminating all internal goroutines used by the
 // Ethereum protocol.
 func (s *LightEthereum) Stop() error {
@@ -26,6 +26,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/mclock"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/eth/downloader"
+	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/light"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
@@ -42,6 +43,9 @@ type clientHandler struct {
 	backend    *LightEthereum
 	syncMode   downloader.SyncMode
 
+	notifications *notificationClient // Notifications relayed by the servers
+	syncedFeed    event.Feed          // Feed of the local chain catching up with a server
+
 	closeCh  chan struct{}
 	wg       sync.WaitGroup // WaitGroup used to track all connected peers.
 	syncDone func()         // Test hooks when syncing is done.
@@ -66,6 +70,7 @@ func newClientHandler(syncMode downloader.SyncMode, ulcServers []string, ulcFrac
 	if checkpoint != nil {
 		height = (checkpoint.SectionIndex+1)*params.CHTFrequency - 1
 	}
+	handler.notifications = newNotificationClient(backend.chainDb, backend.reqDist)
 	handler.fetcher = newLightFetcher(handler)
 	// TODO mcortesi lightest boolean
 	handler.downloader = downloader.New(height, backend.chainDb, nil, backend.eventMux, nil, backend.blockchain, handler.removePeer)
@@ -77,6 +82,7 @@ func (h *clientHandler) stop() {
 	close(h.closeCh)
 	h.downloader.Terminate()
 	h.fetcher.close()
+	h.notifications.close()
 	h.wg.Wait()
 }
 
@@ -124,6 +130,7 @@ func (h *clientHandler) handle(p *peer) error {
 
 	connectedAt := mclock.Now()
 	defer func() {
+		h.notifications.disconnected(p)
 		h.backend.peers.Unregister(p.id)
 		connectionTimer.Update(time.Duration(mclock.Now() - connectedAt))
 		serverConnectionGauge.Update(int64(h.backend.peers.Len()))
@@ -136,6 +143,8 @@ func (h *clientHandler) handle(p *peer) error {
 		h.backend.serverPool.registered(p.poolEntry)
 	}
 
+	h.notifications.connected(p)
+
 	// Loop until we receive a RequestEtherbase response or timeout.
 	go func() {
 		maxRequests := 10
@@ -346,6 +355,23 @@ func (h *clientHandler) handleMsg(p *peer) error {
 		p.fcServer.ReceivedReply(resp.ReqID, resp.BV)
 		p.Log().Trace("Setting peer etherbase", "etherbase", resp.Etherbase, "Peer ID", p.ID)
 		p.SetEtherbase(resp.Etherbase)
+	case NotificationsMsg:
+		p.Log().Trace("Received notifications")
+		var resp struct {
+			ReqID, BV     uint64
+			Notifications []*Notification
+		}
+		if err := msg.Decode(&resp); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		// Notifications pushed as they happen are not replies to a request, but
+		// the server charged them to the buffer all the same
+		if resp.ReqID != 0 {
+			p.fcServer.ReceivedReply(resp.ReqID, resp.BV)
+		} else {
+			p.fcServer.ReceivedPush(resp.BV)
+		}
+		h.notifications.deliver(p, resp.ReqID, resp.Notifications)
 	default:
 		p.Log().Trace("Received invalid message", "code", msg.Code)
 		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
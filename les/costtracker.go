@@ -45,6 +45,7 @@ var (
 		SendTxV2Msg:            {0, 450000},
 		GetTxStatusMsg:         {0, 250000},
 		GetEtherbaseMsg:        {10000, 1},
+		GetNotificationsMsg:    {50000, 2000},
 	}
 	// maximum incoming message size estimates
 	reqMaxInSize = requestCostTable{
@@ -57,6 +58,7 @@ var (
 		SendTxV2Msg:            {0, 16500},
 		GetTxStatusMsg:         {0, 50},
 		GetEtherbaseMsg:        {0, 10},
+		GetNotificationsMsg:    {2200, 0},
 	}
 	// maximum outgoing message size estimates
 	reqMaxOutSize = requestCostTable{
@@ -69,6 +71,7 @@ var (
 		SendTxV2Msg:            {0, 100},
 		GetTxStatusMsg:         {0, 100},
 		GetEtherbaseMsg:        {0, 100},
+		GetNotificationsMsg:    {0, 400},
 	}
 	// request amounts that have to fit into the minimum buffer size minBufferMultiplier times
 	minBufferReqAmount = map[uint64]uint64{
@@ -81,6 +84,7 @@ var (
 		SendTxV2Msg:            8,
 		GetTxStatusMsg:         64,
 		GetEtherbaseMsg:        1,
+		GetNotificationsMsg:    16,
 	}
 	minBufferMultiplier = 3
 )
@@ -261,10 +265,6 @@ func (ct *costTracker) gfLoop() {
 	ct.factor = math.Exp(gfLog)
 	factor, totalRecharge = ct.factor, ct.utilTarget*ct.factor
 
-	// In order to perform factor data statistics under the high request pressure,
-	// we only adjust factor when recent factor usage beyond the threshold.
-	threshold := gfUsageThreshold * float64(gfUsageTC) * ct.utilTarget / flowcontrol.FixedPointMultiplier
-
 	go func() {
 		saveCostFactor := func() {
 			var data [8]byte
@@ -277,6 +277,13 @@ func (ct *costTracker) gfLoop() {
 		for {
 			select {
 			case r := <-ct.reqInfoCh:
+				// In order to perform factor data statistics under the high request pressure,
+				// we only adjust factor when recent factor usage beyond the threshold.
+				ct.gfLock.RLock()
+				utilTarget := ct.utilTarget
+				ct.gfLock.RUnlock()
+				threshold := gfUsageThreshold * float64(gfUsageTC) * utilTarget / flowcontrol.FixedPointMultiplier
+
 				relCost := int64(factor * r.servingTime * 100 / r.avgTimeCost) // Convert the value to a percentage form
 
 				// Record more metrics if we are debugging
@@ -353,7 +360,7 @@ func (ct *costTracker) gfLoop() {
 					factor = math.Exp(gfLog)
 					// Notify outside modules the new factor and totalRecharge.
 					if time.Duration(now-lastUpdate) > time.Second {
-						totalRecharge, lastUpdate = ct.utilTarget*factor, now
+						totalRecharge, lastUpdate = utilTarget*factor, now
 						ct.gfLock.Lock()
 						ct.factor = factor
 						ch := ct.totalRechargeCh
@@ -400,6 +407,17 @@ func (ct *costTracker) totalRecharge() uint64 {
 	return uint64(ct.factor * ct.utilTarget)
 }
 
+// setUtilTarget updates the percentage of time allowed for serving requests and
+// returns the new total recharge value. The bandwidth based cost factors are
+// left unchanged, as the cost tables have already been sent to the clients.
+func (ct *costTracker) setUtilTarget(lightServ int) uint64 {
+	ct.gfLock.Lock()
+	defer ct.gfLock.Unlock()
+
+	ct.utilTarget = float64(lightServ) * flowcontrol.FixedPointMultiplier / 100
+	return uint64(ct.factor * ct.utilTarget)
+}
+
 // subscribeTotalRecharge returns all future updates to the total recharge value
 // through a channel and also returns the current value
 func (ct *costTracker) subscribeTotalRecharge(ch chan uint64) uint64 {
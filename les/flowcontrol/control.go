@@ -127,6 +127,24 @@ func (node *ClientNode) OneTimeCost(cost uint64) {
 	node.cm.updateBuffer(node, -int64(cost), now)
 }
 
+// AcceptOneTimeCost subtracts the given amount from the node's buffer if the
+// buffer covers it, reporting whether it did. Unlike OneTimeCost, it is meant
+// for messages sent to the client unsolicited, which the client accounts for
+// from the buffer value they report.
+func (node *ClientNode) AcceptOneTimeCost(cost uint64) bool {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+
+	now := node.cm.clock.Now()
+	node.update(now)
+	if int64(cost) > node.bufValue {
+		return false
+	}
+	node.bufValue -= int64(cost)
+	node.cm.updateBuffer(node, -int64(cost), now)
+	return true
+}
+
 // Freeze notifies the client manager about a client freeze event in which case
 // the total capacity allowance is slightly reduced.
 func (node *ClientNode) Freeze() {
@@ -250,6 +268,7 @@ type ServerNode struct {
 	params      ServerParams
 	sumCost     uint64            // sum of req costs sent to this server
 	pending     map[uint64]uint64 // value = sumCost after sending the given req
+	pendingCost map[uint64]uint64 // value = max cost of the given req
 	log         *logger
 	lock        sync.RWMutex
 }
@@ -263,6 +282,7 @@ func NewServerNode(params ServerParams, clock mclock.Clock) *ServerNode {
 		lastTime:    clock.Now(),
 		params:      params,
 		pending:     make(map[uint64]uint64),
+		pendingCost: make(map[uint64]uint64),
 	}
 	if keepLogs > 0 {
 		node.log = newLogger(keepLogs)
@@ -356,6 +376,7 @@ func (node *ServerNode) QueuedRequest(reqID, maxCost uint64) {
 	}
 	node.sumCost += maxCost
 	node.pending[reqID] = node.sumCost
+	node.pendingCost[reqID] = maxCost
 	if node.log != nil {
 		node.log.add(now, fmt.Sprintf("queued  reqID=%d  bufEst=%d  maxCost=%d  sumCost=%d", reqID, node.bufEstimate, maxCost, node.sumCost))
 	}
@@ -377,6 +398,7 @@ func (node *ServerNode) ReceivedReply(reqID, bv uint64) {
 		return
 	}
 	delete(node.pending, reqID)
+	delete(node.pendingCost, reqID)
 	cc := node.sumCost - sc
 	newEstimate := uint64(0)
 	if bv > cc {
@@ -396,6 +418,34 @@ func (node *ServerNode) ReceivedReply(reqID, bv uint64) {
 	}
 }
 
+// ReceivedPush sets the estimated buffer value according to the value included in
+// a message pushed by the server without a request, whose cost the server charged
+// to the buffer. The server may not have received the pending requests yet when
+// it sent the value, so their costs are deducted from it.
+func (node *ServerNode) ReceivedPush(bv uint64) {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+
+	now := node.clock.Now()
+	node.recalcBLE(now)
+	if bv > node.params.BufLimit {
+		bv = node.params.BufLimit
+	}
+	var cc uint64
+	for _, cost := range node.pendingCost {
+		cc += cost
+	}
+	node.bufEstimate = 0
+	if bv > cc {
+		node.bufEstimate = bv - cc
+	}
+	node.bufRecharge = node.bufEstimate < node.params.BufLimit
+	node.lastTime = now
+	if node.log != nil {
+		node.log.add(now, fmt.Sprintf("pushed  bufEst=%d  reportedBv=%d  pendingCost=%d", node.bufEstimate, bv, cc))
+	}
+}
+
 // ResumeFreeze cleans all pending requests and sets the buffer estimate to the
 // reported value after resuming from a frozen state
 func (node *ServerNode) ResumeFreeze(bv uint64) {
@@ -404,6 +454,7 @@ func (node *ServerNode) ResumeFreeze(bv uint64) {
 
 	for reqID := range node.pending {
 		delete(node.pending, reqID)
+		delete(node.pendingCost, reqID)
 	}
 	now := node.clock.Now()
 	node.recalcBLE(now)
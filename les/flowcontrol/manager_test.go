@@ -123,3 +123,40 @@ func (n *testNode) send(t *testing.T, now mclock.AbsTime) bool {
 	n.totalCost += rcost
 	return true
 }
+
+// Tests that the buffer estimate of a client follows the costs the server charges
+// for pushed messages, so that its requests are not rejected afterwards.
+func TestPushedMessageCost(t *testing.T) {
+	var (
+		clock  = &mclock.Simulated{}
+		params = ServerParams{BufLimit: 1000000, MinRecharge: 1000}
+		client = NewClientNode(NewClientManager(PieceWiseLinear{{0, params.MinRecharge}}, clock), params)
+		server = NewServerNode(params, clock)
+		cost   = uint64(300000)
+	)
+	for i := uint64(1); i <= 10; i++ {
+		// Push several messages, as long as the buffer covers them
+		pushed := 0
+		for j := 0; j < 4; j++ {
+			if !client.AcceptOneTimeCost(cost) {
+				continue
+			}
+			pushed++
+			bv, _ := client.BufferStatus()
+			server.ReceivedPush(bv)
+		}
+		if i == 1 && pushed != 3 {
+			t.Fatalf("pushed messages mismatch: have %d, want 3", pushed)
+		}
+		// Send a request as soon as the buffer estimate allows
+		wait, _ := server.CanSend(cost)
+		clock.Run(wait)
+		server.QueuedRequest(i, cost)
+		if ok, _, _ := client.AcceptRequest(i, i, cost); !ok {
+			bv, _ := client.BufferStatus()
+			t.Fatalf("request %d rejected after waiting %v: buffer %d, cost %d", i, wait, bv, cost)
+		}
+		bv := client.RequestProcessed(i, i, cost, cost)
+		server.ReceivedReply(i, bv)
+	}
+}
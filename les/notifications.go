@@ -0,0 +1,656 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/rlp"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const (
+	MaxNotificationFetch           = 256                // Amount of stored notifications to be delivered per request
+	maxNotificationFilters         = 64                 // Maximum number of addresses and topics in a notification filter
+	maxStoredNotifications         = 4096               // Maximum number of notifications stored for a disconnected client
+	maxStoredNotificationSize      = 1024 * 1024        // Maximum size of the notifications stored for a disconnected client
+	maxTotalStoredNotificationSize = 1024 * 1024 * 1024 // Maximum size of the notifications stored for all clients
+	maxNotifiedClients             = 10000
+	maxQueuedNotificationBlocks    = 1024 // Maximum number of blocks waiting for their notifications to be relayed
+
+	// notificationRetention is how long the filter of a client which doesn't
+	// reconnect is kept, with the notifications stored for it.
+	notificationRetention = 7 * 24 * time.Hour
+)
+
+var (
+	notifyFilterPrefix = []byte("lesNotifyFilter-") // notifyFilterPrefix + client id -> notifiedClientRLP
+	notifyPrefix       = []byte("lesNotify-")       // notifyPrefix + client id + block number + index -> Notification
+
+	errTooManyNotificationFilters = errors.New("too many notification filters")
+)
+
+// NotificationFilter selects the transactions and logs a light client is
+// notified of.
+type NotificationFilter struct {
+	// Addresses are the accounts whose transactions are relayed, with the logs
+	// emitted by them or with them as an indexed topic, like token transfers.
+	Addresses []common.Address `json:"addresses"`
+	// Topics are the log topics relayed whichever contract emits them.
+	Topics []common.Hash `json:"topics"`
+}
+
+// Empty reports whether the filter selects nothing.
+func (f *NotificationFilter) Empty() bool {
+	return len(f.Addresses) == 0 && len(f.Topics) == 0
+}
+
+func (f *NotificationFilter) validate() error {
+	if len(f.Addresses)+len(f.Topics) > maxNotificationFilters {
+		return errTooManyNotificationFilters
+	}
+	return nil
+}
+
+// compile returns the sets of addresses and topics of the filter, the topics
+// including the addresses left padded as indexed event arguments.
+func (f *NotificationFilter) compile() (addresses map[common.Address]bool, topics map[common.Hash]bool) {
+	addresses = make(map[common.Address]bool, len(f.Addresses))
+	topics = make(map[common.Hash]bool, len(f.Addresses)+len(f.Topics))
+	for _, address := range f.Addresses {
+		addresses[address] = true
+		topics[common.BytesToHash(address.Bytes())] = true
+	}
+	for _, topic := range f.Topics {
+		topics[topic] = true
+	}
+	return addresses, topics
+}
+
+// matchLog reports whether the log is selected by the compiled filter.
+func matchLog(l *types.Log, addresses map[common.Address]bool, topics map[common.Hash]bool) bool {
+	if addresses[l.Address] {
+		return true
+	}
+	for _, topic := range l.Topics {
+		if topics[topic] {
+			return true
+		}
+	}
+	return false
+}
+
+// Notification is a transaction or a log selected by the filter of a light
+// client, relayed to it with the NotificationsMsg message.
+type Notification struct {
+	BlockNumber uint64      `json:"blockNumber"`
+	BlockHash   common.Hash `json:"blockHash"`
+	TxHash      common.Hash `json:"transactionHash"`
+	TxIndex     uint        `json:"transactionIndex"`
+
+	// IsLog distinguishes log notifications, with the emitting contract as
+	// Address, from transaction notifications, with the recipient as Address.
+	IsLog    bool `json:"isLog"`
+	LogIndex uint `json:"logIndex"`
+
+	From    common.Address `json:"from"` // Sender of a transaction
+	Address common.Address `json:"address"`
+	Value   *big.Int       `json:"value"` // Value of a transaction
+	Topics  []common.Hash  `json:"topics"`
+	Data    []byte         `json:"data"`
+}
+
+// id identifies the transaction or log notified, to deduplicate notifications
+// relayed by several servers.
+func (n *Notification) id() common.Hash {
+	var index [9]byte
+	if n.IsLog {
+		index[0] = 1
+	}
+	binary.BigEndian.PutUint64(index[1:], uint64(n.LogIndex))
+	return common.BytesToHash(append(append(n.BlockHash.Bytes(), n.TxHash.Bytes()...), index[:]...))
+}
+
+// blockNotifications returns the notifications of a block selected by a filter.
+func blockNotifications(block *types.Block, logs []*types.Log, signer types.Signer, filter *NotificationFilter) []*Notification {
+	addresses, topics := filter.compile()
+	return matchNotifications(block, logs, signer, addresses, topics)
+}
+
+// matchNotifications returns the notifications of a block selected by a
+// compiled filter.
+func matchNotifications(block *types.Block, logs []*types.Log, signer types.Signer, addresses map[common.Address]bool, topics map[common.Hash]bool) []*Notification {
+	var notifications []*Notification
+	for i, tx := range block.Transactions() {
+		from, _ := types.Sender(signer, tx)
+		var to common.Address
+		if tx.To() != nil {
+			to = *tx.To()
+		}
+		if !addresses[from] && !addresses[to] {
+			continue
+		}
+		notifications = append(notifications, &Notification{
+			BlockNumber: block.NumberU64(),
+			BlockHash:   block.Hash(),
+			TxHash:      tx.Hash(),
+			TxIndex:     uint(i),
+			From:        from,
+			Address:     to,
+			Value:       tx.Value(),
+		})
+	}
+	for _, l := range logs {
+		if !matchLog(l, addresses, topics) {
+			continue
+		}
+		notifications = append(notifications, &Notification{
+			BlockNumber: block.NumberU64(),
+			BlockHash:   block.Hash(),
+			TxHash:      l.TxHash,
+			TxIndex:     l.TxIndex,
+			IsLog:       true,
+			LogIndex:    l.Index,
+			Address:     l.Address,
+			Topics:      l.Topics,
+			Data:        l.Data,
+		})
+	}
+	return notifications
+}
+
+// notifiedClient is a light client which registered a notification filter.
+type notifiedClient struct {
+	filter            NotificationFilter
+	addresses         map[common.Address]bool // Compiled filter
+	topics            map[common.Hash]bool
+	lastSeen          time.Time // Last time the client was connected
+	stored            int       // Number of notifications stored for the client
+	storedSize        int       // Total size of the notifications stored for the client
+	registering, peer *peer     // Connected peer fetching its stored notifications, or receiving them as they happen
+}
+
+// setFilter sets and compiles the filter of the client.
+func (c *notifiedClient) setFilter(filter NotificationFilter) {
+	c.filter = filter
+	c.addresses, c.topics = filter.compile()
+}
+
+// notifiedClientRLP is the stored form of a notified client.
+type notifiedClientRLP struct {
+	Filter   NotificationFilter
+	LastSeen uint64
+}
+
+// notificationServer relays to light clients the transactions and logs selected
+// by their filters. Notifications are pushed to connected clients, and stored in
+// the database while clients are disconnected until they request them.
+type notificationServer struct {
+	db    ethdb.Database
+	chain *core.BlockChain
+
+	lock       sync.Mutex
+	clients    map[enode.ID]*notifiedClient
+	storedSize int // Total size of the notifications stored for all clients
+
+	push func(p *peer, notifications []*Notification) bool // Sends notifications to a connected client
+}
+
+func newNotificationServer(db ethdb.Database, chain *core.BlockChain, push func(p *peer, notifications []*Notification) bool) *notificationServer {
+	s := &notificationServer{
+		db:      db,
+		chain:   chain,
+		clients: make(map[enode.ID]*notifiedClient),
+		push:    push,
+	}
+	it := db.NewIteratorWithPrefix(notifyFilterPrefix)
+	for it.Next() {
+		var id enode.ID
+		copy(id[:], it.Key()[len(notifyFilterPrefix):])
+		var enc notifiedClientRLP
+		if err := rlp.DecodeBytes(it.Value(), &enc); err != nil {
+			log.Error("Invalid notification filter", "id", id, "err", err)
+			continue
+		}
+		client := &notifiedClient{lastSeen: time.Unix(int64(enc.LastSeen), 0)}
+		client.setFilter(enc.Filter)
+		s.clients[id] = client
+	}
+	it.Release()
+	for id, client := range s.clients {
+		client.stored, client.storedSize = s.countStored(id)
+		s.storedSize += client.storedSize
+	}
+	return s
+}
+
+// loop relays the notifications of the new blocks of the chain until the
+// channel is closed. The blocks are queued for processing, so that the chain
+// isn't held up by a large number of clients.
+func (s *notificationServer) loop(closeCh chan struct{}) {
+	chainCh := make(chan core.ChainEvent, 10)
+	sub := s.chain.SubscribeChainEvent(chainCh)
+	defer sub.Unsubscribe()
+
+	var (
+		queue []core.ChainEvent
+		done  chan struct{} // Non-nil while a block is processed
+	)
+	for {
+		if done == nil && len(queue) > 0 {
+			done = make(chan struct{})
+			go func(ev core.ChainEvent) {
+				s.processBlock(ev.Block, ev.Logs)
+				close(done)
+			}(queue[0])
+			queue = queue[1:]
+		}
+		select {
+		case ev := <-chainCh:
+			if len(queue) >= maxQueuedNotificationBlocks {
+				log.Warn("Dropping notifications of queued block", "number", queue[0].Block.NumberU64(), "hash", queue[0].Hash)
+				queue = queue[1:]
+			}
+			queue = append(queue, ev)
+		case <-done:
+			done = nil
+		case <-sub.Err():
+			return
+		case <-closeCh:
+			if done != nil {
+				<-done
+			}
+			return
+		}
+	}
+}
+
+// processBlock relays the notifications of a block to the clients, dropping the
+// clients which didn't reconnect within the retention period.
+func (s *notificationServer) processBlock(block *types.Block, logs []*types.Log) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	signer := types.MakeSigner(s.chain.Config(), block.Number())
+	for id, client := range s.clients {
+		if client.peer == nil && client.registering == nil && time.Since(client.lastSeen) > notificationRetention {
+			log.Debug("Dropping notifications of expired light client", "id", id)
+			s.remove(id)
+			continue
+		}
+		notifications := matchNotifications(block, logs, signer, client.addresses, client.topics)
+		if len(notifications) == 0 {
+			continue
+		}
+		if client.peer != nil && s.push(client.peer, notifications) {
+			continue
+		}
+		s.store(id, client, block.NumberU64(), notifications)
+	}
+}
+
+// register sets the filter of a connected client and returns up to max of the
+// notifications stored for it, with a function deleting them from the database
+// to be called once they are delivered. If none are left, the following
+// notifications are pushed to the peer. An empty filter unregisters the client.
+// It also reports whether a new filter was set.
+func (s *notificationServer) register(p *peer, filter NotificationFilter, max uint64) ([]*Notification, func(), bool) {
+	id := p.ID()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	client := s.clients[id]
+	if client == nil {
+		if filter.Empty() {
+			return nil, func() {}, false
+		}
+		if len(s.clients) >= maxNotifiedClients {
+			p.Log().Debug("Too many notified light clients, ignoring filter")
+			return nil, func() {}, false
+		}
+		client = new(notifiedClient)
+		s.clients[id] = client
+	}
+	changed := !reflect.DeepEqual(client.filter, filter)
+	if changed {
+		client.setFilter(filter)
+	}
+	client.lastSeen = time.Now()
+	notifications, keys := s.takeStored(id, max)
+	if filter.Empty() {
+		s.remove(id)
+		return notifications, func() {}, false
+	}
+	s.writeClient(id, client)
+	if len(keys) == client.stored {
+		client.peer = p
+	} else {
+		client.registering = p
+	}
+	delivered := func() {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		if s.clients[id] != client {
+			return // Unregistered meanwhile
+		}
+		s.deleteStored(id, client, keys)
+		if client.registering == p && client.stored == 0 {
+			client.registering, client.peer = nil, p
+		}
+	}
+	return notifications, delivered, changed
+}
+
+// disconnected stops pushing notifications to a client, storing them instead.
+func (s *notificationServer) disconnected(p *peer) {
+	id := p.ID()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if client := s.clients[id]; client != nil && (client.peer == p || client.registering == p) {
+		client.peer, client.registering, client.lastSeen = nil, nil, time.Now()
+		s.writeClient(id, client)
+	}
+}
+
+func (s *notificationServer) writeClient(id enode.ID, client *notifiedClient) {
+	enc, err := rlp.EncodeToBytes(&notifiedClientRLP{Filter: client.filter, LastSeen: uint64(client.lastSeen.Unix())})
+	if err != nil {
+		log.Crit("Failed to encode notification filter", "err", err)
+	}
+	if err := s.db.Put(notifyFilterKey(id), enc); err != nil {
+		log.Crit("Failed to store notification filter", "err", err)
+	}
+}
+
+// remove deletes a client with its stored notifications.
+func (s *notificationServer) remove(id enode.ID) {
+	client := s.clients[id]
+	delete(s.clients, id)
+	s.trimStored(id, client, -1, -1)
+	if err := s.db.Delete(notifyFilterKey(id)); err != nil {
+		log.Crit("Failed to delete notification filter", "err", err)
+	}
+}
+
+// store writes the notifications of a block for a disconnected client, dropping
+// its oldest notifications beyond the maximum count and size. The notifications
+// are dropped if the total size of the stored notifications of all the clients
+// would exceed its maximum.
+func (s *notificationServer) store(id enode.ID, client *notifiedClient, number uint64, notifications []*Notification) {
+	batch := s.db.NewBatch()
+	size := 0
+	for i, notification := range notifications {
+		enc, err := rlp.EncodeToBytes(notification)
+		if err != nil {
+			log.Crit("Failed to encode notification", "err", err)
+		}
+		batch.Put(notificationKey(id, number, uint32(i)), enc)
+		size += len(enc)
+	}
+	if s.storedSize+size > maxTotalStoredNotificationSize {
+		log.Debug("Too many stored notifications, dropping", "id", id, "number", number, "count", len(notifications))
+		return
+	}
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to store notifications", "err", err)
+	}
+	client.stored += len(notifications)
+	client.storedSize += size
+	s.storedSize += size
+	s.trimStored(id, client, maxStoredNotifications, maxStoredNotificationSize)
+}
+
+// takeStored returns the oldest stored notifications of a client, with their
+// keys in the database.
+func (s *notificationServer) takeStored(id enode.ID, max uint64) ([]*Notification, [][]byte) {
+	var (
+		notifications []*Notification
+		keys          [][]byte
+	)
+	it := s.db.NewIteratorWithPrefix(notificationPrefix(id))
+	defer it.Release()
+
+	for uint64(len(notifications)) < max && it.Next() {
+		var notification Notification
+		if err := rlp.DecodeBytes(it.Value(), &notification); err != nil {
+			log.Error("Invalid stored notification", "id", id, "err", err)
+		} else {
+			notifications = append(notifications, &notification)
+		}
+		keys = append(keys, common.CopyBytes(it.Key()))
+	}
+	return notifications, keys
+}
+
+// deleteStored deletes stored notifications of a client, skipping the ones
+// which were deleted already.
+func (s *notificationServer) deleteStored(id enode.ID, client *notifiedClient, keys [][]byte) {
+	batch := s.db.NewBatch()
+	for _, key := range keys {
+		enc, err := s.db.Get(key)
+		if err != nil {
+			continue
+		}
+		batch.Delete(key)
+		client.stored--
+		client.storedSize -= len(enc)
+		s.storedSize -= len(enc)
+	}
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to delete notifications", "err", err)
+	}
+}
+
+// trimStored deletes the oldest notifications of a client until their count
+// and total size are within the given limits, or all of them if negative.
+func (s *notificationServer) trimStored(id enode.ID, client *notifiedClient, maxCount, maxSize int) {
+	it := s.db.NewIteratorWithPrefix(notificationPrefix(id))
+	defer it.Release()
+
+	batch := s.db.NewBatch()
+	for (client.stored > maxCount || client.storedSize > maxSize) && it.Next() {
+		batch.Delete(common.CopyBytes(it.Key()))
+		client.stored--
+		client.storedSize -= len(it.Value())
+		s.storedSize -= len(it.Value())
+	}
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to delete notifications", "err", err)
+	}
+}
+
+// countStored returns the number and total size of the notifications stored for
+// a client.
+func (s *notificationServer) countStored(id enode.ID) (count int, size int) {
+	it := s.db.NewIteratorWithPrefix(notificationPrefix(id))
+	defer it.Release()
+
+	for it.Next() {
+		count++
+		size += len(it.Value())
+	}
+	return count, size
+}
+
+// notifyFilterKey = notifyFilterPrefix + client id
+func notifyFilterKey(id enode.ID) []byte {
+	return append(append([]byte{}, notifyFilterPrefix...), id.Bytes()...)
+}
+
+// notificationPrefix = notifyPrefix + client id
+func notificationPrefix(id enode.ID) []byte {
+	return append(append([]byte{}, notifyPrefix...), id.Bytes()...)
+}
+
+// notificationKey = notifyPrefix + client id + block number (uint64 big endian) + index (uint32 big endian)
+func notificationKey(id enode.ID, number uint64, index uint32) []byte {
+	key := notificationPrefix(id)
+	var enc [12]byte
+	binary.BigEndian.PutUint64(enc[:8], number)
+	binary.BigEndian.PutUint32(enc[8:], index)
+	return append(key, enc[:]...)
+}
+
+// notifyClientFilterKey stores the notification filter of a light client.
+var notifyClientFilterKey = []byte("lesNotifyClientFilter")
+
+// notificationClient registers the notification filter of a light client with
+// its servers and delivers the notifications they relay to the subscribers.
+type notificationClient struct {
+	db   ethdb.Database
+	dist *requestDistributor
+
+	lock   sync.RWMutex
+	filter NotificationFilter
+	peers  map[*peer]struct{} // Connected servers supporting notifications
+	seen   *lru.Cache         // Notifications already delivered, relayed by several servers
+
+	feed  event.Feed
+	scope event.SubscriptionScope
+}
+
+func newNotificationClient(db ethdb.Database, dist *requestDistributor) *notificationClient {
+	seen, _ := lru.New(MaxNotificationFetch * 4)
+	c := &notificationClient{
+		db:    db,
+		dist:  dist,
+		peers: make(map[*peer]struct{}),
+		seen:  seen,
+	}
+	if enc, err := db.Get(notifyClientFilterKey); err == nil {
+		if err := rlp.DecodeBytes(enc, &c.filter); err != nil {
+			log.Error("Invalid notification filter", "err", err)
+		}
+	}
+	return c
+}
+
+// Filter returns the notification filter of the client.
+func (c *notificationClient) Filter() NotificationFilter {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.filter
+}
+
+// SetFilter stores the notification filter of the client and registers it with
+// the connected servers. An empty filter unregisters the client.
+func (c *notificationClient) SetFilter(filter NotificationFilter) error {
+	if err := filter.validate(); err != nil {
+		return err
+	}
+	enc, err := rlp.EncodeToBytes(&filter)
+	if err != nil {
+		return err
+	}
+	if err := c.db.Put(notifyClientFilterKey, enc); err != nil {
+		return err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.filter = filter
+	for p := range c.peers {
+		c.request(p, filter)
+	}
+	return nil
+}
+
+// connected registers the filter with a new server and fetches the notifications
+// it stored while the client was disconnected.
+func (c *notificationClient) connected(p *peer) {
+	if p.version < lpv4 || p.onlyAnnounce {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.peers[p] = struct{}{}
+	if !c.filter.Empty() {
+		c.request(p, c.filter)
+	}
+}
+
+func (c *notificationClient) disconnected(p *peer) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	delete(c.peers, p)
+}
+
+// request queues a notifications request to a server.
+func (c *notificationClient) request(p *peer, filter NotificationFilter) {
+	reqID := genReqID()
+	rq := &distReq{
+		getCost: func(dp distPeer) uint64 {
+			return dp.(*peer).GetRequestCost(GetNotificationsMsg, MaxNotificationFetch)
+		},
+		canSend: func(dp distPeer) bool {
+			return dp.(*peer) == p
+		},
+		request: func(dp distPeer) func() {
+			peer := dp.(*peer)
+			cost := peer.GetRequestCost(GetNotificationsMsg, MaxNotificationFetch)
+			peer.fcServer.QueuedRequest(reqID, cost)
+			return func() { peer.RequestNotifications(reqID, cost, filter) }
+		},
+	}
+	c.dist.queue(rq)
+}
+
+// deliver sends the notifications relayed by a server to the subscribers, and
+// fetches the next batch if a reply was full.
+func (c *notificationClient) deliver(p *peer, reqID uint64, notifications []*Notification) {
+	for _, notification := range notifications {
+		if ok, _ := c.seen.ContainsOrAdd(notification.id(), struct{}{}); ok {
+			continue
+		}
+		c.feed.Send(notification)
+	}
+	if reqID != 0 && len(notifications) >= MaxNotificationFetch {
+		c.lock.Lock()
+		if _, ok := c.peers[p]; ok {
+			c.request(p, c.filter)
+		}
+		c.lock.Unlock()
+	}
+}
+
+// SubscribeNotifications subscribes to the notifications relayed by the servers.
+func (c *notificationClient) SubscribeNotifications(ch chan<- *Notification) event.Subscription {
+	return c.scope.Track(c.feed.Subscribe(ch))
+}
+
+func (c *notificationClient) close() {
+	c.scope.Close()
+}
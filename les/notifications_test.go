@@ -0,0 +1,218 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestNotificationFilter(t *testing.T) {
+	var (
+		signer   = types.HomesteadSigner{}
+		token    = common.HexToAddress("0x1000")
+		transfer = common.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+	)
+	tx1, _ := types.SignTx(types.NewTransaction(0, userAddr1, big.NewInt(10000), params.TxGas, nil, nil, nil, nil, nil), signer, bankKey)
+	tx2, _ := types.SignTx(types.NewTransaction(1, token, big.NewInt(0), 100000, nil, nil, nil, nil, nil), signer, bankKey)
+	block := types.NewBlock(&types.Header{Number: big.NewInt(1)}, []*types.Transaction{tx1, tx2}, nil, nil, nil)
+	logs := []*types.Log{
+		// Token transfer from the bank to the second user
+		{Address: token, Topics: []common.Hash{transfer, common.BytesToHash(bankAddr.Bytes()), common.BytesToHash(userAddr2.Bytes())}, TxHash: tx2.Hash(), TxIndex: 1, Index: 0},
+		// Other event of the token
+		{Address: token, Topics: []common.Hash{common.HexToHash("0x01")}, TxHash: tx2.Hash(), TxIndex: 1, Index: 1},
+	}
+	tests := []struct {
+		filter NotificationFilter
+		want   []common.Hash // Hashes of the transactions notified, with the index of the logs
+		logs   []uint
+	}{
+		{NotificationFilter{}, nil, nil},
+		{NotificationFilter{Addresses: []common.Address{userAddr1}}, []common.Hash{tx1.Hash()}, nil},
+		{NotificationFilter{Addresses: []common.Address{bankAddr}}, []common.Hash{tx1.Hash(), tx2.Hash(), tx2.Hash()}, []uint{0}},
+		{NotificationFilter{Addresses: []common.Address{userAddr2}}, []common.Hash{tx2.Hash()}, []uint{0}},
+		{NotificationFilter{Addresses: []common.Address{token}}, []common.Hash{tx2.Hash(), tx2.Hash(), tx2.Hash()}, []uint{0, 1}},
+		{NotificationFilter{Topics: []common.Hash{transfer}}, []common.Hash{tx2.Hash()}, []uint{0}},
+	}
+	for i, tt := range tests {
+		notifications := blockNotifications(block, logs, signer, &tt.filter)
+		var hashes []common.Hash
+		var indexes []uint
+		for _, n := range notifications {
+			hashes = append(hashes, n.TxHash)
+			if n.IsLog {
+				indexes = append(indexes, n.LogIndex)
+			} else if n.From != bankAddr {
+				t.Errorf("test %d: sender mismatch: have %x, want %x", i, n.From, bankAddr)
+			}
+		}
+		if len(hashes) != len(tt.want) || len(indexes) != len(tt.logs) {
+			t.Errorf("test %d: notifications mismatch: have %x (logs %v), want %x (logs %v)", i, hashes, indexes, tt.want, tt.logs)
+			continue
+		}
+		for j := range hashes {
+			if hashes[j] != tt.want[j] {
+				t.Errorf("test %d: notification %d mismatch: have %x, want %x", i, j, hashes[j], tt.want[j])
+			}
+		}
+		for j := range indexes {
+			if indexes[j] != tt.logs[j] {
+				t.Errorf("test %d: log %d mismatch: have %d, want %d", i, j, indexes[j], tt.logs[j])
+			}
+		}
+	}
+}
+
+func TestNotificationsLes4(t *testing.T) {
+	server, tearDown := newServerEnv(t, 0, lpv4, nil, false, true, 0)
+	defer tearDown()
+
+	chain := server.handler.blockchain
+	signer := types.HomesteadSigner{}
+	nonce := uint64(0)
+	addBlock := func() *types.Transaction {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, userAddr1, big.NewInt(10000), params.TxGas, nil, nil, nil, nil, nil), signer, bankKey)
+		nonce++
+		blocks, _ := core.GenerateChain(params.AllEthashProtocolChanges, chain.CurrentBlock(), ethash.NewFaker(), server.db, 1, func(i int, block *core.BlockGen) {
+			block.AddTx(tx)
+		})
+		if _, err := chain.InsertChain(blocks); err != nil {
+			t.Fatalf("failed to insert block: %v", err)
+		}
+		return tx
+	}
+	filter := NotificationFilter{Addresses: []common.Address{userAddr1}}
+
+	// Registering the filter returns the notifications stored so far: none
+	type req struct {
+		ReqID  uint64
+		Max    uint64
+		Filter NotificationFilter
+	}
+	p2p.Send(server.peer.app, GetNotificationsMsg, req{1, MaxNotificationFetch, filter})
+	if err := expectResponse(server.peer.app, NotificationsMsg, 1, testBufLimit, []*Notification{}); err != nil {
+		t.Fatalf("notifications mismatch: %v", err)
+	}
+
+	// Notifications are pushed to the connected client
+	tx := addBlock()
+	msg, err := server.peer.app.ReadMsg()
+	if err != nil {
+		t.Fatalf("failed to read pushed notifications: %v", err)
+	}
+	var resp struct {
+		ReqID, BV     uint64
+		Notifications []*Notification
+	}
+	if msg.Code != NotificationsMsg {
+		t.Fatalf("message code mismatch: have %d, want %d", msg.Code, NotificationsMsg)
+	}
+	if err := msg.Decode(&resp); err != nil {
+		t.Fatalf("failed to decode pushed notifications: %v", err)
+	}
+	if resp.ReqID != 0 || len(resp.Notifications) != 1 || resp.Notifications[0].TxHash != tx.Hash() {
+		t.Fatalf("pushed notifications mismatch: have %+v", resp)
+	}
+
+	// Notifications are stored for disconnected clients until they reconnect
+	offline := newPeer(lpv4, NetworkId, false, p2p.NewPeer(enode.ID{1}, "offline", nil), nil)
+	notifications := server.handler.notifications
+	if _, _, changed := notifications.register(offline, filter, MaxNotificationFetch); !changed {
+		t.Fatalf("new filter not reported as changed")
+	}
+	notifications.disconnected(offline)
+	tx1 := addBlock()
+	tx2 := addBlock()
+	waitStored := func(want int) {
+		for i := 0; i < 50; i++ {
+			if count, _ := notifications.countStored(offline.ID()); count == want {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		count, _ := notifications.countStored(offline.ID())
+		t.Fatalf("stored notifications mismatch: have %d, want %d", count, want)
+	}
+	waitStored(2)
+
+	// Stored notifications are returned again until they are delivered
+	for i := 0; i < 2; i++ {
+		stored, _, changed := notifications.register(offline, filter, 1)
+		if len(stored) != 1 || stored[0].TxHash != tx1.Hash() {
+			t.Fatalf("first stored notification mismatch: have %+v", stored)
+		}
+		if changed {
+			t.Fatalf("unchanged filter reported as changed")
+		}
+	}
+	waitStored(2)
+	stored, delivered, _ := notifications.register(offline, filter, 1)
+	delivered()
+	waitStored(1)
+	stored, delivered, _ = notifications.register(offline, filter, MaxNotificationFetch)
+	if len(stored) != 1 || stored[0].TxHash != tx2.Hash() {
+		t.Fatalf("second stored notification mismatch: have %+v", stored)
+	}
+	delivered()
+	waitStored(0)
+
+	// Unregistering drops the client
+	notifications.register(offline, NotificationFilter{}, MaxNotificationFetch)
+	if _, ok := notifications.clients[offline.ID()]; ok {
+		t.Fatalf("client not unregistered")
+	}
+}
+
+func TestNotificationStorageLimits(t *testing.T) {
+	var (
+		server = newNotificationServer(rawdb.NewMemoryDatabase(), nil, nil)
+		id     = enode.ID{1}
+		client = new(notifiedClient)
+	)
+	server.clients[id] = client
+
+	// The oldest notifications are dropped beyond the size limit of a client
+	for i := uint64(0); i < 10; i++ {
+		server.store(id, client, i, []*Notification{{BlockNumber: i, Data: make([]byte, maxStoredNotificationSize/4)}})
+	}
+	count, size := server.countStored(id)
+	if count != 3 || count != client.stored || size != client.storedSize || size != server.storedSize {
+		t.Fatalf("stored notifications mismatch: have %d (%d bytes), tracked %d (%d bytes, %d in total), want 3", count, size, client.stored, client.storedSize, server.storedSize)
+	}
+	stored, _ := server.takeStored(id, MaxNotificationFetch)
+	for i, notification := range stored {
+		if notification.BlockNumber != uint64(7+i) {
+			t.Errorf("stored notification %d: block number mismatch: have %d, want %d", i, notification.BlockNumber, 7+i)
+		}
+	}
+	// New notifications are dropped beyond the size limit of all the clients
+	server.storedSize = maxTotalStoredNotificationSize - 100
+	server.store(id, client, 10, []*Notification{{BlockNumber: 10, Data: make([]byte, 100)}})
+	if count, _ := server.countStored(id); count != 3 {
+		t.Fatalf("notifications stored beyond the total limit: have %d, want 3", count)
+	}
+}
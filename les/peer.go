@@ -468,6 +468,32 @@ func (p *peer) ReplyTxStatus(reqID uint64, stats []light.TxStatus) *reply {
 	return &reply{p.rw, TxStatusMsg, reqID, data}
 }
 
+// ReplyNotifications creates a reply with a batch of notifications, or a message
+// pushing them to the client if reqID is zero.
+func (p *peer) ReplyNotifications(reqID uint64, notifications []*Notification, sent func()) *reply {
+	data, _ := rlp.EncodeToBytes(notifications)
+	var w p2p.MsgWriter = p.rw
+	if sent != nil {
+		w = &sentHookWriter{MsgWriter: p.rw, sent: sent}
+	}
+	return &reply{w, NotificationsMsg, reqID, data}
+}
+
+// sentHookWriter is a message writer calling a function once a message was
+// written successfully.
+type sentHookWriter struct {
+	p2p.MsgWriter
+	sent func()
+}
+
+func (w *sentHookWriter) WriteMsg(msg p2p.Msg) error {
+	if err := w.MsgWriter.WriteMsg(msg); err != nil {
+		return err
+	}
+	w.sent()
+	return nil
+}
+
 // RequestHeadersByHash fetches a batch of blocks' headers corresponding to the
 // specified header query, based on the hash of an origin block.
 func (p *peer) RequestHeadersByHash(reqID, cost uint64, origin common.Hash, amount int, skip int, reverse bool) error {
@@ -520,6 +546,18 @@ func (p *peer) RequestTxStatus(reqID, cost uint64, txHashes []common.Hash) error
 	return sendRequest(p.rw, GetTxStatusMsg, reqID, cost, txHashes)
 }
 
+// RequestNotifications registers the notification filter of the light client
+// and fetches the notifications stored for it by a remote node.
+func (p *peer) RequestNotifications(reqID, cost uint64, filter NotificationFilter) error {
+	p.Log().Debug("Requesting notifications", "addresses", len(filter.Addresses), "topics", len(filter.Topics))
+	type req struct {
+		ReqID  uint64
+		Max    uint64
+		Filter NotificationFilter
+	}
+	return p2p.Send(p.rw, GetNotificationsMsg, req{reqID, MaxNotificationFetch, filter})
+}
+
 // RequestEtherbase fetches the etherbase of a remote node.
 func (p *peer) RequestEtherbase(reqID, cost uint64) error {
 	p.Log().Debug("Requesting etherbase for peer", "enode", p.id)
@@ -755,7 +793,7 @@ func (p *peer) Handshake(td *big.Int, head common.Hash, headNum uint64, genesis
 
 		if !p.onlyAnnounce {
 			for msgCode := range reqAvgTimeCost {
-				if p.fcCosts[msgCode] == nil {
+				if msgCode < ProtocolLengths[uint(p.version)] && p.fcCosts[msgCode] == nil {
 					return errResp(ErrUselessPeer, "peer does not support message %d", msgCode)
 				}
 			}
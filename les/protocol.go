@@ -33,17 +33,18 @@ import (
 const (
 	lpv2 = 2
 	lpv3 = 3
+	lpv4 = 4
 )
 
 // Supported versions of the les protocol (first is primary)
 var (
-	ClientProtocolVersions    = []uint{lpv2, lpv3}
-	ServerProtocolVersions    = []uint{lpv2, lpv3}
+	ClientProtocolVersions    = []uint{lpv2, lpv3, lpv4}
+	ServerProtocolVersions    = []uint{lpv2, lpv3, lpv4}
 	AdvertiseProtocolVersions = []uint{lpv2} // clients are searching for the first advertised protocol in the list
 )
 
 // Number of implemented message corresponding to different protocol versions.
-var ProtocolLengths = map[uint]uint64{lpv2: 24, lpv3: 26}
+var ProtocolLengths = map[uint]uint64{lpv2: 24, lpv3: 26, lpv4: 28}
 
 const (
 	NetworkId          = 1
@@ -77,6 +78,9 @@ const (
 	// Protocol messages introduced in LPV3
 	StopMsg   = 0x18
 	ResumeMsg = 0x19
+	// Celo specific, introduced in LPV4
+	GetNotificationsMsg = 0x1a
+	NotificationsMsg    = 0x1b
 )
 
 type requestInfo struct {
@@ -94,6 +98,7 @@ var requests = map[uint64]requestInfo{
 	SendTxV2Msg:            {"SendTxV2", MaxTxSend},
 	GetTxStatusMsg:         {"GetTxStatus", MaxTxStatus},
 	GetEtherbaseMsg:        {"GetEtherbase", MaxEtherbase},
+	GetNotificationsMsg:    {"GetNotifications", MaxNotificationFetch},
 }
 
 type errCode int
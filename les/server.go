@@ -18,6 +18,7 @@ package les
 
 import (
 	"crypto/ecdsa"
+	"math/big"
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
@@ -53,6 +54,15 @@ type LesServer struct {
 	minCapacity, maxCapacity, freeCapacity uint64
 	threadsIdle                            int // Request serving threads count when system is idle.
 	threadsBusy                            int // Request serving threads count when system is busy(block insertion).
+
+	limitsCh chan servingLimits // Channel used to update the serving limits at runtime
+}
+
+// servingLimits are the light serving settings which can be updated while the
+// server is running.
+type servingLimits struct {
+	lightServ  int // Maximum percentage of time allowed for serving LES requests
+	lightPeers int // Maximum number of LES client peers
 }
 
 func NewLesServer(e *eth.Ethereum, config *eth.Config) (*LesServer, error) {
@@ -61,12 +71,6 @@ func NewLesServer(e *eth.Ethereum, config *eth.Config) (*LesServer, error) {
 	for i, pv := range AdvertiseProtocolVersions {
 		lesTopics[i] = lesTopic(e.BlockChain().Genesis().Hash(), pv)
 	}
-	// Calculate the number of threads used to service the light client
-	// requests based on the user-specified value.
-	threads := config.LightServ * 4 / 100
-	if threads < 4 {
-		threads = 4
-	}
 	srv := &LesServer{
 		lesCommons: lesCommons{
 			genesis:          e.BlockChain().Genesis().Hash(),
@@ -84,9 +88,9 @@ func NewLesServer(e *eth.Ethereum, config *eth.Config) (*LesServer, error) {
 		lesTopics:    lesTopics,
 		fcManager:    flowcontrol.NewClientManager(nil, &mclock.System{}),
 		servingQueue: newServingQueue(int64(time.Millisecond*10), float64(config.LightServ)/100),
-		threadsBusy:  config.LightServ/100 + 1,
-		threadsIdle:  threads,
+		limitsCh:     make(chan servingLimits),
 	}
+	srv.threadsBusy, srv.threadsIdle = servingThreads(config.LightServ)
 	srv.handler = newServerHandler(srv, e.BlockChain(), e.ChainDb(), e.TxPool(), e.Synced, config.Etherbase, config.GatewayFee)
 	srv.costTracker, srv.minCapacity = newCostTracker(e.ChainDb(), config)
 	srv.freeCapacity = srv.minCapacity
@@ -108,12 +112,7 @@ func NewLesServer(e *eth.Ethereum, config *eth.Config) (*LesServer, error) {
 	// response. Most of the clients want this guarantee but don't actually need
 	// to send requests most of the time. Our goal is to serve as many clients as
 	// possible while the actually used server capacity does not exceed the limits
-	totalRecharge := srv.costTracker.totalRecharge()
-	srv.maxCapacity = srv.freeCapacity * uint64(srv.config.LightPeers)
-	if totalRecharge > srv.maxCapacity {
-		srv.maxCapacity = totalRecharge
-	}
-	srv.fcManager.SetCapacityLimits(srv.freeCapacity, srv.maxCapacity, srv.freeCapacity*2)
+	srv.setCapacityLimits(srv.costTracker.totalRecharge(), srv.config.LightPeers)
 	srv.clientPool = newClientPool(srv.chainDb, srv.freeCapacity, mclock.System{}, func(id enode.ID) { go srv.peers.Unregister(peerIdToString(id)) })
 	srv.clientPool.setDefaultFactors(priceFactors{0, 1, 1}, priceFactors{0, 1, 1})
 
@@ -235,6 +234,41 @@ func (s *LesServer) SetContractBackend(backend bind.ContractBackend) {
 	s.oracle.start(backend)
 }
 
+// servingThreads calculates the number of threads used to serve the light client
+// requests when the system is busy and idle, based on the user-specified value.
+func servingThreads(lightServ int) (busy, idle int) {
+	idle = lightServ * 4 / 100
+	if idle < 4 {
+		idle = 4
+	}
+	return lightServ/100 + 1, idle
+}
+
+// setCapacityLimits sets the maximum capacity of the client manager so that it
+// can serve the given number of free clients.
+func (s *LesServer) setCapacityLimits(totalRecharge uint64, lightPeers int) {
+	s.maxCapacity = s.freeCapacity * uint64(lightPeers)
+	if totalRecharge > s.maxCapacity {
+		s.maxCapacity = totalRecharge
+	}
+	s.fcManager.SetCapacityLimits(s.freeCapacity, s.maxCapacity, s.freeCapacity*2)
+}
+
+// SetServingLimits updates the percentage of time allowed for serving requests
+// and the maximum number of client peers of the running server.
+func (s *LesServer) SetServingLimits(lightServ, lightPeers int) {
+	select {
+	case s.limitsCh <- servingLimits{lightServ: lightServ, lightPeers: lightPeers}:
+	case <-s.closeCh:
+	}
+}
+
+// SetGatewayFee updates the minimum gateway fee of transactions relayed for
+// light clients.
+func (s *LesServer) SetGatewayFee(gatewayFee *big.Int) {
+	s.handler.setGatewayFee(gatewayFee)
+}
+
 // capacityManagement starts an event handler loop that updates the recharge curve of
 // the client manager and adjusts the client pool's size according to the total
 // capacity updates coming from the client manager
@@ -250,7 +284,8 @@ func (s *LesServer) capacityManagement() {
 
 	totalCapacityCh := make(chan uint64, 100)
 	totalCapacity := s.fcManager.SubscribeTotalCapacity(totalCapacityCh)
-	s.clientPool.setLimits(s.config.LightPeers, totalCapacity)
+	lightPeers := s.config.LightPeers
+	s.clientPool.setLimits(lightPeers, totalCapacity)
 
 	var (
 		busy         bool
@@ -283,11 +318,21 @@ func (s *LesServer) capacityManagement() {
 		case totalCapacity = <-totalCapacityCh:
 			totalCapacityGauge.Update(int64(totalCapacity))
 			newFreePeers := totalCapacity / s.freeCapacity
-			if newFreePeers < freePeers && newFreePeers < uint64(s.config.LightPeers) {
+			if newFreePeers < freePeers && newFreePeers < uint64(lightPeers) {
 				log.Warn("Reduced free peer connections", "from", freePeers, "to", newFreePeers)
 			}
 			freePeers = newFreePeers
-			s.clientPool.setLimits(s.config.LightPeers, totalCapacity)
+			s.clientPool.setLimits(lightPeers, totalCapacity)
+		case limits := <-s.limitsCh:
+			s.threadsBusy, s.threadsIdle = servingThreads(limits.lightServ)
+			s.servingQueue.setUtilTarget(float64(limits.lightServ) / 100)
+			totalRecharge = s.costTracker.setUtilTarget(limits.lightServ)
+			totalRechargeGauge.Update(int64(totalRecharge))
+			lightPeers = limits.lightPeers
+			s.setCapacityLimits(totalRecharge, lightPeers)
+			s.clientPool.setLimits(lightPeers, totalCapacity)
+			updateRecharge()
+			log.Info("Light serving limits updated", "lightserv", limits.lightServ, "lightpeers", lightPeers)
 		case <-s.closeCh:
 			return
 		}
@@ -75,8 +75,10 @@ type serverHandler struct {
 	synced  func() bool    // Callback function used to determine whether local node is synced.
 
 	// CELO Specific
-	etherbase  common.Address
-	gatewayFee *big.Int
+	etherbase     common.Address
+	gatewayFee    *big.Int
+	feeLock       sync.RWMutex // Protects gatewayFee, which can be updated at runtime
+	notifications *notificationServer
 
 	// Testing fields
 	addTxsSync bool
@@ -93,13 +95,18 @@ func newServerHandler(server *LesServer, blockchain *core.BlockChain, chainDb et
 		etherbase:  etherbase,
 		gatewayFee: gatewayFee,
 	}
+	handler.notifications = newNotificationServer(chainDb, blockchain, handler.pushNotifications)
 	return handler
 }
 
 // start starts the server handler.
 func (h *serverHandler) start() {
-	h.wg.Add(1)
+	h.wg.Add(2)
 	go h.broadcastHeaders()
+	go func() {
+		defer h.wg.Done()
+		h.notifications.loop(h.closeCh)
+	}()
 }
 
 // stop stops the server handler.
@@ -154,6 +161,7 @@ func (h *serverHandler) handle(p *peer) error {
 	connectedAt := mclock.Now()
 	defer func() {
 		wg.Wait() // Ensure all background task routines have exited.
+		h.notifications.disconnected(p)
 		h.server.peers.Unregister(p.id)
 		h.server.clientPool.disconnect(p)
 		clientConnectionGauge.Update(int64(h.server.peers.Len()))
@@ -864,6 +872,38 @@ func (h *serverHandler) handleMsg(p *peer, wg *sync.WaitGroup) error {
 			}()
 		}
 
+	case GetNotificationsMsg:
+		// Celo: Register the notification filter and deliver stored notifications
+		p.Log().Trace("Received notifications request")
+		var req struct {
+			ReqID  uint64
+			Max    uint64
+			Filter NotificationFilter
+		}
+		if err := msg.Decode(&req); err != nil {
+			clientErrorMeter.Mark(1)
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		if err := req.Filter.validate(); err != nil {
+			clientErrorMeter.Mark(1)
+			return errResp(ErrRequestRejected, "%v", err)
+		}
+		if accept(req.ReqID, req.Max, MaxNotificationFetch) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				notifications, delivered, changed := h.notifications.register(p, req.Filter, req.Max)
+				if changed {
+					// Matching a new filter against every block costs as much as a full request
+					cost := p.fcCosts.getMaxCost(GetNotificationsMsg, MaxNotificationFetch)
+					p.fcClient.OneTimeCost(cost)
+					h.server.clientPool.requestCost(p, cost)
+				}
+				reply := p.ReplyNotifications(req.ReqID, notifications, delivered)
+				sendResponse(req.ReqID, req.Max, reply, task.done())
+			}()
+		}
+
 	default:
 		p.Log().Trace("Received invalid message", "code", msg.Code)
 		clientErrorMeter.Mark(1)
@@ -995,6 +1035,34 @@ func (h *serverHandler) broadcastHeaders() {
 	}
 }
 
+// pushNotifications sends notifications to a connected client as they happen,
+// charging their size to its flow control buffer. It returns false if the client
+// can't be served or its buffer can't cover the cost, in which case the
+// notifications are stored for it.
+func (h *serverHandler) pushNotifications(p *peer, notifications []*Notification) bool {
+	if p.isFrozen() || !p.canQueue() {
+		return false
+	}
+	reply := p.ReplyNotifications(0, notifications, nil)
+	cost := h.server.costTracker.realCost(0, 0, reply.size())
+	if !p.fcClient.AcceptOneTimeCost(cost) {
+		return false
+	}
+	h.server.clientPool.requestCost(p, cost)
+	p.queueSend(func() {
+		// Report the buffer value when sending, so that it accounts for all the
+		// requests replied to before
+		bv, _ := p.fcClient.BufferStatus()
+		if err := reply.send(bv); err != nil {
+			select {
+			case p.errCh <- err:
+			default:
+			}
+		}
+	})
+	return true
+}
+
 func (h *serverHandler) verifyGatewayFee(gatewayFeeRecipient *common.Address, gatewayFee *big.Int) error {
 
 	// If this node does not specify an etherbase, accept any GatewayFeeRecipient.
@@ -1011,10 +1079,21 @@ func (h *serverHandler) verifyGatewayFee(gatewayFeeRecipient *common.Address, ga
 	}
 
 	// Check that the value of the supplied gateway fee is at least the minimum.
-	if h.gatewayFee != nil && h.gatewayFee.Cmp(common.Big0) > 0 {
-		if gatewayFee == nil || gatewayFee.Cmp(h.gatewayFee) < 0 {
-			return fmt.Errorf("gateway fee value must be at least %s, got %s", h.gatewayFee, gatewayFee)
+	h.feeLock.RLock()
+	minFee := h.gatewayFee
+	h.feeLock.RUnlock()
+	if minFee != nil && minFee.Cmp(common.Big0) > 0 {
+		if gatewayFee == nil || gatewayFee.Cmp(minFee) < 0 {
+			return fmt.Errorf("gateway fee value must be at least %s, got %s", minFee, gatewayFee)
 		}
 	}
 	return nil
 }
+
+// setGatewayFee updates the minimum gateway fee of transactions relayed for light clients.
+func (h *serverHandler) setGatewayFee(gatewayFee *big.Int) {
+	h.feeLock.Lock()
+	defer h.feeLock.Unlock()
+
+	h.gatewayFee = gatewayFee
+}
@@ -23,6 +23,7 @@ import (
 	"math"
 	"math/rand"
 	"net"
+	"sort"
 	"strconv"
 	"sync"
 	"time"
@@ -86,6 +87,8 @@ const (
 	// initStatsWeight is used to initialize previously unknown peers with good
 	// statistics to give a chance to prove themselves
 	initStatsWeight = 1
+	// pausedDiscoverPeriod is the topic search period while the pool is paused
+	pausedDiscoverPeriod = time.Hour
 )
 
 // connReq represents a request for peer connection.
@@ -108,6 +111,12 @@ type registerReq struct {
 	done  chan struct{}
 }
 
+// pauseReq represents a request for pausing or resuming the pool.
+type pauseReq struct {
+	paused bool
+	done   chan struct{}
+}
+
 // serverPool implements a pool for storing and selecting newly discovered and already
 // known light server nodes. It received discovered nodes, stores statistics about
 // known nodes and takes care of always having enough good quality servers connected.
@@ -135,6 +144,11 @@ type serverPool struct {
 	connCh                     chan *connReq
 	disconnCh                  chan *disconnReq
 	registerCh                 chan *registerReq
+	pauseCh                    chan *pauseReq
+
+	// Servers connected when the pool was last paused, redialed first on resume
+	paused   bool
+	lastGood []enode.ID
 
 	closeCh chan struct{}
 	wg      sync.WaitGroup
@@ -151,6 +165,7 @@ func newServerPool(db ethdb.Database, ulcServers []string) *serverPool {
 		connCh:       make(chan *connReq),
 		disconnCh:    make(chan *disconnReq),
 		registerCh:   make(chan *registerReq),
+		pauseCh:      make(chan *pauseReq),
 		closeCh:      make(chan struct{}),
 		knownSelect:  newWeightedRandomSelect(),
 		newSelect:    newWeightedRandomSelect(),
@@ -169,6 +184,7 @@ func (pool *serverPool) start(server *p2p.Server, topic discv5.Topic) {
 	pool.dbKey = append([]byte("serverPool/"), []byte(topic)...)
 	pool.loadNodes()
 	pool.connectToTrustedNodes()
+	pool.dialLastGood()
 
 	if pool.server.DiscV5 != nil {
 		pool.discSetPeriod = make(chan time.Duration, 1)
@@ -186,6 +202,20 @@ func (pool *serverPool) stop() {
 	pool.wg.Wait()
 }
 
+// setPaused pauses or resumes the pool. While paused, the pool disconnects from
+// all servers and doesn't dial new ones. The statistics of the known servers and
+// the servers connected on pause are saved, so that they can be redialed first on
+// resume, even after a restart.
+func (pool *serverPool) setPaused(paused bool) {
+	req := &pauseReq{paused: paused, done: make(chan struct{})}
+	select {
+	case pool.pauseCh <- req:
+	case <-pool.closeCh:
+		return
+	}
+	<-req.done
+}
+
 // discoverNodes wraps SearchTopic, converting result nodes to enode.Node.
 func (pool *serverPool) discoverNodes() {
 	ch := make(chan *discv5.Node)
@@ -286,9 +316,7 @@ func (pool *serverPool) eventLoop() {
 	defer pool.wg.Done()
 	lookupCnt := 0
 	var convTime mclock.AbsTime
-	if pool.discSetPeriod != nil {
-		pool.discSetPeriod <- time.Millisecond * 100
-	}
+	pool.setDiscoverPeriod(time.Millisecond * 100)
 
 	// disconnect updates service quality statistics depending on the connection time
 	// and disconnection initiator.
@@ -358,14 +386,14 @@ func (pool *serverPool) eventLoop() {
 				lookupCnt++
 				if pool.fastDiscover && (lookupCnt == 50 || time.Duration(mclock.Now()-convTime) > time.Minute) {
 					pool.fastDiscover = false
-					if pool.discSetPeriod != nil {
-						pool.discSetPeriod <- time.Minute
-					}
+					pool.setDiscoverPeriod(time.Minute)
 				}
 			}
 
 		case req := <-pool.connCh:
-			if pool.trustedNodes[req.p.ID()] != nil {
+			if pool.paused {
+				req.result <- nil
+			} else if pool.trustedNodes[req.p.ID()] != nil {
 				// ignore trusted nodes
 				req.result <- &poolEntry{trusted: true}
 			} else {
@@ -417,6 +445,14 @@ func (pool *serverPool) eventLoop() {
 			// Handle peer disconnection requests.
 			disconnect(req, req.stopped)
 
+		case req := <-pool.pauseCh:
+			if req.paused && !pool.paused {
+				pool.pause()
+			} else if !req.paused && pool.paused {
+				pool.resume()
+			}
+			close(req.done)
+
 		case <-pool.closeCh:
 			if pool.discSetPeriod != nil {
 				close(pool.discSetPeriod)
@@ -539,6 +575,100 @@ func (pool *serverPool) saveNodes() {
 	}
 }
 
+// setDiscoverPeriod sets the topic search period without blocking the event loop. A
+// period not taken by the topic search yet is replaced.
+func (pool *serverPool) setDiscoverPeriod(period time.Duration) {
+	if pool.discSetPeriod == nil {
+		return
+	}
+	select {
+	case <-pool.discSetPeriod:
+	default:
+	}
+	select {
+	case pool.discSetPeriod <- period:
+	default:
+	}
+}
+
+// lastGoodKey returns the database key of the servers connected on the last pause.
+func (pool *serverPool) lastGoodKey() []byte {
+	return append([]byte("serverPoolLastGood/"), []byte(pool.topic)...)
+}
+
+// pause disconnects from all servers and saves the server statistics and the
+// connected servers.
+func (pool *serverPool) pause() {
+	pool.paused = true
+	pool.lastGood = pool.lastGood[:0]
+	for id, entry := range pool.entries {
+		if entry.state == psRegistered {
+			pool.lastGood = append(pool.lastGood, id)
+		}
+	}
+	// saveNodes drains the known queue, restore it to keep running
+	known := make([]*poolEntry, 0, len(pool.knownQueue.queue))
+	for _, entry := range pool.knownQueue.queue {
+		known = append(known, entry)
+	}
+	sort.Slice(known, func(i, j int) bool { return known[i].queueIdx < known[j].queueIdx })
+	pool.saveNodes()
+	for _, entry := range known {
+		pool.knownQueue.setLatest(entry)
+	}
+	if enc, err := rlp.EncodeToBytes(pool.lastGood); err == nil {
+		pool.db.Put(pool.lastGoodKey(), enc)
+	}
+	log.Debug("Paused server pool", "connected", len(pool.lastGood))
+
+	pool.setDiscoverPeriod(pausedDiscoverPeriod)
+	if pool.server == nil {
+		return
+	}
+	pool.server.SetDiscoveryPaused(true)
+	for _, entry := range pool.entries {
+		if entry.state != psNotConnected {
+			pool.server.RemovePeer(entry.node, p2p.ExplicitStaticPurpose)
+		}
+	}
+	for _, node := range pool.trustedNodes {
+		pool.server.RemovePeer(node, p2p.ExplicitStaticPurpose)
+	}
+}
+
+// resume reconnects to the trusted servers and the servers connected on pause,
+// then dials new servers as usual.
+func (pool *serverPool) resume() {
+	pool.paused = false
+	log.Debug("Resumed server pool", "lastGood", len(pool.lastGood))
+
+	if pool.fastDiscover {
+		pool.setDiscoverPeriod(time.Millisecond * 100)
+	} else {
+		pool.setDiscoverPeriod(time.Minute)
+	}
+	if pool.server != nil {
+		pool.server.SetDiscoveryPaused(false)
+		pool.connectToTrustedNodes()
+	}
+	pool.dialLastGood()
+	pool.checkDial()
+}
+
+// dialLastGood dials the servers connected when the pool was last paused.
+func (pool *serverPool) dialLastGood() {
+	if pool.lastGood == nil {
+		if enc, err := pool.db.Get(pool.lastGoodKey()); err == nil {
+			rlp.DecodeBytes(enc, &pool.lastGood)
+		}
+	}
+	for _, id := range pool.lastGood {
+		if entry := pool.entries[id]; entry != nil && pool.knownSelected < targetServerCount {
+			pool.dial(entry, true)
+		}
+	}
+}
+
 // removeEntry removes a pool entry when the entry count limit is reached.
 // Note that it is called by the new/known queues from which the entry has already
 // been removed so removing it from the queues is not necessary.
@@ -581,6 +711,9 @@ func (pool *serverPool) updateCheckDial(entry *poolEntry) {
 // checkDial checks if new dials can/should be made. It tries to select servers both
 // based on good statistics and recent discovery.
 func (pool *serverPool) checkDial() {
+	if pool.paused {
+		return
+	}
 	fillWithKnownSelects := !pool.fastDiscover
 	for pool.knownSelected < targetKnownSelect {
 		entry := pool.knownSelect.choose()
@@ -0,0 +1,86 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+func TestServerPoolPause(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	pool := newServerPool(db, nil)
+	pool.topic = "test"
+	pool.dbKey = []byte("serverPool/test")
+
+	// One registered server and one known but disconnected server
+	newEntry := func(state int) *poolEntry {
+		key, _ := crypto.GenerateKey()
+		entry := pool.findOrNewNode(enode.NewV4(&key.PublicKey, net.ParseIP("127.0.0.1"), 30303, 30303))
+		pool.newQueue.remove(entry)
+		entry.known, entry.state = true, state
+		entry.lastConnected = &poolEntryAddress{ip: entry.node.IP(), port: uint16(entry.node.TCP())}
+		pool.knownQueue.setLatest(entry)
+		return entry
+	}
+	connected := newEntry(psRegistered)
+	newEntry(psNotConnected)
+
+	// Topic search periods are set without waiting for the search to take them
+	pool.discSetPeriod = make(chan time.Duration, 1)
+	pool.setDiscoverPeriod(time.Minute)
+
+	pool.pause()
+	if !pool.paused {
+		t.Fatal("pool not paused")
+	}
+	if len(pool.lastGood) != 1 || pool.lastGood[0] != connected.node.ID() {
+		t.Fatalf("last good servers mismatch: have %v, want [%v]", pool.lastGood, connected.node.ID())
+	}
+	if len(pool.knownQueue.queue) != 2 {
+		t.Fatalf("known servers dropped on pause: have %d, want 2", len(pool.knownQueue.queue))
+	}
+	if period := <-pool.discSetPeriod; period != pausedDiscoverPeriod {
+		t.Fatalf("paused topic search period mismatch: have %v, want %v", period, pausedDiscoverPeriod)
+	}
+	pool.pause()
+	pool.resume()
+	if pool.paused {
+		t.Fatal("pool not resumed")
+	}
+	if period := <-pool.discSetPeriod; period != 100*time.Millisecond {
+		t.Fatalf("resumed topic search period mismatch: have %v, want %v", period, 100*time.Millisecond)
+	}
+
+	// A restarted pool loads the statistics and the last good servers
+	restarted := newServerPool(db, nil)
+	restarted.topic = pool.topic
+	restarted.dbKey = pool.dbKey
+	restarted.loadNodes()
+	restarted.dialLastGood()
+	if len(restarted.entries) != 2 {
+		t.Errorf("known servers mismatch: have %d, want 2", len(restarted.entries))
+	}
+	if len(restarted.lastGood) != 1 || restarted.lastGood[0] != connected.node.ID() {
+		t.Errorf("last good servers mismatch: have %v, want [%v]", restarted.lastGood, connected.node.ID())
+	}
+}
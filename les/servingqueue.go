@@ -36,6 +36,7 @@ type servingQueue struct {
 	queueAddCh, queueBestCh chan *servingTask
 	stopThreadCh, quit      chan struct{}
 	setThreadsCh            chan int
+	setUtilTargetCh         chan float64
 
 	wg          sync.WaitGroup
 	threadCount int          // number of currently running threads
@@ -123,24 +124,31 @@ func (t *servingTask) waitOrStop() bool {
 // newServingQueue returns a new servingQueue
 func newServingQueue(suspendBias int64, utilTarget float64) *servingQueue {
 	sq := &servingQueue{
-		queue:          prque.New(nil),
-		suspendBias:    suspendBias,
-		queueAddCh:     make(chan *servingTask, 100),
-		queueBestCh:    make(chan *servingTask),
-		stopThreadCh:   make(chan struct{}),
-		quit:           make(chan struct{}),
-		setThreadsCh:   make(chan int, 10),
-		burstLimit:     uint64(utilTarget * bufLimitRatio * 1200000),
-		burstDropLimit: uint64(utilTarget * bufLimitRatio * 1000000),
-		burstDecRate:   utilTarget,
-		lastUpdate:     mclock.Now(),
+		queue:           prque.New(nil),
+		suspendBias:     suspendBias,
+		queueAddCh:      make(chan *servingTask, 100),
+		queueBestCh:     make(chan *servingTask),
+		stopThreadCh:    make(chan struct{}),
+		quit:            make(chan struct{}),
+		setThreadsCh:    make(chan int, 10),
+		setUtilTargetCh: make(chan float64),
+		lastUpdate:      mclock.Now(),
 	}
+	sq.applyUtilTarget(utilTarget)
 	sq.wg.Add(2)
 	go sq.queueLoop()
 	go sq.threadCountLoop()
 	return sq
 }
 
+// applyUtilTarget sets the burst limits of the queue derived from the ratio of
+// time allowed for serving requests.
+func (sq *servingQueue) applyUtilTarget(utilTarget float64) {
+	sq.burstLimit = uint64(utilTarget * bufLimitRatio * 1200000)
+	sq.burstDropLimit = uint64(utilTarget * bufLimitRatio * 1000000)
+	sq.burstDecRate = utilTarget
+}
+
 // newTask creates a new task with the given priority
 func (sq *servingQueue) newTask(peer *peer, maxTime uint64, priority int64) *servingTask {
 	return &servingTask{
@@ -304,6 +312,9 @@ func (sq *servingQueue) queueLoop() {
 			select {
 			case task := <-sq.queueAddCh:
 				sq.addTask(task)
+			case utilTarget := <-sq.setUtilTargetCh:
+				sq.updateRecentTime()
+				sq.applyUtilTarget(utilTarget)
 			case sq.queueBestCh <- sq.best:
 				sq.updateRecentTime()
 				sq.queuedTime -= expTime
@@ -323,6 +334,9 @@ func (sq *servingQueue) queueLoop() {
 			select {
 			case task := <-sq.queueAddCh:
 				sq.addTask(task)
+			case utilTarget := <-sq.setUtilTargetCh:
+				sq.updateRecentTime()
+				sq.applyUtilTarget(utilTarget)
 			case <-sq.quit:
 				sq.wg.Done()
 				return
@@ -371,6 +385,15 @@ func (sq *servingQueue) setThreads(threadCount int) {
 	}
 }
 
+// setUtilTarget sets the ratio of time allowed for serving requests, which
+// limits the serving time bursts of the queue.
+func (sq *servingQueue) setUtilTarget(utilTarget float64) {
+	select {
+	case sq.setUtilTargetCh <- utilTarget:
+	case <-sq.quit:
+	}
+}
+
 // stop stops task processing as soon as possible and shuts down the serving queue.
 func (sq *servingQueue) stop() {
 	close(sq.quit)
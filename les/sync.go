@@ -100,12 +100,14 @@ func (h *clientHandler) synchronise(peer *peer) {
 		// In the 'lightest' mode used with IBFT, each block has a difficulty of 1, so block number
 		// corresponds to the difficulty level. So make sure peer's block number is greater than our own.
 		if peer.headBlockInfo().Number <= latest.Number.Uint64() {
+			h.syncedFeed.Send(SyncedEvent{Head: latest})
 			return
 		}
 	} else {
 		// Make sure the peer's TD is higher than our own.
 		currentTd := rawdb.ReadTd(h.backend.chainDb, latest.Hash(), latest.Number.Uint64())
 		if currentTd != nil && peer.headBlockInfo().Td.Cmp(currentTd) < 0 {
+			h.syncedFeed.Send(SyncedEvent{Head: latest})
 			return
 		}
 	}
@@ -195,4 +197,5 @@ func (h *clientHandler) synchronise(peer *peer) {
 		return
 	}
 	log.Debug("Synchronise finished", "elapsed", common.PrettyDuration(time.Since(start)))
+	h.syncedFeed.Send(SyncedEvent{Head: h.backend.blockchain.CurrentHeader()})
 }
@@ -114,6 +114,33 @@ public class AndroidTest extends InstrumentationTestCase {
 				@Override public void onNewHead(final Header header) {}
 			};
 			ec.subscribeNewHead(ctx, handler,  16);
+
+			// Pause and resume the node as when the app moves to the background
+			node.pause();
+			node.resume(new SyncHandler() {
+				@Override public void onSynced(final Header header) {}
+			});
+		} catch (Exception e) {
+			fail(e.toString());
+		}
+	}
+
+	public void testCeloNodeConfig() {
+		try {
+			Hash root = Geth.newHashFromHex("0x0000000000000000000000000000000000000000000000000000000000000001");
+			Enodes servers = Geth.newEnodesEmpty();
+			servers.append(new Enode("enode://1dd9d65c4552b5eb43d5ad55a2ee3f56c6cbc1c64a5c8d659f51fcd51bace24351232b8d7821617d2b29b54b81cdefb9b3e9c37d7fd5f63270bcc9e1a6f6a439@1.2.3.4:30303"));
+
+			// Configure the Celo options with the builder methods
+			NodeConfig config = new NodeConfig()
+				.withTrustedCheckpoint(new TrustedCheckpoint(10, root, root, root))
+				.withLightServers(servers)
+				.withMaxGatewayFee(new BigInt(5000))
+				.withDefaultFeeCurrency(Geth.newAddressFromHex("0x000000000000000000000000000000000000ce10"));
+
+			assertEquals(10, config.getTrustedCheckpoint().getSectionIndex());
+			assertEquals(1, config.getLightServers().size());
+			assertEquals(5000, config.getMaxGatewayFee().getInt64());
 		} catch (Exception e) {
 			fail(e.toString());
 		}
@@ -22,13 +22,16 @@ package geth
 import (
 	"encoding/json"
 	"fmt"
+	"math/big"
 	"path/filepath"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/ethstats"
+	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/internal/debug"
 	"github.com/ethereum/go-ethereum/les"
 	"github.com/ethereum/go-ethereum/node"
@@ -104,6 +107,22 @@ type NodeConfig struct {
 	// pipe path on Windows), whereas if it's a resolvable path name (absolute or
 	// relative), then that specific path is enforced. An empty path disables IPC.
 	IPCPath string
+
+	// TrustedCheckpoint is the checkpoint the light client starts syncing from,
+	// instead of the one hard coded for the network. Nil means the hard coded one.
+	TrustedCheckpoint *TrustedCheckpoint
+
+	// LightServers are the light servers the node prefers to connect to, dialed
+	// on start and redialed whenever the connection drops.
+	LightServers *Enodes
+
+	// MaxGatewayFee is the maximum gateway fee the node accepts to pay to the
+	// light servers relaying its transactions. Nil means no limit.
+	MaxGatewayFee *BigInt
+
+	// DefaultFeeCurrency is the fee currency of the transactions that don't
+	// specify one. Nil means the native token.
+	DefaultFeeCurrency *Address
 }
 
 // defaultNodeConfig contains the default node configuration values to use if all
@@ -122,9 +141,74 @@ func NewNodeConfig() *NodeConfig {
 	return &config
 }
 
+// WithTrustedCheckpoint sets the checkpoint the light client starts syncing from.
+func (c *NodeConfig) WithTrustedCheckpoint(checkpoint *TrustedCheckpoint) *NodeConfig {
+	c.TrustedCheckpoint = checkpoint
+	return c
+}
+
+// WithLightServers sets the light servers the node prefers to connect to.
+func (c *NodeConfig) WithLightServers(servers *Enodes) *NodeConfig {
+	c.LightServers = servers
+	return c
+}
+
+// WithMaxGatewayFee sets the maximum gateway fee the node accepts to pay.
+func (c *NodeConfig) WithMaxGatewayFee(fee *BigInt) *NodeConfig {
+	c.MaxGatewayFee = fee
+	return c
+}
+
+// WithDefaultFeeCurrency sets the fee currency of the transactions that don't
+// specify one.
+func (c *NodeConfig) WithDefaultFeeCurrency(currency *Address) *NodeConfig {
+	c.DefaultFeeCurrency = currency
+	return c
+}
+
+// ethConfig creates the configuration of the light Ethereum protocol.
+func (c *NodeConfig) ethConfig(genesis *core.Genesis) *eth.Config {
+	ethConf := eth.DefaultConfig
+	ethConf.Genesis = genesis
+
+	ethConf.SyncMode = getSyncMode(c.SyncMode)
+	ethConf.NetworkId = uint64(c.EthereumNetworkID)
+	ethConf.DatabaseCache = c.EthereumDatabaseCache
+	// Use an in memory DB for validatorEnode table
+	ethConf.Istanbul.ValidatorEnodeDBPath = ""
+	// Use an in memory DB for roundState table
+	ethConf.Istanbul.RoundStateDBPath = ""
+
+	if c.TrustedCheckpoint != nil {
+		checkpoint := *c.TrustedCheckpoint.checkpoint
+		ethConf.Checkpoint = &checkpoint
+	}
+	if c.LightServers != nil {
+		for _, node := range c.LightServers.nodes {
+			ethConf.LightServers = append(ethConf.LightServers, node.String())
+		}
+	}
+	if c.MaxGatewayFee != nil {
+		ethConf.MaxGatewayFee = new(big.Int).Set(c.MaxGatewayFee.bigint)
+	}
+	if c.DefaultFeeCurrency != nil {
+		currency := c.DefaultFeeCurrency.address
+		ethConf.DefaultFeeCurrency = &currency
+	}
+	return &ethConf
+}
+
 // Node represents a Geth Ethereum node instance.
 type Node struct {
 	node *node.Node
+
+	syncSub   event.Subscription // Subscription of the handler waiting for the node to sync
+	syncSubMu sync.Mutex
+}
+
+// SyncHandler is a client-side callback invoked when a resumed node is synced.
+type SyncHandler interface {
+	OnSynced(head *Header)
 }
 
 // NewNode creates and configures a new Geth node.
@@ -186,18 +270,9 @@ func NewNode(datadir string, config *NodeConfig) (stack *Node, _ error) {
 	}
 	// Register the Ethereum protocol if requested
 	if config.EthereumEnabled {
-		ethConf := eth.DefaultConfig
-		ethConf.Genesis = genesis
-
-		ethConf.SyncMode = getSyncMode(config.SyncMode)
-		ethConf.NetworkId = uint64(config.EthereumNetworkID)
-		ethConf.DatabaseCache = config.EthereumDatabaseCache
-		// Use an in memory DB for validatorEnode table
-		ethConf.Istanbul.ValidatorEnodeDBPath = ""
-		// Use an in memory DB for roundState table
-		ethConf.Istanbul.RoundStateDBPath = ""
+		ethConf := config.ethConfig(genesis)
 		if err := rawStack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
-			return les.New(ctx, &ethConf)
+			return les.New(ctx, ethConf)
 		}); err != nil {
 			return nil, fmt.Errorf("ethereum init: %v", err)
 		}
@@ -221,7 +296,7 @@ func NewNode(datadir string, config *NodeConfig) (stack *Node, _ error) {
 			return nil, fmt.Errorf("whisper init: %v", err)
 		}
 	}
-	return &Node{rawStack}, nil
+	return &Node{node: rawStack}, nil
 }
 
 func getSyncMode(syncMode int) downloader.SyncMode {
@@ -248,6 +323,7 @@ func getSyncMode(syncMode int) downloader.SyncMode {
 // Close terminates a running node along with all it's services, tearing internal
 // state doen too. It's not possible to restart a closed node.
 func (n *Node) Close() error {
+	n.setSyncSub(nil)
 	return n.node.Close()
 }
 
@@ -259,9 +335,59 @@ func (n *Node) Start() error {
 // Stop terminates a running node along with all it's services. If the node was
 // not started, an error is returned.
 func (n *Node) Stop() error {
+	n.setSyncSub(nil)
 	return n.node.Stop()
 }
 
+// Pause throttles the networking of a running node, e.g. while the app is in the
+// background. The node disconnects from its servers but keeps its server statistics
+// and sync progress, so that Resume continues where it left off.
+func (n *Node) Pause() error {
+	var lesServ *les.LightEthereum
+	if err := n.node.Service(&lesServ); err != nil {
+		return err
+	}
+	n.setSyncSub(nil)
+	lesServ.Pause()
+	return nil
+}
+
+// Resume reconnects a paused node to the servers it was connected to. If the
+// handler is not nil, it is called once the node is synced again.
+func (n *Node) Resume(handler SyncHandler) error {
+	var lesServ *les.LightEthereum
+	if err := n.node.Service(&lesServ); err != nil {
+		return err
+	}
+	if handler != nil {
+		ch := make(chan les.SyncedEvent, 1)
+		sub := lesServ.SubscribeSyncedEvent(ch)
+		n.setSyncSub(sub)
+		go func() {
+			defer sub.Unsubscribe()
+			select {
+			case ev := <-ch:
+				handler.OnSynced(&Header{ev.Head})
+			case <-sub.Err():
+			}
+		}()
+	}
+	lesServ.Resume()
+	return nil
+}
+
+// setSyncSub replaces the subscription of the handler waiting for the node to
+// sync, dropping the previous one.
+func (n *Node) setSyncSub(sub event.Subscription) {
+	n.syncSubMu.Lock()
+	defer n.syncSubMu.Unlock()
+
+	if n.syncSub != nil {
+		n.syncSub.Unsubscribe()
+	}
+	n.syncSub = sub
+}
+
 // GetEthereumClient retrieves a client to access the Ethereum subsystem.
 func (n *Node) GetEthereumClient() (client *EthereumClient, _ error) {
 	rpc, err := n.node.Attach()
@@ -0,0 +1,90 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package geth
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/eth/downloader"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const testLightServer = "enode://1dd9d65c4552b5eb43d5ad55a2ee3f56c6cbc1c64a5c8d659f51fcd51bace24351232b8d7821617d2b29b54b81cdefb9b3e9c37d7fd5f63270bcc9e1a6f6a439@1.2.3.4:30303"
+
+func TestNodeConfigCeloOptions(t *testing.T) {
+	// The defaults leave the protocol defaults untouched
+	ethConf := NewNodeConfig().ethConfig(nil)
+	if ethConf.Checkpoint != nil || ethConf.LightServers != nil || ethConf.MaxGatewayFee != nil || ethConf.DefaultFeeCurrency != nil {
+		t.Fatalf("default config sets Celo options: %+v", ethConf)
+	}
+	if ethConf.SyncMode != downloader.LightSync {
+		t.Errorf("sync mode mismatch: have %v, want %v", ethConf.SyncMode, downloader.LightSync)
+	}
+
+	sectionHead, _ := NewHashFromHex(common.HexToHash("0x01").Hex())
+	chtRoot, _ := NewHashFromHex(common.HexToHash("0x02").Hex())
+	bloomRoot, _ := NewHashFromHex(common.HexToHash("0x03").Hex())
+	server, err := NewEnode(testLightServer)
+	if err != nil {
+		t.Fatalf("failed to parse enode: %v", err)
+	}
+	servers := NewEnodesEmpty()
+	servers.Append(server)
+	currency, _ := NewAddressFromHex("0x000000000000000000000000000000000000ce10")
+
+	config := NewNodeConfig().
+		WithTrustedCheckpoint(NewTrustedCheckpoint(10, sectionHead, chtRoot, bloomRoot)).
+		WithLightServers(servers).
+		WithMaxGatewayFee(NewBigInt(5000)).
+		WithDefaultFeeCurrency(currency)
+	config.SyncMode = LightestSync
+	ethConf = config.ethConfig(nil)
+
+	checkpoint := &params.TrustedCheckpoint{
+		SectionIndex: 10,
+		SectionHead:  common.HexToHash("0x01"),
+		CHTRoot:      common.HexToHash("0x02"),
+		BloomRoot:    common.HexToHash("0x03"),
+	}
+	if !reflect.DeepEqual(ethConf.Checkpoint, checkpoint) {
+		t.Errorf("checkpoint mismatch: have %+v, want %+v", ethConf.Checkpoint, checkpoint)
+	}
+	if !reflect.DeepEqual(ethConf.LightServers, []string{testLightServer}) {
+		t.Errorf("light servers mismatch: have %v, want %v", ethConf.LightServers, []string{testLightServer})
+	}
+	if ethConf.MaxGatewayFee == nil || ethConf.MaxGatewayFee.Int64() != 5000 {
+		t.Errorf("max gateway fee mismatch: have %v, want %v", ethConf.MaxGatewayFee, 5000)
+	}
+	if ethConf.DefaultFeeCurrency == nil || *ethConf.DefaultFeeCurrency != currency.address {
+		t.Errorf("default fee currency mismatch: have %v, want %v", ethConf.DefaultFeeCurrency, currency.address)
+	}
+	if ethConf.SyncMode != downloader.LightestSync {
+		t.Errorf("sync mode mismatch: have %v, want %v", ethConf.SyncMode, downloader.LightestSync)
+	}
+
+	// The protocol config is detached from the mobile values
+	config.MaxGatewayFee.SetInt64(1)
+	if ethConf.MaxGatewayFee.Int64() != 5000 {
+		t.Errorf("max gateway fee changed with the node config")
+	}
+	if eth.DefaultConfig.Checkpoint != nil || eth.DefaultConfig.LightServers != nil {
+		t.Errorf("default protocol config modified")
+	}
+}
@@ -59,3 +59,31 @@ func FoundationBootnodes() *Enodes {
 	}
 	return nodes
 }
+
+// TrustedCheckpoint represents the post-processed trie roots (CHT and BloomTrie)
+// of a section of the chain, which a light client trusts to start syncing from.
+type TrustedCheckpoint struct {
+	checkpoint *params.TrustedCheckpoint
+}
+
+// NewTrustedCheckpoint creates a checkpoint of the given section of the chain.
+func NewTrustedCheckpoint(sectionIndex int64, sectionHead, chtRoot, bloomRoot *Hash) *TrustedCheckpoint {
+	return &TrustedCheckpoint{&params.TrustedCheckpoint{
+		SectionIndex: uint64(sectionIndex),
+		SectionHead:  sectionHead.hash,
+		CHTRoot:      chtRoot.hash,
+		BloomRoot:    bloomRoot.hash,
+	}}
+}
+
+// GetSectionIndex returns the index of the section of the checkpoint.
+func (c *TrustedCheckpoint) GetSectionIndex() int64 { return int64(c.checkpoint.SectionIndex) }
+
+// GetSectionHead returns the hash of the last block of the section.
+func (c *TrustedCheckpoint) GetSectionHead() *Hash { return &Hash{c.checkpoint.SectionHead} }
+
+// GetCHTRoot returns the root of the canonical hash trie of the section.
+func (c *TrustedCheckpoint) GetCHTRoot() *Hash { return &Hash{c.checkpoint.CHTRoot} }
+
+// GetBloomRoot returns the root of the bloom trie of the section.
+func (c *TrustedCheckpoint) GetBloomRoot() *Hash { return &Hash{c.checkpoint.BloomRoot} }
@@ -105,6 +105,65 @@ func (api *PrivateAdminAPI) RemoveTrustedPeer(url string) (bool, error) {
 	return true, nil
 }
 
+// PinPeer keeps a remote node connected with the given purpose (one of
+// "static", "trusted", "validator" or "proxy"), across dial and eviction
+// cycles, regardless of the purposes managed by the node itself.
+func (api *PrivateAdminAPI) PinPeer(url string, purpose string) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	node, err := enode.Parse(enode.ValidSchemes, url)
+	if err != nil {
+		return false, fmt.Errorf("invalid enode: %v", err)
+	}
+	flag, err := p2p.ParsePurpose(purpose)
+	if err != nil {
+		return false, err
+	}
+	server.PinPeer(node, flag)
+	return true, nil
+}
+
+// UnpinPeer removes the pin from a remote node, which is disconnected unless it
+// is still needed for another purpose.
+func (api *PrivateAdminAPI) UnpinPeer(url string) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	node, err := enode.Parse(enode.ValidSchemes, url)
+	if err != nil {
+		return false, fmt.Errorf("invalid enode: %v", err)
+	}
+	server.UnpinPeer(node)
+	return true, nil
+}
+
+// PeerHistory retrieves the recent dial, connection and drop events of a remote
+// node, together with the reasons of failures and disconnects.
+func (api *PrivateAdminAPI) PeerHistory(url string) ([]p2p.PeerHistoryEvent, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	node, err := enode.Parse(enode.ValidSchemes, url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid enode: %v", err)
+	}
+	return server.PeerHistory(node), nil
+}
+
+// ReloadConfig re-reads the configuration file of the node, applies the changed
+// settings which can be updated at runtime and reports the ones which require
+// a restart.
+func (api *PrivateAdminAPI) ReloadConfig() (*ConfigChanges, error) {
+	return api.node.ReloadConfig()
+}
+
 // PeerEvents creates an RPC subscription which receives peer events from the
 // node's p2p.Server
 func (api *PrivateAdminAPI) PeerEvents(ctx context.Context) (*rpc.Subscription, error) {
@@ -292,6 +351,17 @@ func (api *PublicAdminAPI) Peers() ([]*p2p.PeerInfo, error) {
 	return server.PeersInfo(), nil
 }
 
+// PeersByPurpose retrieves all the information we know about each individual
+// peer at the protocol granularity, grouped by the purposes the peers are
+// connected for.
+func (api *PublicAdminAPI) PeersByPurpose() (map[string][]*p2p.PeerInfo, error) {
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	return server.PeersByPurpose(), nil
+}
+
 // NodeInfo retrieves all the information we know about the host node at the
 // protocol granularity.
 func (api *PublicAdminAPI) NodeInfo() (*p2p.NodeInfo, error) {
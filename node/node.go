@@ -71,6 +71,9 @@ type Node struct {
 	wsListener net.Listener // Websocket RPC listener socket to server API requests
 	wsHandler  *rpc.Server  // Websocket RPC request handler to process the API requests
 
+	reloader     ConfigReloader // Callback re-reading the configuration, nil if not supported
+	reloaderLock sync.Mutex     // Serializes configuration reloads
+
 	stop chan struct{} // Channel to wait for termination notifications
 	lock sync.RWMutex
 
@@ -582,6 +585,15 @@ func (n *Node) Server() *p2p.Server {
 	return n.server
 }
 
+// ProxyServer retrieves the currently running internal facing P2P server of a
+// proxy, or nil if the node is not a proxy.
+func (n *Node) ProxyServer() *p2p.Server {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	return n.proxyServer
+}
+
 // Service retrieves a currently running service registered of a specific type.
 func (n *Node) Service(service interface{}) error {
 	n.lock.RLock()
@@ -600,6 +612,40 @@ func (n *Node) Service(service interface{}) error {
 	return ErrServiceUnknown
 }
 
+// ConfigChanges reports the outcome of a configuration reload.
+type ConfigChanges struct {
+	Applied         []string `json:"applied"`         // Changed settings applied to the running node
+	RestartRequired []string `json:"restartRequired"` // Changed settings which take effect after a restart
+	Overridden      []string `json:"overridden"`      // Changed settings overridden by command line flags
+}
+
+// ConfigReloader re-reads the configuration of the node, applies the settings
+// that can be changed at runtime and reports what changed.
+type ConfigReloader func() (*ConfigChanges, error)
+
+// SetConfigReloader sets the callback used to reload the node configuration.
+func (n *Node) SetConfigReloader(reloader ConfigReloader) {
+	n.reloaderLock.Lock()
+	defer n.reloaderLock.Unlock()
+
+	n.reloader = reloader
+}
+
+// ReloadConfig re-reads the node configuration and applies the settings that can
+// be changed without restarting the node.
+func (n *Node) ReloadConfig() (*ConfigChanges, error) {
+	n.reloaderLock.Lock()
+	defer n.reloaderLock.Unlock()
+
+	if n.Server() == nil {
+		return nil, ErrNodeStopped
+	}
+	if n.reloader == nil {
+		return nil, ErrNoReloader
+	}
+	return n.reloader()
+}
+
 // DataDir retrieves the current datadir used by the protocol stack.
 // Deprecated: No files should be stored in this directory, use InstanceDir instead.
 func (n *Node) DataDir() string {
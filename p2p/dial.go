@@ -75,6 +75,7 @@ type dialstate struct {
 
 	start         time.Time // time when the dialer was first used
 	lookupRunning bool
+	lookupPaused  func() bool // reports whether discovery lookups are paused, may be nil
 	dialing       map[enode.ID]connFlag
 	lookupBuf     []*enode.Node // current discovery lookup results
 	static        map[enode.ID]*dialTask
@@ -186,7 +187,7 @@ func (s *dialstate) newTasks(nRunning int, peers map[enode.ID]*Peer, now time.Ti
 	s.lookupBuf = s.lookupBuf[:copy(s.lookupBuf, s.lookupBuf[i:])]
 
 	// Launch a discovery lookup if more candidates are needed.
-	if len(s.lookupBuf) < needDynDials && !s.lookupRunning {
+	if len(s.lookupBuf) < needDynDials && !s.lookupRunning && (s.lookupPaused == nil || !s.lookupPaused()) {
 		s.lookupRunning = true
 		newtasks = append(newtasks, &discoverTask{want: needDynDials - len(s.lookupBuf)})
 	}
@@ -307,6 +308,7 @@ type dialError struct {
 func (t *dialTask) dial(srv *Server, dest *enode.Node) error {
 	fd, err := srv.Dialer.Dial(dest)
 	if err != nil {
+		srv.recordPeerEvent(dest.ID(), PeerHistoryDialFailed, false, err)
 		return &dialError{err}
 	}
 	mfd := newMeteredConn(fd, false, &net.TCPAddr{IP: dest.IP(), Port: dest.TCP()})
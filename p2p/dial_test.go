@@ -354,6 +354,36 @@ func TestDialStateNetRestrict(t *testing.T) {
 	})
 }
 
+// This test checks that no discovery lookups are launched while they are paused.
+func TestDialStateLookupPaused(t *testing.T) {
+	// Lookups are paused in the first round only
+	rounds := 0
+	dialer := newDialState(enode.ID{}, 5, &Config{})
+	dialer.lookupPaused = func() bool {
+		rounds++
+		return rounds == 1
+	}
+
+	runDialTest(t, dialtest{
+		init: dialer,
+		rounds: []round{
+			// No discovery query is launched while paused.
+			{
+				new: []task{},
+			},
+			// A discovery query is launched once resumed.
+			{
+				peers: []*Peer{
+					{rw: &conn{flags: dynDialedConn, node: newNode(uintID(1), nil)}},
+				},
+				new: []task{
+					&discoverTask{want: 4},
+				},
+			},
+		},
+	})
+}
+
 // This test checks that static dials are launched.
 func TestDialStateStaticDial(t *testing.T) {
 	config := &Config{
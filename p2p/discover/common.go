@@ -44,6 +44,7 @@ type Config struct {
 	Unhandled        chan<- ReadPacket // unhandled packets are sent on this channel
 	Log              log.Logger        // if set, log messages go here
 	PingIPFromPacket bool
+	NodeFilter       func(*enode.Node) bool // if set, only matching nodes are served in neighbors responses
 }
 
 // ListenUDP starts listening for discovery packets on the given UDP socket.
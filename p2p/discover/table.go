@@ -30,6 +30,7 @@ import (
 	"net"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -76,6 +77,7 @@ type Table struct {
 	net        transport
 	refreshReq chan chan struct{}
 	initDone   chan struct{}
+	paused     int32 // whether the periodic refresh and revalidation are paused (atomic)
 
 	closeOnce sync.Once
 	closeReq  chan struct{}
@@ -220,6 +222,20 @@ func (tab *Table) isInitDone() bool {
 	}
 }
 
+// setPaused pauses or resumes the periodic refresh and revalidation of the table.
+func (tab *Table) setPaused(paused bool) {
+	var flag int32
+	if paused {
+		flag = 1
+	}
+	atomic.StoreInt32(&tab.paused, flag)
+}
+
+// isPaused returns whether the periodic refresh and revalidation of the table are paused.
+func (tab *Table) isPaused() bool {
+	return atomic.LoadInt32(&tab.paused) == 1
+}
+
 func (tab *Table) refresh() <-chan struct{} {
 	done := make(chan struct{})
 	select {
@@ -252,7 +268,7 @@ loop:
 		select {
 		case <-refresh.C:
 			tab.seedRand()
-			if refreshDone == nil {
+			if refreshDone == nil && !tab.isPaused() {
 				refreshDone = make(chan struct{})
 				go tab.doRefresh(refreshDone)
 			}
@@ -268,6 +284,10 @@ loop:
 			}
 			waiting, refreshDone = nil, nil
 		case <-revalidate.C:
+			if tab.isPaused() {
+				revalidate.Reset(tab.nextRevalidateTime())
+				continue
+			}
 			revalidateDone = make(chan struct{})
 			go tab.doRevalidate(revalidateDone)
 		case <-revalidateDone:
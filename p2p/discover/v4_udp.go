@@ -211,6 +211,7 @@ type UDPv4 struct {
 	closeOnce        sync.Once
 	wg               sync.WaitGroup
 	pingIPFromPacket bool
+	nodeFilter       func(*enode.Node) bool
 
 	addReplyMatcher chan *replyMatcher
 	gotreply        chan reply
@@ -277,6 +278,7 @@ func ListenV4(c UDPConn, ln *enode.LocalNode, cfg Config) (*UDPv4, error) {
 		cancelCloseCtx:   cancel,
 		log:              cfg.Log,
 		pingIPFromPacket: cfg.PingIPFromPacket,
+		nodeFilter:       cfg.NodeFilter,
 	}
 	if t.log == nil {
 		t.log = log.Root()
@@ -310,6 +312,12 @@ func (t *UDPv4) Close() {
 	})
 }
 
+// SetPaused pauses or resumes the periodic refresh and revalidation of the node table.
+// Queries of other nodes are still answered while paused.
+func (t *UDPv4) SetPaused(paused bool) {
+	t.tab.setPaused(paused)
+}
+
 // Resolve searches for a specific node with the given ID and tries to get the most recent
 // version of the node record for it. It returns n if the node could not be resolved.
 func (t *UDPv4) Resolve(n *enode.Node) *enode.Node {
@@ -911,6 +919,9 @@ func (req *findnodeV4) handle(t *UDPv4, from *net.UDPAddr, fromID enode.ID, mac
 	p := neighborsV4{Expiration: uint64(time.Now().Add(expiration).Unix())}
 	var sent bool
 	for _, n := range closest {
+		if t.nodeFilter != nil && !t.nodeFilter(unwrapNode(n)) {
+			continue
+		}
 		if netutil.CheckRelayIP(from.IP, n.IP()) == nil {
 			p.Nodes = append(p.Nodes, nodeToRPC(n))
 		}
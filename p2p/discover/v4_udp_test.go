@@ -315,6 +315,37 @@ func TestUDPv4_findnode(t *testing.T) {
 	waitNeighbors(want)
 }
 
+// This test checks that nodes rejected by the node filter aren't served.
+func TestUDPv4_findnodeFilter(t *testing.T) {
+	test := newUDPTest(t)
+	defer test.close()
+	test.udp.nodeFilter = func(n *enode.Node) bool { return n.IP()[3]%2 == 0 }
+
+	nodes := &nodesByDistance{target: testTarget.id()}
+	for i := 0; i < 6; i++ {
+		key := newkey()
+		n := wrapNode(enode.NewV4(&key.PublicKey, net.IP{10, 13, 0, byte(i)}, 0, 2000))
+		n.livenessChecks = 1
+		nodes.push(n, 6)
+	}
+	fillTable(test.table, nodes.entries)
+
+	remoteID := encodePubkey(&test.remotekey.PublicKey).id()
+	test.table.db.UpdateLastPongReceived(remoteID, test.remoteaddr.IP, time.Now())
+
+	test.packetIn(nil, &findnodeV4{Target: testTarget, Expiration: futureExp})
+	test.waitPacketOut(func(p *neighborsV4, to *net.UDPAddr, hash []byte) {
+		if len(p.Nodes) != 3 {
+			t.Errorf("wrong number of results: got %d, want 3", len(p.Nodes))
+		}
+		for _, n := range p.Nodes {
+			if n.IP[3]%2 != 0 {
+				t.Errorf("result includes filtered node %v", n.IP)
+			}
+		}
+	})
+}
+
 func TestUDPv4_findnodeMultiReply(t *testing.T) {
 	test := newUDPTest(t)
 	defer test.close()
@@ -22,6 +22,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -69,6 +70,7 @@ type Network struct {
 	tableOpResp      chan struct{}
 	topicRegisterReq chan topicRegisterReq
 	topicSearchReq   chan topicSearchReq
+	paused           int32 // whether the periodic lookups are paused (atomic)
 
 	// State of the main loop.
 	tab           *Table
@@ -170,6 +172,21 @@ func (net *Network) Close() {
 	}
 }
 
+// SetPaused pauses or resumes the periodic table refresh and the topic search lookups.
+// Queries of other nodes are still answered while paused.
+func (net *Network) SetPaused(paused bool) {
+	var flag int32
+	if paused {
+		flag = 1
+	}
+	atomic.StoreInt32(&net.paused, flag)
+}
+
+// isPaused returns whether the periodic lookups are paused.
+func (net *Network) isPaused() bool {
+	return atomic.LoadInt32(&net.paused) == 1
+}
+
 // Self returns the local node.
 // The returned node should not be modified by the caller.
 func (net *Network) Self() *Node {
@@ -534,7 +551,7 @@ loop:
 			}
 
 		case topic := <-topicSearch:
-			if activeSearchCount < maxSearchCount {
+			if activeSearchCount < maxSearchCount && !net.isPaused() {
 				activeSearchCount++
 				target := net.ticketStore.nextSearchLookup(topic)
 				go func() {
@@ -598,11 +615,15 @@ loop:
 			log.Trace("<-refreshTimer.C")
 			// TODO: ideally we would start the refresh timer after
 			// fallback nodes have been set for the first time.
-			if refreshDone == nil {
+			if refreshDone == nil && !net.isPaused() {
 				refreshDone = make(chan struct{})
 				net.refresh(refreshDone)
 			}
 		case <-bucketRefreshTimer.C:
+			if net.isPaused() {
+				bucketRefreshTimer.Reset(bucketRefreshInterval)
+				continue
+			}
 			target := net.tab.chooseBucketRefreshTarget()
 			go func() {
 				net.lookup(target, false)
@@ -363,6 +363,11 @@ outer:
 			if _, ok := result[proto.Name]; ok {
 				primary := make(map[string]*protoRW)
 				primary[proto.Name] = result[proto.Name]
+				for name, rw := range result {
+					if rw.Auxiliary {
+						primary[name] = rw
+					}
+				}
 				return primary
 			}
 		}
@@ -0,0 +1,112 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+const (
+	maxPeerHistoryEvents = 32  // Maximum number of events remembered per node
+	maxPeerHistoryNodes  = 512 // Maximum number of nodes with remembered events
+)
+
+// Types of the connection events recorded in the peer history.
+const (
+	PeerHistoryDialFailed      = "dialFailed"      // The TCP connection could not be established
+	PeerHistoryHandshakeFailed = "handshakeFailed" // The encryption or protocol handshake failed, or the peer was rejected
+	PeerHistoryConnected       = "connected"       // The peer was added
+	PeerHistoryDropped         = "dropped"         // The peer disconnected
+)
+
+// PeerHistoryEvent is a connection event recorded for a remote node, used to
+// diagnose why connections to the node fail or do not last.
+type PeerHistoryEvent struct {
+	Time            time.Time `json:"time"`
+	Type            string    `json:"type"`
+	Inbound         bool      `json:"inbound"`
+	Reason          string    `json:"reason,omitempty"`
+	RemoteRequested bool      `json:"remoteRequested,omitempty"` // Whether a drop was requested by the remote node
+}
+
+// peerHistory keeps the most recent connection events of the most recently
+// seen nodes.
+type peerHistory struct {
+	events map[enode.ID][]PeerHistoryEvent
+	lock   sync.Mutex
+}
+
+func newPeerHistory() *peerHistory {
+	return &peerHistory{events: make(map[enode.ID][]PeerHistoryEvent)}
+}
+
+// add records an event for the given node, evicting the node whose latest event
+// is the oldest if too many nodes are tracked.
+func (h *peerHistory) add(id enode.ID, event PeerHistoryEvent) {
+	if h == nil {
+		return // Server not started
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	events, ok := h.events[id]
+	if !ok && len(h.events) >= maxPeerHistoryNodes {
+		var (
+			oldest     enode.ID
+			oldestTime time.Time
+		)
+		for id, events := range h.events {
+			if last := events[len(events)-1].Time; oldestTime.IsZero() || last.Before(oldestTime) {
+				oldest, oldestTime = id, last
+			}
+		}
+		delete(h.events, oldest)
+	}
+	if len(events) >= maxPeerHistoryEvents {
+		events = append(events[:0:0], events[len(events)-maxPeerHistoryEvents+1:]...)
+	}
+	h.events[id] = append(events, event)
+}
+
+// get returns a copy of the events recorded for the given node, oldest first.
+func (h *peerHistory) get(id enode.ID) []PeerHistoryEvent {
+	if h == nil {
+		return nil
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	return append([]PeerHistoryEvent{}, h.events[id]...)
+}
+
+// recordPeerEvent adds a connection event to the history of the given node.
+func (srv *Server) recordPeerEvent(id enode.ID, typ string, inbound bool, err error) {
+	event := PeerHistoryEvent{Time: time.Now(), Type: typ, Inbound: inbound}
+	if err != nil {
+		event.Reason = err.Error()
+	}
+	srv.history.add(id, event)
+}
+
+// PeerHistory returns the recent dial, connection and drop events of the given
+// node, oldest first.
+func (srv *Server) PeerHistory(node *enode.Node) []PeerHistoryEvent {
+	return srv.history.get(node.ID())
+}
@@ -277,6 +277,18 @@ func TestMatchProtocols(t *testing.T) {
 			Local:  []Protocol{{Version: 1, Length: 1}, {Version: 2, Length: 2}, {Version: 3, Length: 3}, {Name: "a"}},
 			Match:  map[string]protoRW{"": {Protocol: Protocol{Version: 3}}, "a": {Protocol: Protocol{Name: "a"}, offset: 3}},
 		},
+		{
+			// Primary protocol drops other protocols
+			Remote: []Cap{{Name: "a"}, {Name: "b"}},
+			Local:  []Protocol{{Name: "a", Length: 1}, {Name: "b", Primary: true}},
+			Match:  map[string]protoRW{"b": {Protocol: Protocol{Name: "b"}, offset: 1}},
+		},
+		{
+			// Auxiliary protocols run alongside the primary protocol
+			Remote: []Cap{{Name: "a"}, {Name: "b"}, {Name: "c"}},
+			Local:  []Protocol{{Name: "a", Length: 1, Auxiliary: true}, {Name: "b", Length: 1, Primary: true}, {Name: "c"}},
+			Match:  map[string]protoRW{"a": {Protocol: Protocol{Name: "a"}}, "b": {Protocol: Protocol{Name: "b"}, offset: 1}},
+		},
 	}
 
 	for i, tt := range tests {
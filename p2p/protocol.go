@@ -39,6 +39,10 @@ type Protocol struct {
 	// Whether this should be the primary form of communication between nodes that support this protocol.
 	Primary bool
 
+	// Whether this protocol complements the primary protocol and keeps running
+	// alongside it, instead of being dropped when the primary protocol matches.
+	Auxiliary bool
+
 	// Run is called in a new goroutine when the protocol has been
 	// negotiated with a peer. It should read and write messages from
 	// rw. The Payload for each message must be fully consumed.
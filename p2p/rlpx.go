@@ -39,6 +39,7 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/crypto/ecies"
 	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/golang/snappy"
 	"golang.org/x/crypto/sha3"
@@ -194,6 +195,21 @@ func (t *rlpx) doEncHandshake(prv *ecdsa.PrivateKey, dial *ecdsa.PublicKey) (*ec
 	return sec.Remote.ExportECDSA(), nil
 }
 
+// CheckNodeKey dials the TCP endpoint of a node and runs the encryption handshake,
+// which only succeeds if the remote end holds the private key of the node.
+func CheckNodeKey(n *enode.Node, prv *ecdsa.PrivateKey) error {
+	addr := &net.TCPAddr{IP: n.IP(), Port: n.TCP()}
+	fd, err := net.DialTimeout("tcp", addr.String(), defaultDialTimeout)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	t := newRLPX(fd).(*rlpx)
+	_, err = t.doEncHandshake(prv, n.Pubkey())
+	return err
+}
+
 // encHandshake contains the state of the encryption handshake.
 type encHandshake struct {
 	initiator            bool
@@ -34,6 +34,7 @@ import (
 	"github.com/davecgh/go-spew/spew"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/crypto/ecies"
+	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/p2p/simulations/pipes"
 	"github.com/ethereum/go-ethereum/rlp"
 	"golang.org/x/crypto/sha3"
@@ -144,6 +145,38 @@ func testEncHandshake(token []byte) error {
 	return nil
 }
 
+func TestCheckNodeKey(t *testing.T) {
+	var (
+		prv, _      = crypto.GenerateKey()
+		remote, _   = crypto.GenerateKey()
+		impostor, _ = crypto.GenerateKey()
+	)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			fd, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			c := newRLPX(fd).(*rlpx)
+			c.doEncHandshake(remote, nil)
+			fd.Close()
+		}
+	}()
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	if err := CheckNodeKey(enode.NewV4(&remote.PublicKey, net.IP{127, 0, 0, 1}, port, 0), prv); err != nil {
+		t.Errorf("handshake with the right key failed: %v", err)
+	}
+	if err := CheckNodeKey(enode.NewV4(&impostor.PublicKey, net.IP{127, 0, 0, 1}, port, 0), prv); err == nil {
+		t.Error("handshake with the wrong key succeeded")
+	}
+}
+
 func TestProtocolHandshake(t *testing.T) {
 	var (
 		prv0, _ = crypto.GenerateKey()
@@ -182,6 +182,8 @@ type Server struct {
 	lock    sync.Mutex // protects running
 	running bool
 
+	discoveryPaused int32 // whether node discovery is paused (atomic)
+
 	listener     net.Listener
 	ourHandshake *protoHandshake
 	loopWG       sync.WaitGroup // loop, listenLoop
@@ -202,6 +204,8 @@ type Server struct {
 	removestatic            chan *nodeArgs
 	addtrusted              chan *nodeArgs
 	removetrusted           chan *nodeArgs
+	pin                     chan *nodeArgs
+	unpin                   chan *nodeArgs
 	peerOp                  chan peerOpFunc
 	peerOpDone              chan struct{}
 	delpeer                 chan peerDrop
@@ -210,6 +214,8 @@ type Server struct {
 
 	// State of run loop and listenLoop.
 	inboundHistory expHeap
+
+	history *peerHistory // Recent connection events of remote nodes, for diagnostics
 }
 
 type peerOpFunc func(peers map[enode.ID]*Peer)
@@ -224,10 +230,35 @@ const (
 	ExplicitTrustedPurpose             = 1 << 1
 	ValidatorPurpose                   = 1 << 2
 	ProxyPurpose                       = 1 << 3
+	PinnedPurpose                      = 1 << 4 // Set by operators to keep a peer regardless of its other purposes
 
-	AnyPurpose = ExplicitStaticPurpose | ExplicitTrustedPurpose | ValidatorPurpose | ProxyPurpose // This value should be the bitwise OR of all possible PurposeFlag values
+	AnyPurpose = ExplicitStaticPurpose | ExplicitTrustedPurpose | ValidatorPurpose | ProxyPurpose | PinnedPurpose // This value should be the bitwise OR of all possible PurposeFlag values
 )
 
+// purposeNames are the short names of the purposes, as used by the admin API.
+var purposeNames = []struct {
+	purpose PurposeFlag
+	name    string
+}{
+	{ExplicitStaticPurpose, "static"},
+	{ExplicitTrustedPurpose, "trusted"},
+	{ValidatorPurpose, "validator"},
+	{ProxyPurpose, "proxy"},
+	{PinnedPurpose, "pinned"},
+}
+
+// ParsePurpose returns the purpose with the given short name, as listed by
+// Server.PeersByPurpose. The pinned purpose is reserved for PinPeer and can't
+// be parsed.
+func ParsePurpose(name string) (PurposeFlag, error) {
+	for _, p := range purposeNames {
+		if p.name == name && p.purpose != PinnedPurpose {
+			return p.purpose, nil
+		}
+	}
+	return NoPurpose, fmt.Errorf("unknown peer purpose %q", name)
+}
+
 func NewPurposeFlag() *PurposeFlag {
 	purposeFlag := new(PurposeFlag)
 	*purposeFlag = NoPurpose
@@ -268,6 +299,9 @@ func (pf *PurposeFlag) String() string {
 	if pf.IsSet(ProxyPurpose) {
 		s += "-ProxyPurpose"
 	}
+	if pf.IsSet(PinnedPurpose) {
+		s += "-PinnedPurpose"
+	}
 	if s != "" {
 		s = s[1:]
 	}
@@ -440,6 +474,66 @@ func (srv *Server) RemoveTrustedPeer(node *enode.Node, purpose PurposeFlag) {
 	}
 }
 
+// PinPeer keeps the given node connected with the given purpose, regardless of
+// any other purposes being added or removed for it, until UnpinPeer is called.
+// The node is treated as both a static and a trusted node.
+func (srv *Server) PinPeer(node *enode.Node, purpose PurposeFlag) {
+	select {
+	case srv.pin <- &nodeArgs{node: node, purpose: purpose}:
+	case <-srv.quit:
+	}
+}
+
+// UnpinPeer removes the pin from the given node, along with the purposes the
+// pin added to it. The node is kept connected only if it still has a purpose
+// it held independently of the pin.
+func (srv *Server) UnpinPeer(node *enode.Node) {
+	select {
+	case srv.unpin <- &nodeArgs{node: node}:
+	case <-srv.quit:
+	}
+}
+
+// pinnedPurposes are the static and trusted purposes added to a node by pinning it.
+type pinnedPurposes struct {
+	static, trusted PurposeFlag
+}
+
+// PeersByPurpose returns the connected peers grouped by the short names of their
+// static and trusted purposes. Peers without any purpose are listed as "dynamic".
+func (srv *Server) PeersByPurpose() map[string][]*PeerInfo {
+	grouped := make(map[string][]*Peer)
+	select {
+	case srv.peerOp <- func(peers map[enode.ID]*Peer) {
+		for _, p := range peers {
+			var purposes PurposeFlag
+			if p.StaticNodePurposes != nil {
+				purposes |= *p.StaticNodePurposes
+			}
+			if p.TrustedNodePurposes != nil {
+				purposes |= *p.TrustedNodePurposes
+			}
+			if purposes == NoPurpose {
+				grouped["dynamic"] = append(grouped["dynamic"], p)
+				continue
+			}
+			for _, name := range purposeNames {
+				if purposes&name.purpose != 0 {
+					grouped[name.name] = append(grouped[name.name], p)
+				}
+			}
+		}
+	}:
+		<-srv.peerOpDone
+	case <-srv.quit:
+	}
+	infos := make(map[string][]*PeerInfo, len(grouped))
+	for name, peers := range grouped {
+		infos[name] = peersInfo(peers)
+	}
+	return infos
+}
+
 // SubscribeEvents subscribes the given channel to peer events
 func (srv *Server) SubscribeEvents(ch chan *PeerEvent) event.Subscription {
 	return srv.peerFeed.Subscribe(ch)
@@ -457,6 +551,34 @@ func (srv *Server) Self() *enode.Node {
 	return ln.Node()
 }
 
+// SetDiscoveryPaused pauses or resumes node discovery. While paused, the discovery
+// tables are not refreshed and no lookups are made to find new peers, but the
+// queries of other nodes are still answered.
+func (srv *Server) SetDiscoveryPaused(paused bool) {
+	var flag int32
+	if paused {
+		flag = 1
+	}
+	atomic.StoreInt32(&srv.discoveryPaused, flag)
+
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+	if !srv.running {
+		return
+	}
+	if srv.ntab != nil {
+		srv.ntab.SetPaused(paused)
+	}
+	if srv.DiscV5 != nil {
+		srv.DiscV5.SetPaused(paused)
+	}
+}
+
+// DiscoveryPaused returns whether node discovery is paused.
+func (srv *Server) DiscoveryPaused() bool {
+	return atomic.LoadInt32(&srv.discoveryPaused) == 1
+}
+
 // DiscoverTableInfo gets information on all the buckets in the
 // discover table
 func (srv *Server) DiscoverTableInfo() *discover.TableInfo {
@@ -548,8 +670,11 @@ func (srv *Server) Start() (err error) {
 	srv.removestatic = make(chan *nodeArgs)
 	srv.addtrusted = make(chan *nodeArgs)
 	srv.removetrusted = make(chan *nodeArgs)
+	srv.pin = make(chan *nodeArgs)
+	srv.unpin = make(chan *nodeArgs)
 	srv.peerOp = make(chan peerOpFunc)
 	srv.peerOpDone = make(chan struct{})
+	srv.history = newPeerHistory()
 
 	if err := srv.setupLocalNode(); err != nil {
 		return err
@@ -565,6 +690,7 @@ func (srv *Server) Start() (err error) {
 
 	dynPeers := srv.maxDialedConns()
 	dialer := newDialState(srv.localnode.ID(), dynPeers, &srv.Config)
+	dialer.lookupPaused = srv.DiscoveryPaused
 	srv.loopWG.Add(1)
 	go srv.run(dialer)
 	return nil
@@ -676,6 +802,7 @@ func (srv *Server) setupDiscovery() error {
 			return err
 		}
 		srv.ntab = ntab
+		srv.ntab.SetPaused(srv.DiscoveryPaused())
 		srv.discmix.AddSource(ntab.RandomNodes())
 		srv.staticNodeResolver = ntab
 	}
@@ -696,6 +823,7 @@ func (srv *Server) setupDiscovery() error {
 			return err
 		}
 		srv.DiscV5 = ntab
+		srv.DiscV5.SetPaused(srv.DiscoveryPaused())
 	}
 	return nil
 }
@@ -745,6 +873,7 @@ func (srv *Server) run(dialstate dialer) {
 		peers        = make(map[enode.ID]*Peer)
 		inboundCount = 0
 		trusted      = make(map[enode.ID]*PurposeFlag, len(srv.TrustedNodes))
+		pins         = make(map[enode.ID]pinnedPurposes)
 		taskdone     = make(chan task, maxActiveDialTasks)
 		tick         = time.NewTicker(30 * time.Second)
 		runningTasks []task
@@ -863,6 +992,38 @@ func (srv *Server) run(dialstate dialer) {
 		}
 	}
 
+	addPin := func(n *enode.Node, purpose PurposeFlag) {
+		purpose |= PinnedPurpose
+
+		// Record the purposes the node didn't hold yet, to remove only those when unpinned
+		added := pins[n.ID()]
+		if purposes, ok := static[n.ID()]; ok {
+			added.static |= purpose &^ *purposes
+		} else {
+			added.static |= purpose
+		}
+		if purposes, ok := trusted[n.ID()]; ok {
+			added.trusted |= purpose &^ *purposes
+		} else {
+			added.trusted |= purpose
+		}
+		pins[n.ID()] = added
+
+		addStatic(n, purpose)
+		addTrusted(n, purpose)
+	}
+
+	removePin := func(n *enode.Node) {
+		added, ok := pins[n.ID()]
+		if !ok {
+			return
+		}
+		delete(pins, n.ID())
+
+		removeStatic(n, added.static)
+		removeTrusted(n, added.trusted)
+	}
+
 running:
 	for {
 		scheduleTasks()
@@ -896,6 +1057,14 @@ running:
 			// from the trusted node set.
 			srv.log.Trace("Removing trusted node", "node", removeTrustedArgs.node, "purpose", removeTrustedArgs.purpose)
 			removeTrusted(removeTrustedArgs.node, removeTrustedArgs.purpose)
+		case pinArgs := <-srv.pin:
+			// This channel is used by PinPeer to pin a node.
+			srv.log.Trace("Pinning node", "node", pinArgs.node, "purpose", pinArgs.purpose)
+			addPin(pinArgs.node, pinArgs.purpose)
+		case unpinArgs := <-srv.unpin:
+			// This channel is used by UnpinPeer to unpin a node.
+			srv.log.Trace("Unpinning node", "node", unpinArgs.node)
+			removePin(unpinArgs.node)
 		case op := <-srv.peerOp:
 			// This channel is used by Peers and PeerCount and ValPeers.
 			op(peers)
@@ -937,6 +1106,7 @@ running:
 				}
 				name := truncateName(c.name)
 				p.log.Debug("Adding p2p peer", "addr", p.RemoteAddr(), "peers", len(peers)+1, "name", name)
+				srv.recordPeerEvent(c.node.ID(), PeerHistoryConnected, p.Inbound(), nil)
 				go srv.runPeer(p)
 				peers[c.node.ID()] = p
 				if p.Inbound() {
@@ -958,6 +1128,11 @@ running:
 			if pd.Inbound() {
 				inboundCount--
 			}
+			event := PeerHistoryEvent{Time: time.Now(), Type: PeerHistoryDropped, Inbound: pd.Inbound(), RemoteRequested: pd.requested}
+			if pd.err != nil {
+				event.Reason = pd.err.Error()
+			}
+			srv.history.add(pd.ID(), event)
 		}
 	}
 
@@ -1106,6 +1281,14 @@ func (srv *Server) SetupConn(fd net.Conn, flags connFlag, dialDest *enode.Node)
 	if err != nil {
 		c.close(err)
 		srv.log.Trace("Setting up connection failed", "addr", fd.RemoteAddr(), "err", err)
+
+		// Record the failure if the remote identity is known
+		if node := c.node; node != nil || dialDest != nil {
+			if node == nil {
+				node = dialDest
+			}
+			srv.recordPeerEvent(node.ID(), PeerHistoryHandshakeFailed, flags&inboundConn != 0, err)
+		}
 	}
 	return err
 }
@@ -205,6 +205,122 @@ func TestServerDial(t *testing.T) {
 	}
 }
 
+// This test checks that pinned peers stay connected when their other purposes
+// are removed, and that their connection events are recorded.
+func TestServerPinPeer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not setup listener: %v", err)
+	}
+	defer listener.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		accepted <- conn
+	}()
+
+	connected := make(chan *Peer, 1)
+	remid := &newkey().PublicKey
+	srv := startTestServer(t, remid, func(p *Peer) { connected <- p })
+	defer srv.Stop()
+
+	tcpAddr := listener.Addr().(*net.TCPAddr)
+	node := enode.NewV4(remid, tcpAddr.IP, tcpAddr.Port, 0)
+	srv.PinPeer(node, ValidatorPurpose)
+
+	select {
+	case conn := <-accepted:
+		defer conn.Close()
+	case <-time.After(1 * time.Second):
+		t.Fatal("server did not connect within one second")
+	}
+	select {
+	case <-connected:
+	case <-time.After(1 * time.Second):
+		t.Fatal("server did not launch peer within one second")
+	}
+	byPurpose := srv.PeersByPurpose()
+	if len(byPurpose["validator"]) != 1 || len(byPurpose["pinned"]) != 1 {
+		t.Fatalf("pinned validator peer not grouped by purpose: %v", byPurpose)
+	}
+	// Removing the validator purpose must keep the pinned peer connected
+	srv.RemovePeer(node, ValidatorPurpose)
+	srv.RemoveTrustedPeer(node, ValidatorPurpose)
+	byPurpose = srv.PeersByPurpose()
+	if len(byPurpose["validator"]) != 0 || len(byPurpose["pinned"]) != 1 {
+		t.Fatalf("pinned peer not kept after purpose removal: %v", byPurpose)
+	}
+	history := srv.PeerHistory(node)
+	if len(history) != 1 || history[0].Type != PeerHistoryConnected || history[0].Inbound {
+		t.Fatalf("peer history mismatch: %+v", history)
+	}
+	// Unpinning drops the peer, which must be recorded
+	srv.UnpinPeer(node)
+	for i := 0; i < 100 && len(srv.PeerHistory(node)) < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if history = srv.PeerHistory(node); len(history) != 2 || history[1].Type != PeerHistoryDropped || history[1].Reason == "" {
+		t.Fatalf("peer drop not recorded: %+v", history)
+	}
+}
+
+// This test checks that unpinning a peer keeps the purposes it held before
+// being pinned, and that the pinned purpose can't be set by users.
+func TestServerUnpinKeepsPurposes(t *testing.T) {
+	if _, err := ParsePurpose("pinned"); err == nil {
+		t.Fatal("pinned purpose parsed")
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not setup listener: %v", err)
+	}
+	defer listener.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		accepted <- conn
+	}()
+
+	connected := make(chan *Peer, 1)
+	remid := &newkey().PublicKey
+	srv := startTestServer(t, remid, func(p *Peer) { connected <- p })
+	defer srv.Stop()
+
+	tcpAddr := listener.Addr().(*net.TCPAddr)
+	node := enode.NewV4(remid, tcpAddr.IP, tcpAddr.Port, 0)
+	srv.AddPeer(node, ValidatorPurpose)
+	srv.AddTrustedPeer(node, ValidatorPurpose)
+
+	select {
+	case conn := <-accepted:
+		defer conn.Close()
+	case <-time.After(1 * time.Second):
+		t.Fatal("server did not connect within one second")
+	}
+	select {
+	case <-connected:
+	case <-time.After(1 * time.Second):
+		t.Fatal("server did not launch peer within one second")
+	}
+	srv.PinPeer(node, ValidatorPurpose|ProxyPurpose)
+	byPurpose := srv.PeersByPurpose()
+	if len(byPurpose["validator"]) != 1 || len(byPurpose["proxy"]) != 1 || len(byPurpose["pinned"]) != 1 {
+		t.Fatalf("pinned peer not grouped by purpose: %v", byPurpose)
+	}
+	// Unpinning removes the proxy purpose added by the pin, but not the validator one
+	srv.UnpinPeer(node)
+	byPurpose = srv.PeersByPurpose()
+	if len(byPurpose["validator"]) != 1 || len(byPurpose["proxy"]) != 0 || len(byPurpose["pinned"]) != 0 {
+		t.Fatalf("purposes mismatch after unpinning: %v", byPurpose)
+	}
+}
+
 // This test checks that tasks generated by dialstate are
 // actually executed and taskdone is called for them.
 func TestServerTaskScheduling(t *testing.T) {
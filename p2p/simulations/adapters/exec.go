@@ -120,6 +120,10 @@ func (e *ExecAdapter) NewNode(config *NodeConfig) (Node, error) {
 	// Listen on a localhost port, which we set when we
 	// initialise NodeConfig (usually a random port)
 	conf.Stack.P2P.ListenAddr = fmt.Sprintf(":%d", config.Port)
+	if config.Proxy {
+		conf.Stack.Proxy = true
+		conf.Stack.ProxyP2P.ListenAddr = fmt.Sprintf(":%d", config.ProxyPort)
+	}
 
 	node := &ExecNode{
 		ID:      config.ID,
@@ -106,6 +106,11 @@ func (s *SimAdapter) NewNode(config *NodeConfig) (Node, error) {
 			Dialer:          s,
 			EnableMsgEvents: config.EnableMsgEvents,
 		},
+		Proxy: config.Proxy,
+		ProxyP2P: p2p.Config{
+			Dialer:          s,
+			EnableMsgEvents: config.EnableMsgEvents,
+		},
 		NoUSB:  true,
 		Logger: log.New("node.id", id.String()),
 	})
@@ -125,13 +130,17 @@ func (s *SimAdapter) NewNode(config *NodeConfig) (Node, error) {
 }
 
 // Dial implements the p2p.NodeDialer interface by connecting to the node using
-// an in-memory net.Pipe
+// an in-memory net.Pipe. Dials to the proxy port of a proxy reach its internal
+// facing server.
 func (s *SimAdapter) Dial(dest *enode.Node) (conn net.Conn, err error) {
 	node, ok := s.GetNode(dest.ID())
 	if !ok {
 		return nil, fmt.Errorf("unknown node: %s", dest.ID())
 	}
 	srv := node.Server()
+	if node.config.Proxy && dest.TCP() == int(node.config.ProxyPort) {
+		srv = node.node.ProxyServer()
+	}
 	if srv == nil {
 		return nil, fmt.Errorf("node not running: %s", dest.ID())
 	}
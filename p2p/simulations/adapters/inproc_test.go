@@ -20,10 +20,16 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"net"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/p2p/simulations/pipes"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 func TestTCPPipe(t *testing.T) {
@@ -200,3 +206,61 @@ func TestNetPipeBidirections(t *testing.T) {
 		}
 	}
 }
+
+// noopService is a node service without protocols nor APIs.
+type noopService struct{}
+
+func (noopService) Protocols() []p2p.Protocol { return nil }
+func (noopService) APIs() []rpc.API           { return nil }
+func (noopService) Start(*p2p.Server) error   { return nil }
+func (noopService) Stop() error               { return nil }
+
+// Tests that dials to the proxy port of a proxy reach its internal facing
+// server, and dials to its regular port its external facing one.
+func TestSimAdapterDialProxy(t *testing.T) {
+	adapter := NewSimAdapter(Services{
+		"noop": func(*ServiceContext) (node.Service, error) { return noopService{}, nil },
+	})
+	newNode := func(proxy bool) *SimNode {
+		config := RandomNodeConfig()
+		config.Services = []string{"noop"}
+		if proxy {
+			config.Proxy = true
+			config.ProxyPort = config.Port + 1
+		}
+		node, err := adapter.NewNode(config)
+		if err != nil {
+			t.Fatalf("failed to create node: %v", err)
+		}
+		if err := node.Start(nil); err != nil {
+			t.Fatalf("failed to start node: %v", err)
+		}
+		return node.(*SimNode)
+	}
+	proxy, validator, peer := newNode(true), newNode(false), newNode(false)
+	defer proxy.Stop()
+	defer validator.Stop()
+	defer peer.Stop()
+
+	proxyConfig := proxy.config
+	internal := enode.NewV4(&proxyConfig.PrivateKey.PublicKey, net.IP{127, 0, 0, 1}, int(proxyConfig.ProxyPort), 0)
+	external := enode.NewV4(&proxyConfig.PrivateKey.PublicKey, net.IP{127, 0, 0, 1}, int(proxyConfig.Port), 0)
+
+	validator.Server().AddPeer(internal, p2p.ProxyPurpose)
+	peer.Server().AddPeer(external, p2p.ExplicitStaticPurpose)
+
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		if proxy.node.ProxyServer().PeerCount() == 1 && proxy.Server().PeerCount() == 1 {
+			break
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("proxy peers mismatch: internal %d, external %d", proxy.node.ProxyServer().PeerCount(), proxy.Server().PeerCount())
+		}
+	}
+	if peers := proxy.node.ProxyServer().Peers(); peers[0].ID() != validator.ID {
+		t.Errorf("internal facing peer mismatch: have %v, want %v", peers[0].ID(), validator.ID)
+	}
+	if peers := proxy.Server().Peers(); peers[0].ID() != peer.ID {
+		t.Errorf("external facing peer mismatch: have %v, want %v", peers[0].ID(), peer.ID)
+	}
+}
@@ -117,6 +117,11 @@ type NodeConfig struct {
 	Reachable func(id enode.ID) bool
 
 	Port uint16
+
+	// Proxy makes the node run a second, internal facing devp2p server which
+	// proxied validators connect to, listening on ProxyPort
+	Proxy     bool
+	ProxyPort uint16
 }
 
 // nodeConfigJSON is used to encode and decode NodeConfig as JSON by encoding
@@ -129,6 +134,8 @@ type nodeConfigJSON struct {
 	Properties      []string `json:"properties"`
 	EnableMsgEvents bool     `json:"enable_msg_events"`
 	Port            uint16   `json:"port"`
+	Proxy           bool     `json:"proxy"`
+	ProxyPort       uint16   `json:"proxy_port"`
 }
 
 // MarshalJSON implements the json.Marshaler interface by encoding the config
@@ -141,6 +148,8 @@ func (n *NodeConfig) MarshalJSON() ([]byte, error) {
 		Properties:      n.Properties,
 		Port:            n.Port,
 		EnableMsgEvents: n.EnableMsgEvents,
+		Proxy:           n.Proxy,
+		ProxyPort:       n.ProxyPort,
 	}
 	if n.PrivateKey != nil {
 		confJSON.PrivateKey = hex.EncodeToString(crypto.FromECDSA(n.PrivateKey))
@@ -179,6 +188,8 @@ func (n *NodeConfig) UnmarshalJSON(data []byte) error {
 	n.Properties = confJSON.Properties
 	n.Port = confJSON.Port
 	n.EnableMsgEvents = confJSON.EnableMsgEvents
+	n.Proxy = confJSON.Proxy
+	n.ProxyPort = confJSON.ProxyPort
 
 	return nil
 }
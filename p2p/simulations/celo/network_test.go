@@ -0,0 +1,62 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package celo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/simulations"
+	"github.com/ethereum/go-ethereum/p2p/simulations/adapters"
+)
+
+// Tests that validators behind a proxy and a standby proxy reach consensus,
+// peer with their proxies through the internal facing servers and fail over to
+// the standby proxies, without leaking their enodes.
+func TestStandbyProxyNetwork(t *testing.T) {
+	topology := Topology{Validators: 2, ProxiesPerValidator: 2, FullNodes: 1, LightClients: 1}
+	spec, err := NewSpec(topology.Validators)
+	if err != nil {
+		t.Fatalf("failed to create spec: %v", err)
+	}
+	network := simulations.NewNetwork(adapters.NewSimAdapter(spec.Services()), &simulations.NetworkConfig{ID: "celo-test"})
+	defer network.Shutdown()
+
+	layout, err := topology.Build(network, spec)
+	if err != nil {
+		t.Fatalf("failed to build network: %v", err)
+	}
+	result, err := Run(network, layout, 10*time.Second)
+	if err != nil {
+		t.Fatalf("failed to run network: %v", err)
+	}
+	if len(result.Leaks) > 0 {
+		t.Errorf("validator leaks: %v", result.Leaks)
+	}
+	if result.Consensus.Count == 0 {
+		t.Errorf("no consensus messages delivered")
+	}
+	for _, validator := range layout.Validators {
+		proxies := layout.Proxies[validator]
+		if network.GetNode(proxies[0]).Up() {
+			t.Errorf("validator %s: failed proxy still running", validator.TerminalString())
+		}
+		if conn := network.GetConn(validator, proxies[1]); conn == nil || !conn.Up {
+			t.Errorf("validator %s: not connected to standby proxy", validator.TerminalString())
+		}
+	}
+}
@@ -0,0 +1,324 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package celo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	istanbulBackend "github.com/ethereum/go-ethereum/consensus/istanbul/backend"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/simulations"
+)
+
+// LatencyStats summarizes the delivery latencies of a kind of message.
+type LatencyStats struct {
+	Count int
+	Mean  time.Duration
+	P50   time.Duration
+	P95   time.Duration
+	Max   time.Duration
+}
+
+func (s LatencyStats) String() string {
+	if s.Count == 0 {
+		return "no messages"
+	}
+	return fmt.Sprintf("%d messages, mean %v, p50 %v, p95 %v, max %v", s.Count, s.Mean, s.P50, s.P95, s.Max)
+}
+
+func newLatencyStats(latencies []time.Duration) LatencyStats {
+	if len(latencies) == 0 {
+		return LatencyStats{}
+	}
+	sorted := append([]time.Duration{}, latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var total time.Duration
+	for _, latency := range sorted {
+		total += latency
+	}
+	percentile := func(p int) time.Duration {
+		return sorted[(len(sorted)-1)*p/100]
+	}
+	return LatencyStats{
+		Count: len(sorted),
+		Mean:  total / time.Duration(len(sorted)),
+		P50:   percentile(50),
+		P95:   percentile(95),
+		Max:   sorted[len(sorted)-1],
+	}
+}
+
+// Leak is a node which learned how to reach a proxied validator directly.
+type Leak struct {
+	Validator enode.ID
+	Peer      enode.ID
+	Reason    string
+}
+
+func (l Leak) String() string {
+	return fmt.Sprintf("validator %s leaked to %s: %s", l.Validator.TerminalString(), l.Peer.TerminalString(), l.Reason)
+}
+
+// Result is the outcome of a scenario.
+type Result struct {
+	Consensus LatencyStats // Delivery latency of consensus messages between peers
+
+	// AnnouncePropagation is the time from the first announce message sent until
+	// every other relaying node received one, if AnnounceComplete.  Peers of the
+	// compact announce versions gossip the version certificates in announce
+	// versions messages instead of the announce messages, so both count.
+	AnnouncePropagation time.Duration
+	AnnounceComplete    bool
+
+	Leaks []Leak
+}
+
+func (r *Result) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Consensus message latency: %v\n", r.Consensus)
+	if r.AnnounceComplete {
+		fmt.Fprintf(&b, "Announce propagation:      %v\n", r.AnnouncePropagation)
+	} else {
+		fmt.Fprintf(&b, "Announce propagation:      incomplete\n")
+	}
+	if len(r.Leaks) == 0 {
+		fmt.Fprintf(&b, "Validator IP leaks:        none\n")
+	} else {
+		fmt.Fprintf(&b, "Validator IP leaks:        %d\n", len(r.Leaks))
+		for _, leak := range r.Leaks {
+			fmt.Fprintf(&b, "  %v\n", leak)
+		}
+	}
+	return b.String()
+}
+
+// msgKey identifies the messages of a kind sent from a node to another.
+type msgKey struct {
+	from, to enode.ID
+	protocol string
+	code     uint64
+}
+
+// Recorder collects the events of a simulation running a layout and computes
+// the measurements of a scenario from them.
+type Recorder struct {
+	layout *Layout
+
+	lock          sync.Mutex
+	pending       map[msgKey][]time.Time // Send times of the messages not received yet
+	latencies     []time.Duration
+	announceStart time.Time
+	announceFrom  enode.ID               // Sender of the first announce or announce versions
+	announced     map[enode.ID]time.Time // First announce or announce versions received by each node
+	leaks         []Leak
+}
+
+// NewRecorder creates a recorder for the network built with the layout.
+func NewRecorder(layout *Layout) *Recorder {
+	return &Recorder{
+		layout:    layout,
+		pending:   make(map[msgKey][]time.Time),
+		announced: make(map[enode.ID]time.Time),
+	}
+}
+
+// Record processes an event of the simulation.
+func (r *Recorder) Record(ev *simulations.Event) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	switch ev.Type {
+	case simulations.EventTypeConn:
+		if ev.Conn.Up && !ev.Control {
+			r.checkConn(ev.Conn.One, ev.Conn.Other)
+		}
+	case simulations.EventTypeMsg:
+		msg := ev.Msg
+		if msg.Protocol != eth.ProtocolName && msg.Protocol != eth.ConsensusProtocolName {
+			return
+		}
+		switch msg.Code {
+		case istanbulBackend.ConsensusMsgCode:
+			key := msgKey{from: msg.One, to: msg.Other, protocol: msg.Protocol, code: msg.Code}
+			if !msg.Received {
+				r.pending[key] = append(r.pending[key], ev.Time)
+				return
+			}
+			// Messages between two peers are delivered in order
+			if sent := r.pending[key]; len(sent) > 0 {
+				r.latencies = append(r.latencies, ev.Time.Sub(sent[0]))
+				r.pending[key] = sent[1:]
+			}
+		case istanbulBackend.AnnounceMsgCode, istanbulBackend.AnnounceVersionsMsgCode:
+			if !msg.Received {
+				if r.announceStart.IsZero() {
+					r.announceStart, r.announceFrom = ev.Time, msg.One
+				}
+				return
+			}
+			if _, ok := r.announced[msg.Other]; !ok {
+				r.announced[msg.Other] = ev.Time
+			}
+		}
+	}
+}
+
+// checkConn records a leak if a proxied validator is connected to any node but
+// its proxies.
+func (r *Recorder) checkConn(one, other enode.ID) {
+	for _, pair := range [][2]enode.ID{{one, other}, {other, one}} {
+		validator, peer := pair[0], pair[1]
+		if r.layout.Proxied(validator) && !r.layout.IsProxy(validator, peer) {
+			r.leaks = append(r.leaks, Leak{Validator: validator, Peer: peer, Reason: "direct connection"})
+		}
+	}
+}
+
+// CheckValEnodeTables queries the validator enode table of every relaying node
+// and records a leak for each proxied validator listed with its own enode
+// rather than its proxy's.
+func (r *Recorder) CheckValEnodeTables(net *simulations.Network) error {
+	validators := make(map[common.Address]enode.ID)
+	for _, id := range r.layout.Validators {
+		if r.layout.Proxied(id) {
+			validators[r.layout.Addresses[id]] = id
+		}
+	}
+	if len(validators) == 0 {
+		return nil
+	}
+	for _, id := range r.layout.Relays() {
+		relay := net.GetNode(id)
+		if !relay.Up() {
+			continue // Proxy stopped by a failover
+		}
+		client, err := relay.Client()
+		if err != nil {
+			return err
+		}
+		var table map[string]struct {
+			Enode string `json:"enode"`
+		}
+		if err := client.Call(&table, "istanbul_getValEnodeTable"); err != nil {
+			return fmt.Errorf("failed to get the validator enode table of %s: %v", id.TerminalString(), err)
+		}
+		for address, entry := range table {
+			validator, ok := validators[common.HexToAddress(address)]
+			if !ok || validator == id {
+				continue
+			}
+			node, err := enode.ParseV4(entry.Enode)
+			if err != nil {
+				log.Warn("Invalid enode in validator enode table", "node", id, "enode", entry.Enode, "err", err)
+				continue
+			}
+			if node.ID() == validator {
+				r.lock.Lock()
+				r.leaks = append(r.leaks, Leak{Validator: validator, Peer: id, Reason: "validator enode table"})
+				r.lock.Unlock()
+			}
+		}
+	}
+	return nil
+}
+
+// Result returns the measurements of the events recorded so far.
+func (r *Recorder) Result() *Result {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	result := &Result{
+		Consensus: newLatencyStats(r.latencies),
+		Leaks:     append([]Leak{}, r.leaks...),
+	}
+	if r.announceStart.IsZero() {
+		return result
+	}
+	var last time.Time
+	result.AnnounceComplete = true
+	for _, id := range r.layout.Relays() {
+		if id == r.announceFrom {
+			continue
+		}
+		received, ok := r.announced[id]
+		if !ok {
+			result.AnnounceComplete = false
+			break
+		}
+		if received.After(last) {
+			last = received
+		}
+	}
+	if result.AnnounceComplete {
+		result.AnnouncePropagation = last.Sub(r.announceStart)
+	}
+	return result
+}
+
+// Run starts and connects the nodes of the layout, records the events of the
+// network for the given duration and returns the measurements. Validators with
+// standby proxies fail over to them halfway through.
+func Run(net *simulations.Network, layout *Layout, duration time.Duration) (*Result, error) {
+	recorder := NewRecorder(layout)
+	events := make(chan *simulations.Event, 1024)
+	sub := net.Events().Subscribe(events)
+	defer sub.Unsubscribe()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case ev := <-events:
+				recorder.Record(ev)
+			case <-sub.Err():
+				return
+			}
+		}
+	}()
+
+	if err := layout.Start(net); err != nil {
+		return nil, err
+	}
+	if err := layout.Connect(net); err != nil {
+		return nil, err
+	}
+	time.Sleep(duration / 2)
+	for _, validator := range layout.Validators {
+		if len(layout.Proxies[validator]) > 1 {
+			if err := layout.Failover(net, validator); err != nil {
+				return nil, err
+			}
+		}
+	}
+	time.Sleep(duration - duration/2)
+
+	if err := recorder.CheckValEnodeTables(net); err != nil {
+		return nil, err
+	}
+	sub.Unsubscribe()
+	<-done
+	return recorder.Result(), nil
+}
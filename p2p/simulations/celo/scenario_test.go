@@ -0,0 +1,144 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package celo
+
+import (
+	"testing"
+	"time"
+
+	istanbulBackend "github.com/ethereum/go-ethereum/consensus/istanbul/backend"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/simulations"
+)
+
+func testLayout() *Layout {
+	id := func(b byte) enode.ID { return enode.ID{b} }
+	return &Layout{
+		Validators:   []enode.ID{id(1), id(2)},
+		Proxies:      map[enode.ID][]enode.ID{id(1): {id(11), id(13)}, id(2): {id(12)}},
+		FullNodes:    []enode.ID{id(21)},
+		LightClients: []enode.ID{id(31)},
+	}
+}
+
+func msgEvent(at time.Time, from, to enode.ID, protocol string, code uint64, received bool) *simulations.Event {
+	return &simulations.Event{
+		Type: simulations.EventTypeMsg,
+		Time: at,
+		Msg:  &simulations.Msg{One: from, Other: to, Protocol: protocol, Code: code, Received: received},
+	}
+}
+
+func connEvent(one, other enode.ID) *simulations.Event {
+	return &simulations.Event{
+		Type: simulations.EventTypeConn,
+		Time: time.Now(),
+		Conn: &simulations.Conn{One: one, Other: other, Up: true},
+	}
+}
+
+func TestRecorderConsensusLatency(t *testing.T) {
+	layout := testLayout()
+	r := NewRecorder(layout)
+	start := time.Now()
+	one, other := layout.Proxies[layout.Validators[0]][0], layout.FullNodes[0]
+
+	// Two messages in flight on the consensus protocol, one on the eth protocol
+	r.Record(msgEvent(start, one, other, eth.ConsensusProtocolName, istanbulBackend.ConsensusMsgCode, false))
+	r.Record(msgEvent(start.Add(time.Millisecond), one, other, eth.ConsensusProtocolName, istanbulBackend.ConsensusMsgCode, false))
+	r.Record(msgEvent(start.Add(2*time.Millisecond), one, other, eth.ProtocolName, istanbulBackend.ConsensusMsgCode, false))
+	r.Record(msgEvent(start.Add(4*time.Millisecond), one, other, eth.ConsensusProtocolName, istanbulBackend.ConsensusMsgCode, true))
+	r.Record(msgEvent(start.Add(5*time.Millisecond), one, other, eth.ConsensusProtocolName, istanbulBackend.ConsensusMsgCode, true))
+	r.Record(msgEvent(start.Add(10*time.Millisecond), one, other, eth.ProtocolName, istanbulBackend.ConsensusMsgCode, true))
+	// Other protocols and unmatched receives are ignored
+	r.Record(msgEvent(start, one, other, "les", istanbulBackend.ConsensusMsgCode, false))
+	r.Record(msgEvent(start, other, one, eth.ConsensusProtocolName, istanbulBackend.ConsensusMsgCode, true))
+
+	stats := r.Result().Consensus
+	want := LatencyStats{Count: 3, Mean: 16 * time.Millisecond / 3, P50: 4 * time.Millisecond, P95: 4 * time.Millisecond, Max: 8 * time.Millisecond}
+	if stats != want {
+		t.Errorf("consensus latency mismatch: have %+v, want %+v", stats, want)
+	}
+}
+
+func TestRecorderAnnouncePropagation(t *testing.T) {
+	layout := testLayout()
+	r := NewRecorder(layout)
+	start := time.Now()
+	v1, v2 := layout.Validators[0], layout.Validators[1]
+	p1, p2, standby := layout.Proxies[v1][0], layout.Proxies[v2][0], layout.Proxies[v1][1]
+	full := layout.FullNodes[0]
+
+	if r.Result().AnnounceComplete {
+		t.Fatal("announce complete before any announce")
+	}
+	r.Record(msgEvent(start, v1, p1, eth.ConsensusProtocolName, istanbulBackend.AnnounceMsgCode, false))
+	r.Record(msgEvent(start.Add(time.Millisecond), v1, p1, eth.ConsensusProtocolName, istanbulBackend.AnnounceMsgCode, true))
+	r.Record(msgEvent(start.Add(2*time.Millisecond), p1, full, eth.ConsensusProtocolName, istanbulBackend.AnnounceVersionsMsgCode, true))
+	r.Record(msgEvent(start.Add(3*time.Millisecond), full, p2, eth.ConsensusProtocolName, istanbulBackend.AnnounceVersionsMsgCode, true))
+	r.Record(msgEvent(start.Add(4*time.Millisecond), full, standby, eth.ConsensusProtocolName, istanbulBackend.AnnounceMsgCode, true))
+	if r.Result().AnnounceComplete {
+		t.Fatal("announce complete before reaching every relay")
+	}
+	r.Record(msgEvent(start.Add(7*time.Millisecond), p2, v2, eth.ProtocolName, istanbulBackend.AnnounceMsgCode, true))
+	r.Record(msgEvent(start.Add(9*time.Millisecond), p2, v2, eth.ProtocolName, istanbulBackend.AnnounceMsgCode, true))
+
+	result := r.Result()
+	if !result.AnnounceComplete {
+		t.Fatal("announce incomplete")
+	}
+	if result.AnnouncePropagation != 7*time.Millisecond {
+		t.Errorf("announce propagation mismatch: have %v, want %v", result.AnnouncePropagation, 7*time.Millisecond)
+	}
+}
+
+func TestRecorderLeaks(t *testing.T) {
+	layout := testLayout()
+	r := NewRecorder(layout)
+	v1 := layout.Validators[0]
+
+	r.Record(connEvent(v1, layout.Proxies[v1][0]))
+	r.Record(connEvent(layout.Proxies[v1][1], v1))
+	r.Record(connEvent(layout.FullNodes[0], layout.Proxies[v1][0]))
+	if leaks := r.Result().Leaks; len(leaks) != 0 {
+		t.Fatalf("unexpected leaks: %v", leaks)
+	}
+	r.Record(connEvent(layout.FullNodes[0], v1))
+	leaks := r.Result().Leaks
+	if len(leaks) != 1 || leaks[0].Validator != v1 || leaks[0].Peer != layout.FullNodes[0] {
+		t.Fatalf("leak mismatch: have %v", leaks)
+	}
+}
+
+func TestTopologyValidate(t *testing.T) {
+	for name, topology := range Templates {
+		if err := topology.Validate(); err != nil {
+			t.Errorf("template %s: %v", name, err)
+		}
+	}
+	for _, topology := range []Topology{
+		{},
+		{Validators: 4, ProxiesPerValidator: -1},
+		{Validators: 4, LightClients: 1},
+		{Validators: 4, FullNodes: -1},
+	} {
+		if err := topology.Validate(); err == nil {
+			t.Errorf("invalid topology %+v accepted", topology)
+		}
+	}
+}
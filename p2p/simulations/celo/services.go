@@ -0,0 +1,292 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package celo provides Celo node services for the simulation adapters,
+// templates to build networks of validators, proxies, full nodes and light
+// clients, and scenarios measuring how consensus and announce messages
+// propagate through them.
+package celo
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"net"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/istanbul"
+	istanbulBackend "github.com/ethereum/go-ethereum/consensus/istanbul/backend"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	blscrypto "github.com/ethereum/go-ethereum/crypto/bls"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/eth/downloader"
+	"github.com/ethereum/go-ethereum/les"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/simulations"
+	"github.com/ethereum/go-ethereum/p2p/simulations/adapters"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Names of the Celo node services, which are also the properties of the nodes
+// running them.
+const (
+	ServiceValidator   = "celo-validator"
+	ServiceProxy       = "celo-proxy"
+	ServiceFullNode    = "celo-fullnode"
+	ServiceLightClient = "celo-lightclient"
+)
+
+// Spec is the chain shared by the Celo nodes of a simulation: the genesis
+// block with its validators, and the roles of the nodes in proxied setups.
+//
+// The services read the roles when a node starts, so they must be set before.
+// Since the spec lives in the memory of the simulation, the services only run
+// on the in-memory simulation adapter.
+type Spec struct {
+	Genesis       *core.Genesis
+	ValidatorKeys []*ecdsa.PrivateKey // Keys of the genesis validators, also used as their node keys
+
+	BlockPeriod          uint64 // Minimum interval between blocks, in seconds
+	AnnounceGossipPeriod uint64 // Interval between announce messages, in seconds
+
+	lock    sync.RWMutex
+	proxies map[enode.ID][]proxyPair    // Proxies of each proxied validator
+	proxied map[enode.ID]common.Address // Validator of each proxy
+}
+
+// proxyPair holds the internal and external facing enodes of a proxy.
+type proxyPair struct {
+	internal, external *enode.Node
+}
+
+// NewSpec creates the spec of a chain validated by the given number of
+// validators, with freshly generated keys.
+func NewSpec(validators int) (*Spec, error) {
+	keys := make([]*ecdsa.PrivateKey, validators)
+	data := make([]istanbul.ValidatorData, validators)
+	for i := range keys {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			return nil, err
+		}
+		blsPrivateKey, err := blscrypto.ECDSAToBLS(key)
+		if err != nil {
+			return nil, err
+		}
+		blsPublicKey, err := blscrypto.PrivateToPublic(blsPrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = key
+		data[i] = istanbul.ValidatorData{Address: crypto.PubkeyToAddress(key.PublicKey), BLSPublicKey: blsPublicKey}
+	}
+
+	config := *params.TestChainConfig
+	config.Ethash = nil
+	config.Clique = nil
+	config.Istanbul = &params.IstanbulConfig{Epoch: 1000, LookbackWindow: 12}
+	config.FullHeaderChainAvailable = true
+	genesis := &core.Genesis{
+		Config:     &config,
+		GasLimit:   params.DefaultGasLimit,
+		Difficulty: big.NewInt(1),
+		Mixhash:    types.IstanbulDigest,
+		Alloc:      make(core.GenesisAlloc),
+	}
+	istanbulBackend.AppendValidatorsToGenesisBlock(genesis, data)
+
+	return &Spec{
+		Genesis:              genesis,
+		ValidatorKeys:        keys,
+		BlockPeriod:          1,
+		AnnounceGossipPeriod: 5,
+		proxies:              make(map[enode.ID][]proxyPair),
+		proxied:              make(map[enode.ID]common.Address),
+	}, nil
+}
+
+// AddProxy adds the proxy with the given configuration to the proxies of the
+// validator. The validator peers with the first proxy added when it starts.
+func (s *Spec) AddProxy(validator *ecdsa.PrivateKey, proxy *adapters.NodeConfig) {
+	localhost := net.IP{127, 0, 0, 1}
+	pair := proxyPair{
+		internal: enode.NewV4(&proxy.PrivateKey.PublicKey, localhost, int(proxy.ProxyPort), 0),
+		external: enode.NewV4(&proxy.PrivateKey.PublicKey, localhost, int(proxy.Port), 0),
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	id := enode.PubkeyToIDV4(&validator.PublicKey)
+	s.proxies[id] = append(s.proxies[id], pair)
+	s.proxied[proxy.ID] = crypto.PubkeyToAddress(validator.PublicKey)
+}
+
+// switchProxy makes a running validator peer with another of its proxies.
+func (s *Spec) switchProxy(net *simulations.Network, validator, proxy enode.ID) error {
+	s.lock.RLock()
+	proxies := s.proxies[validator]
+	s.lock.RUnlock()
+
+	var next *proxyPair
+	for i := range proxies {
+		if proxies[i].internal.ID() == proxy {
+			next = &proxies[i]
+		}
+	}
+	if next == nil {
+		return fmt.Errorf("node %s is not a proxy of validator %s", proxy.TerminalString(), validator.TerminalString())
+	}
+	client, err := net.GetNode(validator).Client()
+	if err != nil {
+		return err
+	}
+	// Removing a proxy the validator doesn't peer with is a no-op
+	for _, pair := range proxies {
+		if err := client.Call(nil, "istanbul_removeProxy", pair.internal.URLv4()); err != nil {
+			return err
+		}
+	}
+	return client.Call(nil, "istanbul_addProxy", next.internal.URLv4(), next.external.URLv4())
+}
+
+// Services returns the Celo node services of the chain.
+func (s *Spec) Services() adapters.Services {
+	return adapters.Services{
+		ServiceValidator:   s.newValidator,
+		ServiceProxy:       s.newProxy,
+		ServiceFullNode:    s.newFullNode,
+		ServiceLightClient: s.newLightClient,
+	}
+}
+
+// ethConfig returns the configuration shared by all the nodes of the chain.
+func (s *Spec) ethConfig() *eth.Config {
+	config := eth.DefaultConfig
+	config.Genesis = s.Genesis
+	config.NetworkId = s.Genesis.Config.ChainID.Uint64()
+	config.SyncMode = downloader.FullSync
+	config.LightServ = 0
+	config.Istanbul.Epoch = s.Genesis.Config.Istanbul.Epoch
+	config.Istanbul.LookbackWindow = s.Genesis.Config.Istanbul.LookbackWindow
+	config.Istanbul.BlockPeriod = s.BlockPeriod
+	config.Istanbul.AnnounceGossipPeriod = s.AnnounceGossipPeriod
+	config.Istanbul.ValidatorEnodeDBPath = ""
+	config.Istanbul.RoundStateDBPath = ""
+	return &config
+}
+
+// validatorKey returns the validator key of a node, which is its node key.
+func (s *Spec) validatorKey(ctx *adapters.ServiceContext) (*ecdsa.PrivateKey, error) {
+	address := crypto.PubkeyToAddress(ctx.Config.PrivateKey.PublicKey)
+	for _, key := range s.ValidatorKeys {
+		if crypto.PubkeyToAddress(key.PublicKey) == address {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("node %s is not a genesis validator", ctx.Config.Name)
+}
+
+func (s *Spec) newValidator(ctx *adapters.ServiceContext) (node.Service, error) {
+	key, err := s.validatorKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// The validator signs with an account of the keystore, as in a real node
+	ks := ctx.NodeContext.AccountManager.Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+	account, err := ks.ImportECDSA(key, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := ks.Unlock(account, ""); err != nil {
+		return nil, err
+	}
+	config := s.ethConfig()
+	config.Miner.Etherbase = account.Address
+	config.BLSbase = account.Address
+
+	s.lock.RLock()
+	proxies := s.proxies[ctx.Config.ID]
+	s.lock.RUnlock()
+	if len(proxies) > 0 {
+		config.Istanbul.Proxied = true
+		config.Istanbul.ProxyInternalFacingNode = proxies[0].internal
+		config.Istanbul.ProxyExternalFacingNode = proxies[0].external
+	}
+	ethereum, err := eth.New(ctx.NodeContext, config)
+	if err != nil {
+		return nil, err
+	}
+	return &validatorService{Ethereum: ethereum, account: account}, nil
+}
+
+func (s *Spec) newProxy(ctx *adapters.ServiceContext) (node.Service, error) {
+	s.lock.RLock()
+	validator, ok := s.proxied[ctx.Config.ID]
+	s.lock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("node %s is not the proxy of a validator", ctx.Config.Name)
+	}
+	if !ctx.Config.Proxy {
+		return nil, fmt.Errorf("node %s has no internal facing server", ctx.Config.Name)
+	}
+	config := s.ethConfig()
+	config.Istanbul.Proxy = true
+	config.Istanbul.ProxiedValidatorAddress = validator
+	return eth.New(ctx.NodeContext, config)
+}
+
+func (s *Spec) newFullNode(ctx *adapters.ServiceContext) (node.Service, error) {
+	config := s.ethConfig()
+	config.LightServ = eth.DefaultConfig.LightServ
+	config.LightPeers = eth.DefaultConfig.LightPeers
+	ethereum, err := eth.New(ctx.NodeContext, config)
+	if err != nil {
+		return nil, err
+	}
+	server, err := les.NewLesServer(ethereum, config)
+	if err != nil {
+		return nil, err
+	}
+	ethereum.AddLesServer(server)
+	return ethereum, nil
+}
+
+func (s *Spec) newLightClient(ctx *adapters.ServiceContext) (node.Service, error) {
+	config := s.ethConfig()
+	config.SyncMode = downloader.LightSync
+	return les.New(ctx.NodeContext, config)
+}
+
+// validatorService is a full node which starts validating with its account
+// once the node is running.
+type validatorService struct {
+	*eth.Ethereum
+	account accounts.Account
+}
+
+// Start implements node.Service, starting the node and the validator.
+func (v *validatorService) Start(srv *p2p.Server) error {
+	if err := v.Ethereum.Start(srv); err != nil {
+		return err
+	}
+	return v.StartMining(1)
+}
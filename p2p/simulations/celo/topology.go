@@ -0,0 +1,271 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package celo
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/simulations"
+	"github.com/ethereum/go-ethereum/p2p/simulations/adapters"
+)
+
+// Topology describes the nodes of a Celo network and how they connect: proxies
+// and full nodes form a mesh, validators connect to their proxies or, when not
+// proxied, to each other and to the mesh, and light clients connect to a full
+// node.
+//
+// A validator peers with one proxy at a time, the first of its proxies. The
+// other ones join the mesh as standby proxies, see Layout.Failover.
+type Topology struct {
+	Validators          int // Number of genesis validators
+	ProxiesPerValidator int // Number of proxies in front of each validator
+	FullNodes           int // Number of full nodes serving light clients
+	LightClients        int // Number of light clients
+}
+
+// Templates are the named topologies available to the scenarios.
+var Templates = map[string]Topology{
+	"mesh":    {Validators: 4, FullNodes: 2, LightClients: 2},
+	"proxied": {Validators: 4, ProxiesPerValidator: 1, FullNodes: 2, LightClients: 2},
+	"standby": {Validators: 4, ProxiesPerValidator: 2, FullNodes: 2, LightClients: 2},
+	"single":  {Validators: 1, ProxiesPerValidator: 1, FullNodes: 1, LightClients: 1},
+}
+
+// TemplateNames returns the sorted names of the topology templates.
+func TemplateNames() []string {
+	names := make([]string, 0, len(Templates))
+	for name := range Templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate checks whether the topology can be built.
+func (t Topology) Validate() error {
+	switch {
+	case t.Validators < 1:
+		return errors.New("at least one validator is required")
+	case t.ProxiesPerValidator < 0 || t.FullNodes < 0 || t.LightClients < 0:
+		return errors.New("negative number of nodes")
+	case t.LightClients > 0 && t.FullNodes == 0:
+		return errors.New("light clients need a full node to connect to")
+	}
+	return nil
+}
+
+// Layout holds the nodes of a network built from a topology, by role.
+type Layout struct {
+	Validators   []enode.ID
+	Proxies      map[enode.ID][]enode.ID // Proxies of each proxied validator
+	FullNodes    []enode.ID
+	LightClients []enode.ID
+
+	Addresses map[enode.ID]common.Address // Validator address of each validator
+
+	spec   *Spec
+	lock   sync.Mutex
+	active map[enode.ID]int // Index of the proxy each validator peers with, if not the first
+}
+
+// Proxied reports whether the validator runs behind a proxy.
+func (l *Layout) Proxied(validator enode.ID) bool {
+	return len(l.Proxies[validator]) > 0
+}
+
+// IsProxy reports whether the node is one of the proxies of the validator.
+func (l *Layout) IsProxy(validator, id enode.ID) bool {
+	for _, proxy := range l.Proxies[validator] {
+		if proxy == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Relays returns the nodes which relay consensus and announce messages, that is
+// every node but the light clients.
+func (l *Layout) Relays() []enode.ID {
+	ids := append([]enode.ID{}, l.Validators...)
+	for _, validator := range l.Validators {
+		ids = append(ids, l.Proxies[validator]...)
+	}
+	return append(ids, l.FullNodes...)
+}
+
+// Failover stops the proxy a running validator peers with and makes the
+// validator peer with its next proxy instead.
+func (l *Layout) Failover(net *simulations.Network, validator enode.ID) error {
+	proxies := l.Proxies[validator]
+	if len(proxies) < 2 {
+		return fmt.Errorf("validator %s has no standby proxy", validator.TerminalString())
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	current := l.active[validator]
+	if current == len(proxies)-1 {
+		return fmt.Errorf("validator %s has no standby proxy left", validator.TerminalString())
+	}
+	if err := l.spec.switchProxy(net, validator, proxies[current+1]); err != nil {
+		return err
+	}
+	l.active[validator] = current + 1
+	return net.Stop(proxies[current])
+}
+
+// Build creates the nodes of the topology in the network, with the services of
+// the spec, and returns their layout. The nodes are neither started nor
+// connected, see Layout.Connect.
+func (t Topology) Build(net *simulations.Network, spec *Spec) (*Layout, error) {
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+	if len(spec.ValidatorKeys) != t.Validators {
+		return nil, fmt.Errorf("spec has %d validators, topology %d", len(spec.ValidatorKeys), t.Validators)
+	}
+	layout := &Layout{
+		Proxies:   make(map[enode.ID][]enode.ID),
+		Addresses: make(map[enode.ID]common.Address),
+		spec:      spec,
+		active:    make(map[enode.ID]int),
+	}
+	for i, key := range spec.ValidatorKeys {
+		conf := adapters.RandomNodeConfig()
+		conf.PrivateKey = key
+		conf.ID = enode.PubkeyToIDV4(&key.PublicKey)
+		conf.Name = fmt.Sprintf("validator%02d", i)
+		conf.Services = []string{ServiceValidator}
+		conf.Properties = []string{ServiceValidator}
+
+		for j := 0; j < t.ProxiesPerValidator; j++ {
+			proxy := adapters.RandomNodeConfig()
+			proxy.Name = fmt.Sprintf("proxy%02d-%d", i, j)
+			proxy.Services = []string{ServiceProxy}
+			proxy.Properties = []string{ServiceProxy}
+			proxy.Proxy = true
+			port, err := freePort()
+			if err != nil {
+				return nil, err
+			}
+			proxy.ProxyPort = port
+			spec.AddProxy(key, proxy)
+			if _, err := net.NewNodeWithConfig(proxy); err != nil {
+				return nil, err
+			}
+			layout.Proxies[conf.ID] = append(layout.Proxies[conf.ID], proxy.ID)
+		}
+		if _, err := net.NewNodeWithConfig(conf); err != nil {
+			return nil, err
+		}
+		layout.Validators = append(layout.Validators, conf.ID)
+		layout.Addresses[conf.ID] = crypto.PubkeyToAddress(key.PublicKey)
+	}
+	for i := 0; i < t.FullNodes; i++ {
+		conf := adapters.RandomNodeConfig()
+		conf.Name = fmt.Sprintf("fullnode%02d", i)
+		conf.Services = []string{ServiceFullNode}
+		conf.Properties = []string{ServiceFullNode}
+		if _, err := net.NewNodeWithConfig(conf); err != nil {
+			return nil, err
+		}
+		layout.FullNodes = append(layout.FullNodes, conf.ID)
+	}
+	for i := 0; i < t.LightClients; i++ {
+		conf := adapters.RandomNodeConfig()
+		conf.Name = fmt.Sprintf("light%02d", i)
+		conf.Services = []string{ServiceLightClient}
+		conf.Properties = []string{ServiceLightClient}
+		if _, err := net.NewNodeWithConfig(conf); err != nil {
+			return nil, err
+		}
+		layout.LightClients = append(layout.LightClients, conf.ID)
+	}
+	return layout, nil
+}
+
+// Start starts the nodes of the layout, proxies before the validators dialing
+// them.
+func (l *Layout) Start(net *simulations.Network) error {
+	for _, validator := range l.Validators {
+		for _, proxy := range l.Proxies[validator] {
+			if err := net.Start(proxy); err != nil {
+				return err
+			}
+		}
+	}
+	ids := append(append(append([]enode.ID{}, l.Validators...), l.FullNodes...), l.LightClients...)
+	for _, id := range ids {
+		if err := net.Start(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Connect connects the started nodes of the layout. Proxied validators connect
+// to their proxies by themselves, so they are left out of the mesh.
+func (l *Layout) Connect(net *simulations.Network) error {
+	var mesh []enode.ID
+	for _, validator := range l.Validators {
+		if l.Proxied(validator) {
+			mesh = append(mesh, l.Proxies[validator]...)
+		} else {
+			mesh = append(mesh, validator)
+		}
+	}
+	mesh = append(mesh, l.FullNodes...)
+	for i := range mesh {
+		for j := i + 1; j < len(mesh); j++ {
+			if err := connect(net, mesh[i], mesh[j]); err != nil {
+				return err
+			}
+		}
+	}
+	for i, light := range l.LightClients {
+		if err := connect(net, light, l.FullNodes[i%len(l.FullNodes)]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// connect connects two nodes, ignoring connections made by the nodes already.
+func connect(net *simulations.Network, one, other enode.ID) error {
+	if err := net.Connect(one, other); err != nil && !strings.Contains(err.Error(), "already connected") {
+		return err
+	}
+	return nil
+}
+
+// freePort returns a free TCP port of the loopback interface.
+func freePort() (uint16, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return uint16(l.Addr().(*net.TCPAddr).Port), nil
+}
@@ -235,16 +235,16 @@ var (
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllEthashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, new(EthashConfig), nil, nil, true}
+	AllEthashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, new(EthashConfig), nil, nil, true}
 
 	// AllCliqueProtocolChanges contains every protocol change (EIPs) introduced
 	// and accepted by the Ethereum core developers into the Clique consensus.
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, &CliqueConfig{Period: 0, Epoch: 30000}, nil, true}
+	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, nil, &CliqueConfig{Period: 0, Epoch: 30000}, nil, true}
 
-	TestChainConfig = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, new(EthashConfig), nil, nil, true}
+	TestChainConfig = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, new(EthashConfig), nil, nil, true}
 	TestRules       = TestChainConfig.Rules(new(big.Int))
 )
 
@@ -316,6 +316,11 @@ type ChainConfig struct {
 	IstanbulBlock       *big.Int `json:"istanbulBlock,omitempty"`       // Istanbul switch block (nil = no fork, 0 = already on istanbul)
 	EWASMBlock          *big.Int `json:"ewasmBlock,omitempty"`          // EWASM switch block (nil = no fork, 0 = already activated)
 
+	// Celo specific protocol changes
+	BatchedFeesBlock *big.Int `json:"batchedFeesBlock,omitempty"` // Batched fee currency credits switch block (nil = no fork, 0 = already activated)
+	TypedTxBlock     *big.Int `json:"typedTxBlock,omitempty"`     // Typed transaction envelope switch block (nil = no fork, 0 = already activated)
+	BaseFeeBlock     *big.Int `json:"baseFeeBlock,omitempty"`     // Dynamic base fee switch block (nil = no fork, 0 = already activated)
+
 	// Various consensus engines
 	Ethash   *EthashConfig   `json:"ethash,omitempty"`
 	Clique   *CliqueConfig   `json:"clique,omitempty"`
@@ -369,7 +374,7 @@ func (c *ChainConfig) String() string {
 	default:
 		engine = "unknown"
 	}
-	return fmt.Sprintf("{ChainID: %v Homestead: %v DAO: %v DAOSupport: %v EIP150: %v EIP155: %v EIP158: %v Byzantium: %v Constantinople: %v Petersburg: %v Istanbul: %v Engine: %v}",
+	return fmt.Sprintf("{ChainID: %v Homestead: %v DAO: %v DAOSupport: %v EIP150: %v EIP155: %v EIP158: %v Byzantium: %v Constantinople: %v Petersburg: %v Istanbul: %v BatchedFees: %v TypedTx: %v BaseFee: %v Engine: %v}",
 		c.ChainID,
 		c.HomesteadBlock,
 		c.DAOForkBlock,
@@ -381,6 +386,9 @@ func (c *ChainConfig) String() string {
 		c.ConstantinopleBlock,
 		c.PetersburgBlock,
 		c.IstanbulBlock,
+		c.BatchedFeesBlock,
+		c.TypedTxBlock,
+		c.BaseFeeBlock,
 		engine,
 	)
 }
@@ -437,6 +445,21 @@ func (c *ChainConfig) IsEWASM(num *big.Int) bool {
 	return isForked(c.EWASMBlock, num)
 }
 
+// IsBatchedFees returns whether num is either equal to the batched fees fork block or greater.
+func (c *ChainConfig) IsBatchedFees(num *big.Int) bool {
+	return isForked(c.BatchedFeesBlock, num)
+}
+
+// IsTypedTx returns whether num is either equal to the typed transaction fork block or greater.
+func (c *ChainConfig) IsTypedTx(num *big.Int) bool {
+	return isForked(c.TypedTxBlock, num)
+}
+
+// IsBaseFee returns whether num is either equal to the dynamic base fee fork block or greater.
+func (c *ChainConfig) IsBaseFee(num *big.Int) bool {
+	return isForked(c.BaseFeeBlock, num)
+}
+
 // CheckCompatible checks whether scheduled fork transitions have been imported
 // with a mismatching chain configuration.
 func (c *ChainConfig) CheckCompatible(newcfg *ChainConfig, height uint64) *ConfigCompatError {
@@ -528,6 +551,15 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	if isForkIncompatible(c.EWASMBlock, newcfg.EWASMBlock, head) {
 		return newCompatError("ewasm fork block", c.EWASMBlock, newcfg.EWASMBlock)
 	}
+	if isForkIncompatible(c.BatchedFeesBlock, newcfg.BatchedFeesBlock, head) {
+		return newCompatError("batched fees fork block", c.BatchedFeesBlock, newcfg.BatchedFeesBlock)
+	}
+	if isForkIncompatible(c.TypedTxBlock, newcfg.TypedTxBlock, head) {
+		return newCompatError("typed transaction fork block", c.TypedTxBlock, newcfg.TypedTxBlock)
+	}
+	if isForkIncompatible(c.BaseFeeBlock, newcfg.BaseFeeBlock, head) {
+		return newCompatError("base fee fork block", c.BaseFeeBlock, newcfg.BaseFeeBlock)
+	}
 	return nil
 }
 
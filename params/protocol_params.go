@@ -146,6 +146,12 @@ const (
 	HashHeaderGas               uint64 = 20000 // Cost of hashing a block header.
 	GetParentSealBitmapGas      uint64 = 500   // Cost of reading the parent seal bitmap from the chain.
 	GetVerifiedSealBitmapGas    uint64 = 55000 // Cost of verifying the seal on a given RLP encoded header.
+
+	// Celo base fee market
+	BaseFeeChangeDenominator uint64 = 8         // Bounds the amount the base fee can change between blocks.
+	ElasticityMultiplier     uint64 = 2         // Bounds the maximum gas limit a block may have relative to its gas target.
+	InitialBaseFee           uint64 = 500000000 // Base fee of the first block after the base fee fork, in CELO wei.
+	MinimumBaseFee           uint64 = 100000000 // The minimum that the base fee may ever be, in CELO wei.
 )
 
 var (
@@ -202,6 +208,7 @@ const (
 	MaxGasForCommitments                           uint64 = 2000000
 	MaxGasForComputeCommitment                     uint64 = 2000000
 	MaxGasForCreditToTransactions                  uint64 = 100000
+	MaxGasForCreditToManyPerRecipient              uint64 = 50000
 	MaxGasForDebitFromTransactions                 uint64 = 100000
 	MaxGasForDistributeEpochPayment                uint64 = 1 * 1000000
 	MaxGasForDistributeEpochRewards                uint64 = 1 * 1000000
@@ -0,0 +1,127 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package apitypes
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// CeloTypes are the definitions of the Celo types typed data can reference
+// without defining them.
+var CeloTypes = Types{
+	"CeloTransaction": []Type{
+		{Name: "nonce", Type: "uint256"},
+		{Name: "gasPrice", Type: "uint256"},
+		{Name: "gas", Type: "uint256"},
+		{Name: "feeCurrency", Type: "address"},
+		{Name: "gatewayFeeRecipient", Type: "address"},
+		{Name: "gatewayFee", Type: "uint256"},
+		{Name: "to", Type: "address"},
+		{Name: "value", Type: "uint256"},
+		{Name: "data", Type: "bytes"},
+	},
+}
+
+// AddCeloTypes adds the definitions of the Celo types the typed data references
+// without defining them. If the domain type isn't defined either, it is defined
+// with the fields set in the domain and the chain ID, so that SetChainID binds
+// the typed data to the chain even if the domain omits it.
+func (typedData *TypedData) AddCeloTypes() {
+	if typedData.Types == nil {
+		typedData.Types = make(Types)
+	}
+	if _, ok := typedData.Types["EIP712Domain"]; !ok {
+		typedData.Types["EIP712Domain"] = typedData.Domain.types()
+	}
+	seen := map[string]bool{typedData.PrimaryType: true}
+	for pending := []string{typedData.PrimaryType}; len(pending) > 0; {
+		name := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		fields, ok := typedData.Types[name]
+		if !ok {
+			if fields, ok = CeloTypes[name]; !ok {
+				continue
+			}
+			typedData.Types[name] = fields
+		}
+		for _, field := range fields {
+			if ref := field.typeName(); field.isReferenceType() && !seen[ref] {
+				seen[ref] = true
+				pending = append(pending, ref)
+			}
+		}
+	}
+}
+
+// SetChainID sets the chain ID of the domain if missing and declared by the
+// domain type, and otherwise checks it matches the given one.
+func (typedData *TypedData) SetChainID(chainID *big.Int) error {
+	if typedData.Domain.ChainId == nil {
+		for _, field := range typedData.Types["EIP712Domain"] {
+			if field.Name == "chainId" {
+				typedData.Domain.ChainId = (*math.HexOrDecimal256)(new(big.Int).Set(chainID))
+				break
+			}
+		}
+		return nil
+	}
+	if have := (*big.Int)(typedData.Domain.ChainId); have.Cmp(chainID) != 0 {
+		return fmt.Errorf("chainId mismatch: have %v, want %v", have, chainID)
+	}
+	return nil
+}
+
+// TypedDataAndHash returns the EIP-712 hash of the typed data, and the data it
+// hashes:
+// hash = keccak256("\x19${byteVersion}${domainSeparator}${hashStruct(message)}")
+func TypedDataAndHash(typedData TypedData) ([]byte, []byte, error) {
+	domainSeparator, err := typedData.HashStruct("EIP712Domain", typedData.Domain.Map())
+	if err != nil {
+		return nil, nil, err
+	}
+	typedDataHash, err := typedData.HashStruct(typedData.PrimaryType, typedData.Message)
+	if err != nil {
+		return nil, nil, err
+	}
+	rawData := []byte(fmt.Sprintf("\x19\x01%s%s", string(domainSeparator), string(typedDataHash)))
+	return crypto.Keccak256(rawData), rawData, nil
+}
+
+// types returns the definition of the domain type with the chain ID and the
+// other fields set in the domain, in the order of EIP-712.
+func (domain *TypedDataDomain) types() []Type {
+	var fields []Type
+	if len(domain.Name) > 0 {
+		fields = append(fields, Type{Name: "name", Type: "string"})
+	}
+	if len(domain.Version) > 0 {
+		fields = append(fields, Type{Name: "version", Type: "string"})
+	}
+	fields = append(fields, Type{Name: "chainId", Type: "uint256"})
+	if len(domain.VerifyingContract) > 0 {
+		fields = append(fields, Type{Name: "verifyingContract", Type: "address"})
+	}
+	if len(domain.Salt) > 0 {
+		fields = append(fields, Type{Name: "salt", Type: "bytes32"})
+	}
+	return fields
+}
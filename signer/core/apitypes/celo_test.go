@@ -0,0 +1,127 @@
+// Copyright 2020 The Celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package apitypes
+
+import (
+	"bytes"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func celoTypedData() TypedData {
+	return TypedData{
+		Types: Types{
+			"Payment": []Type{
+				{Name: "memo", Type: "string"},
+				{Name: "tx", Type: "CeloTransaction"},
+			},
+		},
+		PrimaryType: "Payment",
+		Domain:      TypedDataDomain{Name: "Celo Payments", Version: "1"},
+		Message: TypedDataMessage{
+			"memo": "Coffee",
+			"tx": map[string]interface{}{
+				"nonce":               "1",
+				"gasPrice":            "1000000000",
+				"gas":                 "21000",
+				"feeCurrency":         "0x765DE816845861e75A25fCA122bb6898B8B1282a",
+				"gatewayFeeRecipient": "0x0000000000000000000000000000000000000000",
+				"gatewayFee":          "0",
+				"to":                  "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB",
+				"value":               "1000000000000000000",
+				"data":                "0x",
+			},
+		},
+	}
+}
+
+func TestAddCeloTypes(t *testing.T) {
+	typedData := celoTypedData()
+	typedData.AddCeloTypes()
+
+	if !reflect.DeepEqual(typedData.Types["CeloTransaction"], CeloTypes["CeloTransaction"]) {
+		t.Errorf("CeloTransaction type not added: have %v", typedData.Types["CeloTransaction"])
+	}
+	domainType := []Type{{Name: "name", Type: "string"}, {Name: "version", Type: "string"}, {Name: "chainId", Type: "uint256"}}
+	if !reflect.DeepEqual(typedData.Types["EIP712Domain"], domainType) {
+		t.Errorf("domain type mismatch: have %v, want %v", typedData.Types["EIP712Domain"], domainType)
+	}
+	if want := "Payment(string memo,CeloTransaction tx)CeloTransaction(uint256 nonce,uint256 gasPrice,uint256 gas,address feeCurrency,address gatewayFeeRecipient,uint256 gatewayFee,address to,uint256 value,bytes data)"; string(typedData.EncodeType("Payment")) != want {
+		t.Errorf("encoded type mismatch: have %s, want %s", typedData.EncodeType("Payment"), want)
+	}
+
+	// Types defined by the typed data take precedence
+	custom := celoTypedData()
+	custom.Types["CeloTransaction"] = []Type{{Name: "nonce", Type: "uint256"}}
+	custom.AddCeloTypes()
+	if len(custom.Types["CeloTransaction"]) != 1 {
+		t.Errorf("defined CeloTransaction type replaced")
+	}
+}
+
+func TestSetChainID(t *testing.T) {
+	// The chain ID isn't defaulted if the domain type defined by the typed data
+	// doesn't declare it
+	declared := celoTypedData()
+	declared.Types["EIP712Domain"] = []Type{{Name: "name", Type: "string"}, {Name: "version", Type: "string"}}
+	declared.AddCeloTypes()
+	if err := declared.SetChainID(big.NewInt(42220)); err != nil {
+		t.Fatalf("failed to set chain ID: %v", err)
+	}
+	if declared.Domain.ChainId != nil {
+		t.Fatalf("undeclared chain ID set: %v", declared.Domain.ChainId)
+	}
+
+	// Typed data omitting both the domain type and the chain ID is bound to the chain
+	typedData := celoTypedData()
+	typedData.AddCeloTypes()
+	if _, _, err := TypedDataAndHash(typedData); err == nil {
+		t.Fatal("typed data without chain ID hashed")
+	}
+	if err := typedData.SetChainID(big.NewInt(42220)); err != nil {
+		t.Fatalf("failed to set chain ID: %v", err)
+	}
+	if have := (*big.Int)(typedData.Domain.ChainId); have == nil || have.Int64() != 42220 {
+		t.Fatalf("chain ID mismatch: have %v, want %v", have, 42220)
+	}
+	if err := typedData.SetChainID(big.NewInt(44787)); err == nil {
+		t.Fatal("chain ID mismatch accepted")
+	}
+	hash, rawData, err := TypedDataAndHash(typedData)
+	if err != nil {
+		t.Fatalf("failed to hash typed data: %v", err)
+	}
+	if len(hash) != 32 || len(rawData) != 66 || !bytes.HasPrefix(rawData, []byte("\x19\x01")) {
+		t.Errorf("typed data hash mismatch: hash %x, data %x", hash, rawData)
+	}
+	other := celoTypedData()
+	other.AddCeloTypes()
+	if err := other.SetChainID(big.NewInt(44787)); err != nil {
+		t.Fatalf("failed to set chain ID: %v", err)
+	}
+	if otherHash, _, err := TypedDataAndHash(other); err != nil || bytes.Equal(hash, otherHash) {
+		t.Errorf("typed data hash not bound to the chain: have %x, err %v", otherHash, err)
+	}
+	messages, err := typedData.Format()
+	if err != nil {
+		t.Fatalf("failed to format typed data: %v", err)
+	}
+	if tx := messages[1].Value.([]*NameValueType)[1]; tx.Typ != "CeloTransaction" {
+		t.Errorf("formatted transaction type mismatch: have %s", tx.Typ)
+	}
+}